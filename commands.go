@@ -0,0 +1,402 @@
+package main
+
+import (
+	"agnos/internal/entities"
+	"agnos/internal/migrations"
+	"agnos/internal/routes"
+	usecasesPatient "agnos/internal/usecases/patient"
+	"agnos/internal/usecases/synthetic"
+	"agnos/pkg/encryption"
+	"agnos/pkg/migrate"
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+// printJSON writes a command's result to stdout.
+func printJSON(result interface{}) error {
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(encoded))
+	return nil
+}
+
+// subcommand splits args into the subcommand name and its arguments.
+func subcommand(args []string, usage string) (string, []string, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return "", nil, errors.New("usage: " + usage)
+	}
+	return args[0], args[1:], nil
+}
+
+// validate reports the struct's validation errors in the same form as the
+// HTTP handlers.
+func validate(data interface{}) error {
+	if err := validator.New().Struct(data); err != nil {
+		errs := err.(validator.ValidationErrors)
+		messages := make([]string, 0)
+		for _, e := range errs {
+			messages = append(messages, fmt.Sprintf("%s is %s", strings.ToLower(e.Field()), strings.ToLower(e.Tag())))
+		}
+		return errors.New(strings.Join(messages, ", "))
+	}
+	return nil
+}
+
+// readPassword takes the password from the first line of stdin when asked
+// to, so that it never shows in the process list or shell history, and
+// otherwise generates one. generated tells the caller to print it.
+func readPassword(fromStdin bool, stdin io.Reader) (password string, generated bool, err error) {
+	if !fromStdin {
+		password, err = randomPassword()
+		return password, true, err
+	}
+	line, err := bufio.NewReader(stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", false, err
+	}
+	password = strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", false, errors.New("password on stdin is empty")
+	}
+	return password, false, nil
+}
+
+func randomPassword() (string, error) {
+	buf := make([]byte, 18)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// runMigrate handles `migrate up`, `migrate down [steps]` and
+// `migrate status`.
+func runMigrate(ctx context.Context, db *gorm.DB, args []string) error {
+	name, args, err := subcommand(args, "migrate up|down [steps]|status")
+	if err != nil {
+		return err
+	}
+	migrator, err := migrations.New(db)
+	if err != nil {
+		return err
+	}
+	switch name {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		return printJSON(migrationNames(applied))
+	case "down":
+		steps := 1
+		if len(args) > 0 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				return fmt.Errorf("steps must be a positive number, got %q", args[0])
+			}
+			steps = n
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		return printJSON(migrationNames(reverted))
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		return printJSON(statuses)
+	}
+	return fmt.Errorf("unknown migrate command %q", name)
+}
+
+func migrationNames(done []migrate.Migration) []string {
+	names := make([]string, 0, len(done))
+	for _, migration := range done {
+		names = append(names, migration.String())
+	}
+	return names
+}
+
+// runStaff handles `staff create`, `staff reset-password` and
+// `staff deactivate`.
+func runStaff(ctx context.Context, db *gorm.DB, args []string) error {
+	name, args, err := subcommand(args, "staff create|reset-password|deactivate [flags]")
+	if err != nil {
+		return err
+	}
+	staffService := routes.StaffService(db)
+
+	flags := flag.NewFlagSet("staff "+name, flag.ContinueOnError)
+	username := flags.String("username", "", "staff username")
+	switch name {
+	case "create":
+		hospital := flags.String("hospital", "", "hospital the staff works at")
+		role := flags.String("role", entities.RoleReceptionist, "admin, clinician or receptionist")
+		passwordStdin := flags.Bool("password-stdin", false, "read the password from stdin instead of generating one")
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		password, generated, err := readPassword(*passwordStdin, os.Stdin)
+		if err != nil {
+			return err
+		}
+		staff := &entities.Staff{Username: *username, Password: password, Hospital: *hospital, Role: *role}
+		if err := validate(staff); err != nil {
+			return err
+		}
+		created, err := staffService.CreateStaff(ctx, staff)
+		if err != nil {
+			return err
+		}
+		result := map[string]interface{}{"id": created.ID, "username": created.Username, "hospital": created.Hospital, "role": created.Role}
+		if generated {
+			result["password"] = password
+		}
+		return printJSON(result)
+	case "reset-password":
+		passwordStdin := flags.Bool("password-stdin", false, "read the new password from stdin instead of generating one")
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		if *username == "" {
+			return errors.New("username is required")
+		}
+		password, generated, err := readPassword(*passwordStdin, os.Stdin)
+		if err != nil {
+			return err
+		}
+		if err := staffService.ResetPassword(ctx, *username, password); err != nil {
+			return err
+		}
+		result := map[string]interface{}{"username": *username}
+		if generated {
+			result["password"] = password
+		}
+		return printJSON(result)
+	case "deactivate":
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		if *username == "" {
+			return errors.New("username is required")
+		}
+		if err := staffService.DeactivateStaff(ctx, *username); err != nil {
+			return err
+		}
+		return printJSON(map[string]interface{}{"username": *username, "deactivated": true})
+	}
+	return fmt.Errorf("unknown staff command %q", name)
+}
+
+// runHospital handles `hospital create`.
+func runHospital(ctx context.Context, db *gorm.DB, args []string) error {
+	name, args, err := subcommand(args, "hospital create --name NAME")
+	if err != nil {
+		return err
+	}
+	if name != "create" {
+		return fmt.Errorf("unknown hospital command %q", name)
+	}
+	flags := flag.NewFlagSet("hospital create", flag.ContinueOnError)
+	hospitalName := flags.String("name", "", "hospital name, as staff and patients refer to it")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	hospital := &entities.Hospital{Name: *hospitalName}
+	if err := validate(hospital); err != nil {
+		return err
+	}
+	created, err := routes.HospitalService(db).CreateHospital(ctx, hospital)
+	if err != nil {
+		return err
+	}
+	return printJSON(created)
+}
+
+// runSeed registers a demo hospital with one account per role and some
+// synthetic patients, all through the same use cases as the API.
+func runSeed(ctx context.Context, db *gorm.DB, args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	hospitalName := flags.String("hospital", "Demo Hospital", "hospital to create the demo data in")
+	count := flags.Int("patients", 20, "number of synthetic patients")
+	seed := flags.Uint64("seed", 1, "seed for the synthetic patients")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	hospitals, err := routes.HospitalService(db).ListHospitals(ctx)
+	if err != nil {
+		return err
+	}
+	for _, hospital := range hospitals {
+		if strings.EqualFold(hospital.Name, *hospitalName) {
+			return fmt.Errorf("hospital %s already exist; seed only fills a new hospital", hospital.Name)
+		}
+	}
+	hospital, err := routes.HospitalService(db).CreateHospital(ctx, &entities.Hospital{Name: *hospitalName})
+	if err != nil {
+		return err
+	}
+
+	password, err := randomPassword()
+	if err != nil {
+		return err
+	}
+	prefix := strings.ToLower(strings.Join(strings.Fields(hospital.Name), "-"))
+	staffService := routes.StaffService(db)
+	usernames := make([]string, 0)
+	for _, role := range []string{entities.RoleAdmin, entities.RoleClinician, entities.RoleReceptionist} {
+		staff := &entities.Staff{Username: prefix + "-" + role, Password: password, Hospital: hospital.Name, Role: role}
+		if _, err := staffService.CreateStaff(ctx, staff); err != nil {
+			return fmt.Errorf("staff %s: %w", staff.Username, err)
+		}
+		usernames = append(usernames, staff.Username)
+	}
+
+	generator, err := synthetic.NewGenerator(synthetic.Options{Seed: *seed, Hospitals: []string{hospital.Name}})
+	if err != nil {
+		return err
+	}
+	patientService := routes.PatientService(db)
+	created := 0
+	for i := 0; i < *count; i++ {
+		patient := generator.Patient()
+		if messages := usecasesPatient.ValidatePatient(patient); len(messages) > 0 {
+			return fmt.Errorf("patient %s: %s", patient.PatientHn, strings.Join(messages, ", "))
+		}
+		if _, err := patientService.CreatePatient(ctx, patient); err != nil {
+			return fmt.Errorf("patient %s: %w", patient.PatientHn, err)
+		}
+		created++
+	}
+
+	return printJSON(map[string]interface{}{
+		"hospital": hospital.Name,
+		"staff":    usernames,
+		"password": password,
+		"patients": created,
+	})
+}
+
+// runGenerate handles `generate`, which writes synthetic patients to the
+// database, CSV or JSON. The same flags always give the same patients.
+func runGenerate(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("generate", flag.ContinueOnError)
+	count := flags.Int("count", 100, "number of patients")
+	seed := flags.Uint64("seed", 1, "random seed")
+	hospitals := flags.String("hospitals", "", "comma separated hospitals to spread the patients across")
+	format := flags.String("format", "csv", "db, csv or json")
+	output := flags.String("output", "-", "file to write csv or json to, - for stdout")
+	asOf := flags.String("as-of", synthetic.DefaultAsOf.Format("2006-01-02"), "date that ages are counted from")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	options := synthetic.Options{Seed: *seed}
+	for _, hospital := range strings.Split(*hospitals, ",") {
+		if hospital = strings.TrimSpace(hospital); hospital != "" {
+			options.Hospitals = append(options.Hospitals, hospital)
+		}
+	}
+	var err error
+	if options.AsOf, err = time.Parse("2006-01-02", *asOf); err != nil {
+		return fmt.Errorf("as-of must be a date like 2025-01-01, got %q", *asOf)
+	}
+	generator, err := synthetic.NewGenerator(options)
+	if err != nil {
+		return err
+	}
+
+	var writer synthetic.Writer
+	var out io.Writer = os.Stdout
+	if *format != "db" && *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	switch *format {
+	case "db":
+		db := openMigratedDatabase()
+		loadKeyring(db)
+		writer = synthetic.NewRepositoryWriter(ctx, routes.PatientRepository(db))
+	case "csv":
+		writer = synthetic.NewCsvWriter(out)
+	case "json":
+		writer = synthetic.NewJsonWriter(out)
+	default:
+		return fmt.Errorf("format must be db, csv or json, got %q", *format)
+	}
+
+	for i := 0; i < *count; i++ {
+		patient := generator.Patient()
+		if err := writer.Write(patient); err != nil {
+			return fmt.Errorf("patient %s: %w", patient.PatientHn, err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	if *format == "db" {
+		return printJSON(map[string]interface{}{"patients": *count, "hospitals": options.Hospitals})
+	}
+	return nil
+}
+
+// runKeys handles `keys rotate`.
+func runKeys(ctx context.Context, db *gorm.DB, keyring *encryption.Keyring, args []string) error {
+	name, _, err := subcommand(args, "keys rotate")
+	if err != nil {
+		return err
+	}
+	if name != "rotate" {
+		return fmt.Errorf("unknown keys command %q", name)
+	}
+	result, err := routes.KeyService(db, keyring).RotateKeys(ctx)
+	if err != nil {
+		return err
+	}
+	return printJSON(result)
+}
+
+// runAudit handles `audit verify`, which fails when any chain is broken.
+func runAudit(ctx context.Context, db *gorm.DB, args []string) error {
+	name, _, err := subcommand(args, "audit verify")
+	if err != nil {
+		return err
+	}
+	if name != "verify" {
+		return fmt.Errorf("unknown audit command %q", name)
+	}
+	result, err := routes.AuditService(db).Verify(ctx)
+	if err != nil {
+		return err
+	}
+	if err := printJSON(result); err != nil {
+		return err
+	}
+	if !result.Valid() {
+		return fmt.Errorf("%d audit log entries failed verification", len(result.Broken))
+	}
+	return nil
+}
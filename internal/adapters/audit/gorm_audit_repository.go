@@ -0,0 +1,78 @@
+package adapters
+
+import (
+	"agnos/internal/entities"
+	"agnos/internal/usecases/audit"
+	"agnos/pkg/database"
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// auditChainLockKey and the hashed chain key identify the lock in
+// pg_advisory_xact_lock that keeps a hospital's chain linear.
+const auditChainLockKey int32 = 0x61756474
+
+type GormAuditRepository struct {
+	db *gorm.DB
+}
+
+func NewGormAuditRepository(db *gorm.DB) audit.AuditRepository {
+	return &GormAuditRepository{db: db}
+}
+
+// Save appends entry to its hospital's chain. Reading the previous hash and
+// inserting happen under a lock held until the outermost transaction
+// commits, so that concurrent writers never fork the chain.
+func (r *GormAuditRepository) Save(ctx context.Context, entry *entities.AuditLog) (*entities.AuditLog, error) {
+	err := database.Transaction(r.db.WithContext(ctx), func(tx *gorm.DB) error {
+		if err := lockChain(tx, entry.ChainKey()); err != nil {
+			return err
+		}
+		var last entities.AuditLog
+		err := tx.Unscoped().Select("hash").Where(database.EqualFold(tx, "hospital", entry.ChainKey())).Order("id DESC").Take(&last).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		entry.Seal(last.Hash, time.Now())
+		return tx.Create(entry).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// lockChain takes the lock on the chain called key. Postgres releases its
+// advisory lock at commit, and database.Transaction releases the MySQL named
+// lock after it. SQLite needs none, as its transactions take the database's
+// write lock when they begin.
+func lockChain(tx *gorm.DB, key string) error {
+	switch tx.Dialector.Name() {
+	case database.Postgres:
+		return tx.Exec("SELECT pg_advisory_xact_lock(?, hashtext(?))", auditChainLockKey, key).Error
+	case database.MySQL:
+		// Lock names are limited to 64 characters.
+		name := fmt.Sprintf("agnos_audit_%x", sha1.Sum([]byte(key)))
+		var locked sql.NullInt64
+		if err := tx.Raw("SELECT GET_LOCK(?, -1)", name).Row().Scan(&locked); err != nil {
+			return err
+		}
+		if locked.Int64 != 1 {
+			return fmt.Errorf("could not lock the audit chain of %q", key)
+		}
+	}
+	return nil
+}
+
+func (r *GormAuditRepository) FindInBatches(ctx context.Context, batchSize int, fn func(entries []*entities.AuditLog) error) error {
+	var entries []*entities.AuditLog
+	return r.db.WithContext(ctx).Unscoped().Order("id").FindInBatches(&entries, batchSize, func(tx *gorm.DB, batch int) error {
+		return fn(entries)
+	}).Error
+}
@@ -0,0 +1,85 @@
+package adapters
+
+import (
+	"agnos/internal/entities"
+	"agnos/internal/usecases/audit"
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MemoryAuditRepository keeps audit entries in a slice, chained per
+// hospital like GormAuditRepository.
+type MemoryAuditRepository struct {
+	mu      sync.RWMutex
+	entries []entities.AuditLog
+	nextId  uint
+}
+
+func NewMemoryAuditRepository() audit.AuditRepository {
+	return &MemoryAuditRepository{}
+}
+
+func (r *MemoryAuditRepository) Save(ctx context.Context, entry *entities.AuditLog) (*entities.AuditLog, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var prevHash string
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		if r.entries[i].ChainKey() == entry.ChainKey() {
+			prevHash = r.entries[i].Hash
+			break
+		}
+	}
+	now := time.Now()
+	entry.Seal(prevHash, now)
+	r.nextId++
+	entry.ID = r.nextId
+	entry.UpdatedAt = now
+	r.entries = append(r.entries, *entry)
+	return entry, nil
+}
+
+func (r *MemoryAuditRepository) FindInBatches(ctx context.Context, batchSize int, fn func(entries []*entities.AuditLog) error) error {
+	r.mu.RLock()
+	entries := make([]*entities.AuditLog, 0, len(r.entries))
+	for _, stored := range r.entries {
+		entries = append(entries, &stored)
+	}
+	r.mu.RUnlock()
+
+	for batch := range slices.Chunk(entries, batchSize) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Snapshot returns a function that puts the repository back as it is now,
+// for an in-memory unit of work that rolls back.
+func (r *MemoryAuditRepository) Snapshot() (restore func()) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entries, nextId := slices.Clone(r.entries), r.nextId
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.entries, r.nextId = entries, nextId
+	}
+}
+
+// Remove deletes the entries with ids for good, for the in-memory retention
+// repository.
+func (r *MemoryAuditRepository) Remove(ids ...uint) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = slices.DeleteFunc(r.entries, func(e entities.AuditLog) bool { return slices.Contains(ids, e.ID) })
+}
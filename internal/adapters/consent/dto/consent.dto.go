@@ -0,0 +1,20 @@
+package dto
+
+type GrantConsentDto struct {
+	PatientId  uint   `json:"patient_id" validate:"required"`
+	Purpose    string `json:"purpose" validate:"required,oneof=data_sharing marketing research"`
+	Version    string `json:"version" validate:"required"`
+	Channel    string `json:"channel" validate:"required,oneof=web paper kiosk verbal"`
+	Hospital   string `json:"-"`
+	RecordedBy string `json:"-"`
+	Role       string `json:"-"`
+}
+
+type WithdrawConsentDto struct {
+	PatientId  uint   `json:"patient_id" validate:"required"`
+	Purpose    string `json:"purpose" validate:"required,oneof=data_sharing marketing research"`
+	Channel    string `json:"channel" validate:"required,oneof=web paper kiosk verbal"`
+	Hospital   string `json:"-"`
+	RecordedBy string `json:"-"`
+	Role       string `json:"-"`
+}
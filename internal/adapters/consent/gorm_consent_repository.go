@@ -0,0 +1,67 @@
+package adapters
+
+import (
+	"agnos/internal/entities"
+	"agnos/internal/usecases/consent"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type GormConsentRepository struct {
+	db *gorm.DB
+}
+
+func NewGormConsentRepository(db *gorm.DB) consent.ConsentRepository {
+	return &GormConsentRepository{db: db}
+}
+
+func (r *GormConsentRepository) Save(ctx context.Context, consent *entities.Consent) (*entities.Consent, error) {
+	if err := r.db.WithContext(ctx).Omit("Patient").Save(consent).Error; err != nil {
+		return nil, err
+	}
+	return consent, nil
+}
+
+func (r *GormConsentRepository) FindActive(ctx context.Context, patientId uint, purpose string) (*entities.Consent, error) {
+	var consent entities.Consent
+	err := r.db.WithContext(ctx).Where("patient_id = ? AND purpose = ? AND withdrawn_at IS NULL", patientId, purpose).
+		Order("granted_at DESC").
+		First(&consent).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &consent, nil
+}
+
+func (r *GormConsentRepository) FindByPatient(ctx context.Context, patientId uint) ([]*entities.Consent, error) {
+	var consents []*entities.Consent
+	if err := r.db.WithContext(ctx).Where("patient_id = ?", patientId).Order("granted_at DESC").Find(&consents).Error; err != nil {
+		return nil, err
+	}
+	return consents, nil
+}
+
+func (r *GormConsentRepository) ConsentedPatientIds(ctx context.Context, patientIds []uint, purpose string) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).Model(&entities.Consent{}).
+		Where("patient_id IN ? AND purpose = ? AND withdrawn_at IS NULL", patientIds, purpose).
+		Distinct().
+		Pluck("patient_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (r *GormConsentRepository) FindPatient(ctx context.Context, id uint) (*entities.Patient, error) {
+	var patient entities.Patient
+	if err := r.db.WithContext(ctx).First(&patient, id).Error; err != nil {
+		return nil, err
+	}
+	return &patient, nil
+}
@@ -0,0 +1,118 @@
+package adapters
+
+import (
+	"agnos/internal/adapters/consent/dto"
+	"agnos/internal/usecases/consent"
+	"agnos/pkg/middleware"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type HttpConsentHandler struct {
+	consentUseCase consent.ConsentUseCase
+}
+
+func NewHttpConsentRepository(usecase consent.ConsentUseCase) *HttpConsentHandler {
+	return &HttpConsentHandler{consentUseCase: usecase}
+}
+
+func (h *HttpConsentHandler) GrantConsent(c *gin.Context) {
+	payload, exist := c.Get("payload")
+	if !exist {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	claims := payload.(jwt.MapClaims)
+
+	var data dto.GrantConsentDto
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if messages := validate(&data); len(messages) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": messages})
+		return
+	}
+
+	data.Hospital = claims["hospital"].(string)
+	data.RecordedBy = claims["username"].(string)
+	data.Role = middleware.ClaimRole(claims)
+
+	consent, err := h.consentUseCase.GrantConsent(c.Request.Context(), &data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "grant success", "statusCode": 200, "data": consent})
+}
+
+func (h *HttpConsentHandler) WithdrawConsent(c *gin.Context) {
+	payload, exist := c.Get("payload")
+	if !exist {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	claims := payload.(jwt.MapClaims)
+
+	var data dto.WithdrawConsentDto
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if messages := validate(&data); len(messages) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": messages})
+		return
+	}
+
+	data.Hospital = claims["hospital"].(string)
+	data.RecordedBy = claims["username"].(string)
+	data.Role = middleware.ClaimRole(claims)
+
+	consent, err := h.consentUseCase.WithdrawConsent(c.Request.Context(), &data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "withdraw success", "statusCode": 200, "data": consent})
+}
+
+func (h *HttpConsentHandler) ListConsents(c *gin.Context) {
+	payload, exist := c.Get("payload")
+	if !exist {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	claims := payload.(jwt.MapClaims)
+
+	id, err := strconv.ParseUint(c.Param("patient_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "patient_id is invalid"})
+		return
+	}
+
+	consents, err := h.consentUseCase.ListConsents(c.Request.Context(), claims["hospital"].(string), uint(id))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "search success", "statusCode": 200, "data": consents})
+}
+
+func validate(data interface{}) []string {
+	err := validator.New().Struct(data)
+	if err == nil {
+		return nil
+	}
+
+	messages := make([]string, 0)
+	for _, e := range err.(validator.ValidationErrors) {
+		messages = append(messages, fmt.Sprintf("%s is %s", strings.ToLower(e.Field()), strings.ToLower(e.Tag())))
+	}
+	return messages
+}
@@ -0,0 +1,126 @@
+package adapters
+
+import (
+	"agnos/internal/entities"
+	"agnos/internal/usecases/consent"
+	"agnos/internal/usecases/patient"
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryConsentRepository keeps consents in a slice with the same semantics
+// as GormConsentRepository. Patients are looked up in patients.
+type MemoryConsentRepository struct {
+	patients patient.PatientRepository
+
+	mu       sync.RWMutex
+	consents []entities.Consent
+	nextId   uint
+}
+
+func NewMemoryConsentRepository(patients patient.PatientRepository) consent.ConsentRepository {
+	return &MemoryConsentRepository{patients: patients}
+}
+
+func (r *MemoryConsentRepository) Save(ctx context.Context, consent *entities.Consent) (*entities.Consent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if consent.CreatedAt.IsZero() {
+		consent.CreatedAt = now
+	}
+	consent.UpdatedAt = now
+	for i := range r.consents {
+		if consent.ID != 0 && r.consents[i].ID == consent.ID {
+			r.consents[i] = *consent
+			return consent, nil
+		}
+	}
+	if consent.ID == 0 {
+		r.nextId++
+		consent.ID = r.nextId
+	} else if consent.ID > r.nextId {
+		r.nextId = consent.ID
+	}
+	r.consents = append(r.consents, *consent)
+	return consent, nil
+}
+
+func (r *MemoryConsentRepository) FindActive(ctx context.Context, patientId uint, purpose string) (*entities.Consent, error) {
+	consents, err := r.FindByPatient(ctx, patientId)
+	if err != nil {
+		return nil, err
+	}
+	for _, consent := range consents {
+		if consent.Purpose == purpose && consent.Active() {
+			return consent, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MemoryConsentRepository) FindByPatient(ctx context.Context, patientId uint) ([]*entities.Consent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var consents []*entities.Consent
+	for _, stored := range r.consents {
+		if stored.PatientID == patientId && !stored.DeletedAt.Valid {
+			consents = append(consents, &stored)
+		}
+	}
+	sort.SliceStable(consents, func(i, j int) bool { return consents[i].GrantedAt.After(consents[j].GrantedAt) })
+	return consents, nil
+}
+
+func (r *MemoryConsentRepository) ConsentedPatientIds(ctx context.Context, patientIds []uint, purpose string) ([]uint, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var ids []uint
+	for _, stored := range r.consents {
+		if stored.Purpose == purpose && stored.Active() && !stored.DeletedAt.Valid &&
+			slices.Contains(patientIds, stored.PatientID) && !slices.Contains(ids, stored.PatientID) {
+			ids = append(ids, stored.PatientID)
+		}
+	}
+	return ids, nil
+}
+
+func (r *MemoryConsentRepository) FindPatient(ctx context.Context, id uint) (*entities.Patient, error) {
+	return r.patients.FindById(ctx, id)
+}
+
+// Snapshot returns a function that puts the repository back as it is now,
+// for an in-memory unit of work that rolls back.
+func (r *MemoryConsentRepository) Snapshot() (restore func()) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	consents, nextId := slices.Clone(r.consents), r.nextId
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.consents, r.nextId = consents, nextId
+	}
+}
+
+// RemovePatients deletes the consents of the patients with ids for good,
+// for the in-memory repositories that erase patients.
+func (r *MemoryConsentRepository) RemovePatients(ids ...uint) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.consents = slices.DeleteFunc(r.consents, func(c entities.Consent) bool { return slices.Contains(ids, c.PatientID) })
+}
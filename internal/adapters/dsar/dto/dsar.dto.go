@@ -0,0 +1,47 @@
+package dto
+
+import "agnos/internal/entities"
+
+type CreateDsarDto struct {
+	Type        string `json:"type" validate:"required,oneof=access erasure"`
+	Identifier  string `json:"identifier" validate:"required"`
+	ErasureMode string `json:"erasure_mode" validate:"required_if=Type erasure,omitempty,oneof=delete anonymize"`
+	Reason      string `json:"reason"`
+	Hospital    string `json:"-"`
+	RequestedBy string `json:"-"`
+	Role        string `json:"-"`
+}
+
+type ReviewDsarDto struct {
+	Id         uint   `json:"-"`
+	Approve    bool   `json:"-"`
+	Message    string `json:"message"`
+	Hospital   string `json:"-"`
+	ReviewedBy string `json:"-"`
+	Role       string `json:"-"`
+}
+
+type ExecuteDsarDto struct {
+	Id         uint   `json:"-"`
+	Hospital   string `json:"-"`
+	ExecutedBy string `json:"-"`
+	Role       string `json:"-"`
+}
+
+type LegalHoldDto struct {
+	PatientId uint   `json:"-"`
+	Hold      bool   `json:"hold"`
+	Reason    string `json:"reason" validate:"required_if=Hold true"`
+	Hospital  string `json:"-"`
+	SetBy     string `json:"-"`
+	Role      string `json:"-"`
+}
+
+// SubjectData is the machine-readable export produced for an access request.
+type SubjectData struct {
+	Patient       *entities.Patient              `json:"patient"`
+	MergedRecords []*entities.Patient            `json:"merged_records"`
+	Consents      []*entities.Consent            `json:"consents"`
+	AuditTrail    []*entities.AuditLog           `json:"audit_trail"`
+	Requests      []*entities.DataSubjectRequest `json:"requests"`
+}
@@ -0,0 +1,165 @@
+package adapters
+
+import (
+	"agnos/internal/adapters/dsar/dto"
+	"agnos/internal/entities"
+	"agnos/internal/usecases/dsar"
+	"agnos/pkg/database"
+	"context"
+	"fmt"
+	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GormDsarRepository struct {
+	db *gorm.DB
+}
+
+func NewGormDsarRepository(db *gorm.DB) dsar.DsarRepository {
+	return &GormDsarRepository{db: db}
+}
+
+func (r *GormDsarRepository) SaveRequest(ctx context.Context, request *entities.DataSubjectRequest) (*entities.DataSubjectRequest, error) {
+	if err := r.db.WithContext(ctx).Save(request).Error; err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func (r *GormDsarRepository) FindRequest(ctx context.Context, id uint) (*entities.DataSubjectRequest, error) {
+	var request entities.DataSubjectRequest
+	if err := r.db.WithContext(ctx).First(&request, id).Error; err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *GormDsarRepository) FindRequests(ctx context.Context, hospital string, status string) ([]*entities.DataSubjectRequest, error) {
+	var requests []*entities.DataSubjectRequest
+
+	db := r.db.WithContext(ctx).Where(database.EqualFold(r.db, "hospital", hospital))
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	if err := db.Order("id DESC").Find(&requests).Error; err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+func (r *GormDsarRepository) FindPatientByIdentifier(ctx context.Context, hospital string, identifier string) (*entities.Patient, error) {
+	index, err := entities.IdentifierIndex(identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	var patient entities.Patient
+	err = r.db.WithContext(ctx).Where(database.EqualFold(r.db, "hospital", hospital)).
+		Where(r.db.Where("national_id_index = ?", index).Or("passport_id_index = ?", index).Or("patient_hn = ?", identifier)).
+		First(&patient).Error
+	if err != nil {
+		return nil, err
+	}
+	return &patient, nil
+}
+
+func (r *GormDsarRepository) FindPatient(ctx context.Context, id uint) (*entities.Patient, error) {
+	var patient entities.Patient
+	if err := r.db.WithContext(ctx).First(&patient, id).Error; err != nil {
+		return nil, err
+	}
+	return &patient, nil
+}
+
+func (r *GormDsarRepository) CollectSubjectData(ctx context.Context, patientId uint) (*dto.SubjectData, error) {
+	subject := &dto.SubjectData{}
+	db := r.db.WithContext(ctx)
+
+	var patient entities.Patient
+	if err := db.First(&patient, patientId).Error; err != nil {
+		return nil, err
+	}
+	subject.Patient = &patient
+
+	if err := db.Unscoped().Where("merged_into_id = ?", patientId).Find(&subject.MergedRecords).Error; err != nil {
+		return nil, err
+	}
+
+	ids := linkedIds(patientId, subject.MergedRecords)
+	if err := db.Where("patient_id IN ?", ids).Order("granted_at").Find(&subject.Consents).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("patient_id IN ?", ids).Order("id").Find(&subject.Requests).Error; err != nil {
+		return nil, err
+	}
+
+	resourceIds := make([]string, 0, len(ids))
+	for _, id := range ids {
+		resourceIds = append(resourceIds, strconv.FormatUint(uint64(id), 10))
+	}
+	if err := db.Where("resource = ? AND resource_id IN ?", "patient", resourceIds).Order("id").Find(&subject.AuditTrail).Error; err != nil {
+		return nil, err
+	}
+	return subject, nil
+}
+
+// ErasePatient removes the patient and the records merged into it. Delete
+// mode removes the rows; anonymize mode keeps them for statistics with every
+// personal field cleared. Audit entries are always kept. SQLite locks no
+// rows, but its writers take the database lock up front, which serializes
+// them all the same.
+func (r *GormDsarRepository) ErasePatient(ctx context.Context, patientId uint, mode string) error {
+	tx := r.db.WithContext(ctx)
+	var linked []*entities.Patient
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Unscoped().
+		Where("id = ? OR merged_into_id = ?", patientId, patientId).
+		Order("id").Find(&linked).Error; err != nil {
+		return err
+	}
+	ids := make([]uint, 0, len(linked))
+	for _, p := range linked {
+		if p.LegalHold {
+			return dsar.ErrLegalHold
+		}
+		ids = append(ids, p.ID)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	// Merge snapshots are full copies of the merged patient.
+	if err := tx.Model(&entities.PatientMerge{}).Where("merged_id IN ? OR survivor_id IN ?", ids, ids).Update("snapshot", "").Error; err != nil {
+		return err
+	}
+
+	switch mode {
+	case entities.ErasureModeDelete:
+		if err := tx.Unscoped().Where("patient_id IN ?", ids).Delete(&entities.Consent{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("patient_id IN ? OR candidate_id IN ?", ids, ids).Delete(&entities.PatientDuplicate{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("id IN ?", ids).Delete(&entities.Patient{}).Error
+	case entities.ErasureModeAnonymize:
+		return tx.Unscoped().Model(&entities.Patient{}).Where("id IN ?", ids).Updates(entities.AnonymizedColumns()).Error
+	}
+	return fmt.Errorf("erasure mode %s is not supported", mode)
+}
+
+func (r *GormDsarRepository) SetLegalHold(ctx context.Context, patientId uint, hold bool, reason string) error {
+	return r.db.WithContext(ctx).Model(&entities.Patient{}).Where("id = ?", patientId).Updates(map[string]interface{}{
+		"legal_hold":        hold,
+		"legal_hold_reason": reason,
+	}).Error
+}
+
+func linkedIds(patientId uint, merged []*entities.Patient) []uint {
+	ids := []uint{patientId}
+	for _, p := range merged {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
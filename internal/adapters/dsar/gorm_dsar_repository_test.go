@@ -0,0 +1,53 @@
+package adapters_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	adapters "agnos/internal/adapters/dsar"
+	"agnos/internal/dbtest"
+	"agnos/internal/entities"
+	"agnos/internal/routes"
+	"agnos/internal/usecases/dsar"
+	"agnos/pkg/encryption"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func testProvider(t *testing.T) encryption.KeyProvider {
+	provider, err := encryption.NewLocalProvider("test", map[string][]byte{"test": bytes.Repeat([]byte{1}, 32)}, bytes.Repeat([]byte{2}, 32))
+	if err != nil {
+		t.Fatalf("failed to load encryption keys: %v", err)
+	}
+	return provider
+}
+
+func TestGormDsarRepository_ErasePatientKeepsMergedRecordsOnHold(t *testing.T) {
+	dbtest.Each(t, "dsar_repository_test", func(t *testing.T, db *gorm.DB) {
+		if _, err := routes.EncryptionKeyring(db, testProvider(t)); err != nil {
+			t.Fatalf("failed to load data key: %v", err)
+		}
+		ctx := context.Background()
+		survivor := &entities.Patient{FirstNameEn: "Somsak", NationalId: "1234567890123", Hospital: "Bangkok Hospital"}
+		require.NoError(t, db.Create(survivor).Error)
+		merged := &entities.Patient{FirstNameEn: "Somsak", NationalId: "1234567890124", Hospital: "Bangkok Hospital", MergedIntoID: &survivor.ID, LegalHold: true}
+		require.NoError(t, db.Create(merged).Error)
+		require.NoError(t, db.Delete(merged).Error)
+
+		repo := adapters.NewGormDsarRepository(db)
+		err := repo.ErasePatient(ctx, survivor.ID, entities.ErasureModeDelete)
+		assert.ErrorIs(t, err, dsar.ErrLegalHold)
+
+		var kept []entities.Patient
+		require.NoError(t, db.Unscoped().Where("id IN ?", []uint{survivor.ID, merged.ID}).Find(&kept).Error)
+		assert.Equal(t, 2, len(kept))
+
+		require.NoError(t, db.Unscoped().Model(merged).Update("legal_hold", false).Error)
+		require.NoError(t, repo.ErasePatient(ctx, survivor.ID, entities.ErasureModeDelete))
+		require.NoError(t, db.Unscoped().Where("id IN ?", []uint{survivor.ID, merged.ID}).Find(&kept).Error)
+		assert.Empty(t, kept)
+	})
+}
@@ -0,0 +1,208 @@
+package adapters
+
+import (
+	"agnos/internal/adapters/dsar/dto"
+	"agnos/internal/usecases/dsar"
+	"agnos/pkg/middleware"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type HttpDsarHandler struct {
+	dsarUseCase dsar.DsarUseCase
+}
+
+func NewHttpDsarRepository(usecase dsar.DsarUseCase) *HttpDsarHandler {
+	return &HttpDsarHandler{dsarUseCase: usecase}
+}
+
+func (h *HttpDsarHandler) CreateRequest(c *gin.Context) {
+	claims := c.MustGet("payload").(jwt.MapClaims)
+
+	var data dto.CreateDsarDto
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if messages := validate(&data); len(messages) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": messages})
+		return
+	}
+
+	data.Hospital = claims["hospital"].(string)
+	data.RequestedBy = claims["username"].(string)
+	data.Role = middleware.ClaimRole(claims)
+
+	request, err := h.dsarUseCase.CreateRequest(c.Request.Context(), &data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "create success", "statusCode": 201, "data": request})
+}
+
+func (h *HttpDsarHandler) ListRequests(c *gin.Context) {
+	claims := c.MustGet("payload").(jwt.MapClaims)
+
+	requests, err := h.dsarUseCase.ListRequests(c.Request.Context(), claims["hospital"].(string), c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "search success", "statusCode": 200, "data": requests})
+}
+
+func (h *HttpDsarHandler) GetRequest(c *gin.Context) {
+	claims := c.MustGet("payload").(jwt.MapClaims)
+
+	id, ok := paramId(c, "id")
+	if !ok {
+		return
+	}
+
+	request, err := h.dsarUseCase.GetRequest(c.Request.Context(), claims["hospital"].(string), id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "search success", "statusCode": 200, "data": request})
+}
+
+func (h *HttpDsarHandler) ApproveRequest(c *gin.Context) {
+	h.review(c, true)
+}
+
+func (h *HttpDsarHandler) RejectRequest(c *gin.Context) {
+	h.review(c, false)
+}
+
+func (h *HttpDsarHandler) review(c *gin.Context, approve bool) {
+	claims := c.MustGet("payload").(jwt.MapClaims)
+
+	id, ok := paramId(c, "id")
+	if !ok {
+		return
+	}
+
+	var data dto.ReviewDsarDto
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&data); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	data.Id, data.Approve = id, approve
+	data.Hospital = claims["hospital"].(string)
+	data.ReviewedBy = claims["username"].(string)
+	data.Role = middleware.ClaimRole(claims)
+
+	request, err := h.dsarUseCase.ReviewRequest(c.Request.Context(), &data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "review success", "statusCode": 200, "data": request})
+}
+
+func (h *HttpDsarHandler) ExportRequest(c *gin.Context) {
+	data, ok := executeDto(c)
+	if !ok {
+		return
+	}
+
+	subject, err := h.dsarUseCase.ExportRequest(c.Request.Context(), data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="dsar-%d-%s.json"`, data.Id, time.Now().Format("20060102150405")))
+	c.JSON(http.StatusOK, subject)
+}
+
+func (h *HttpDsarHandler) EraseRequest(c *gin.Context) {
+	data, ok := executeDto(c)
+	if !ok {
+		return
+	}
+
+	request, err := h.dsarUseCase.EraseRequest(c.Request.Context(), data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "erase " + request.Status, "statusCode": 200, "data": request})
+}
+
+func (h *HttpDsarHandler) SetLegalHold(c *gin.Context) {
+	claims := c.MustGet("payload").(jwt.MapClaims)
+
+	id, ok := paramId(c, "patient_id")
+	if !ok {
+		return
+	}
+
+	var data dto.LegalHoldDto
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if messages := validate(&data); len(messages) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": messages})
+		return
+	}
+	data.PatientId = id
+	data.Hospital = claims["hospital"].(string)
+	data.SetBy = claims["username"].(string)
+	data.Role = middleware.ClaimRole(claims)
+
+	patient, err := h.dsarUseCase.SetLegalHold(c.Request.Context(), &data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "legal hold updated", "statusCode": 200, "data": gin.H{"patient_id": patient.ID, "legal_hold": patient.LegalHold}})
+}
+
+func executeDto(c *gin.Context) (*dto.ExecuteDsarDto, bool) {
+	claims := c.MustGet("payload").(jwt.MapClaims)
+
+	id, ok := paramId(c, "id")
+	if !ok {
+		return nil, false
+	}
+	return &dto.ExecuteDsarDto{
+		Id:         id,
+		Hospital:   claims["hospital"].(string),
+		ExecutedBy: claims["username"].(string),
+		Role:       middleware.ClaimRole(claims),
+	}, true
+}
+
+func paramId(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": name + " is invalid"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func validate(data interface{}) []string {
+	err := validator.New().Struct(data)
+	if err == nil {
+		return nil
+	}
+
+	messages := make([]string, 0)
+	for _, e := range err.(validator.ValidationErrors) {
+		messages = append(messages, fmt.Sprintf("%s is %s", strings.ToLower(e.Field()), strings.ToLower(e.Tag())))
+	}
+	return messages
+}
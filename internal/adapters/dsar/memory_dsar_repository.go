@@ -0,0 +1,236 @@
+package adapters
+
+import (
+	adaptersConsent "agnos/internal/adapters/consent"
+	"agnos/internal/adapters/dsar/dto"
+	adaptersMpi "agnos/internal/adapters/mpi"
+	adaptersPatient "agnos/internal/adapters/patient"
+	patientDto "agnos/internal/adapters/patient/dto"
+	"agnos/internal/entities"
+	"agnos/internal/usecases/audit"
+	"agnos/internal/usecases/dsar"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MemoryDsarRepository keeps data subject requests in memory with the same
+// semantics as GormDsarRepository. It collects and erases subject data in
+// the other in-memory repositories.
+type MemoryDsarRepository struct {
+	patients *adaptersPatient.MemoryPatientRepository
+	consents *adaptersConsent.MemoryConsentRepository
+	mpi      *adaptersMpi.MemoryMpiRepository
+	audit    audit.AuditRepository
+
+	mu       sync.RWMutex
+	requests map[uint]entities.DataSubjectRequest
+	nextId   uint
+}
+
+func NewMemoryDsarRepository(patients *adaptersPatient.MemoryPatientRepository, consents *adaptersConsent.MemoryConsentRepository, mpi *adaptersMpi.MemoryMpiRepository, audit audit.AuditRepository) dsar.DsarRepository {
+	return &MemoryDsarRepository{patients: patients, consents: consents, mpi: mpi, audit: audit, requests: map[uint]entities.DataSubjectRequest{}}
+}
+
+func (r *MemoryDsarRepository) SaveRequest(ctx context.Context, request *entities.DataSubjectRequest) (*entities.DataSubjectRequest, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if request.ID == 0 {
+		r.nextId++
+		request.ID = r.nextId
+	}
+	if request.CreatedAt.IsZero() {
+		request.CreatedAt = now
+	}
+	request.UpdatedAt = now
+	r.requests[request.ID] = *request
+	return request, nil
+}
+
+func (r *MemoryDsarRepository) FindRequest(ctx context.Context, id uint) (*entities.DataSubjectRequest, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	request, ok := r.requests[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &request, nil
+}
+
+func (r *MemoryDsarRepository) FindRequests(ctx context.Context, hospital string, status string) ([]*entities.DataSubjectRequest, error) {
+	requests, err := r.matching(ctx, func(request *entities.DataSubjectRequest) bool {
+		return strings.EqualFold(request.Hospital, hospital) && (status == "" || request.Status == status)
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(requests)
+	return requests, nil
+}
+
+// matching returns copies of the requests that match, by ID.
+func (r *MemoryDsarRepository) matching(ctx context.Context, matches func(request *entities.DataSubjectRequest) bool) ([]*entities.DataSubjectRequest, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	requests := make([]*entities.DataSubjectRequest, 0)
+	for _, stored := range r.requests {
+		if matches(&stored) {
+			request := stored
+			requests = append(requests, &request)
+		}
+	}
+	sort.Slice(requests, func(i, j int) bool { return requests[i].ID < requests[j].ID })
+	return requests, nil
+}
+
+func (r *MemoryDsarRepository) FindPatientByIdentifier(ctx context.Context, hospital string, identifier string) (*entities.Patient, error) {
+	patients, err := r.patients.Findone(ctx, &patientDto.SearchPatientDto{Identifier: identifier, Hospital: hospital})
+	if err != nil {
+		return nil, err
+	}
+	if len(patients) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return patients[0], nil
+}
+
+func (r *MemoryDsarRepository) FindPatient(ctx context.Context, id uint) (*entities.Patient, error) {
+	return r.patients.FindById(ctx, id)
+}
+
+func (r *MemoryDsarRepository) CollectSubjectData(ctx context.Context, patientId uint) (*dto.SubjectData, error) {
+	patient, err := r.patients.FindById(ctx, patientId)
+	if err != nil {
+		return nil, err
+	}
+	subject := &dto.SubjectData{Patient: patient, MergedRecords: r.mergedInto(patientId)}
+	ids := linkedIds(patientId, subject.MergedRecords)
+
+	for _, id := range ids {
+		consents, err := r.consents.FindByPatient(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		subject.Consents = append(subject.Consents, consents...)
+	}
+	sort.SliceStable(subject.Consents, func(i, j int) bool { return subject.Consents[i].GrantedAt.Before(subject.Consents[j].GrantedAt) })
+
+	subject.Requests, err = r.matching(ctx, func(request *entities.DataSubjectRequest) bool { return slices.Contains(ids, request.PatientID) })
+	if err != nil {
+		return nil, err
+	}
+
+	resourceIds := make([]string, 0, len(ids))
+	for _, id := range ids {
+		resourceIds = append(resourceIds, strconv.FormatUint(uint64(id), 10))
+	}
+	err = r.audit.FindInBatches(ctx, 500, func(entries []*entities.AuditLog) error {
+		for _, entry := range entries {
+			if entry.Resource == "patient" && slices.Contains(resourceIds, entry.ResourceId) {
+				subject.AuditTrail = append(subject.AuditTrail, entry)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return subject, nil
+}
+
+// ErasePatient removes the patient and the records merged into it. Delete
+// mode removes them; anonymize mode keeps them with every personal field
+// cleared. Audit entries are always kept.
+func (r *MemoryDsarRepository) ErasePatient(ctx context.Context, patientId uint, mode string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	merged := r.mergedInto(patientId)
+	linked := merged
+	if patient, err := r.patients.FindById(ctx, patientId); err == nil {
+		linked = append(linked, patient)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	for _, p := range linked {
+		if p.LegalHold {
+			return dsar.ErrLegalHold
+		}
+	}
+	ids := linkedIds(patientId, merged)
+
+	switch mode {
+	case entities.ErasureModeDelete:
+		r.mpi.ClearSnapshots(ids, ids)
+		r.consents.RemovePatients(ids...)
+		r.mpi.RemovePatients(ids...)
+		r.patients.Remove(ids...)
+		return nil
+	case entities.ErasureModeAnonymize:
+		r.mpi.ClearSnapshots(ids, ids)
+		r.patients.Anonymize(ids...)
+		return nil
+	}
+	return fmt.Errorf("erasure mode %s is not supported", mode)
+}
+
+func (r *MemoryDsarRepository) SetLegalHold(ctx context.Context, patientId uint, hold bool, reason string) error {
+	patient, err := r.patients.FindById(ctx, patientId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// An update of no rows is no error.
+		return nil
+	} else if err != nil {
+		return err
+	}
+	patient.LegalHold, patient.LegalHoldReason = hold, reason
+	r.patients.Put(patient)
+	return nil
+}
+
+// Snapshot returns a function that puts the requests back as they are now,
+// for an in-memory unit of work that rolls back. Subject data is restored
+// through its own repositories.
+func (r *MemoryDsarRepository) Snapshot() (restore func()) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	requests, nextId := maps.Clone(r.requests), r.nextId
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.requests, r.nextId = requests, nextId
+	}
+}
+
+// mergedInto returns the records merged into the patient, which are
+// soft-deleted.
+func (r *MemoryDsarRepository) mergedInto(patientId uint) []*entities.Patient {
+	merged := make([]*entities.Patient, 0)
+	for _, p := range r.patients.Unscoped() {
+		if p.MergedIntoID != nil && *p.MergedIntoID == patientId {
+			merged = append(merged, p)
+		}
+	}
+	return merged
+}
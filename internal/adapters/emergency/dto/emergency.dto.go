@@ -0,0 +1,19 @@
+package dto
+
+type GrantEmergencyDto struct {
+	Identifier        string `json:"identifier" validate:"required"`
+	Hospital          string `json:"hospital" validate:"required"`
+	Reason            string `json:"reason" validate:"required,min=10"`
+	Clinician         string `json:"-"`
+	ClinicianHospital string `json:"-"`
+	Role              string `json:"-"`
+}
+
+type ReviewEmergencyDto struct {
+	Id         uint   `json:"-"`
+	Status     string `json:"status" validate:"required,oneof=justified unjustified"`
+	Note       string `json:"note"`
+	Hospital   string `json:"-"`
+	ReviewedBy string `json:"-"`
+	Role       string `json:"-"`
+}
@@ -0,0 +1,46 @@
+package adapters
+
+import (
+	"agnos/internal/entities"
+	"agnos/internal/usecases/emergency"
+	"agnos/pkg/database"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type GormEmergencyRepository struct {
+	db *gorm.DB
+}
+
+func NewGormEmergencyRepository(db *gorm.DB) emergency.EmergencyRepository {
+	return &GormEmergencyRepository{db: db}
+}
+
+func (r *GormEmergencyRepository) SaveAccess(ctx context.Context, access *entities.EmergencyAccess) (*entities.EmergencyAccess, error) {
+	if err := r.db.WithContext(ctx).Save(access).Error; err != nil {
+		return nil, err
+	}
+	return access, nil
+}
+
+func (r *GormEmergencyRepository) FindAccess(ctx context.Context, id uint) (*entities.EmergencyAccess, error) {
+	var access entities.EmergencyAccess
+	if err := r.db.WithContext(ctx).First(&access, id).Error; err != nil {
+		return nil, err
+	}
+	return &access, nil
+}
+
+func (r *GormEmergencyRepository) FindAccesses(ctx context.Context, patientHospital string, status string) ([]*entities.EmergencyAccess, error) {
+	var accesses []*entities.EmergencyAccess
+
+	db := r.db.WithContext(ctx).Where(database.EqualFold(r.db, "patient_hospital", patientHospital))
+	if status != "" {
+		db = db.Where("review_status = ?", status)
+	}
+	if err := db.Order("id DESC").Find(&accesses).Error; err != nil {
+		return nil, err
+	}
+	return accesses, nil
+}
@@ -0,0 +1,122 @@
+package adapters
+
+import (
+	"agnos/internal/adapters/emergency/dto"
+	"agnos/internal/usecases/emergency"
+	"agnos/pkg/middleware"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type HttpEmergencyHandler struct {
+	emergencyUseCase emergency.EmergencyUseCase
+}
+
+func NewHttpEmergencyRepository(usecase emergency.EmergencyUseCase) *HttpEmergencyHandler {
+	return &HttpEmergencyHandler{emergencyUseCase: usecase}
+}
+
+// GrantAccess breaks the glass and returns a token that carries the grant.
+// The token expires with the grant and only opens the granted patient.
+func (h *HttpEmergencyHandler) GrantAccess(c *gin.Context) {
+	claims := c.MustGet("payload").(jwt.MapClaims)
+
+	var data dto.GrantEmergencyDto
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if messages := validate(&data); len(messages) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": messages})
+		return
+	}
+
+	data.Clinician = claims["username"].(string)
+	data.ClinicianHospital = claims["hospital"].(string)
+	data.Role = middleware.ClaimRole(claims)
+
+	access, err := h.emergencyUseCase.GrantAccess(c.Request.Context(), &data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	emergencyClaims := jwt.MapClaims{}
+	for key, value := range claims {
+		emergencyClaims[key] = value
+	}
+	emergencyClaims["emergency_access_id"] = access.ID
+	emergencyClaims["emergency_patient_id"] = access.PatientID
+	if exp, err := claims.GetExpirationTime(); err != nil || exp == nil || access.ExpiresAt.Before(exp.Time) {
+		emergencyClaims["exp"] = access.ExpiresAt.Unix()
+	}
+
+	token, err := middleware.SignToken(emergencyClaims)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "emergency access granted", "statusCode": 200, "data": access, "token": token})
+}
+
+func (h *HttpEmergencyHandler) ListAccesses(c *gin.Context) {
+	claims := c.MustGet("payload").(jwt.MapClaims)
+
+	accesses, err := h.emergencyUseCase.ListAccesses(c.Request.Context(), claims["hospital"].(string), c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "search success", "statusCode": 200, "data": accesses})
+}
+
+func (h *HttpEmergencyHandler) ReviewAccess(c *gin.Context) {
+	claims := c.MustGet("payload").(jwt.MapClaims)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id is invalid"})
+		return
+	}
+
+	var data dto.ReviewEmergencyDto
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if messages := validate(&data); len(messages) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": messages})
+		return
+	}
+
+	data.Id = uint(id)
+	data.Hospital = claims["hospital"].(string)
+	data.ReviewedBy = claims["username"].(string)
+	data.Role = middleware.ClaimRole(claims)
+
+	access, err := h.emergencyUseCase.ReviewAccess(c.Request.Context(), &data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "review success", "statusCode": 200, "data": access})
+}
+
+func validate(data interface{}) []string {
+	err := validator.New().Struct(data)
+	if err == nil {
+		return nil
+	}
+
+	messages := make([]string, 0)
+	for _, e := range err.(validator.ValidationErrors) {
+		messages = append(messages, fmt.Sprintf("%s is %s", strings.ToLower(e.Field()), strings.ToLower(e.Tag())))
+	}
+	return messages
+}
@@ -0,0 +1,78 @@
+package adapters
+
+import (
+	"agnos/internal/entities"
+	"agnos/internal/usecases/emergency"
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MemoryEmergencyRepository keeps break-the-glass grants in a map with the
+// same semantics as GormEmergencyRepository.
+type MemoryEmergencyRepository struct {
+	mu       sync.RWMutex
+	accesses map[uint]entities.EmergencyAccess
+	nextId   uint
+}
+
+func NewMemoryEmergencyRepository() emergency.EmergencyRepository {
+	return &MemoryEmergencyRepository{accesses: map[uint]entities.EmergencyAccess{}}
+}
+
+func (r *MemoryEmergencyRepository) SaveAccess(ctx context.Context, access *entities.EmergencyAccess) (*entities.EmergencyAccess, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if access.ID == 0 {
+		r.nextId++
+		access.ID = r.nextId
+	}
+	if access.CreatedAt.IsZero() {
+		access.CreatedAt = now
+	}
+	access.UpdatedAt = now
+	r.accesses[access.ID] = *access
+	return access, nil
+}
+
+func (r *MemoryEmergencyRepository) FindAccess(ctx context.Context, id uint) (*entities.EmergencyAccess, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	access, ok := r.accesses[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &access, nil
+}
+
+func (r *MemoryEmergencyRepository) FindAccesses(ctx context.Context, patientHospital string, status string) ([]*entities.EmergencyAccess, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	accesses := make([]*entities.EmergencyAccess, 0)
+	for _, stored := range r.accesses {
+		if !strings.EqualFold(stored.PatientHospital, patientHospital) || (status != "" && stored.ReviewStatus != status) {
+			continue
+		}
+		access := stored
+		accesses = append(accesses, &access)
+	}
+	sort.Slice(accesses, func(i, j int) bool { return accesses[i].ID > accesses[j].ID })
+	return accesses, nil
+}
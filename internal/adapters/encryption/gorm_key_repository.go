@@ -0,0 +1,81 @@
+package adapters
+
+import (
+	"agnos/internal/entities"
+	"agnos/pkg/encryption"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type GormKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewGormKeyRepository(db *gorm.DB) encryption.KeyStore {
+	return &GormKeyRepository{db: db}
+}
+
+func (r *GormKeyRepository) ActiveDataKey(ctx context.Context) (*encryption.DataKey, error) {
+	var key entities.EncryptionKey
+	err := r.db.WithContext(ctx).Where("active = ?", true).Order("id DESC").First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toDataKey(&key), nil
+}
+
+func (r *GormKeyRepository) FindDataKey(ctx context.Context, id uint) (*encryption.DataKey, error) {
+	var key entities.EncryptionKey
+	if err := r.db.WithContext(ctx).First(&key, id).Error; err != nil {
+		return nil, err
+	}
+	return toDataKey(&key), nil
+}
+
+func (r *GormKeyRepository) ListDataKeys(ctx context.Context) ([]*encryption.DataKey, error) {
+	var keys []*entities.EncryptionKey
+	if err := r.db.WithContext(ctx).Order("id").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+
+	dataKeys := make([]*encryption.DataKey, 0, len(keys))
+	for _, key := range keys {
+		dataKeys = append(dataKeys, toDataKey(key))
+	}
+	return dataKeys, nil
+}
+
+func (r *GormKeyRepository) SaveDataKey(ctx context.Context, key *encryption.DataKey) error {
+	db := r.db.WithContext(ctx)
+	entity := entities.EncryptionKey{
+		WrappedKey:  key.WrappedKey,
+		MasterKeyId: key.MasterKeyId,
+		Active:      key.Active,
+	}
+	if key.Id != 0 {
+		if err := db.First(&entity, key.Id).Error; err != nil {
+			return err
+		}
+		entity.WrappedKey, entity.MasterKeyId, entity.Active = key.WrappedKey, key.MasterKeyId, key.Active
+	}
+
+	if err := db.Save(&entity).Error; err != nil {
+		return err
+	}
+	key.Id = entity.ID
+	return nil
+}
+
+func toDataKey(key *entities.EncryptionKey) *encryption.DataKey {
+	return &encryption.DataKey{
+		Id:          key.ID,
+		WrappedKey:  key.WrappedKey,
+		MasterKeyId: key.MasterKeyId,
+		Active:      key.Active,
+	}
+}
@@ -0,0 +1,53 @@
+package adapters
+
+import (
+	"agnos/internal/entities"
+	"agnos/internal/usecases/keys"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type GormRotationRepository struct {
+	db *gorm.DB
+}
+
+func NewGormRotationRepository(db *gorm.DB) keys.KeyRepository {
+	return &GormRotationRepository{db: db}
+}
+
+// ReencryptPatients saves every patient again, including soft-deleted ones,
+// so that the encrypted serializer seals them with the active data key.
+func (r *GormRotationRepository) ReencryptPatients(ctx context.Context, batchSize int) (int, error) {
+	var patients []*entities.Patient
+	total := 0
+	err := r.db.WithContext(ctx).Unscoped().Model(&entities.Patient{}).FindInBatches(&patients, batchSize, func(tx *gorm.DB, batch int) error {
+		if err := r.db.WithContext(ctx).Unscoped().Save(&patients).Error; err != nil {
+			return err
+		}
+		total += len(patients)
+		return nil
+	}).Error
+	return total, err
+}
+
+// BackfillIndexes saves again the patients that have an identifier or
+// contact but no blind index for it, such as rows written before the
+// indexes existed, so that lookups and the duplicate check find them.
+func (r *GormRotationRepository) BackfillIndexes(ctx context.Context, batchSize int) (int, error) {
+	var patients []*entities.Patient
+	total := 0
+	err := r.db.WithContext(ctx).Unscoped().Model(&entities.Patient{}).
+		Where("national_id <> '' AND national_id_index = ''").
+		Or("passport_id <> '' AND passport_id_index = ''").
+		Or("phone_number <> '' AND phone_number_index = ''").
+		Or("email <> '' AND email_index = ''").
+		FindInBatches(&patients, batchSize, func(tx *gorm.DB, batch int) error {
+			if err := r.db.WithContext(ctx).Unscoped().Save(&patients).Error; err != nil {
+				return err
+			}
+			total += len(patients)
+			return nil
+		}).Error
+	return total, err
+}
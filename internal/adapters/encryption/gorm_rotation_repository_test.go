@@ -0,0 +1,61 @@
+package adapters_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	adapters "agnos/internal/adapters/encryption"
+	"agnos/internal/dbtest"
+	"agnos/internal/entities"
+	"agnos/internal/routes"
+	"agnos/pkg/encryption"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func testProvider(t *testing.T) encryption.KeyProvider {
+	provider, err := encryption.NewLocalProvider("test", map[string][]byte{"test": bytes.Repeat([]byte{1}, 32)}, bytes.Repeat([]byte{2}, 32))
+	if err != nil {
+		t.Fatalf("failed to load encryption keys: %v", err)
+	}
+	return provider
+}
+
+func TestGormRotationRepository_BackfillIndexes(t *testing.T) {
+	dbtest.Each(t, "rotation_repository_test", func(t *testing.T, db *gorm.DB) {
+		if _, err := routes.EncryptionKeyring(db, testProvider(t)); err != nil {
+			t.Fatalf("failed to load data key: %v", err)
+		}
+		born := time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)
+		legacy := &entities.Patient{NationalId: "1100000000001", PassportId: "AA1234567", Email: "somchai@example.com", DateBirth: born, Hospital: "Bangkok Hospital"}
+		indexed := &entities.Patient{NationalId: "1100000000002", DateBirth: born, Hospital: "Bangkok Hospital"}
+		withoutContacts := &entities.Patient{NationalId: "1100000000003", DateBirth: born, Hospital: "Bangkok Hospital"}
+		for _, p := range []*entities.Patient{legacy, indexed, withoutContacts} {
+			require.NoError(t, db.Create(p).Error)
+		}
+		// Rows written before the blind indexes existed have them empty.
+		require.NoError(t, db.Model(legacy).UpdateColumns(map[string]interface{}{"national_id_index": "", "passport_id_index": "", "email_index": ""}).Error)
+
+		repo := adapters.NewGormRotationRepository(db)
+		count, err := repo.BackfillIndexes(context.Background(), 10)
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+
+		var stored entities.Patient
+		require.NoError(t, db.First(&stored, legacy.ID).Error)
+		want, err := entities.IdentifierIndex(legacy.NationalId)
+		require.NoError(t, err)
+		assert.Equal(t, want, stored.NationalIdIndex)
+		assert.NotEmpty(t, stored.PassportIdIndex)
+		assert.NotEmpty(t, stored.EmailIndex)
+		assert.Empty(t, stored.PhoneNumberIndex)
+
+		count, err = repo.BackfillIndexes(context.Background(), 10)
+		require.NoError(t, err)
+		assert.Zero(t, count)
+	})
+}
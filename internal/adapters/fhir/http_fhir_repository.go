@@ -0,0 +1,233 @@
+package adapters
+
+import (
+	"agnos/internal/adapters/patient/dto"
+	"agnos/internal/entities"
+	"agnos/internal/usecases/mpi"
+	usecases "agnos/internal/usecases/patient"
+	"agnos/pkg/logging"
+	"agnos/pkg/mask"
+	"agnos/pkg/middleware"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+const fhirContentType = "application/fhir+json; charset=utf-8"
+
+type HttpFhirHandler struct {
+	patientUseCase usecases.PatientUseCase
+	mpiUseCase     mpi.MpiUseCase
+}
+
+func NewHttpFhirRepository(usecase usecases.PatientUseCase, mpiUseCase mpi.MpiUseCase) *HttpFhirHandler {
+	return &HttpFhirHandler{patientUseCase: usecase, mpiUseCase: mpiUseCase}
+}
+
+func (h *HttpFhirHandler) AuthRequired(c *gin.Context) {
+	claims, err := middleware.ParseToken(c)
+	if err != nil {
+		writeOutcome(c, http.StatusUnauthorized, "login", "Unauthorized")
+		c.Abort()
+		return
+	}
+
+	c.Set("payload", claims)
+	c.Next()
+}
+
+func (h *HttpFhirHandler) Metadata(c *gin.Context) {
+	writeResource(c, http.StatusOK, &CapabilityStatement{
+		ResourceType: "CapabilityStatement",
+		Status:       "active",
+		Date:         time.Now().UTC().Format(birthDateLayout),
+		Kind:         "instance",
+		Software:     CapabilitySoftware{Name: "agnos"},
+		FhirVersion:  "4.0.1",
+		Format:       []string{"json"},
+		Rest: []CapabilityRest{{
+			Mode: "server",
+			Resource: []CapabilityResource{{
+				Type:        "Patient",
+				Profile:     "http://hl7.org/fhir/StructureDefinition/Patient",
+				Interaction: []CapabilityInteraction{{Code: "read"}, {Code: "search-type"}, {Code: "create"}},
+				SearchParam: []CapabilitySearchParam{
+					{Name: "identifier", Type: "token"},
+					{Name: "name", Type: "string"},
+					{Name: "birthdate", Type: "date"},
+					{Name: "gender", Type: "token"},
+				},
+			}},
+		}},
+	})
+}
+
+func (h *HttpFhirHandler) ReadPatient(c *gin.Context) {
+	claims := c.MustGet("payload").(jwt.MapClaims)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		writeOutcome(c, http.StatusNotFound, "not-found", fmt.Sprintf("Patient/%s not found", c.Param("id")))
+		return
+	}
+
+	patient, err := h.patientUseCase.GetPatient(c.Request.Context(), uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !strings.EqualFold(patient.Hospital, claims["hospital"].(string))) {
+		writeOutcome(c, http.StatusNotFound, "not-found", fmt.Sprintf("Patient/%d not found", id))
+		return
+	}
+	if err != nil {
+		writeOutcome(c, http.StatusInternalServerError, "exception", err.Error())
+		return
+	}
+	policy := mask.PolicyFor(middleware.ClaimRole(claims))
+	writeResource(c, http.StatusOK, ToFhirPatient(usecases.MaskPatient(patient, policy)))
+}
+
+func (h *HttpFhirHandler) SearchPatient(c *gin.Context) {
+	claims := c.MustGet("payload").(jwt.MapClaims)
+
+	params := dto.SearchPatientDto{
+		Name:     c.Query("name"),
+		Hospital: claims["hospital"].(string),
+	}
+
+	system, value, hasSystem := strings.Cut(c.Query("identifier"), "|")
+	if !hasSystem {
+		system, value = "", system
+	}
+	params.Identifier = value
+
+	if birthdate := c.Query("birthdate"); birthdate != "" {
+		date, err := parseBirthdate(birthdate)
+		if err != nil {
+			writeOutcome(c, http.StatusBadRequest, "invalid", err.Error())
+			return
+		}
+		params.DateofBirth = date
+	}
+
+	if gender := c.Query("gender"); gender != "" {
+		if gender != "male" && gender != "female" && gender != "other" && gender != "unknown" {
+			writeOutcome(c, http.StatusBadRequest, "invalid", "gender must be one of male, female, other, unknown")
+			return
+		}
+		params.Gender = gender
+	}
+
+	patients, err := h.patientUseCase.SearchPatient(c.Request.Context(), &params)
+	if err != nil {
+		writeOutcome(c, http.StatusInternalServerError, "exception", err.Error())
+		return
+	}
+
+	policy := mask.PolicyFor(middleware.ClaimRole(claims))
+	base := baseUrl(c)
+	bundle := &Bundle{
+		ResourceType: "Bundle",
+		Type:         "searchset",
+		Link:         []BundleLink{{Relation: "self", Url: base + c.Request.URL.RequestURI()}},
+		Entry:        make([]BundleEntry, 0),
+	}
+	for _, patient := range patients {
+		if hasSystem && !matchesIdentifier(patient, system, value) {
+			continue
+		}
+		resource := ToFhirPatient(usecases.MaskPatient(patient, policy))
+		bundle.Entry = append(bundle.Entry, BundleEntry{
+			FullUrl:  fmt.Sprintf("%s/fhir/Patient/%s", base, resource.Id),
+			Resource: resource,
+			Search:   &BundleEntrySearch{Mode: "match"},
+		})
+	}
+	bundle.Total = len(bundle.Entry)
+
+	writeResource(c, http.StatusOK, bundle)
+}
+
+func (h *HttpFhirHandler) CreatePatient(c *gin.Context) {
+	claims := c.MustGet("payload").(jwt.MapClaims)
+
+	var resource Patient
+	if err := c.ShouldBindJSON(&resource); err != nil {
+		writeOutcome(c, http.StatusBadRequest, "structure", err.Error())
+		return
+	}
+
+	data, err := FromFhirPatient(&resource)
+	if err != nil {
+		writeOutcome(c, http.StatusBadRequest, "invalid", err.Error())
+		return
+	}
+	data.Hospital = claims["hospital"].(string)
+
+	if messages := usecases.ValidatePatient(data); len(messages) > 0 {
+		writeOutcome(c, http.StatusBadRequest, "invalid", strings.Join(messages, ", "))
+		return
+	}
+
+	patient, err := h.patientUseCase.CreatePatient(c.Request.Context(), data)
+	if err != nil {
+		writeOutcome(c, http.StatusBadRequest, "processing", err.Error())
+		return
+	}
+	if _, err := h.mpiUseCase.DetectDuplicates(c.Request.Context(), patient); err != nil {
+		logging.For("fhir").ErrorContext(c.Request.Context(), "duplicate detection failed", "patient_id", patient.ID, "error", err.Error())
+	}
+
+	created := ToFhirPatient(usecases.MaskPatient(patient, mask.PolicyFor(middleware.ClaimRole(claims))))
+	c.Header("Location", fmt.Sprintf("%s/fhir/Patient/%s", baseUrl(c), created.Id))
+	writeResource(c, http.StatusCreated, created)
+}
+
+func parseBirthdate(value string) (time.Time, error) {
+	if strings.HasPrefix(value, "eq") {
+		value = strings.TrimPrefix(value, "eq")
+	} else if len(value) > 2 && value[0] >= 'a' && value[0] <= 'z' {
+		return time.Time{}, fmt.Errorf("birthdate prefix %s is not supported", value[:2])
+	}
+
+	date, err := time.Parse(birthDateLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("birthdate must be in YYYY-MM-DD format")
+	}
+	return date, nil
+}
+
+func matchesIdentifier(patient *entities.Patient, system string, value string) bool {
+	switch system {
+	case SystemNationalId:
+		return patient.NationalId == value
+	case SystemPassport:
+		return patient.PassportId == value
+	case SystemHn:
+		return patient.PatientHn == value
+	case "":
+		return true
+	}
+	return false
+}
+
+func baseUrl(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s", scheme, c.Request.Host)
+}
+
+func writeResource(c *gin.Context, status int, resource interface{}) {
+	c.Header("Content-Type", fhirContentType)
+	c.JSON(status, resource)
+}
+
+func writeOutcome(c *gin.Context, status int, code string, diagnostics string) {
+	writeResource(c, status, NewOperationOutcome(code, diagnostics))
+}
@@ -0,0 +1,162 @@
+package adapters
+
+import (
+	"agnos/internal/entities"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const birthDateLayout = "2006-01-02"
+
+func ToFhirPatient(patient *entities.Patient) *Patient {
+	resource := &Patient{
+		ResourceType: "Patient",
+		Id:           strconv.FormatUint(uint64(patient.ID), 10),
+		Gender:       patient.Gender,
+	}
+	if !patient.UpdatedAt.IsZero() {
+		resource.Meta = &Meta{LastUpdated: patient.UpdatedAt.UTC().Format(time.RFC3339)}
+	}
+
+	if patient.NationalId != "" {
+		resource.Identifier = append(resource.Identifier, Identifier{
+			Use:    "official",
+			Type:   identifierType("NI", "National unique individual identifier"),
+			System: SystemNationalId,
+			Value:  patient.NationalId,
+		})
+	}
+	if patient.PassportId != "" {
+		resource.Identifier = append(resource.Identifier, Identifier{
+			Use:    "official",
+			Type:   identifierType("PPN", "Passport number"),
+			System: SystemPassport,
+			Value:  patient.PassportId,
+		})
+	}
+	if patient.PatientHn != "" {
+		resource.Identifier = append(resource.Identifier, Identifier{
+			Use:      "usual",
+			Type:     identifierType("MR", "Medical record number"),
+			System:   SystemHn,
+			Value:    patient.PatientHn,
+			Assigner: &Reference{Display: patient.Hospital},
+		})
+	}
+
+	if name := humanName("th", patient.FirstNameTh, patient.MiddleNameTh, patient.LastNameTh); name != nil {
+		resource.Name = append(resource.Name, *name)
+	}
+	if name := humanName("en", patient.FirstNameEn, patient.MiddleNameEn, patient.LastNameEn); name != nil {
+		resource.Name = append(resource.Name, *name)
+	}
+
+	if patient.PhoneNumber != "" {
+		resource.Telecom = append(resource.Telecom, ContactPoint{System: "phone", Value: patient.PhoneNumber, Use: "mobile"})
+	}
+	if patient.Email != "" {
+		resource.Telecom = append(resource.Telecom, ContactPoint{System: "email", Value: patient.Email})
+	}
+
+	if !patient.DateBirth.IsZero() {
+		resource.BirthDate = patient.DateBirth.Format(birthDateLayout)
+	}
+	if patient.Hospital != "" {
+		resource.ManagingOrganization = &Reference{Display: patient.Hospital}
+	}
+	return resource
+}
+
+func FromFhirPatient(resource *Patient) (*entities.Patient, error) {
+	if resource.ResourceType != "Patient" {
+		return nil, fmt.Errorf("resourceType must be Patient")
+	}
+
+	patient := &entities.Patient{Gender: resource.Gender}
+
+	for _, identifier := range resource.Identifier {
+		switch identifier.System {
+		case SystemNationalId:
+			patient.NationalId = identifier.Value
+		case SystemPassport:
+			patient.PassportId = identifier.Value
+		case SystemHn:
+			patient.PatientHn = identifier.Value
+		}
+	}
+
+	for _, name := range resource.Name {
+		given := make([]string, 2)
+		copy(given, name.Given)
+		if nameLanguage(name) == "th" {
+			patient.FirstNameTh, patient.MiddleNameTh, patient.LastNameTh = given[0], given[1], name.Family
+		} else {
+			patient.FirstNameEn, patient.MiddleNameEn, patient.LastNameEn = given[0], given[1], name.Family
+		}
+	}
+
+	for _, telecom := range resource.Telecom {
+		switch telecom.System {
+		case "phone":
+			patient.PhoneNumber = telecom.Value
+		case "email":
+			patient.Email = telecom.Value
+		}
+	}
+
+	if resource.BirthDate != "" {
+		birthDate, err := time.Parse(birthDateLayout, resource.BirthDate)
+		if err != nil {
+			return nil, fmt.Errorf("birthDate must be in YYYY-MM-DD format")
+		}
+		patient.DateBirth = birthDate
+	}
+
+	if resource.ManagingOrganization != nil {
+		patient.Hospital = resource.ManagingOrganization.Display
+	}
+	return patient, nil
+}
+
+func identifierType(code string, display string) *CodeableConcept {
+	return &CodeableConcept{Coding: []Coding{{System: systemIdentifierType, Code: code, Display: display}}}
+}
+
+func humanName(language string, first string, middle string, last string) *HumanName {
+	given := make([]string, 0, 2)
+	for _, part := range []string{first, middle} {
+		if part = strings.TrimSpace(part); part != "" && part != "-" {
+			given = append(given, part)
+		}
+	}
+	if len(given) == 0 && last == "" {
+		return nil
+	}
+
+	return &HumanName{
+		Extension: []Extension{{Url: extensionLanguage, ValueCode: language}},
+		Use:       "official",
+		Text:      strings.TrimSpace(strings.Join(append(given, last), " ")),
+		Family:    last,
+		Given:     given,
+	}
+}
+
+// nameLanguage reads the language extension and falls back to looking for
+// Thai script when a sender leaves it out.
+func nameLanguage(name HumanName) string {
+	for _, extension := range name.Extension {
+		if extension.Url == extensionLanguage {
+			return strings.ToLower(extension.ValueCode)
+		}
+	}
+	for _, r := range name.Family + strings.Join(name.Given, "") {
+		if unicode.Is(unicode.Thai, r) {
+			return "th"
+		}
+	}
+	return "en"
+}
@@ -0,0 +1,63 @@
+package adapters_test
+
+import (
+	"testing"
+	"time"
+
+	adapters "agnos/internal/adapters/fhir"
+	"agnos/internal/entities"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFhirPatient_RoundTrip(t *testing.T) {
+	patient := &entities.Patient{
+		FirstNameTh:  "ปลาบปลื้ม",
+		MiddleNameTh: "-",
+		LastNameTh:   "ยอดจันทร์",
+		FirstNameEn:  "Plabpluem",
+		MiddleNameEn: "D",
+		LastNameEn:   "Yodchan",
+		DateBirth:    time.Date(1995, 7, 21, 0, 0, 0, 0, time.UTC),
+		PatientHn:    "HN00001",
+		NationalId:   "1234567890123",
+		PassportId:   "AA1234567",
+		PhoneNumber:  "0812345678",
+		Email:        "plabpluem@example.com",
+		Gender:       "male",
+		Hospital:     "Bangkok Hospital",
+	}
+
+	resource := adapters.ToFhirPatient(patient)
+	assert.Equal(t, "1995-07-21", resource.BirthDate)
+	assert.Equal(t, 3, len(resource.Identifier))
+	assert.Equal(t, 2, len(resource.Name))
+	assert.Equal(t, []string{"ปลาบปลื้ม"}, resource.Name[0].Given)
+	assert.Equal(t, "Plabpluem D Yodchan", resource.Name[1].Text)
+
+	mapped, err := adapters.FromFhirPatient(resource)
+	assert.NoError(t, err)
+	assert.Equal(t, patient.NationalId, mapped.NationalId)
+	assert.Equal(t, patient.PassportId, mapped.PassportId)
+	assert.Equal(t, patient.PatientHn, mapped.PatientHn)
+	assert.Equal(t, patient.FirstNameTh, mapped.FirstNameTh)
+	assert.Equal(t, patient.LastNameEn, mapped.LastNameEn)
+	assert.Equal(t, patient.MiddleNameEn, mapped.MiddleNameEn)
+	assert.Equal(t, patient.Email, mapped.Email)
+	assert.Equal(t, patient.DateBirth, mapped.DateBirth)
+}
+
+func TestFhirPatient_FallsBackToThaiScript(t *testing.T) {
+	resource := &adapters.Patient{
+		ResourceType: "Patient",
+		Name: []adapters.HumanName{
+			{Family: "ชวนศรี", Given: []string{"สมศักดิ์"}},
+			{Family: "Chunsri", Given: []string{"Somsak"}},
+		},
+	}
+
+	mapped, err := adapters.FromFhirPatient(resource)
+	assert.NoError(t, err)
+	assert.Equal(t, "สมศักดิ์", mapped.FirstNameTh)
+	assert.Equal(t, "Somsak", mapped.FirstNameEn)
+}
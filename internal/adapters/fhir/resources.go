@@ -0,0 +1,147 @@
+package adapters
+
+const (
+	SystemNationalId = "https://terms.sil-th.org/id/th-cid"
+	SystemPassport   = "http://hl7.org/fhir/sid/passport"
+	SystemHn         = "https://terms.sil-th.org/id/hn"
+
+	systemIdentifierType = "http://terminology.hl7.org/CodeSystem/v2-0203"
+	extensionLanguage    = "http://hl7.org/fhir/StructureDefinition/language"
+)
+
+type Meta struct {
+	LastUpdated string `json:"lastUpdated,omitempty"`
+}
+
+type Coding struct {
+	System  string `json:"system,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+type CodeableConcept struct {
+	Coding []Coding `json:"coding,omitempty"`
+	Text   string   `json:"text,omitempty"`
+}
+
+type Reference struct {
+	Reference string `json:"reference,omitempty"`
+	Display   string `json:"display,omitempty"`
+}
+
+type Extension struct {
+	Url       string `json:"url"`
+	ValueCode string `json:"valueCode,omitempty"`
+}
+
+type Identifier struct {
+	Use      string           `json:"use,omitempty"`
+	Type     *CodeableConcept `json:"type,omitempty"`
+	System   string           `json:"system,omitempty"`
+	Value    string           `json:"value,omitempty"`
+	Assigner *Reference       `json:"assigner,omitempty"`
+}
+
+type HumanName struct {
+	Extension []Extension `json:"extension,omitempty"`
+	Use       string      `json:"use,omitempty"`
+	Text      string      `json:"text,omitempty"`
+	Family    string      `json:"family,omitempty"`
+	Given     []string    `json:"given,omitempty"`
+}
+
+type ContactPoint struct {
+	System string `json:"system,omitempty"`
+	Value  string `json:"value,omitempty"`
+	Use    string `json:"use,omitempty"`
+}
+
+type Patient struct {
+	ResourceType         string         `json:"resourceType"`
+	Id                   string         `json:"id,omitempty"`
+	Meta                 *Meta          `json:"meta,omitempty"`
+	Identifier           []Identifier   `json:"identifier,omitempty"`
+	Active               *bool          `json:"active,omitempty"`
+	Name                 []HumanName    `json:"name,omitempty"`
+	Telecom              []ContactPoint `json:"telecom,omitempty"`
+	Gender               string         `json:"gender,omitempty"`
+	BirthDate            string         `json:"birthDate,omitempty"`
+	ManagingOrganization *Reference     `json:"managingOrganization,omitempty"`
+}
+
+type BundleEntrySearch struct {
+	Mode string `json:"mode,omitempty"`
+}
+
+type BundleEntry struct {
+	FullUrl  string             `json:"fullUrl,omitempty"`
+	Resource interface{}        `json:"resource,omitempty"`
+	Search   *BundleEntrySearch `json:"search,omitempty"`
+}
+
+type BundleLink struct {
+	Relation string `json:"relation"`
+	Url      string `json:"url"`
+}
+
+type Bundle struct {
+	ResourceType string        `json:"resourceType"`
+	Type         string        `json:"type"`
+	Total        int           `json:"total"`
+	Link         []BundleLink  `json:"link,omitempty"`
+	Entry        []BundleEntry `json:"entry,omitempty"`
+}
+
+type OperationOutcomeIssue struct {
+	Severity    string `json:"severity"`
+	Code        string `json:"code"`
+	Diagnostics string `json:"diagnostics,omitempty"`
+}
+
+type OperationOutcome struct {
+	ResourceType string                  `json:"resourceType"`
+	Issue        []OperationOutcomeIssue `json:"issue"`
+}
+
+func NewOperationOutcome(code string, diagnostics string) *OperationOutcome {
+	return &OperationOutcome{
+		ResourceType: "OperationOutcome",
+		Issue:        []OperationOutcomeIssue{{Severity: "error", Code: code, Diagnostics: diagnostics}},
+	}
+}
+
+type CapabilitySearchParam struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type CapabilityInteraction struct {
+	Code string `json:"code"`
+}
+
+type CapabilityResource struct {
+	Type        string                  `json:"type"`
+	Profile     string                  `json:"profile,omitempty"`
+	Interaction []CapabilityInteraction `json:"interaction"`
+	SearchParam []CapabilitySearchParam `json:"searchParam,omitempty"`
+}
+
+type CapabilityRest struct {
+	Mode     string               `json:"mode"`
+	Resource []CapabilityResource `json:"resource"`
+}
+
+type CapabilitySoftware struct {
+	Name string `json:"name"`
+}
+
+type CapabilityStatement struct {
+	ResourceType string             `json:"resourceType"`
+	Status       string             `json:"status"`
+	Date         string             `json:"date"`
+	Kind         string             `json:"kind"`
+	Software     CapabilitySoftware `json:"software"`
+	FhirVersion  string             `json:"fhirVersion"`
+	Format       []string           `json:"format"`
+	Rest         []CapabilityRest   `json:"rest"`
+}
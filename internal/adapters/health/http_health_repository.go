@@ -0,0 +1,62 @@
+package adapters
+
+import (
+	"agnos/pkg/health"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HttpHealthHandler struct {
+	checker *health.Checker
+	details func() gin.H
+	started time.Time
+}
+
+// NewHttpHealthRepository serves checker's results. details adds runtime
+// information, such as pool stats, to the admin health report.
+func NewHttpHealthRepository(checker *health.Checker, details func() gin.H) *HttpHealthHandler {
+	return &HttpHealthHandler{checker: checker, details: details, started: time.Now()}
+}
+
+// Liveness only says the process is serving requests. It never checks
+// dependencies so that a database outage does not restart every replica.
+func (h *HttpHealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": health.StatusUp})
+}
+
+// Readiness runs every check. Errors are left out because the endpoint is
+// not authenticated.
+func (h *HttpHealthHandler) Readiness(c *gin.Context) {
+	report := h.checker.Run(c.Request.Context())
+
+	checks := make(gin.H, len(report.Checks))
+	for name, result := range report.Checks {
+		checks[name] = result.Status
+	}
+	c.JSON(statusCode(report), gin.H{"status": report.Status, "checks": checks})
+}
+
+func (h *HttpHealthHandler) Health(c *gin.Context) {
+	report := h.checker.Run(c.Request.Context())
+
+	response := gin.H{
+		"status":         report.Status,
+		"checks":         report.Checks,
+		"uptime_seconds": int64(time.Since(h.started).Seconds()),
+	}
+	if h.details != nil {
+		for key, value := range h.details() {
+			response[key] = value
+		}
+	}
+	c.JSON(statusCode(report), response)
+}
+
+func statusCode(report health.Report) int {
+	if report.Status == health.StatusDown {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
+}
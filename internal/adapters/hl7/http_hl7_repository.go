@@ -0,0 +1,45 @@
+package adapters
+
+import (
+	"agnos/internal/usecases/adt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	hl7ContentType = "application/hl7-v2; charset=utf-8"
+	maxBodySize    = 1 << 20
+)
+
+type HttpHl7Handler struct {
+	adtUseCase adt.AdtUseCase
+}
+
+func NewHttpHl7Repository(usecase adt.AdtUseCase) *HttpHl7Handler {
+	return &HttpHl7Handler{adtUseCase: usecase}
+}
+
+func (h *HttpHl7Handler) IngestMessage(c *gin.Context) {
+	payload, exist := c.Get("payload")
+	if !exist {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	claims := payload.(jwt.MapClaims)
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxBodySize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ack, err := h.adtUseCase.Ingest(c.Request.Context(), body, claims["hospital"].(string))
+	if err != nil {
+		c.Data(http.StatusBadRequest, hl7ContentType, ack)
+		return
+	}
+	c.Data(http.StatusOK, hl7ContentType, ack)
+}
@@ -0,0 +1,184 @@
+package adapters
+
+import (
+	"agnos/internal/usecases/adt"
+	"agnos/pkg/hl7"
+	"agnos/pkg/logging"
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+const idleTimeout = 5 * time.Minute
+
+type MllpHl7Listener struct {
+	adtUseCase adt.AdtUseCase
+	senders    []MllpSender
+	// messageTimeout bounds the processing of each message; zero leaves it
+	// unbounded.
+	messageTimeout time.Duration
+
+	mu       sync.Mutex
+	listener net.Listener
+	serving  bool
+	closing  bool
+	conns    map[net.Conn]struct{}
+	wg       sync.WaitGroup
+}
+
+// NewMllpHl7Listener accepts messages only from senders, and NAKs any other.
+func NewMllpHl7Listener(usecase adt.AdtUseCase, senders []MllpSender, messageTimeout time.Duration) *MllpHl7Listener {
+	return &MllpHl7Listener{adtUseCase: usecase, senders: senders, messageTimeout: messageTimeout}
+}
+
+func (l *MllpHl7Listener) ListenAndServe(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return l.Serve(listener)
+}
+
+func (l *MllpHl7Listener) Serve(listener net.Listener) error {
+	l.mu.Lock()
+	l.listener, l.serving = listener, true
+	l.mu.Unlock()
+	defer func() {
+		l.mu.Lock()
+		l.serving = false
+		l.mu.Unlock()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		l.mu.Lock()
+		if l.conns == nil {
+			l.conns = make(map[net.Conn]struct{})
+		}
+		l.conns[conn] = struct{}{}
+		l.wg.Add(1)
+		l.mu.Unlock()
+		go l.handle(conn)
+	}
+}
+
+func (l *MllpHl7Listener) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.listener == nil {
+		return nil
+	}
+	return l.listener.Close()
+}
+
+// Shutdown stops accepting connections and waits for the open ones to
+// finish the message they are processing. Idle connections are closed at
+// once. It gives up when ctx ends.
+func (l *MllpHl7Listener) Shutdown(ctx context.Context) error {
+	err := l.Close()
+
+	l.mu.Lock()
+	l.closing = true
+	for conn := range l.conns {
+		conn.SetReadDeadline(time.Now())
+	}
+	l.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		l.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Check reports whether the listener is accepting connections.
+func (l *MllpHl7Listener) Check(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.serving {
+		return errors.New("mllp listener is not accepting connections")
+	}
+	return nil
+}
+
+func (l *MllpHl7Listener) handle(conn net.Conn) {
+	defer l.wg.Done()
+	defer func() {
+		conn.Close()
+		l.mu.Lock()
+		delete(l.conns, conn)
+		l.mu.Unlock()
+	}()
+	reader := bufio.NewReader(conn)
+
+	for {
+		// The deadline is set under the lock so that Shutdown cannot be
+		// overtaken by a fresh idle deadline.
+		l.mu.Lock()
+		closing := l.closing
+		if !closing {
+			conn.SetReadDeadline(time.Now().Add(idleTimeout))
+		}
+		l.mu.Unlock()
+		if closing {
+			return
+		}
+
+		message, err := hl7.ReadFrame(reader)
+		if err != nil {
+			return
+		}
+
+		ctx := logging.WithRequestID(context.Background(), logging.NewRequestID())
+		ack, err := l.ingest(ctx, conn.RemoteAddr(), message)
+		if err != nil {
+			logging.For("hl7").WarnContext(ctx, "message rejected", "remote_addr", conn.RemoteAddr().String(), "error", err.Error())
+		}
+		if err := hl7.WriteFrame(conn, ack); err != nil {
+			return
+		}
+	}
+}
+
+// ingest files message under the hospital of its sender, which is found by
+// MSH-4 and the peer address since MLLP carries no credentials.
+func (l *MllpHl7Listener) ingest(ctx context.Context, addr net.Addr, message []byte) ([]byte, error) {
+	parsed, err := hl7.Parse(message)
+	if err != nil {
+		return hl7.BuildAck(nil, hl7.AckReject, err.Error()), err
+	}
+	sender, ok := l.sender(parsed.Get("MSH-4.1"), addr)
+	if !ok {
+		err := fmt.Errorf("sender %s is not allowed", parsed.Get("MSH-4.1"))
+		return hl7.BuildAck(parsed, hl7.AckReject, err.Error()), err
+	}
+
+	if l.messageTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.messageTimeout)
+		defer cancel()
+	}
+	return l.adtUseCase.Ingest(ctx, message, sender.Hospital)
+}
+
+func (l *MllpHl7Listener) sender(facility string, addr net.Addr) (MllpSender, bool) {
+	for _, sender := range l.senders {
+		if sender.matches(facility, addr) {
+			return sender, true
+		}
+	}
+	return MllpSender{}, false
+}
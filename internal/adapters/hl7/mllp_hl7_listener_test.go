@@ -0,0 +1,232 @@
+package adapters_test
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	adapters "agnos/internal/adapters/hl7"
+	mpiDto "agnos/internal/adapters/mpi/dto"
+	"agnos/internal/adapters/patient/dto"
+	"agnos/internal/entities"
+	"agnos/internal/usecases/adt"
+	"agnos/pkg/hl7"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakePatientUseCase struct {
+	patients []*entities.Patient
+}
+
+func (f *fakePatientUseCase) CreatePatient(ctx context.Context, patient *entities.Patient) (*entities.Patient, error) {
+	patient.ID = uint(len(f.patients) + 1)
+	f.patients = append(f.patients, patient)
+	return patient, nil
+}
+
+func (f *fakePatientUseCase) UpdatePatient(ctx context.Context, patient *entities.Patient) (*entities.Patient, error) {
+	return patient, nil
+}
+
+func (f *fakePatientUseCase) SearchPatient(ctx context.Context, query *dto.SearchPatientDto) ([]*entities.Patient, error) {
+	found := make([]*entities.Patient, 0)
+	for _, p := range f.patients {
+		if !strings.EqualFold(p.Hospital, query.Hospital) {
+			continue
+		}
+		if p.NationalId == query.Identifier || p.PassportId == query.Identifier || p.PatientHn == query.Identifier {
+			found = append(found, p)
+		}
+	}
+	return found, nil
+}
+
+func (f *fakePatientUseCase) ExportPatient(ctx context.Context, query *dto.SearchPatientDto, fn func(patients []*entities.Patient) error) error {
+	return nil
+}
+
+func (f *fakePatientUseCase) SearchPatientId(ctx context.Context, id string, hospital string) (*entities.Patient, error) {
+	return nil, nil
+}
+
+func (f *fakePatientUseCase) GetPatient(ctx context.Context, id uint) (*entities.Patient, error) {
+	return nil, nil
+}
+
+type fakeMpiUseCase struct{}
+
+func (fakeMpiUseCase) DetectDuplicates(ctx context.Context, patient *entities.Patient) ([]*entities.PatientDuplicate, error) {
+	return nil, nil
+}
+
+func (fakeMpiUseCase) ListDuplicates(ctx context.Context, hospital string, status string) ([]*entities.PatientDuplicate, error) {
+	return nil, nil
+}
+
+func (fakeMpiUseCase) DismissDuplicate(ctx context.Context, hospital string, id uint) error {
+	return nil
+}
+
+func (fakeMpiUseCase) MergePatient(ctx context.Context, merge *mpiDto.MergePatientDto) (*entities.Patient, error) {
+	return nil, nil
+}
+
+var huahin = []adapters.MllpSender{{Facility: "HUAHIN", Network: &net.IPNet{IP: net.IPv4(127, 0, 0, 0).To4(), Mask: net.CIDRMask(8, 32)}, Hospital: "Hua-Hin Hospital"}}
+
+func sendFixture(t *testing.T, conn net.Conn, reader *bufio.Reader, name string) *hl7.Message {
+	raw, err := os.ReadFile("testdata/" + name)
+	assert.NoError(t, err)
+	assert.NoError(t, hl7.WriteFrame(conn, raw))
+
+	response, err := hl7.ReadFrame(reader)
+	assert.NoError(t, err)
+	ack, err := hl7.Parse(response)
+	assert.NoError(t, err)
+	return ack
+}
+
+func TestMllpListener_RegistersAndUpdatesPatient(t *testing.T) {
+	patients := &fakePatientUseCase{}
+	listener := adapters.NewMllpHl7Listener(adt.NewAdtService(patients, fakeMpiUseCase{}), huahin, time.Second)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go listener.Serve(ln)
+	defer listener.Close()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	assert.NoError(t, err)
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	ack := sendFixture(t, conn, reader, "adt_a04.hl7")
+	assert.Equal(t, "AA", ack.Get("MSA-1"))
+	assert.Equal(t, "MSG00001", ack.Get("MSA-2"))
+
+	assert.Equal(t, 1, len(patients.patients))
+	registered := patients.patients[0]
+	assert.Equal(t, "Hua-Hin Hospital", registered.Hospital)
+	assert.Equal(t, "1234567890123", registered.NationalId)
+	assert.Equal(t, "HN00001", registered.PatientHn)
+	assert.Equal(t, "Plabpluem", registered.FirstNameEn)
+	assert.Equal(t, "ปลาบปลื้ม", registered.FirstNameTh)
+	assert.Equal(t, "male", registered.Gender)
+	assert.Equal(t, "0812345678", registered.PhoneNumber)
+	assert.Equal(t, "plabpluem@example.com", registered.Email)
+	assert.Equal(t, 1995, registered.DateBirth.Year())
+
+	ack = sendFixture(t, conn, reader, "adt_a08.hl7")
+	assert.Equal(t, "AA", ack.Get("MSA-1"))
+	assert.Equal(t, 1, len(patients.patients))
+	assert.Equal(t, "0899999999", registered.PhoneNumber)
+	assert.Equal(t, "", registered.Email)
+	assert.Equal(t, "ปลาบปลื้ม", registered.FirstNameTh)
+
+	ack = sendFixture(t, conn, reader, "adt_a01.hl7")
+	assert.Equal(t, "AR", ack.Get("MSA-1"))
+	assert.Equal(t, "MSG00003", ack.Get("MSA-2"))
+}
+
+func TestMllpListener_ShutdownClosesIdleConnections(t *testing.T) {
+	patients := &fakePatientUseCase{}
+	listener := adapters.NewMllpHl7Listener(adt.NewAdtService(patients, fakeMpiUseCase{}), huahin, time.Second)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go listener.Serve(ln)
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	assert.NoError(t, err)
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	assert.Equal(t, "AA", sendFixture(t, conn, reader, "adt_a04.hl7").Get("MSA-1"))
+	assert.NoError(t, listener.Check(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, listener.Shutdown(ctx))
+
+	_, err = reader.ReadByte()
+	assert.Error(t, err)
+	assert.Eventually(t, func() bool { return listener.Check(context.Background()) != nil }, time.Second, 10*time.Millisecond)
+}
+
+func TestMllpListener_RejectsUnknownSenders(t *testing.T) {
+	_, loopback, _ := net.ParseCIDR("127.0.0.0/8")
+	_, elsewhere, _ := net.ParseCIDR("10.0.0.0/8")
+	tests := []struct {
+		name    string
+		senders []adapters.MllpSender
+		want    string
+	}{
+		{"facility and address match", []adapters.MllpSender{{Facility: "huahin", Network: loopback, Hospital: "Hua-Hin Hospital"}}, "AA"},
+		{"address matches", []adapters.MllpSender{{Network: loopback, Hospital: "Hua-Hin Hospital"}}, "AA"},
+		{"facility without address", []adapters.MllpSender{{Facility: "HUAHIN", Hospital: "Hua-Hin Hospital"}}, "AR"},
+		{"other facility", []adapters.MllpSender{{Facility: "SIRIRAJ", Network: loopback, Hospital: "Siriraj Hospital"}}, "AR"},
+		{"other address", []adapters.MllpSender{{Facility: "HUAHIN", Network: elsewhere, Hospital: "Hua-Hin Hospital"}}, "AR"},
+		{"no senders", nil, "AR"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patients := &fakePatientUseCase{}
+			listener := adapters.NewMllpHl7Listener(adt.NewAdtService(patients, fakeMpiUseCase{}), test.senders, time.Second)
+
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			assert.NoError(t, err)
+			go listener.Serve(ln)
+			defer listener.Close()
+
+			conn, err := net.Dial("tcp", ln.Addr().String())
+			assert.NoError(t, err)
+			defer conn.Close()
+
+			ack := sendFixture(t, conn, bufio.NewReader(conn), "adt_a04.hl7")
+			assert.Equal(t, test.want, ack.Get("MSA-1"))
+			assert.Equal(t, test.want == "AA", len(patients.patients) == 1)
+		})
+	}
+}
+
+func TestMllpListener_FilesUnderTheSendersHospital(t *testing.T) {
+	patients := &fakePatientUseCase{}
+	_, loopback, _ := net.ParseCIDR("127.0.0.0/8")
+	senders := []adapters.MllpSender{{Facility: "HUAHIN", Network: loopback, Hospital: "Bangkok Hospital"}}
+	listener := adapters.NewMllpHl7Listener(adt.NewAdtService(patients, fakeMpiUseCase{}), senders, time.Second)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go listener.Serve(ln)
+	defer listener.Close()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	// The message names Hua-Hin Hospital in PV1-3, which the sender may not
+	// write to.
+	assert.Equal(t, "AA", sendFixture(t, conn, bufio.NewReader(conn), "adt_a04.hl7").Get("MSA-1"))
+	assert.Equal(t, 1, len(patients.patients))
+	assert.Equal(t, "Bangkok Hospital", patients.patients[0].Hospital)
+}
+
+func TestParseMllpSenders(t *testing.T) {
+	senders, err := adapters.ParseMllpSenders(" HUAHIN@10.1.0.0/24=Hua-Hin Hospital, @10.2.0.7=Siriraj Hospital,SIRIRAJ@10.2.0.0/16=Siriraj Hospital")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(senders))
+	assert.Equal(t, "HUAHIN", senders[0].Facility)
+	assert.Equal(t, "10.1.0.0/24", senders[0].Network.String())
+	assert.Equal(t, "Hua-Hin Hospital", senders[0].Hospital)
+	assert.Equal(t, "", senders[1].Facility)
+	assert.Equal(t, "10.2.0.7/32", senders[1].Network.String())
+	assert.Equal(t, "SIRIRAJ", senders[2].Facility)
+
+	for _, value := range []string{"HUAHIN", "HUAHIN=", "HUAHIN=Hua-Hin Hospital", "HUAHIN@=Hua-Hin Hospital", "=Hua-Hin Hospital", "HUAHIN@10.1.0.300=Hua-Hin Hospital"} {
+		_, err := adapters.ParseMllpSenders(value)
+		assert.Error(t, err, value)
+	}
+}
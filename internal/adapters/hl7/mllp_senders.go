@@ -0,0 +1,78 @@
+package adapters
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// MllpSender is a system allowed to send ADT messages over MLLP. MLLP has
+// no credentials, so a sender is recognised by its address, and optionally
+// also by its sending facility (MSH-4), and its messages are always filed
+// under Hospital whatever facility they name. MSH-4 is written by the sender,
+// so it never identifies one on its own.
+type MllpSender struct {
+	// Facility is matched against MSH-4.1 ignoring case; "" matches any.
+	Facility string
+	// Network holds the peer addresses of the sender; nil matches none.
+	Network  *net.IPNet
+	Hospital string
+}
+
+// ParseMllpSenders reads senders such as
+// "HUAHIN@10.1.0.0/24=Hua-Hin Hospital,@10.2.0.7=Siriraj Hospital",
+// separated by commas. The facility may be left out, the address may not.
+func ParseMllpSenders(value string) ([]MllpSender, error) {
+	var senders []MllpSender
+	for _, entry := range strings.Split(value, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		match, hospital, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(hospital) == "" {
+			return nil, fmt.Errorf("%q is not FACILITY@address=hospital", entry)
+		}
+		facility, address, _ := strings.Cut(match, "@")
+		sender := MllpSender{Facility: strings.TrimSpace(facility), Hospital: strings.TrimSpace(hospital)}
+		if address = strings.TrimSpace(address); address != "" {
+			network, err := parseNetwork(address)
+			if err != nil {
+				return nil, err
+			}
+			sender.Network = network
+		}
+		if sender.Network == nil {
+			return nil, fmt.Errorf("%q names no address", entry)
+		}
+		senders = append(senders, sender)
+	}
+	return senders, nil
+}
+
+// parseNetwork reads a CIDR block, or a single address as a block of one.
+func parseNetwork(address string) (*net.IPNet, error) {
+	if strings.Contains(address, "/") {
+		_, network, err := net.ParseCIDR(address)
+		return network, err
+	}
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return nil, fmt.Errorf("%q is not an IP address", address)
+	}
+	bits := 8 * len(ip.To16())
+	if ip.To4() != nil {
+		ip, bits = ip.To4(), 32
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+func (s MllpSender) matches(facility string, addr net.Addr) bool {
+	if s.Facility != "" && !strings.EqualFold(s.Facility, facility) {
+		return false
+	}
+	if s.Network == nil {
+		return false
+	}
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	return ok && s.Network.Contains(tcpAddr.IP)
+}
@@ -0,0 +1,48 @@
+package adapters
+
+import (
+	"agnos/internal/entities"
+	"agnos/internal/usecases/hospital"
+	"agnos/pkg/database"
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+type GormHospitalRepository struct {
+	db *gorm.DB
+}
+
+func NewGormHospitalRepository(db *gorm.DB) hospital.HospitalRepository {
+	return &GormHospitalRepository{db: db}
+}
+
+// Save refuses a name that differs from a registered hospital only in case,
+// since every lookup by hospital ignores case.
+func (r *GormHospitalRepository) Save(ctx context.Context, hospital *entities.Hospital) (*entities.Hospital, error) {
+	var existing entities.Hospital
+	if err := r.db.WithContext(ctx).Where(database.EqualFold(r.db, "name", hospital.Name)).First(&existing).Error; err == nil {
+		return nil, fmt.Errorf("hospital %s already exist", existing.Name)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// The unique index catches a hospital registered since the check above.
+	if err := r.db.WithContext(ctx).Save(hospital).Error; err != nil {
+		if database.IsUniqueViolation(r.db, err) {
+			return nil, fmt.Errorf("hospital %s already exist", hospital.Name)
+		}
+		return nil, err
+	}
+	return hospital, nil
+}
+
+func (r *GormHospitalRepository) FindAll(ctx context.Context) ([]*entities.Hospital, error) {
+	var hospitals []*entities.Hospital
+	if err := r.db.WithContext(ctx).Order("name").Find(&hospitals).Error; err != nil {
+		return nil, err
+	}
+	return hospitals, nil
+}
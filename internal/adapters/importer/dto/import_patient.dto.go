@@ -0,0 +1,11 @@
+package dto
+
+type ImportPatientDto struct {
+	FileName  string
+	Header    []string
+	Rows      [][]string
+	Mapping   map[string]string
+	DryRun    bool
+	Hospital  string
+	CreatedBy string
+}
@@ -0,0 +1,66 @@
+package adapters
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ReadRows returns the header and data rows of a CSV or XLSX upload. The
+// format is taken from format when given, otherwise from the file extension.
+func ReadRows(fileName string, format string, r io.Reader) ([]string, [][]string, error) {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(fileName)), ".")
+	}
+
+	var rows [][]string
+	var err error
+	switch strings.ToLower(format) {
+	case "csv":
+		rows, err = readCsv(r)
+	case "xlsx":
+		rows, err = readXlsx(r)
+	default:
+		return nil, nil, fmt.Errorf("file format %s is not supported", format)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, nil, fmt.Errorf("file is empty")
+	}
+	return rows[0], rows[1:], nil
+}
+
+func readCsv(r io.Reader) ([][]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	return reader.ReadAll()
+}
+
+func readXlsx(r io.Reader) ([][]string, error) {
+	file, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	sheets := file.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, fmt.Errorf("workbook has no sheets")
+	}
+	return file.GetRows(sheets[0])
+}
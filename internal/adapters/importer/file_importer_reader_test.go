@@ -0,0 +1,39 @@
+package adapters_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	adapters "agnos/internal/adapters/importer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestReadRows_Csv(t *testing.T) {
+	csv := "\xef\xbb\xbfnational_id,first_name_th\n1100000000001,สมศักดิ์\n"
+
+	header, rows, err := adapters.ReadRows("patients.csv", "", strings.NewReader(csv))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"national_id", "first_name_th"}, header)
+	assert.Equal(t, [][]string{{"1100000000001", "สมศักดิ์"}}, rows)
+}
+
+func TestReadRows_Xlsx(t *testing.T) {
+	file := excelize.NewFile()
+	file.SetSheetRow("Sheet1", "A1", &[]interface{}{"national_id", "gender"})
+	file.SetSheetRow("Sheet1", "A2", &[]interface{}{"1100000000001", "male"})
+	var buf bytes.Buffer
+	assert.NoError(t, file.Write(&buf))
+
+	header, rows, err := adapters.ReadRows("patients.xlsx", "", &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"national_id", "gender"}, header)
+	assert.Equal(t, [][]string{{"1100000000001", "male"}}, rows)
+}
+
+func TestReadRows_UnsupportedFormat(t *testing.T) {
+	_, _, err := adapters.ReadRows("patients.txt", "", strings.NewReader(""))
+	assert.EqualError(t, err, "file format txt is not supported")
+}
@@ -0,0 +1,74 @@
+package adapters
+
+import (
+	"agnos/internal/entities"
+	"agnos/internal/usecases/importer"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type GormImportRepository struct {
+	db *gorm.DB
+}
+
+func NewGormImportRepository(db *gorm.DB) importer.ImportRepository {
+	return &GormImportRepository{db: db}
+}
+
+func (r *GormImportRepository) SaveJob(ctx context.Context, job *entities.ImportJob) error {
+	return r.db.WithContext(ctx).Create(job).Error
+}
+
+func (r *GormImportRepository) UpdateJob(ctx context.Context, job *entities.ImportJob) error {
+	return r.db.WithContext(ctx).Omit("Errors").Save(job).Error
+}
+
+func (r *GormImportRepository) FindJob(ctx context.Context, id uint) (*entities.ImportJob, error) {
+	var job entities.ImportJob
+	err := r.db.WithContext(ctx).Preload("Errors", func(db *gorm.DB) *gorm.DB {
+		return db.Order("row_no ASC")
+	}).First(&job, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *GormImportRepository) SaveRowErrors(ctx context.Context, rowErrors []*entities.ImportRowError) error {
+	if len(rowErrors) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).CreateInBatches(rowErrors, 500).Error
+}
+
+func (r *GormImportRepository) ExistingNationalIds(ctx context.Context, nationalIds []string) (map[string]bool, error) {
+	ids := make(map[string]string, len(nationalIds))
+	indexes := make([]string, 0, len(nationalIds))
+	for _, id := range nationalIds {
+		index, err := entities.IdentifierIndex(id)
+		if err != nil {
+			return nil, err
+		}
+		ids[index] = id
+		indexes = append(indexes, index)
+	}
+
+	var found []string
+	err := r.db.WithContext(ctx).Model(&entities.Patient{}).Where("national_id_index IN ?", indexes).Pluck("national_id_index", &found).Error
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]bool, len(found))
+	for _, index := range found {
+		existing[ids[index]] = true
+	}
+	return existing, nil
+}
+
+func (r *GormImportRepository) InsertPatients(ctx context.Context, patients []*entities.Patient) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.Create(&patients).Error
+	})
+}
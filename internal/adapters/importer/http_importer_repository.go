@@ -0,0 +1,97 @@
+package adapters
+
+import (
+	"agnos/internal/adapters/importer/dto"
+	"agnos/internal/usecases/importer"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type HttpImportHandler struct {
+	importUseCase importer.ImportUseCase
+}
+
+func NewHttpImportRepository(usecase importer.ImportUseCase) *HttpImportHandler {
+	return &HttpImportHandler{importUseCase: usecase}
+}
+
+func (h *HttpImportHandler) ImportPatient(c *gin.Context) {
+	payload, exist := c.Get("payload")
+	if !exist {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	claims := payload.(jwt.MapClaims)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+
+	data := dto.ImportPatientDto{
+		FileName:  fileHeader.Filename,
+		Hospital:  claims["hospital"].(string),
+		CreatedBy: claims["username"].(string),
+	}
+
+	if dryRun := c.PostForm("dry_run"); dryRun != "" {
+		if data.DryRun, err = strconv.ParseBool(dryRun); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "dry_run must be a boolean"})
+			return
+		}
+	}
+
+	if mapping := c.PostForm("mapping"); mapping != "" {
+		if err := json.Unmarshal([]byte(mapping), &data.Mapping); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "mapping must be a JSON object"})
+			return
+		}
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	data.Header, data.Rows, err = ReadRows(fileHeader.Filename, c.PostForm("format"), file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	job, err := h.importUseCase.StartImport(c.Request.Context(), &data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "import accepted", "statusCode": 202, "data": job})
+}
+
+func (h *HttpImportHandler) GetImportJob(c *gin.Context) {
+	payload, exist := c.Get("payload")
+	if !exist {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	claims := payload.(jwt.MapClaims)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id is invalid"})
+		return
+	}
+
+	job, err := h.importUseCase.GetImportJob(c.Request.Context(), claims["hospital"].(string), uint(id))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "search success", "statusCode": 200, "data": job})
+}
@@ -0,0 +1,152 @@
+package adapters
+
+import (
+	adaptersPatient "agnos/internal/adapters/patient"
+	"agnos/internal/entities"
+	"agnos/internal/usecases/importer"
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MemoryImportRepository keeps import jobs in memory with the same semantics
+// as GormImportRepository. Imported patients go to patients.
+type MemoryImportRepository struct {
+	patients *adaptersPatient.MemoryPatientRepository
+
+	mu        sync.RWMutex
+	jobs      map[uint]entities.ImportJob
+	rowErrors []entities.ImportRowError
+	nextId    uint
+	nextRowId uint
+}
+
+func NewMemoryImportRepository(patients *adaptersPatient.MemoryPatientRepository) importer.ImportRepository {
+	return &MemoryImportRepository{patients: patients, jobs: map[uint]entities.ImportJob{}}
+}
+
+func (r *MemoryImportRepository) SaveJob(ctx context.Context, job *entities.ImportJob) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextId++
+	job.ID = r.nextId
+	job.CreatedAt = time.Now()
+	job.UpdatedAt = job.CreatedAt
+	r.jobs[job.ID] = *job
+	return nil
+}
+
+func (r *MemoryImportRepository) UpdateJob(ctx context.Context, job *entities.ImportJob) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job.UpdatedAt = time.Now()
+	stored := *job
+	stored.Errors = nil
+	r.jobs[job.ID] = stored
+	return nil
+}
+
+func (r *MemoryImportRepository) FindJob(ctx context.Context, id uint) (*entities.ImportJob, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	job, ok := r.jobs[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	for _, rowError := range r.rowErrors {
+		if rowError.ImportJobID == id {
+			job.Errors = append(job.Errors, rowError)
+		}
+	}
+	sort.SliceStable(job.Errors, func(i, j int) bool { return job.Errors[i].Row < job.Errors[j].Row })
+	return &job, nil
+}
+
+func (r *MemoryImportRepository) SaveRowErrors(ctx context.Context, rowErrors []*entities.ImportRowError) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, rowError := range rowErrors {
+		r.nextRowId++
+		rowError.ID = r.nextRowId
+		r.rowErrors = append(r.rowErrors, *rowError)
+	}
+	return nil
+}
+
+func (r *MemoryImportRepository) ExistingNationalIds(ctx context.Context, nationalIds []string) (map[string]bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ids := make(map[string]string, len(nationalIds))
+	for _, id := range nationalIds {
+		index, err := entities.IdentifierIndex(id)
+		if err != nil {
+			return nil, err
+		}
+		ids[index] = id
+	}
+
+	existing := make(map[string]bool)
+	for _, p := range r.patients.Unscoped() {
+		if id, ok := ids[p.NationalIdIndex]; ok && !p.DeletedAt.Valid {
+			existing[id] = true
+		}
+	}
+	return existing, nil
+}
+
+// InsertPatients saves every patient or, when one fails, none of them.
+func (r *MemoryImportRepository) InsertPatients(ctx context.Context, patients []*entities.Patient) error {
+	restore := r.patients.Snapshot()
+	for _, p := range patients {
+		if _, err := r.patients.Save(ctx, p); err != nil {
+			restore()
+			return err
+		}
+	}
+	return nil
+}
+
+// Jobs returns copies of the stored jobs, without their row errors, for
+// the in-memory retention repository.
+func (r *MemoryImportRepository) Jobs() []*entities.ImportJob {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	jobs := make([]*entities.ImportJob, 0, len(r.jobs))
+	for _, stored := range r.jobs {
+		job := stored
+		jobs = append(jobs, &job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+	return jobs
+}
+
+// RemoveJobs deletes the jobs with ids and their row errors for good.
+func (r *MemoryImportRepository) RemoveJobs(ids ...uint) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, id := range ids {
+		delete(r.jobs, id)
+	}
+	r.rowErrors = slices.DeleteFunc(r.rowErrors, func(e entities.ImportRowError) bool { return slices.Contains(ids, e.ImportJobID) })
+}
@@ -0,0 +1,9 @@
+package dto
+
+type MergePatientDto struct {
+	SurvivorId uint   `json:"survivor_id" validate:"required"`
+	MergedId   uint   `json:"merged_id" validate:"required,nefield=SurvivorId"`
+	MergedBy   string `json:"-"`
+	Role       string `json:"-"`
+	Hospital   string `json:"-"`
+}
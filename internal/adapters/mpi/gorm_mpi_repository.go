@@ -0,0 +1,195 @@
+package adapters
+
+import (
+	"agnos/internal/entities"
+	"agnos/internal/usecases/mpi"
+	"agnos/pkg/database"
+	"context"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type GormMpiRepository struct {
+	db *gorm.DB
+}
+
+func NewGormMpiRepository(db *gorm.DB) mpi.MpiRepository {
+	return &GormMpiRepository{db: db}
+}
+
+// namePrefixLength is how many leading characters of a name a blocking key
+// compares, so that a typo further along still lands in the same block.
+const namePrefixLength = 3
+
+// FindCandidates returns patients sharing an identifier with patient first,
+// then those sharing a date of birth and the start of a last name, or a last
+// name and the start of a first name. Each group is in ID order, so the same
+// candidates are scored every time the limit cuts the list short.
+func (r *GormMpiRepository) FindCandidates(ctx context.Context, patient *entities.Patient, limit int) ([]*entities.Patient, error) {
+	// Identifiers and contact details are encrypted, so they are blocked on
+	// their blind indexes. BeforeSave fills these, which every stored
+	// patient has been through.
+	probe := *patient
+	if err := probe.BeforeSave(r.db); err != nil {
+		return nil, err
+	}
+
+	identifiers := r.db.Where("national_id_index = ?", probe.NationalIdIndex)
+	if probe.PassportIdIndex != "" {
+		identifiers = identifiers.Or("passport_id_index = ?", probe.PassportIdIndex)
+	}
+	if probe.PhoneNumberIndex != "" {
+		identifiers = identifiers.Or("phone_number_index = ?", probe.PhoneNumberIndex)
+	}
+	if probe.EmailIndex != "" {
+		identifiers = identifiers.Or("email_index = ?", probe.EmailIndex)
+	}
+	var candidates []*entities.Patient
+	if err := r.candidates(ctx, patient.ID, nil, identifiers, limit, &candidates); err != nil {
+		return nil, err
+	}
+
+	var names *gorm.DB
+	either := func(condition *gorm.DB) {
+		if names == nil {
+			names = r.db.Where(condition)
+		} else {
+			names = names.Or(condition)
+		}
+	}
+	if lastName := r.nameBlock("last_name", patient.LastNameTh, patient.LastNameEn, true); lastName != nil && !patient.DateBirth.IsZero() {
+		either(r.db.Where("date_birth = ?", patient.DateBirth).Where(lastName))
+	}
+	if lastName := r.nameBlock("last_name", patient.LastNameTh, patient.LastNameEn, false); lastName != nil {
+		if firstName := r.nameBlock("first_name", patient.FirstNameTh, patient.FirstNameEn, true); firstName != nil {
+			either(r.db.Where(lastName).Where(firstName))
+		}
+	}
+	if names == nil || len(candidates) >= limit {
+		return candidates, nil
+	}
+
+	seen := make([]uint, 0, len(candidates))
+	for _, candidate := range candidates {
+		seen = append(seen, candidate.ID)
+	}
+	var more []*entities.Patient
+	if err := r.candidates(ctx, patient.ID, seen, names, limit-len(candidates), &more); err != nil {
+		return nil, err
+	}
+	return append(candidates, more...), nil
+}
+
+func (r *GormMpiRepository) candidates(ctx context.Context, id uint, seen []uint, blocks *gorm.DB, limit int, found *[]*entities.Patient) error {
+	query := r.db.WithContext(ctx).Model(&entities.Patient{}).Where("id <> ?", id)
+	if len(seen) > 0 {
+		query = query.Where("id NOT IN ?", seen)
+	}
+	return query.Where(blocks).Order("id").Limit(limit).Find(found).Error
+}
+
+// nameBlock matches a name in Thai or in English, in full or by its first
+// namePrefixLength characters, or is nil when the patient has neither.
+func (r *GormMpiRepository) nameBlock(field string, th string, en string, prefix bool) *gorm.DB {
+	var block *gorm.DB
+	for _, name := range [][2]string{{field + "_th", th}, {field + "_en", en}} {
+		column, value := name[0], strings.TrimSpace(name[1])
+		if value == "" {
+			continue
+		}
+		condition := database.EqualFold(r.db, column, value)
+		if prefix {
+			if runes := []rune(value); len(runes) > namePrefixLength {
+				value = string(runes[:namePrefixLength])
+			}
+			condition = database.HasPrefixFold(r.db, column, value)
+		}
+		if block == nil {
+			block = r.db.Where(condition)
+		} else {
+			block = block.Or(condition)
+		}
+	}
+	return block
+}
+
+func (r *GormMpiRepository) FindPatient(ctx context.Context, id uint) (*entities.Patient, error) {
+	var patient entities.Patient
+	if err := r.db.WithContext(ctx).First(&patient, id).Error; err != nil {
+		return nil, err
+	}
+	return &patient, nil
+}
+
+func (r *GormMpiRepository) SaveDuplicates(ctx context.Context, duplicates []*entities.PatientDuplicate) error {
+	return r.db.WithContext(ctx).Omit("Patient", "Candidate").Create(&duplicates).Error
+}
+
+func (r *GormMpiRepository) FindDuplicates(ctx context.Context, hospital string, status string) ([]*entities.PatientDuplicate, error) {
+	var duplicates []*entities.PatientDuplicate
+
+	err := r.db.WithContext(ctx).Preload("Patient").Preload("Candidate").
+		Where(r.db.Where(database.EqualFold(r.db, "hospital", hospital)).Or(database.EqualFold(r.db, "candidate_hospital", hospital))).
+		Where("status = ?", status).
+		Order("score DESC").
+		Find(&duplicates).Error
+	if err != nil {
+		return nil, err
+	}
+	return duplicates, nil
+}
+
+func (r *GormMpiRepository) FindDuplicate(ctx context.Context, id uint) (*entities.PatientDuplicate, error) {
+	var duplicate entities.PatientDuplicate
+	if err := r.db.WithContext(ctx).First(&duplicate, id).Error; err != nil {
+		return nil, err
+	}
+	return &duplicate, nil
+}
+
+func (r *GormMpiRepository) UpdateDuplicateStatus(ctx context.Context, id uint, status string) error {
+	return r.db.WithContext(ctx).Model(&entities.PatientDuplicate{}).Where("id = ?", id).Update("status", status).Error
+}
+
+func (r *GormMpiRepository) Merge(ctx context.Context, survivor *entities.Patient, merged *entities.Patient, lineage *entities.PatientMerge) (*entities.Patient, error) {
+	tx := r.db.WithContext(ctx)
+	if err := tx.Save(survivor).Error; err != nil {
+		return nil, err
+	}
+
+	// Records previously merged into the losing patient now point at the survivor.
+	if err := tx.Unscoped().Model(&entities.Patient{}).
+		Where("merged_into_id = ?", merged.ID).
+		Update("merged_into_id", survivor.ID).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(merged).Update("merged_into_id", survivor.ID).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Delete(merged).Error; err != nil {
+		return nil, err
+	}
+
+	pair := "(patient_id = ? AND candidate_id = ?) OR (patient_id = ? AND candidate_id = ?)"
+	if err := tx.Model(&entities.PatientDuplicate{}).
+		Where(pair, survivor.ID, merged.ID, merged.ID, survivor.ID).
+		Update("status", entities.DuplicateStatusMerged).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&entities.PatientDuplicate{}).
+		Where("patient_id = ?", merged.ID).
+		Updates(map[string]interface{}{"patient_id": survivor.ID, "hospital": survivor.Hospital}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&entities.PatientDuplicate{}).
+		Where("candidate_id = ?", merged.ID).
+		Updates(map[string]interface{}{"candidate_id": survivor.ID, "candidate_hospital": survivor.Hospital}).Error; err != nil {
+		return nil, err
+	}
+
+	if err := tx.Create(lineage).Error; err != nil {
+		return nil, err
+	}
+	return survivor, nil
+}
@@ -0,0 +1,108 @@
+package adapters
+
+import (
+	"agnos/internal/adapters/mpi/dto"
+	"agnos/internal/usecases/mpi"
+	"agnos/internal/usecases/patient"
+	"agnos/pkg/mask"
+	"agnos/pkg/middleware"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type HttpMpiHandler struct {
+	mpiUseCase mpi.MpiUseCase
+}
+
+func NewHttpMpiRepository(usecase mpi.MpiUseCase) *HttpMpiHandler {
+	return &HttpMpiHandler{mpiUseCase: usecase}
+}
+
+func (h *HttpMpiHandler) ListDuplicates(c *gin.Context) {
+	payload, exist := c.Get("payload")
+	if !exist {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	claims := payload.(jwt.MapClaims)
+
+	duplicates, err := h.mpiUseCase.ListDuplicates(c.Request.Context(), claims["hospital"].(string), c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	policy := mask.PolicyFor(middleware.ClaimRole(claims))
+	for _, duplicate := range duplicates {
+		duplicate.Patient = *patient.MaskPatient(&duplicate.Patient, policy)
+		duplicate.Candidate = *patient.MaskPatient(&duplicate.Candidate, policy)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "search success", "statusCode": 200, "data": duplicates})
+}
+
+func (h *HttpMpiHandler) DismissDuplicate(c *gin.Context) {
+	payload, exist := c.Get("payload")
+	if !exist {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	claims := payload.(jwt.MapClaims)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id is invalid"})
+		return
+	}
+
+	if err := h.mpiUseCase.DismissDuplicate(c.Request.Context(), claims["hospital"].(string), uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "dismiss success", "statusCode": 200})
+}
+
+func (h *HttpMpiHandler) MergePatient(c *gin.Context) {
+	payload, exist := c.Get("payload")
+	if !exist {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	claims := payload.(jwt.MapClaims)
+
+	var data dto.MergePatientDto
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := validator.New().Struct(&data); err != nil {
+		errs := err.(validator.ValidationErrors)
+
+		messages := make([]string, 0)
+		for _, e := range errs {
+			messages = append(messages, fmt.Sprintf("%s is %s", strings.ToLower(e.Field()), strings.ToLower(e.Tag())))
+		}
+
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": messages,
+		})
+		return
+	}
+
+	data.Hospital = claims["hospital"].(string)
+	data.MergedBy = claims["username"].(string)
+	data.Role = middleware.ClaimRole(claims)
+
+	survivor, err := h.mpiUseCase.MergePatient(c.Request.Context(), &data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	policy := mask.PolicyFor(middleware.ClaimRole(claims))
+	c.JSON(http.StatusOK, gin.H{"message": "merge success", "statusCode": 200, "data": patient.MaskPatient(survivor, policy)})
+}
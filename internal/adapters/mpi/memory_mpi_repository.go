@@ -0,0 +1,287 @@
+package adapters
+
+import (
+	adaptersPatient "agnos/internal/adapters/patient"
+	"agnos/internal/entities"
+	"agnos/internal/usecases/mpi"
+	"context"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MemoryMpiRepository keeps duplicates and merge lineage in memory with the
+// same semantics as GormMpiRepository. Patients are read and merged in
+// patients.
+type MemoryMpiRepository struct {
+	patients *adaptersPatient.MemoryPatientRepository
+
+	mu          sync.RWMutex
+	duplicates  map[uint]entities.PatientDuplicate
+	merges      []entities.PatientMerge
+	nextId      uint
+	nextMergeId uint
+}
+
+func NewMemoryMpiRepository(patients *adaptersPatient.MemoryPatientRepository) mpi.MpiRepository {
+	return &MemoryMpiRepository{patients: patients, duplicates: map[uint]entities.PatientDuplicate{}}
+}
+
+// FindCandidates blocks on the same keys as GormMpiRepository: identifiers
+// first, then names, each group in ID order.
+func (r *MemoryMpiRepository) FindCandidates(ctx context.Context, patient *entities.Patient, limit int) ([]*entities.Patient, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	probe := *patient
+	if err := probe.BeforeSave(nil); err != nil {
+		return nil, err
+	}
+
+	identifiers := func(p *entities.Patient) bool {
+		return p.NationalIdIndex == probe.NationalIdIndex ||
+			(probe.PassportIdIndex != "" && p.PassportIdIndex == probe.PassportIdIndex) ||
+			(probe.PhoneNumberIndex != "" && p.PhoneNumberIndex == probe.PhoneNumberIndex) ||
+			(probe.EmailIndex != "" && p.EmailIndex == probe.EmailIndex)
+	}
+
+	var blocks []func(p *entities.Patient) bool
+	if lastName := nameBlock(patient.LastNameTh, patient.LastNameEn, true); lastName != nil && !patient.DateBirth.IsZero() {
+		blocks = append(blocks, func(p *entities.Patient) bool {
+			return p.DateBirth.Equal(patient.DateBirth) && lastName(p.LastNameTh, p.LastNameEn)
+		})
+	}
+	if lastName := nameBlock(patient.LastNameTh, patient.LastNameEn, false); lastName != nil {
+		if firstName := nameBlock(patient.FirstNameTh, patient.FirstNameEn, true); firstName != nil {
+			blocks = append(blocks, func(p *entities.Patient) bool {
+				return lastName(p.LastNameTh, p.LastNameEn) && firstName(p.FirstNameTh, p.FirstNameEn)
+			})
+		}
+	}
+	names := func(p *entities.Patient) bool {
+		return slices.ContainsFunc(blocks, func(block func(p *entities.Patient) bool) bool { return block(p) })
+	}
+
+	var live []*entities.Patient
+	for _, p := range r.patients.Unscoped() {
+		if !p.DeletedAt.Valid && p.ID != patient.ID {
+			live = append(live, p)
+		}
+	}
+	candidates := make([]*entities.Patient, 0)
+	for _, block := range []func(p *entities.Patient) bool{identifiers, names} {
+		for _, p := range live {
+			if len(candidates) >= limit {
+				return candidates, nil
+			}
+			if block(p) && !slices.Contains(candidates, p) {
+				candidates = append(candidates, p)
+			}
+		}
+	}
+	return candidates, nil
+}
+
+// nameBlock matches a name in Thai or in English, in full or by its first
+// namePrefixLength characters, or is nil when the patient has neither.
+func nameBlock(th string, en string, prefix bool) func(th string, en string) bool {
+	var values []string
+	for _, value := range []string{th, en} {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			continue
+		}
+		if runes := []rune(value); prefix && len(runes) > namePrefixLength {
+			value = string(runes[:namePrefixLength])
+		}
+		values = append(values, value)
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return func(th string, en string) bool {
+		for _, value := range values {
+			for _, name := range []string{strings.ToLower(th), strings.ToLower(en)} {
+				if name == value || (prefix && strings.HasPrefix(name, value)) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+func (r *MemoryMpiRepository) FindPatient(ctx context.Context, id uint) (*entities.Patient, error) {
+	return r.patients.FindById(ctx, id)
+}
+
+func (r *MemoryMpiRepository) SaveDuplicates(ctx context.Context, duplicates []*entities.PatientDuplicate) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, duplicate := range duplicates {
+		r.nextId++
+		duplicate.ID = r.nextId
+		duplicate.CreatedAt, duplicate.UpdatedAt = now, now
+		stored := *duplicate
+		stored.Patient, stored.Candidate = entities.Patient{}, entities.Patient{}
+		r.duplicates[duplicate.ID] = stored
+	}
+	return nil
+}
+
+func (r *MemoryMpiRepository) FindDuplicates(ctx context.Context, hospital string, status string) ([]*entities.PatientDuplicate, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	var duplicates []*entities.PatientDuplicate
+	for _, stored := range r.duplicates {
+		party := strings.EqualFold(stored.Hospital, hospital) || strings.EqualFold(stored.CandidateHospital, hospital)
+		if !party || stored.Status != status {
+			continue
+		}
+		duplicate := stored
+		duplicates = append(duplicates, &duplicate)
+	}
+	r.mu.RUnlock()
+
+	// Preloading skips soft-deleted patients, as gorm does.
+	for _, duplicate := range duplicates {
+		if p, err := r.patients.FindById(ctx, duplicate.PatientID); err == nil {
+			duplicate.Patient = *p
+		}
+		if p, err := r.patients.FindById(ctx, duplicate.CandidateID); err == nil {
+			duplicate.Candidate = *p
+		}
+	}
+	sort.SliceStable(duplicates, func(i, j int) bool { return duplicates[i].Score > duplicates[j].Score })
+	return duplicates, nil
+}
+
+func (r *MemoryMpiRepository) FindDuplicate(ctx context.Context, id uint) (*entities.PatientDuplicate, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	duplicate, ok := r.duplicates[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &duplicate, nil
+}
+
+func (r *MemoryMpiRepository) UpdateDuplicateStatus(ctx context.Context, id uint, status string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if duplicate, ok := r.duplicates[id]; ok {
+		duplicate.Status = status
+		duplicate.UpdatedAt = time.Now()
+		r.duplicates[id] = duplicate
+	}
+	return nil
+}
+
+func (r *MemoryMpiRepository) Merge(ctx context.Context, survivor *entities.Patient, merged *entities.Patient, lineage *entities.PatientMerge) (*entities.Patient, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := survivor.BeforeSave(nil); err != nil {
+		return nil, err
+	}
+	r.patients.Put(survivor)
+
+	// Records previously merged into the losing patient now point at the survivor.
+	for _, p := range r.patients.Unscoped() {
+		if p.MergedIntoID != nil && *p.MergedIntoID == merged.ID {
+			p.MergedIntoID = &survivor.ID
+			r.patients.Put(p)
+		}
+	}
+	merged.MergedIntoID = &survivor.ID
+	merged.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	r.patients.Put(merged)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, duplicate := range r.duplicates {
+		if (duplicate.PatientID == survivor.ID && duplicate.CandidateID == merged.ID) ||
+			(duplicate.PatientID == merged.ID && duplicate.CandidateID == survivor.ID) {
+			duplicate.Status = entities.DuplicateStatusMerged
+		}
+		if duplicate.PatientID == merged.ID {
+			duplicate.PatientID, duplicate.Hospital = survivor.ID, survivor.Hospital
+		}
+		if duplicate.CandidateID == merged.ID {
+			duplicate.CandidateID, duplicate.CandidateHospital = survivor.ID, survivor.Hospital
+		}
+		r.duplicates[id] = duplicate
+	}
+
+	r.nextMergeId++
+	lineage.ID = r.nextMergeId
+	lineage.CreatedAt, lineage.UpdatedAt = time.Now(), time.Now()
+	r.merges = append(r.merges, *lineage)
+	return survivor, nil
+}
+
+// Merges returns copies of the merge lineage in ID order.
+func (r *MemoryMpiRepository) Merges() []entities.PatientMerge {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.merges)
+}
+
+// Snapshot returns a function that puts the repository back as it is now,
+// for an in-memory unit of work that rolls back. Patients are restored
+// through their own repository.
+func (r *MemoryMpiRepository) Snapshot() (restore func()) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	duplicates, merges, nextId, nextMergeId := maps.Clone(r.duplicates), slices.Clone(r.merges), r.nextId, r.nextMergeId
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.duplicates, r.merges, r.nextId, r.nextMergeId = duplicates, merges, nextId, nextMergeId
+	}
+}
+
+// The methods below are for the in-memory repositories that erase patients.
+
+// RemovePatients deletes the duplicates of the patients with ids for good.
+func (r *MemoryMpiRepository) RemovePatients(ids ...uint) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, duplicate := range r.duplicates {
+		if slices.Contains(ids, duplicate.PatientID) || slices.Contains(ids, duplicate.CandidateID) {
+			delete(r.duplicates, id)
+		}
+	}
+}
+
+// ClearSnapshots empties the snapshots of the merges of mergedIds into any
+// patient, and of any patient into survivorIds.
+func (r *MemoryMpiRepository) ClearSnapshots(mergedIds []uint, survivorIds []uint) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, merge := range r.merges {
+		if slices.Contains(mergedIds, merge.MergedID) || slices.Contains(survivorIds, merge.SurvivorID) {
+			r.merges[i].Snapshot = ""
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package adapters_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	adapters "agnos/internal/adapters/mpi"
+	"agnos/internal/dbtest"
+	"agnos/internal/entities"
+	"agnos/internal/routes"
+	"agnos/pkg/encryption"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func testProvider(t *testing.T) encryption.KeyProvider {
+	provider, err := encryption.NewLocalProvider("test", map[string][]byte{"test": bytes.Repeat([]byte{1}, 32)}, bytes.Repeat([]byte{2}, 32))
+	if err != nil {
+		t.Fatalf("failed to load encryption keys: %v", err)
+	}
+	return provider
+}
+
+func newPatient(nationalId string, firstName string, lastName string, dateBirth time.Time) *entities.Patient {
+	return &entities.Patient{NationalId: nationalId, FirstNameEn: firstName, LastNameEn: lastName, DateBirth: dateBirth, Hospital: "Bangkok Hospital"}
+}
+
+func TestGormMpiRepositoryFindCandidates(t *testing.T) {
+	dbtest.Each(t, "mpi_repository_test", func(t *testing.T, db *gorm.DB) {
+		if _, err := routes.EncryptionKeyring(db, testProvider(t)); err != nil {
+			t.Fatalf("failed to load data key: %v", err)
+		}
+		born := time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)
+		other := time.Date(1971, 1, 2, 0, 0, 0, 0, time.UTC)
+
+		sameSurnameTypo := newPatient("1100000000002", "Malee", "Jaideee", born)
+		sameNameOtherBirth := newPatient("1100000000003", "Somsak", "Jaidee", other)
+		surnameOnly := newPatient("1100000000004", "Malee", "Jaidee", other)
+		birthOnly := newPatient("1100000000005", "Malee", "Meesuk", born)
+		sameNationalId := newPatient("1100000000001", "Kanya", "Meesuk", other)
+		for _, p := range []*entities.Patient{sameSurnameTypo, sameNameOtherBirth, surnameOnly, birthOnly, sameNationalId} {
+			require.NoError(t, db.Create(p).Error)
+		}
+
+		repo := adapters.NewGormMpiRepository(db)
+		probe := newPatient("1100000000001", "Somchai", "Jaidee", born)
+		ids := func(patients []*entities.Patient) []uint {
+			var ids []uint
+			for _, p := range patients {
+				ids = append(ids, p.ID)
+			}
+			return ids
+		}
+
+		candidates, err := repo.FindCandidates(context.Background(), probe, 10)
+		require.NoError(t, err)
+		assert.Equal(t, []uint{sameNationalId.ID, sameSurnameTypo.ID, sameNameOtherBirth.ID}, ids(candidates))
+
+		// Identifier matches are kept ahead of the name blocks when the
+		// limit cuts the list short.
+		candidates, err = repo.FindCandidates(context.Background(), probe, 2)
+		require.NoError(t, err)
+		assert.Equal(t, []uint{sameNationalId.ID, sameSurnameTypo.ID}, ids(candidates))
+	})
+}
@@ -0,0 +1,6 @@
+package dto
+
+type RevealPatientDto struct {
+	Field  string `json:"field" validate:"required,oneof=national_id passport_id phone_number email"`
+	Reason string `json:"reason" validate:"required"`
+}
@@ -2,13 +2,20 @@ package dto
 
 import "time"
 
+// SearchPatientDto holds the filters of a patient search. Names match as
+// case-insensitive substrings. NationalId, PassportId, Identifier,
+// PhoneNumber and Email are encrypted at rest and match only exactly,
+// after trimming and case folding (phone numbers compare their digits).
 type SearchPatientDto struct {
 	NationalId  string
 	PassportId  string
+	Identifier  string
+	Name        string
 	FirstName   string
 	MiddleName  string
 	LastName    string
 	DateofBirth time.Time
+	Gender      string
 	PhoneNumber string
 	Email       string
 	Hospital    string
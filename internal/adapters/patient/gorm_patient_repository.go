@@ -4,9 +4,12 @@ import (
 	"agnos/internal/adapters/patient/dto"
 	"agnos/internal/entities"
 	"agnos/internal/usecases/patient"
+	"agnos/pkg/database"
+	"context"
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -19,73 +22,170 @@ func NewGormPatientRepository(db *gorm.DB) patient.PatientRepository {
 	return &GormPatientRepository{db: db}
 }
 
-func (r *GormPatientRepository) Save(patient *entities.Patient) (*entities.Patient, error) {
-	if err := r.db.Where("national_id = ?", patient.NationalId).First(patient).Error; err == nil {
+func (r *GormPatientRepository) Save(ctx context.Context, patient *entities.Patient) (*entities.Patient, error) {
+	index, err := entities.IdentifierIndex(patient.NationalId)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.db.WithContext(ctx).Where("national_id_index = ?", index).First(&entities.Patient{}).Error; err == nil {
 		return nil, fmt.Errorf("national_id already exist")
 	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, err
 	}
 
-	if err := r.db.Save(patient).Error; err != nil {
+	if err := r.db.WithContext(ctx).Save(patient).Error; err != nil {
+		return nil, err
+	}
+	return patient, nil
+}
+
+func (r *GormPatientRepository) Update(ctx context.Context, patient *entities.Patient) (*entities.Patient, error) {
+	index, err := entities.IdentifierIndex(patient.NationalId)
+	if err != nil {
+		return nil, err
+	}
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&entities.Patient{}).Where("national_id_index = ? AND id <> ?", index, patient.ID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, fmt.Errorf("national_id already exist")
+	}
+
+	if err := r.db.WithContext(ctx).Save(patient).Error; err != nil {
 		return nil, err
 	}
 	return patient, nil
 }
 
-func (r *GormPatientRepository) Findone(query *dto.SearchPatientDto) ([]*entities.Patient, error) {
+func (r *GormPatientRepository) Findone(ctx context.Context, query *dto.SearchPatientDto) ([]*entities.Patient, error) {
 	var patient []*entities.Patient
 
-	db := r.db.Model(patient)
+	db, err := r.searchQuery(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	err = database.ReadReplica(db).Find(&patient).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return patient, nil
+}
+
+func (r *GormPatientRepository) FindInBatches(ctx context.Context, query *dto.SearchPatientDto, batchSize int, fn func(patients []*entities.Patient) error) error {
+	var patients []*entities.Patient
+
+	db, err := r.searchQuery(ctx, query)
+	if err != nil {
+		return err
+	}
+	return db.Order("id").FindInBatches(&patients, batchSize, func(tx *gorm.DB, batch int) error {
+		return fn(patients)
+	}).Error
+}
+
+func (r *GormPatientRepository) searchQuery(ctx context.Context, query *dto.SearchPatientDto) (*gorm.DB, error) {
+	db := r.db.WithContext(ctx).Model(&entities.Patient{})
+
+	if query.Name != "" {
+		db = db.Where(r.db.Where(database.ContainsFold(r.db, "first_name_th", query.Name)).Or(database.ContainsFold(r.db, "first_name_en", query.Name)).
+			Or(database.ContainsFold(r.db, "middle_name_th", query.Name)).Or(database.ContainsFold(r.db, "middle_name_en", query.Name)).
+			Or(database.ContainsFold(r.db, "last_name_th", query.Name)).Or(database.ContainsFold(r.db, "last_name_en", query.Name)))
+	}
 
 	if query.FirstName != "" {
-		db = db.Where("LOWER(first_name_th) LIKE ?", "%"+strings.ToLower(query.FirstName)+"%").Or("LOWER(first_name_en) LIKE ?", "%"+strings.ToLower(query.FirstName))
+		db = db.Where(r.db.Where(database.ContainsFold(r.db, "first_name_th", query.FirstName)).Or(database.ContainsFold(r.db, "first_name_en", query.FirstName)))
 	}
 
 	if query.LastName != "" {
-		db = db.Where("LOWER(last_name_th) LIKE ?", "%"+strings.ToLower(query.FirstName)+"%").Or("LOWER(last_name_en) LIKE ?", "%"+strings.ToLower(query.FirstName))
+		db = db.Where(r.db.Where(database.ContainsFold(r.db, "last_name_th", query.LastName)).Or(database.ContainsFold(r.db, "last_name_en", query.LastName)))
 	}
 
 	if query.MiddleName != "" {
-		db = db.Where("LOWER(middle_name_th) LIKE ?", "%"+strings.ToLower(query.FirstName)+"%").Or("LOWER(middle_name_en) LIKE ?", "%"+strings.ToLower(query.FirstName))
+		db = db.Where(r.db.Where(database.ContainsFold(r.db, "middle_name_th", query.MiddleName)).Or(database.ContainsFold(r.db, "middle_name_en", query.MiddleName)))
+	}
+
+	if query.Identifier != "" {
+		index, err := entities.IdentifierIndex(query.Identifier)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where(r.db.Where("national_id_index = ?", index).Or("passport_id_index = ?", index).Or("patient_hn = ?", query.Identifier))
+	}
+
+	if !query.DateofBirth.IsZero() {
+		day := query.DateofBirth.Truncate(24 * time.Hour)
+		db = db.Where("date_birth >= ? AND date_birth < ?", day, day.Add(24*time.Hour))
+	}
+
+	if query.Gender != "" {
+		db = db.Where("gender = ?", strings.ToLower(query.Gender))
 	}
 
+	// The identifier and contact columns are encrypted, so they can only be
+	// matched exactly through their blind indexes.
 	if query.PassportId != "" {
-		db = db.Where("LOWER(passport_id) LIKE ?", "%"+strings.ToLower(query.PassportId)+"%")
+		index, err := entities.IdentifierIndex(query.PassportId)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where("passport_id_index = ?", index)
 	}
 
 	if query.Email != "" {
-		db = db.Where("LOWER(email) LIKE ?", "%"+strings.ToLower(query.Email)+"%")
+		index, err := entities.EmailIndex(query.Email)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where("email_index = ?", index)
 	}
 
 	if query.PhoneNumber != "" {
-		db = db.Where("LOWER(phone_number) LIKE ?", "%"+strings.ToLower(query.PhoneNumber)+"%")
+		index, err := entities.PhoneIndex(query.PhoneNumber)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where("phone_number_index = ?", index)
 	}
 
 	if query.NationalId != "" {
-		db = db.Where("LOWER(national_id) LIKE ?", "%"+strings.ToLower(query.NationalId)+"%")
+		index, err := entities.IdentifierIndex(query.NationalId)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where("national_id_index = ?", index)
 	}
 
 	if query.Hospital != "" {
-		lower_query := strings.ToLower(query.Hospital)
-		db = db.Where("LOWER(hospital) = ?", lower_query)
+		db = db.Where(database.EqualFold(r.db, "hospital", query.Hospital))
 	}
-	err := db.Find(&patient).Error
-
-	if err != nil {
-		return nil, err
-	}
-	return patient, nil
+	return db, nil
 }
 
-func (r *GormPatientRepository) FindoneId(param string) (*entities.Patient, error) {
+func (r *GormPatientRepository) FindoneId(ctx context.Context, param string, hospital string) (*entities.Patient, error) {
 	var patient *entities.Patient
 
-	db := r.db.Model(patient).Where("national_id = ?", param).Or("passport_id = ?", param)
+	index, err := entities.IdentifierIndex(param)
+	if err != nil {
+		return nil, err
+	}
+	db := database.ReadReplica(r.db.WithContext(ctx)).Model(patient).
+		Where(r.db.Where("national_id_index = ?", index).Or("passport_id_index = ?", index)).
+		Where(database.EqualFold(r.db, "hospital", hospital))
 
-	err := db.First(&patient).Error
+	err = db.First(&patient).Error
 
 	if err != nil {
 		return nil, err
 	}
 	return patient, nil
 }
+
+func (r *GormPatientRepository) FindById(ctx context.Context, id uint) (*entities.Patient, error) {
+	var patient entities.Patient
+	if err := r.db.WithContext(ctx).First(&patient, id).Error; err != nil {
+		return nil, err
+	}
+	return &patient, nil
+}
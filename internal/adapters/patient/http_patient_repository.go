@@ -3,25 +3,45 @@ package adapters
 import (
 	"agnos/internal/adapters/patient/dto"
 	"agnos/internal/entities"
-	"agnos/internal/usecases/patient"
+	"agnos/internal/usecases/audit"
+	"agnos/internal/usecases/consent"
+	"agnos/internal/usecases/emergency"
+	"agnos/internal/usecases/mpi"
+	usecases "agnos/internal/usecases/patient"
+	"agnos/internal/usecases/sharing"
+	"agnos/pkg/logging"
+	"agnos/pkg/mask"
+	"agnos/pkg/middleware"
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
 )
 
 type HttpPatientHandler struct {
-	patientUseCase patient.PatientUseCase
+	patientUseCase   usecases.PatientUseCase
+	mpiUseCase       mpi.MpiUseCase
+	auditUseCase     audit.AuditUseCase
+	consentUseCase   consent.ConsentUseCase
+	emergencyUseCase emergency.EmergencyUseCase
+	sharingUseCase   sharing.SharingUseCase
 }
 
-func NewHttpPatientRepository(usecase patient.PatientUseCase) *HttpPatientHandler {
-	return &HttpPatientHandler{patientUseCase: usecase}
+func NewHttpPatientRepository(usecase usecases.PatientUseCase, mpiUseCase mpi.MpiUseCase, auditUseCase audit.AuditUseCase, consentUseCase consent.ConsentUseCase, emergencyUseCase emergency.EmergencyUseCase, sharingUseCase sharing.SharingUseCase) *HttpPatientHandler {
+	return &HttpPatientHandler{patientUseCase: usecase, mpiUseCase: mpiUseCase, auditUseCase: auditUseCase, consentUseCase: consentUseCase, emergencyUseCase: emergencyUseCase, sharingUseCase: sharingUseCase}
 }
 
 func (h *HttpPatientHandler) CreatePatient(c *gin.Context) {
+	policy := mask.PolicyFor(middleware.ClaimRole(c.MustGet("payload").(jwt.MapClaims)))
+
 	var data entities.Patient
 
 	if err := c.ShouldBindJSON(&data); err != nil {
@@ -29,27 +49,45 @@ func (h *HttpPatientHandler) CreatePatient(c *gin.Context) {
 		return
 	}
 
-	if err := validator.New().Struct(&data); err != nil {
-		errs := err.(validator.ValidationErrors)
-
-		messages := make([]string, 0)
-		for _, e := range errs {
-			messages = append(messages, fmt.Sprintf("%s is %s", strings.ToLower(e.Field()), strings.ToLower(e.Tag())))
-		}
-
+	if messages := usecases.ValidatePatient(&data); len(messages) > 0 {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": messages,
 		})
 		return
 	}
 
-	patient, err := h.patientUseCase.CreatePatient(&data)
+	patient, err := h.patientUseCase.CreatePatient(c.Request.Context(), &data)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "create success", "statusCode": 201, "data": patient})
+	response := gin.H{"message": "create success", "statusCode": 201, "data": usecases.MaskPatient(patient, policy)}
+	if warning := h.duplicateWarning(c.Request.Context(), patient, policy); warning != nil {
+		response["warning"] = warning
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *HttpPatientHandler) duplicateWarning(ctx context.Context, patient *entities.Patient, policy mask.Policy) gin.H {
+	duplicates, err := h.mpiUseCase.DetectDuplicates(ctx, patient)
+	if err != nil {
+		return nil
+	}
+
+	likely := make([]*entities.PatientDuplicate, 0)
+	for _, duplicate := range duplicates {
+		if duplicate.Likely {
+			shaped := *duplicate
+			shaped.Patient = *usecases.MaskPatient(&duplicate.Patient, policy)
+			shaped.Candidate = *usecases.MaskPatient(&duplicate.Candidate, policy)
+			likely = append(likely, &shaped)
+		}
+	}
+	if len(likely) == 0 {
+		return nil
+	}
+	return gin.H{"message": "patient looks like a likely duplicate", "duplicates": likely}
 }
 
 func (h *HttpPatientHandler) SearchPatient(c *gin.Context) {
@@ -59,23 +97,24 @@ func (h *HttpPatientHandler) SearchPatient(c *gin.Context) {
 		return
 	}
 	claims := payload.(jwt.MapClaims)
-	params := dto.SearchPatientDto{
-		FirstName:   c.Query("first_name"),
-		LastName:    c.Query("last_name"),
-		MiddleName:  c.Query("middle_name"),
-		PassportId:  c.Query("passport_id"),
-		Email:       c.Query("email"),
-		PhoneNumber: c.Query("phone_number"),
-		NationalId:  c.Query("national_id"),
-		Hospital:    claims["hospital"].(string),
+	params := searchParams(c, claims)
+
+	patient, err := h.patientUseCase.SearchPatient(c.Request.Context(), &params)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
+	role := middleware.ClaimRole(claims)
 
-	patient, err := h.patientUseCase.SearchPatient(&params)
+	// Patients of partner hospitals are kept apart from the caller's own so
+	// that each one is labelled with the hospital it came from.
+	partners, err := h.sharingUseCase.SearchPartners(c.Request.Context(), &params, claims["username"].(string), role)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "search success", "statusCode": 200, "data": patient})
+	policy := mask.PolicyFor(role)
+	c.JSON(http.StatusOK, gin.H{"message": "search success", "statusCode": 200, "data": usecases.MaskPatients(patient, policy), "partner_data": partners})
 }
 
 func (h *HttpPatientHandler) SearchPatientId(c *gin.Context) {
@@ -86,11 +125,266 @@ func (h *HttpPatientHandler) SearchPatientId(c *gin.Context) {
 		return
 	}
 
-	patient, err := h.patientUseCase.SearchPatientId(patientID)
+	claims := c.MustGet("payload").(jwt.MapClaims)
+	role := middleware.ClaimRole(claims)
+
+	// The caller's own hospital is searched first. Patients of another
+	// hospital are only visible through a break-the-glass token, and are
+	// looked up in the hospital it was issued for.
+	response := gin.H{"message": "search success", "statusCode": 200}
+	patient, err := h.patientUseCase.SearchPatientId(c.Request.Context(), patientID, claims["hospital"].(string))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		accessId, ok := middleware.ClaimUint(claims, "emergency_access_id")
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		var access *entities.EmergencyAccess
+		patient, access, err = h.emergencyUseCase.FindPatient(c.Request.Context(), accessId, claims["username"].(string), patientID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": gorm.ErrRecordNotFound.Error()})
+			return
+		}
+		if err := h.emergencyUseCase.RecordRead(c.Request.Context(), access, role); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		response["emergency_access"] = gin.H{"id": access.ID, "expires_at": access.ExpiresAt}
+	} else if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response["data"] = usecases.MaskPatient(patient, mask.PolicyFor(role))
+	c.JSON(http.StatusOK, response)
+
+}
+
+func (h *HttpPatientHandler) ExportPatient(c *gin.Context) {
+	payload, exist := c.Get("payload")
+	if !exist {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	claims := payload.(jwt.MapClaims)
+	params := searchParams(c, claims)
+
+	format := c.DefaultQuery("format", "csv")
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("format %s is not supported", format)})
+		return
+	}
+
+	role := middleware.ClaimRole(claims)
+	policy := mask.PolicyFor(role)
+	if value := c.Query("mask"); value != "" {
+		requested, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "mask must be a boolean"})
+			return
+		}
+		if requested {
+			policy = policy.Restrict(mask.MaskAll)
+		}
+	}
+	masked := policy.Masked()
+
+	// An export for a consent-based purpose only contains the patients that
+	// consented to it; one without a purpose, such as a regulatory or
+	// operational export, contains every patient that matches.
+	purpose := c.Query("purpose")
+	if purpose != "" && purpose != entities.ConsentPurposeDataSharing && purpose != entities.ConsentPurposeMarketing && purpose != entities.ConsentPurposeResearch {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("purpose %s is not supported", purpose)})
+		return
+	}
+
+	// Nothing is sent unless the export is on the audit trail first.
+	ctx := c.Request.Context()
+	detail := gin.H{"format": format, "masked": masked, "filters": params}
+	if purpose != "" {
+		detail["purpose"] = purpose
+	}
+	audit := &entities.AuditLog{
+		Actor:     claims["username"].(string),
+		ActorRole: role,
+		Hospital:  params.Hospital,
+		Action:    "patient.export",
+		Resource:  "patient",
+	}
+	if _, err := h.auditUseCase.Record(ctx, audit, detail); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "export could not be audited"})
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="patients-%s.%s"`, time.Now().Format("20060102150405"), format))
+	c.Status(http.StatusOK)
+
+	rows, skipped := 0, 0
+	writer, err := newExportWriter(format, c.Writer)
+	if err == nil {
+		err = h.patientUseCase.ExportPatient(ctx, &params, func(patients []*entities.Patient) error {
+			if purpose != "" {
+				consented, err := h.consentedPatients(ctx, patients, purpose)
+				if err != nil {
+					return err
+				}
+				skipped += len(patients) - len(consented)
+				patients = consented
+			}
+			if err := writer.Write(usecases.MaskPatients(patients, policy)); err != nil {
+				return err
+			}
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+			c.Writer.Flush()
+			rows += len(patients)
+			return nil
+		})
+	}
+
+	// How the export ended is audited even when the client went away part
+	// way.
+	outcome := gin.H{"export_id": audit.ID, "rows": rows}
+	if purpose != "" {
+		outcome["skipped_without_consent"] = skipped
+	}
 	if err != nil {
+		outcome["error"] = err.Error()
+	}
+	_, auditErr := h.auditUseCase.Record(context.WithoutCancel(ctx), &entities.AuditLog{
+		Actor:     audit.Actor,
+		ActorRole: role,
+		Hospital:  params.Hospital,
+		Action:    "patient.export.finished",
+		Resource:  "patient",
+	}, outcome)
+	if auditErr != nil {
+		logging.For("http").ErrorContext(ctx, "export outcome not audited", "export_id", audit.ID, "error", auditErr.Error())
+	}
+	if err != nil {
+		logging.For("http").ErrorContext(ctx, "export failed after streaming started", "rows", rows, "error", err.Error())
+		abortStream(c)
+	}
+}
+
+// abortStream drops the connection of a response whose body has started,
+// so that the client sees a broken download rather than an error message
+// inside the file.
+// Gin refuses to hijack once the body has started, so the connection is
+// taken from the server's writer underneath; HTTP/2 streams cannot be
+// hijacked and are reset by aborting the handler instead.
+func abortStream(c *gin.Context) {
+	if writer, ok := c.Writer.(interface{ Unwrap() http.ResponseWriter }); ok {
+		if conn, _, err := http.NewResponseController(writer.Unwrap()).Hijack(); err == nil {
+			conn.Close()
+			c.Abort()
+			return
+		}
+	}
+	panic(http.ErrAbortHandler)
+}
+
+func (h *HttpPatientHandler) consentedPatients(ctx context.Context, patients []*entities.Patient, purpose string) ([]*entities.Patient, error) {
+	ids := make([]uint, 0, len(patients))
+	for _, p := range patients {
+		ids = append(ids, p.ID)
+	}
+	consented, err := h.consentUseCase.FilterConsented(ctx, ids, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]*entities.Patient, 0, len(consented))
+	for _, p := range patients {
+		if consented[p.ID] {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered, nil
+}
+
+// RevealPatient returns the full value of one sensitive field of one patient.
+// Every reveal is audited with the caller's reason.
+func (h *HttpPatientHandler) RevealPatient(c *gin.Context) {
+	claims := c.MustGet("payload").(jwt.MapClaims)
+	role := middleware.ClaimRole(claims)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id is invalid"})
+		return
+	}
+
+	var data dto.RevealPatientDto
+	if err := c.ShouldBindJSON(&data); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "search success", "statusCode": 200, "data": patient})
 
+	if err := validator.New().Struct(&data); err != nil {
+		errs := err.(validator.ValidationErrors)
+
+		messages := make([]string, 0)
+		for _, e := range errs {
+			messages = append(messages, fmt.Sprintf("%s is %s", strings.ToLower(e.Field()), strings.ToLower(e.Tag())))
+		}
+
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": messages,
+		})
+		return
+	}
+
+	if mask.PolicyFor(role).Action(data.Field) == mask.Omit {
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("role %s can not reveal %s", role, data.Field)})
+		return
+	}
+
+	hospital := claims["hospital"].(string)
+	patient, err := h.patientUseCase.GetPatient(c.Request.Context(), uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !strings.EqualFold(patient.Hospital, hospital)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	value, err := usecases.PatientField(patient, data.Field)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	_, err = h.auditUseCase.Record(c.Request.Context(), &entities.AuditLog{
+		Actor:      claims["username"].(string),
+		ActorRole:  role,
+		Hospital:   hospital,
+		Action:     "patient.reveal",
+		Resource:   "patient",
+		ResourceId: strconv.FormatUint(id, 10),
+	}, gin.H{"field": data.Field, "reason": data.Reason})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "reveal success", "statusCode": 200, "data": gin.H{"id": patient.ID, "field": data.Field, "value": value}})
+}
+
+func searchParams(c *gin.Context, claims jwt.MapClaims) dto.SearchPatientDto {
+	return dto.SearchPatientDto{
+		FirstName:   c.Query("first_name"),
+		LastName:    c.Query("last_name"),
+		MiddleName:  c.Query("middle_name"),
+		PassportId:  c.Query("passport_id"),
+		Email:       c.Query("email"),
+		PhoneNumber: c.Query("phone_number"),
+		NationalId:  c.Query("national_id"),
+		Hospital:    claims["hospital"].(string),
+	}
 }
@@ -0,0 +1,107 @@
+package adapters_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	adapters "agnos/internal/adapters/patient"
+	"agnos/internal/adapters/patient/dto"
+	"agnos/internal/entities"
+	"agnos/internal/usecases/audit"
+	"agnos/internal/usecases/patient"
+	"agnos/pkg/encryption"
+	"agnos/pkg/logging"
+	"agnos/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAuditUseCase struct {
+	err     error
+	entries []*entities.AuditLog
+}
+
+func (f *fakeAuditUseCase) Record(ctx context.Context, entry *entities.AuditLog, detail interface{}) (*entities.AuditLog, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.entries = append(f.entries, entry)
+	return entry, nil
+}
+
+func (f *fakeAuditUseCase) Verify(ctx context.Context) (*audit.VerifyResult, error) {
+	return nil, nil
+}
+
+// failingExport sends the first batch, then fails as a database going away
+// mid-export would.
+type failingExport struct {
+	patient.PatientUseCase
+}
+
+func (f failingExport) ExportPatient(ctx context.Context, query *dto.SearchPatientDto, fn func(patients []*entities.Patient) error) error {
+	err := f.PatientUseCase.ExportPatient(ctx, query, fn)
+	if err != nil {
+		return err
+	}
+	return errors.New("connection reset")
+}
+
+func exportRouter(t *testing.T, patients patient.PatientUseCase, auditUseCase audit.AuditUseCase) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	handler := adapters.NewHttpPatientRepository(patients, nil, auditUseCase, nil, nil, nil)
+
+	r := gin.New()
+	r.Use(middleware.Recovery(logging.For("http")))
+	r.GET("/patient/export", func(c *gin.Context) {
+		c.Set("payload", jwt.MapClaims{"username": "walawala", "hospital": "Bangkok Hospital", "role": entities.RoleClinician})
+	}, handler.ExportPatient)
+	return r
+}
+
+func savedPatients(t *testing.T) patient.PatientUseCase {
+	encryption.SetDefault(encryption.NewKeyring(testProvider(t), nil))
+	service := patient.NewPatientService(adapters.NewMemoryPatientRepository())
+	_, err := service.CreatePatient(context.Background(), &entities.Patient{FirstNameEn: "Somsak", NationalId: "1234567890123", Gender: "male", Hospital: "Bangkok Hospital"})
+	require.NoError(t, err)
+	return service
+}
+
+func TestExportPatient_RefusesWhenAuditFails(t *testing.T) {
+	r := exportRouter(t, savedPatients(t), &fakeAuditUseCase{err: errors.New("audit log unavailable")})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/patient/export?format=csv", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NotContains(t, w.Body.String(), "1234567890123")
+	assert.NotContains(t, w.Body.String(), "national_id")
+}
+
+func TestExportPatient_AbortsConnectionWhenStreamFails(t *testing.T) {
+	auditUseCase := &fakeAuditUseCase{}
+	server := httptest.NewServer(exportRouter(t, failingExport{savedPatients(t)}, auditUseCase))
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/patient/export?format=jsonl")
+	require.NoError(t, err)
+	defer response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	body, err := io.ReadAll(response.Body)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.Contains(t, string(body), "1234567890123")
+	assert.NotContains(t, string(body), "connection reset")
+
+	require.Len(t, auditUseCase.entries, 2)
+	assert.Equal(t, "patient.export", auditUseCase.entries[0].Action)
+	assert.Equal(t, "patient.export.finished", auditUseCase.entries[1].Action)
+}
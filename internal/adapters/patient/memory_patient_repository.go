@@ -0,0 +1,330 @@
+package adapters
+
+import (
+	"agnos/internal/adapters/patient/dto"
+	"agnos/internal/entities"
+	"agnos/internal/usecases/patient"
+	"context"
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MemoryPatientRepository keeps patients in a map with the same semantics
+// as GormPatientRepository, for tests that should not need a database.
+// Identifiers are kept in plain text; only their blind indexes are used for
+// matching, as in the database.
+type MemoryPatientRepository struct {
+	mu       sync.RWMutex
+	patients map[uint]entities.Patient
+	nextId   uint
+}
+
+func NewMemoryPatientRepository() patient.PatientRepository {
+	return &MemoryPatientRepository{patients: map[uint]entities.Patient{}}
+}
+
+func (r *MemoryPatientRepository) Save(ctx context.Context, patient *entities.Patient) (*entities.Patient, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := patient.BeforeSave(nil); err != nil {
+		return nil, err
+	}
+	for _, existing := range r.patients {
+		if !existing.DeletedAt.Valid && existing.NationalIdIndex == patient.NationalIdIndex {
+			return nil, fmt.Errorf("national_id already exist")
+		}
+	}
+	r.store(patient)
+	return patient, nil
+}
+
+func (r *MemoryPatientRepository) Update(ctx context.Context, patient *entities.Patient) (*entities.Patient, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := patient.BeforeSave(nil); err != nil {
+		return nil, err
+	}
+	for id, existing := range r.patients {
+		if id != patient.ID && !existing.DeletedAt.Valid && existing.NationalIdIndex == patient.NationalIdIndex {
+			return nil, fmt.Errorf("national_id already exist")
+		}
+	}
+	r.store(patient)
+	return patient, nil
+}
+
+// Snapshot returns a function that puts the repository back as it is now,
+// for an in-memory unit of work that rolls back.
+func (r *MemoryPatientRepository) Snapshot() (restore func()) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	patients, nextId := maps.Clone(r.patients), r.nextId
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.patients, r.nextId = patients, nextId
+	}
+}
+
+// store inserts patient, or replaces it when it has an ID, as gorm's Save
+// does. The caller holds mu.
+func (r *MemoryPatientRepository) store(patient *entities.Patient) {
+	now := time.Now()
+	if patient.ID == 0 {
+		r.nextId++
+		patient.ID = r.nextId
+	} else if patient.ID > r.nextId {
+		r.nextId = patient.ID
+	}
+	if patient.CreatedAt.IsZero() {
+		patient.CreatedAt = now
+	}
+	patient.UpdatedAt = now
+	r.patients[patient.ID] = *patient
+}
+
+func (r *MemoryPatientRepository) Findone(ctx context.Context, query *dto.SearchPatientDto) ([]*entities.Patient, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.search(query)
+}
+
+func (r *MemoryPatientRepository) FindInBatches(ctx context.Context, query *dto.SearchPatientDto, batchSize int, fn func(patients []*entities.Patient) error) error {
+	patients, err := r.search(query)
+	if err != nil {
+		return err
+	}
+	for start := 0; start < len(patients); start += batchSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		end := min(start+batchSize, len(patients))
+		if err := fn(patients[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// search returns copies of the live patients matching query, by ID.
+func (r *MemoryPatientRepository) search(query *dto.SearchPatientDto) ([]*entities.Patient, error) {
+	matches, err := matcher(query)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	patients := make([]*entities.Patient, 0)
+	for _, stored := range r.patients {
+		if stored.DeletedAt.Valid || !matches(&stored) {
+			continue
+		}
+		found := stored
+		patients = append(patients, &found)
+	}
+	sort.Slice(patients, func(i, j int) bool { return patients[i].ID < patients[j].ID })
+	return patients, nil
+}
+
+// matcher mirrors the filters of GormPatientRepository.searchQuery: names
+// match as case-insensitive substrings, identifiers and contacts through
+// their blind indexes, and the hospital case-insensitively.
+func matcher(query *dto.SearchPatientDto) (func(p *entities.Patient) bool, error) {
+	var filters []func(p *entities.Patient) bool
+	contains := func(needle string, fields func(p *entities.Patient) []string) {
+		needle = strings.ToLower(needle)
+		filters = append(filters, func(p *entities.Patient) bool {
+			for _, field := range fields(p) {
+				if strings.Contains(strings.ToLower(field), needle) {
+					return true
+				}
+			}
+			return false
+		})
+	}
+
+	if query.Name != "" {
+		contains(query.Name, func(p *entities.Patient) []string {
+			return []string{p.FirstNameTh, p.FirstNameEn, p.MiddleNameTh, p.MiddleNameEn, p.LastNameTh, p.LastNameEn}
+		})
+	}
+	if query.FirstName != "" {
+		contains(query.FirstName, func(p *entities.Patient) []string { return []string{p.FirstNameTh, p.FirstNameEn} })
+	}
+	if query.LastName != "" {
+		contains(query.LastName, func(p *entities.Patient) []string { return []string{p.LastNameTh, p.LastNameEn} })
+	}
+	if query.MiddleName != "" {
+		contains(query.MiddleName, func(p *entities.Patient) []string { return []string{p.MiddleNameTh, p.MiddleNameEn} })
+	}
+
+	if query.Identifier != "" {
+		index, err := entities.IdentifierIndex(query.Identifier)
+		if err != nil {
+			return nil, err
+		}
+		identifier := query.Identifier
+		filters = append(filters, func(p *entities.Patient) bool {
+			return p.NationalIdIndex == index || p.PassportIdIndex == index || p.PatientHn == identifier
+		})
+	}
+
+	if !query.DateofBirth.IsZero() {
+		day := query.DateofBirth.Truncate(24 * time.Hour)
+		filters = append(filters, func(p *entities.Patient) bool {
+			return !p.DateBirth.Before(day) && p.DateBirth.Before(day.Add(24*time.Hour))
+		})
+	}
+
+	if query.Gender != "" {
+		gender := strings.ToLower(query.Gender)
+		filters = append(filters, func(p *entities.Patient) bool { return p.Gender == gender })
+	}
+
+	for _, exact := range []struct {
+		value string
+		index func(string) (string, error)
+		field func(p *entities.Patient) string
+	}{
+		{query.PassportId, entities.IdentifierIndex, func(p *entities.Patient) string { return p.PassportIdIndex }},
+		{query.Email, entities.EmailIndex, func(p *entities.Patient) string { return p.EmailIndex }},
+		{query.PhoneNumber, entities.PhoneIndex, func(p *entities.Patient) string { return p.PhoneNumberIndex }},
+		{query.NationalId, entities.IdentifierIndex, func(p *entities.Patient) string { return p.NationalIdIndex }},
+	} {
+		if exact.value == "" {
+			continue
+		}
+		index, err := exact.index(exact.value)
+		if err != nil {
+			return nil, err
+		}
+		field := exact.field
+		filters = append(filters, func(p *entities.Patient) bool { return field(p) == index })
+	}
+
+	if query.Hospital != "" {
+		hospital := query.Hospital
+		filters = append(filters, func(p *entities.Patient) bool { return strings.EqualFold(p.Hospital, hospital) })
+	}
+
+	return func(p *entities.Patient) bool {
+		for _, filter := range filters {
+			if !filter(p) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func (r *MemoryPatientRepository) FindoneId(ctx context.Context, param string, hospital string) (*entities.Patient, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	index, err := entities.IdentifierIndex(param)
+	if err != nil {
+		return nil, err
+	}
+	return r.first(func(p *entities.Patient) bool {
+		return (p.NationalIdIndex == index || p.PassportIdIndex == index) && strings.EqualFold(p.Hospital, hospital)
+	})
+}
+
+func (r *MemoryPatientRepository) FindById(ctx context.Context, id uint) (*entities.Patient, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.first(func(p *entities.Patient) bool { return p.ID == id })
+}
+
+// first returns a copy of the live patient with the lowest ID that matches,
+// or gorm.ErrRecordNotFound like First does.
+func (r *MemoryPatientRepository) first(matches func(p *entities.Patient) bool) (*entities.Patient, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var found *entities.Patient
+	for _, stored := range r.patients {
+		if stored.DeletedAt.Valid || !matches(&stored) {
+			continue
+		}
+		if found == nil || stored.ID < found.ID {
+			copied := stored
+			found = &copied
+		}
+	}
+	if found == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return found, nil
+}
+
+// The methods below let the in-memory repositories of other features change
+// patients the way their database versions do with Unscoped queries.
+
+// Unscoped returns copies of every stored patient by ID, soft-deleted ones
+// included.
+func (r *MemoryPatientRepository) Unscoped() []*entities.Patient {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	patients := make([]*entities.Patient, 0, len(r.patients))
+	for _, stored := range r.patients {
+		found := stored
+		patients = append(patients, &found)
+	}
+	sort.Slice(patients, func(i, j int) bool { return patients[i].ID < patients[j].ID })
+	return patients
+}
+
+// Put stores patient without the checks of Save and stamps UpdatedAt, as
+// an Updates of its columns does.
+func (r *MemoryPatientRepository) Put(patient *entities.Patient) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.store(patient)
+}
+
+// Remove deletes the patients with ids for good.
+func (r *MemoryPatientRepository) Remove(ids ...uint) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, id := range ids {
+		delete(r.patients, id)
+	}
+}
+
+// Anonymize clears the personal fields of the patients with ids, as an
+// Updates with AnonymizedColumns does.
+func (r *MemoryPatientRepository) Anonymize(ids ...uint) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for _, id := range ids {
+		p, ok := r.patients[id]
+		if !ok {
+			continue
+		}
+		p.AnonymizedAt = &now
+		p.FirstNameTh, p.MiddleNameTh, p.LastNameTh = "", "", ""
+		p.FirstNameEn, p.MiddleNameEn, p.LastNameEn = "", "", ""
+		p.DateBirth = time.Time{}
+		p.PatientHn, p.NationalId, p.PassportId, p.PhoneNumber, p.Email = "", "", "", "", ""
+		p.NationalIdIndex, p.PassportIdIndex, p.PhoneNumberIndex, p.EmailIndex = "", "", "", ""
+		r.store(&p)
+	}
+}
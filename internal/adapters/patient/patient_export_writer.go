@@ -0,0 +1,95 @@
+package adapters
+
+import (
+	adaptersFhir "agnos/internal/adapters/fhir"
+	"agnos/internal/entities"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+var exportContentTypes = map[string]string{
+	"csv":    "text/csv; charset=utf-8",
+	"jsonl":  "application/jsonl",
+	"ndjson": "application/fhir+ndjson",
+}
+
+var csvHeader = []string{
+	"id", "patient_hn", "national_id", "passport_id",
+	"first_name_th", "middle_name_th", "last_name_th",
+	"first_name_en", "middle_name_en", "last_name_en",
+	"date_of_birth", "gender", "phone_number", "email", "hospital",
+}
+
+type exportWriter interface {
+	Write(patients []*entities.Patient) error
+	Flush() error
+}
+
+func newExportWriter(format string, w io.Writer) (exportWriter, error) {
+	switch format {
+	case "csv":
+		writer := csv.NewWriter(w)
+		if err := writer.Write(csvHeader); err != nil {
+			return nil, err
+		}
+		return &csvExportWriter{writer: writer}, nil
+	case "jsonl":
+		return &jsonExportWriter{encoder: json.NewEncoder(w)}, nil
+	case "ndjson":
+		return &jsonExportWriter{encoder: json.NewEncoder(w), fhir: true}, nil
+	}
+	return nil, fmt.Errorf("format %s is not supported", format)
+}
+
+type csvExportWriter struct {
+	writer *csv.Writer
+}
+
+func (w *csvExportWriter) Write(patients []*entities.Patient) error {
+	for _, p := range patients {
+		dateBirth := ""
+		if !p.DateBirth.IsZero() {
+			dateBirth = p.DateBirth.Format("2006-01-02")
+		}
+		err := w.writer.Write([]string{
+			strconv.FormatUint(uint64(p.ID), 10), p.PatientHn, p.NationalId, p.PassportId,
+			p.FirstNameTh, p.MiddleNameTh, p.LastNameTh,
+			p.FirstNameEn, p.MiddleNameEn, p.LastNameEn,
+			dateBirth, p.Gender, p.PhoneNumber, p.Email, p.Hospital,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *csvExportWriter) Flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+type jsonExportWriter struct {
+	encoder *json.Encoder
+	fhir    bool
+}
+
+func (w *jsonExportWriter) Write(patients []*entities.Patient) error {
+	for _, p := range patients {
+		var record interface{} = p
+		if w.fhir {
+			record = adaptersFhir.ToFhirPatient(p)
+		}
+		if err := w.encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *jsonExportWriter) Flush() error {
+	return nil
+}
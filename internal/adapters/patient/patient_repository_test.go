@@ -0,0 +1,69 @@
+package adapters_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	adapters "agnos/internal/adapters/patient"
+	"agnos/internal/adapters/patient/dto"
+	"agnos/internal/dbtest"
+	"agnos/internal/entities"
+	"agnos/internal/routes"
+	"agnos/internal/usecases/patient"
+	"agnos/internal/usecases/patient/patienttest"
+	"agnos/pkg/encryption"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func testProvider(t *testing.T) encryption.KeyProvider {
+	provider, err := encryption.NewLocalProvider("test", map[string][]byte{"test": bytes.Repeat([]byte{1}, 32)}, bytes.Repeat([]byte{2}, 32))
+	if err != nil {
+		t.Fatalf("failed to load encryption keys: %v", err)
+	}
+	return provider
+}
+
+func TestMemoryPatientRepository(t *testing.T) {
+	encryption.SetDefault(encryption.NewKeyring(testProvider(t), nil))
+
+	patienttest.RepositoryContract(t, func(t *testing.T) patient.PatientRepository {
+		return adapters.NewMemoryPatientRepository()
+	})
+}
+
+func TestGormPatientRepository(t *testing.T) {
+	dbtest.Each(t, "patient_repository_test", func(t *testing.T, db *gorm.DB) {
+		if _, err := routes.EncryptionKeyring(db, testProvider(t)); err != nil {
+			t.Fatalf("failed to load data key: %v", err)
+		}
+
+		patienttest.RepositoryContract(t, func(t *testing.T) patient.PatientRepository {
+			db.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&entities.Patient{})
+			return adapters.NewGormPatientRepository(db)
+		})
+	})
+}
+
+func TestGormPatientRepository_SearchFailsWithoutKeyring(t *testing.T) {
+	dbtest.Each(t, "patient_repository_keyring_test", func(t *testing.T, db *gorm.DB) {
+		if _, err := routes.EncryptionKeyring(db, testProvider(t)); err != nil {
+			t.Fatalf("failed to load data key: %v", err)
+		}
+		encryption.SetDefault(nil)
+		defer encryption.SetDefault(encryption.NewKeyring(testProvider(t), nil))
+
+		repo := adapters.NewGormPatientRepository(db)
+		for _, query := range []*dto.SearchPatientDto{
+			{Identifier: "1100000000001"}, {NationalId: "1100000000001"}, {PassportId: "AA1234567"},
+			{PhoneNumber: "0812345678"}, {Email: "somchai@example.com"},
+		} {
+			_, err := repo.Findone(context.Background(), query)
+			assert.Error(t, err, "%+v", query)
+			err = repo.FindInBatches(context.Background(), query, 10, func([]*entities.Patient) error { return nil })
+			assert.Error(t, err, "%+v", query)
+		}
+	})
+}
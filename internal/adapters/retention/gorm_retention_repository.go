@@ -0,0 +1,217 @@
+package adapters
+
+import (
+	"agnos/internal/entities"
+	"agnos/internal/usecases/retention"
+	"agnos/pkg/database"
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// retentionLockKey identifies the purge in pg_try_advisory_lock, and
+// retentionLockName in MySQL's GET_LOCK.
+const (
+	retentionLockKey  int64 = 0x61676e6f73
+	retentionLockName       = "agnos_retention"
+)
+
+type GormRetentionRepository struct {
+	db *gorm.DB
+	// mu stands in for the session lock on SQLite, which has none.
+	mu sync.Mutex
+}
+
+func NewGormRetentionRepository(db *gorm.DB) retention.RetentionRepository {
+	return &GormRetentionRepository{db: db}
+}
+
+func (r *GormRetentionRepository) SavePolicy(ctx context.Context, policy *entities.RetentionPolicy) (*entities.RetentionPolicy, error) {
+	if err := r.db.WithContext(ctx).Select("*").Save(policy).Error; err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+func (r *GormRetentionRepository) FindPolicy(ctx context.Context, id uint) (*entities.RetentionPolicy, error) {
+	var policy entities.RetentionPolicy
+	if err := r.db.WithContext(ctx).First(&policy, id).Error; err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (r *GormRetentionRepository) FindPolicies(ctx context.Context, hospital string) ([]*entities.RetentionPolicy, error) {
+	var policies []*entities.RetentionPolicy
+
+	db := r.db.WithContext(ctx).Order("id")
+	if hospital != "" {
+		db = db.Where(database.EqualFold(r.db, "hospital", hospital))
+	}
+	if err := db.Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+func (r *GormRetentionRepository) DeletePolicy(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&entities.RetentionPolicy{}, id).Error
+}
+
+func (r *GormRetentionRepository) SaveRun(ctx context.Context, run *entities.RetentionRun) error {
+	return r.db.WithContext(ctx).Create(run).Error
+}
+
+func (r *GormRetentionRepository) FindRuns(ctx context.Context, hospital string, limit int) ([]*entities.RetentionRun, error) {
+	var runs []*entities.RetentionRun
+	err := r.db.WithContext(ctx).Where(database.EqualFold(r.db, "hospital", hospital)).
+		Order("id DESC").
+		Limit(limit).
+		Find(&runs).Error
+	if err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+func (r *GormRetentionRepository) CountEligible(ctx context.Context, policy *entities.RetentionPolicy, cutoff time.Time) (int64, error) {
+	db, err := r.eligible(r.db.WithContext(ctx), policy, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	if err := db.Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// ApplyBatch processes up to batchSize eligible records in one transaction
+// and returns how many it processed.
+func (r *GormRetentionRepository) ApplyBatch(ctx context.Context, policy *entities.RetentionPolicy, cutoff time.Time, batchSize int) (int, error) {
+	processed := 0
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		db, err := r.eligible(tx, policy, cutoff)
+		if err != nil {
+			return err
+		}
+		var ids []uint
+		if err := db.Order("id").Limit(batchSize).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		processed = len(ids)
+
+		switch policy.Entity + "." + policy.Action {
+		case entities.RetentionEntityPatient + "." + entities.RetentionActionDelete:
+			return deletePatients(tx, ids)
+		case entities.RetentionEntityPatient + "." + entities.RetentionActionAnonymize:
+			return tx.Unscoped().Model(&entities.Patient{}).Where("id IN ?", ids).Updates(entities.AnonymizedColumns()).Error
+		case entities.RetentionEntityAuditLog + "." + entities.RetentionActionDelete:
+			return tx.Unscoped().Where("id IN ?", ids).Delete(&entities.AuditLog{}).Error
+		case entities.RetentionEntityImportJob + "." + entities.RetentionActionDelete:
+			if err := tx.Unscoped().Where("import_job_id IN ?", ids).Delete(&entities.ImportRowError{}).Error; err != nil {
+				return err
+			}
+			return tx.Unscoped().Where("id IN ?", ids).Delete(&entities.ImportJob{}).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return processed, nil
+}
+
+// TryLock takes a session lock: an advisory lock on Postgres, a named lock
+// on MySQL. On SQLite it only keeps the purges of this process apart, so a
+// SQLite database must be served by a single process.
+func (r *GormRetentionRepository) TryLock(ctx context.Context) (func(), bool, error) {
+	dialect := r.db.Dialector.Name()
+	if dialect != database.Postgres && dialect != database.MySQL {
+		if !r.mu.TryLock() {
+			return nil, false, nil
+		}
+		return r.mu.Unlock, true, nil
+	}
+
+	// Session locks belong to a connection, so the lock is taken and
+	// released on one connection held for the whole run.
+	sqlDB, err := r.db.WithContext(ctx).DB()
+	if err != nil {
+		return nil, false, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var ok bool
+	unlock, key := "SELECT pg_advisory_unlock($1)", any(retentionLockKey)
+	if dialect == database.MySQL {
+		var locked sql.NullInt64
+		unlock, key = "SELECT RELEASE_LOCK(?)", retentionLockName
+		err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", key).Scan(&locked)
+		ok = locked.Int64 == 1
+	} else {
+		err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&ok)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, false, err
+	}
+	if !ok {
+		conn.Close()
+		return nil, false, nil
+	}
+	// The lock is released even when ctx has been cancelled, since the
+	// connection goes back to the pool still holding it otherwise.
+	release := context.WithoutCancel(ctx)
+	return func() {
+		conn.ExecContext(release, unlock, key)
+		conn.Close()
+	}, true, nil
+}
+
+// eligible scopes db to the records policy applies to. Patients under a
+// legal hold never are.
+func (r *GormRetentionRepository) eligible(db *gorm.DB, policy *entities.RetentionPolicy, cutoff time.Time) (*gorm.DB, error) {
+	hospital := database.EqualFold(db, "hospital", policy.Hospital)
+
+	switch policy.Entity + "." + policy.Action {
+	case entities.RetentionEntityPatient + "." + entities.RetentionActionDelete:
+		return db.Unscoped().Model(&entities.Patient{}).
+			Where(hospital).Where("legal_hold = ?", false).
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff), nil
+	case entities.RetentionEntityPatient + "." + entities.RetentionActionAnonymize:
+		return db.Unscoped().Model(&entities.Patient{}).
+			Where(hospital).Where("legal_hold = ?", false).
+			Where("updated_at < ? AND anonymized_at IS NULL", cutoff), nil
+	case entities.RetentionEntityAuditLog + "." + entities.RetentionActionDelete:
+		return db.Unscoped().Model(&entities.AuditLog{}).
+			Where(hospital).Where("created_at < ?", cutoff), nil
+	case entities.RetentionEntityImportJob + "." + entities.RetentionActionDelete:
+		return db.Unscoped().Model(&entities.ImportJob{}).
+			Where(hospital).Where("created_at < ?", cutoff), nil
+	}
+	return nil, fmt.Errorf("retention of %s with %s is not supported", policy.Entity, policy.Action)
+}
+
+func deletePatients(tx *gorm.DB, ids []uint) error {
+	if err := tx.Unscoped().Where("patient_id IN ?", ids).Delete(&entities.Consent{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Where("patient_id IN ? OR candidate_id IN ?", ids, ids).Delete(&entities.PatientDuplicate{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&entities.PatientMerge{}).Where("merged_id IN ?", ids).Update("snapshot", "").Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Where("id IN ?", ids).Delete(&entities.Patient{}).Error
+}
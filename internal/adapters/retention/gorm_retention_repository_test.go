@@ -0,0 +1,97 @@
+package adapters_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	adapters "agnos/internal/adapters/retention"
+	"agnos/internal/dbtest"
+	"agnos/internal/entities"
+	"agnos/internal/routes"
+	"agnos/pkg/database"
+	"agnos/pkg/encryption"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func testProvider(t *testing.T) encryption.KeyProvider {
+	provider, err := encryption.NewLocalProvider("test", map[string][]byte{"test": bytes.Repeat([]byte{1}, 32)}, bytes.Repeat([]byte{2}, 32))
+	if err != nil {
+		t.Fatalf("failed to load encryption keys: %v", err)
+	}
+	return provider
+}
+
+func TestGormRetentionRepository_AnonymizesEachPatientOnce(t *testing.T) {
+	dbtest.Each(t, "retention_repository_test", func(t *testing.T, db *gorm.DB) {
+		if _, err := routes.EncryptionKeyring(db, testProvider(t)); err != nil {
+			t.Fatalf("failed to load data key: %v", err)
+		}
+		ctx := context.Background()
+		born := time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)
+		citizen := &entities.Patient{FirstNameEn: "Somsak", NationalId: "1234567890123", DateBirth: born, Hospital: "Bangkok Hospital"}
+		visitor := &entities.Patient{FirstNameEn: "John", PassportId: "AA1234567", DateBirth: born, Hospital: "Bangkok Hospital"}
+		held := &entities.Patient{FirstNameEn: "Malee", NationalId: "1234567890124", DateBirth: born, Hospital: "Bangkok Hospital", LegalHold: true}
+		for _, p := range []*entities.Patient{citizen, visitor, held} {
+			require.NoError(t, db.Create(p).Error)
+		}
+		longAgo := time.Now().AddDate(-2, 0, 0)
+		require.NoError(t, db.Model(&entities.Patient{}).Where("id IN ?", []uint{citizen.ID, visitor.ID, held.ID}).UpdateColumn("updated_at", longAgo).Error)
+
+		repo := adapters.NewGormRetentionRepository(db)
+		policy := &entities.RetentionPolicy{Hospital: "Bangkok Hospital", Entity: entities.RetentionEntityPatient, Action: entities.RetentionActionAnonymize, AfterDays: 365}
+		cutoff := time.Now().AddDate(-1, 0, 0)
+
+		count, err := repo.CountEligible(ctx, policy, cutoff)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+
+		processed, err := repo.ApplyBatch(ctx, policy, cutoff, 10)
+		require.NoError(t, err)
+		assert.Equal(t, 2, processed)
+
+		var anonymized entities.Patient
+		require.NoError(t, db.First(&anonymized, visitor.ID).Error)
+		assert.NotNil(t, anonymized.AnonymizedAt)
+		assert.Empty(t, anonymized.FirstNameEn)
+		assert.Empty(t, anonymized.PassportId)
+		assert.Empty(t, anonymized.PassportIdIndex)
+
+		// Anonymized patients are not matched again, however old they are.
+		require.NoError(t, db.Model(&entities.Patient{}).Where("id IN ?", []uint{citizen.ID, visitor.ID}).UpdateColumn("updated_at", longAgo).Error)
+		count, err = repo.CountEligible(ctx, policy, cutoff)
+		require.NoError(t, err)
+		assert.Zero(t, count)
+	})
+}
+
+func TestGormRetentionRepository_TryLockKeepsOtherReplicasOut(t *testing.T) {
+	dbtest.Each(t, "retention_lock_test", func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		repo := adapters.NewGormRetentionRepository(db)
+		// A SQLite database is served by one process, whose purges share a
+		// repository.
+		other := repo
+		if db.Dialector.Name() != database.SQLite {
+			other = adapters.NewGormRetentionRepository(db)
+		}
+
+		unlock, ok, err := repo.TryLock(ctx)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		_, ok, err = other.TryLock(ctx)
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		unlock()
+		unlock, ok, err = other.TryLock(ctx)
+		require.NoError(t, err)
+		require.True(t, ok)
+		unlock()
+	})
+}
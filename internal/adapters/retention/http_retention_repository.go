@@ -0,0 +1,149 @@
+package adapters
+
+import (
+	"agnos/internal/entities"
+	"agnos/internal/usecases/retention"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type HttpRetentionHandler struct {
+	retentionUseCase retention.RetentionUseCase
+}
+
+func NewHttpRetentionRepository(usecase retention.RetentionUseCase) *HttpRetentionHandler {
+	return &HttpRetentionHandler{retentionUseCase: usecase}
+}
+
+func (h *HttpRetentionHandler) ListPolicies(c *gin.Context) {
+	claims := c.MustGet("payload").(jwt.MapClaims)
+
+	policies, err := h.retentionUseCase.ListPolicies(c.Request.Context(), claims["hospital"].(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "search success", "statusCode": 200, "data": policies})
+}
+
+func (h *HttpRetentionHandler) CreatePolicy(c *gin.Context) {
+	policy, ok := bindPolicy(c)
+	if !ok {
+		return
+	}
+
+	created, err := h.retentionUseCase.CreatePolicy(c.Request.Context(), policy)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "create success", "statusCode": 201, "data": created})
+}
+
+func (h *HttpRetentionHandler) UpdatePolicy(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id is invalid"})
+		return
+	}
+	policy, ok := bindPolicy(c)
+	if !ok {
+		return
+	}
+	policy.ID = uint(id)
+
+	updated, err := h.retentionUseCase.UpdatePolicy(c.Request.Context(), policy)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "update success", "statusCode": 200, "data": updated})
+}
+
+func (h *HttpRetentionHandler) DeletePolicy(c *gin.Context) {
+	claims := c.MustGet("payload").(jwt.MapClaims)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id is invalid"})
+		return
+	}
+
+	if err := h.retentionUseCase.DeletePolicy(c.Request.Context(), claims["hospital"].(string), uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "delete success", "statusCode": 200})
+}
+
+// RunPolicies applies the caller's hospital policies now. dry_run=true only
+// reports what would be processed.
+func (h *HttpRetentionHandler) RunPolicies(c *gin.Context) {
+	claims := c.MustGet("payload").(jwt.MapClaims)
+
+	dryRun := false
+	if value := c.Query("dry_run"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "dry_run must be a boolean"})
+			return
+		}
+		dryRun = parsed
+	}
+
+	runs, err := h.retentionUseCase.Run(c.Request.Context(), claims["hospital"].(string), dryRun)
+	if errors.Is(err, retention.ErrLocked) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "run success", "statusCode": 200, "data": runs})
+}
+
+func (h *HttpRetentionHandler) ListRuns(c *gin.Context) {
+	claims := c.MustGet("payload").(jwt.MapClaims)
+
+	runs, err := h.retentionUseCase.ListRuns(c.Request.Context(), claims["hospital"].(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "search success", "statusCode": 200, "data": runs})
+}
+
+func bindPolicy(c *gin.Context) (*entities.RetentionPolicy, bool) {
+	claims := c.MustGet("payload").(jwt.MapClaims)
+
+	var policy entities.RetentionPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	if err := validator.New().Struct(&policy); err != nil {
+		errs := err.(validator.ValidationErrors)
+
+		messages := make([]string, 0)
+		for _, e := range errs {
+			messages = append(messages, fmt.Sprintf("%s is %s", strings.ToLower(e.Field()), strings.ToLower(e.Tag())))
+		}
+
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": messages,
+		})
+		return nil, false
+	}
+
+	policy.Hospital = claims["hospital"].(string)
+	return &policy, true
+}
@@ -0,0 +1,224 @@
+package adapters
+
+import (
+	adaptersAudit "agnos/internal/adapters/audit"
+	adaptersConsent "agnos/internal/adapters/consent"
+	adaptersImporter "agnos/internal/adapters/importer"
+	adaptersMpi "agnos/internal/adapters/mpi"
+	adaptersPatient "agnos/internal/adapters/patient"
+	"agnos/internal/entities"
+	"agnos/internal/usecases/retention"
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MemoryRetentionRepository keeps policies and runs in memory with the same
+// semantics as GormRetentionRepository, and applies the policies to the
+// other in-memory repositories.
+type MemoryRetentionRepository struct {
+	patients *adaptersPatient.MemoryPatientRepository
+	consents *adaptersConsent.MemoryConsentRepository
+	mpi      *adaptersMpi.MemoryMpiRepository
+	audit    *adaptersAudit.MemoryAuditRepository
+	imports  *adaptersImporter.MemoryImportRepository
+
+	mu        sync.RWMutex
+	policies  map[uint]entities.RetentionPolicy
+	runs      []entities.RetentionRun
+	nextId    uint
+	nextRunId uint
+
+	lock sync.Mutex
+}
+
+func NewMemoryRetentionRepository(patients *adaptersPatient.MemoryPatientRepository, consents *adaptersConsent.MemoryConsentRepository, mpi *adaptersMpi.MemoryMpiRepository, audit *adaptersAudit.MemoryAuditRepository, imports *adaptersImporter.MemoryImportRepository) retention.RetentionRepository {
+	return &MemoryRetentionRepository{patients: patients, consents: consents, mpi: mpi, audit: audit, imports: imports, policies: map[uint]entities.RetentionPolicy{}}
+}
+
+func (r *MemoryRetentionRepository) SavePolicy(ctx context.Context, policy *entities.RetentionPolicy) (*entities.RetentionPolicy, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if policy.ID == 0 {
+		r.nextId++
+		policy.ID = r.nextId
+	}
+	if policy.CreatedAt.IsZero() {
+		policy.CreatedAt = now
+	}
+	policy.UpdatedAt = now
+	r.policies[policy.ID] = *policy
+	return policy, nil
+}
+
+func (r *MemoryRetentionRepository) FindPolicy(ctx context.Context, id uint) (*entities.RetentionPolicy, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	policy, ok := r.policies[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &policy, nil
+}
+
+func (r *MemoryRetentionRepository) FindPolicies(ctx context.Context, hospital string) ([]*entities.RetentionPolicy, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	policies := make([]*entities.RetentionPolicy, 0)
+	for _, stored := range r.policies {
+		if hospital == "" || strings.EqualFold(stored.Hospital, hospital) {
+			policy := stored
+			policies = append(policies, &policy)
+		}
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].ID < policies[j].ID })
+	return policies, nil
+}
+
+func (r *MemoryRetentionRepository) DeletePolicy(ctx context.Context, id uint) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.policies, id)
+	return nil
+}
+
+func (r *MemoryRetentionRepository) SaveRun(ctx context.Context, run *entities.RetentionRun) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextRunId++
+	run.ID = r.nextRunId
+	run.CreatedAt, run.UpdatedAt = time.Now(), time.Now()
+	r.runs = append(r.runs, *run)
+	return nil
+}
+
+func (r *MemoryRetentionRepository) FindRuns(ctx context.Context, hospital string, limit int) ([]*entities.RetentionRun, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	runs := make([]*entities.RetentionRun, 0)
+	for i := len(r.runs) - 1; i >= 0 && len(runs) < limit; i-- {
+		if strings.EqualFold(r.runs[i].Hospital, hospital) {
+			run := r.runs[i]
+			runs = append(runs, &run)
+		}
+	}
+	return runs, nil
+}
+
+func (r *MemoryRetentionRepository) CountEligible(ctx context.Context, policy *entities.RetentionPolicy, cutoff time.Time) (int64, error) {
+	ids, err := r.eligible(ctx, policy, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(ids)), nil
+}
+
+// ApplyBatch processes up to batchSize eligible records and returns how
+// many it processed.
+func (r *MemoryRetentionRepository) ApplyBatch(ctx context.Context, policy *entities.RetentionPolicy, cutoff time.Time, batchSize int) (int, error) {
+	ids, err := r.eligible(ctx, policy, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	ids = ids[:min(batchSize, len(ids))]
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	switch policy.Entity + "." + policy.Action {
+	case entities.RetentionEntityPatient + "." + entities.RetentionActionDelete:
+		r.consents.RemovePatients(ids...)
+		r.mpi.RemovePatients(ids...)
+		r.mpi.ClearSnapshots(ids, nil)
+		r.patients.Remove(ids...)
+	case entities.RetentionEntityPatient + "." + entities.RetentionActionAnonymize:
+		r.patients.Anonymize(ids...)
+	case entities.RetentionEntityAuditLog + "." + entities.RetentionActionDelete:
+		r.audit.Remove(ids...)
+	case entities.RetentionEntityImportJob + "." + entities.RetentionActionDelete:
+		r.imports.RemoveJobs(ids...)
+	}
+	return len(ids), nil
+}
+
+func (r *MemoryRetentionRepository) TryLock(ctx context.Context) (func(), bool, error) {
+	if !r.lock.TryLock() {
+		return nil, false, nil
+	}
+	return r.lock.Unlock, true, nil
+}
+
+// eligible returns the IDs of the records policy applies to, in order.
+// Patients under a legal hold never are.
+func (r *MemoryRetentionRepository) eligible(ctx context.Context, policy *entities.RetentionPolicy, cutoff time.Time) ([]uint, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var ids []uint
+	patients := func(matches func(p *entities.Patient) bool) {
+		for _, p := range r.patients.Unscoped() {
+			if strings.EqualFold(p.Hospital, policy.Hospital) && !p.LegalHold && matches(p) {
+				ids = append(ids, p.ID)
+			}
+		}
+	}
+
+	switch policy.Entity + "." + policy.Action {
+	case entities.RetentionEntityPatient + "." + entities.RetentionActionDelete:
+		patients(func(p *entities.Patient) bool { return p.DeletedAt.Valid && p.DeletedAt.Time.Before(cutoff) })
+	case entities.RetentionEntityPatient + "." + entities.RetentionActionAnonymize:
+		patients(func(p *entities.Patient) bool { return p.UpdatedAt.Before(cutoff) && p.AnonymizedAt == nil })
+	case entities.RetentionEntityAuditLog + "." + entities.RetentionActionDelete:
+		err := r.audit.FindInBatches(ctx, 500, func(entries []*entities.AuditLog) error {
+			for _, entry := range entries {
+				if strings.EqualFold(entry.Hospital, policy.Hospital) && entry.CreatedAt.Before(cutoff) {
+					ids = append(ids, entry.ID)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	case entities.RetentionEntityImportJob + "." + entities.RetentionActionDelete:
+		for _, job := range r.imports.Jobs() {
+			if strings.EqualFold(job.Hospital, policy.Hospital) && job.CreatedAt.Before(cutoff) {
+				ids = append(ids, job.ID)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("retention of %s with %s is not supported", policy.Entity, policy.Action)
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
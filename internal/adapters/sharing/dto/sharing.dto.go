@@ -0,0 +1,28 @@
+package dto
+
+import "agnos/internal/entities"
+
+type ProposeAgreementDto struct {
+	PartnerHospital string            `json:"partner_hospital" validate:"required"`
+	Direction       string            `json:"direction" validate:"required,oneof=outbound inbound both"`
+	Fields          map[string]string `json:"fields"`
+	Roles           []string          `json:"roles" validate:"required,min=1,dive,oneof=admin clinician receptionist"`
+	Hospital        string            `json:"-"`
+	ProposedBy      string            `json:"-"`
+	Role            string            `json:"-"`
+}
+
+type AgreementActionDto struct {
+	Id       uint   `json:"-"`
+	Hospital string `json:"-"`
+	Actor    string `json:"-"`
+	Role     string `json:"-"`
+}
+
+// PartnerResult is a patient found at a partner hospital, labelled with the
+// hospital it came from and the agreement that allowed it.
+type PartnerResult struct {
+	SourceHospital string            `json:"source_hospital"`
+	AgreementId    uint              `json:"agreement_id"`
+	Patient        *entities.Patient `json:"patient"`
+}
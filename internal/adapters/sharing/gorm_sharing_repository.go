@@ -0,0 +1,47 @@
+package adapters
+
+import (
+	"agnos/internal/entities"
+	"agnos/internal/usecases/sharing"
+	"agnos/pkg/database"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type GormSharingRepository struct {
+	db *gorm.DB
+}
+
+func NewGormSharingRepository(db *gorm.DB) sharing.SharingRepository {
+	return &GormSharingRepository{db: db}
+}
+
+func (r *GormSharingRepository) SaveAgreement(ctx context.Context, agreement *entities.SharingAgreement) (*entities.SharingAgreement, error) {
+	if err := r.db.WithContext(ctx).Save(agreement).Error; err != nil {
+		return nil, err
+	}
+	return agreement, nil
+}
+
+func (r *GormSharingRepository) FindAgreement(ctx context.Context, id uint) (*entities.SharingAgreement, error) {
+	var agreement entities.SharingAgreement
+	if err := r.db.WithContext(ctx).First(&agreement, id).Error; err != nil {
+		return nil, err
+	}
+	return &agreement, nil
+}
+
+// FindAgreements returns the agreements hospital is a party to, on either side.
+func (r *GormSharingRepository) FindAgreements(ctx context.Context, hospital string, status string) ([]*entities.SharingAgreement, error) {
+	var agreements []*entities.SharingAgreement
+
+	db := r.db.WithContext(ctx).Where(r.db.Where(database.EqualFold(r.db, "hospital", hospital)).Or(database.EqualFold(r.db, "partner_hospital", hospital)))
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	if err := db.Order("id DESC").Find(&agreements).Error; err != nil {
+		return nil, err
+	}
+	return agreements, nil
+}
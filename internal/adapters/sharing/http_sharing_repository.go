@@ -0,0 +1,116 @@
+package adapters
+
+import (
+	"agnos/internal/adapters/sharing/dto"
+	"agnos/internal/usecases/sharing"
+	"agnos/pkg/middleware"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type HttpSharingHandler struct {
+	sharingUseCase sharing.SharingUseCase
+}
+
+func NewHttpSharingRepository(usecase sharing.SharingUseCase) *HttpSharingHandler {
+	return &HttpSharingHandler{sharingUseCase: usecase}
+}
+
+func (h *HttpSharingHandler) ProposeAgreement(c *gin.Context) {
+	claims := c.MustGet("payload").(jwt.MapClaims)
+
+	var data dto.ProposeAgreementDto
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if messages := validate(&data); len(messages) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": messages})
+		return
+	}
+
+	data.Hospital = claims["hospital"].(string)
+	data.ProposedBy = claims["username"].(string)
+	data.Role = middleware.ClaimRole(claims)
+
+	agreement, err := h.sharingUseCase.ProposeAgreement(c.Request.Context(), &data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "agreement proposed", "statusCode": 200, "data": agreement})
+}
+
+func (h *HttpSharingHandler) ListAgreements(c *gin.Context) {
+	claims := c.MustGet("payload").(jwt.MapClaims)
+
+	agreements, err := h.sharingUseCase.ListAgreements(c.Request.Context(), claims["hospital"].(string), c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "search success", "statusCode": 200, "data": agreements})
+}
+
+func (h *HttpSharingHandler) AcceptAgreement(c *gin.Context) {
+	data, ok := actionParams(c)
+	if !ok {
+		return
+	}
+
+	agreement, err := h.sharingUseCase.AcceptAgreement(c.Request.Context(), data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "agreement accepted", "statusCode": 200, "data": agreement})
+}
+
+func (h *HttpSharingHandler) RevokeAgreement(c *gin.Context) {
+	data, ok := actionParams(c)
+	if !ok {
+		return
+	}
+
+	agreement, err := h.sharingUseCase.RevokeAgreement(c.Request.Context(), data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "agreement revoked", "statusCode": 200, "data": agreement})
+}
+
+func actionParams(c *gin.Context) (*dto.AgreementActionDto, bool) {
+	claims := c.MustGet("payload").(jwt.MapClaims)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id is invalid"})
+		return nil, false
+	}
+	return &dto.AgreementActionDto{
+		Id:       uint(id),
+		Hospital: claims["hospital"].(string),
+		Actor:    claims["username"].(string),
+		Role:     middleware.ClaimRole(claims),
+	}, true
+}
+
+func validate(data interface{}) []string {
+	err := validator.New().Struct(data)
+	if err == nil {
+		return nil
+	}
+
+	messages := make([]string, 0)
+	for _, e := range err.(validator.ValidationErrors) {
+		messages = append(messages, fmt.Sprintf("%s is %s", strings.ToLower(e.Field()), strings.ToLower(e.Tag())))
+	}
+	return messages
+}
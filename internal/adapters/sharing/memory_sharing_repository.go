@@ -0,0 +1,90 @@
+package adapters
+
+import (
+	"agnos/internal/entities"
+	"agnos/internal/usecases/sharing"
+	"context"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MemorySharingRepository keeps sharing agreements in a map with the same
+// semantics as GormSharingRepository.
+type MemorySharingRepository struct {
+	mu         sync.RWMutex
+	agreements map[uint]entities.SharingAgreement
+	nextId     uint
+}
+
+func NewMemorySharingRepository() sharing.SharingRepository {
+	return &MemorySharingRepository{agreements: map[uint]entities.SharingAgreement{}}
+}
+
+func (r *MemorySharingRepository) SaveAgreement(ctx context.Context, agreement *entities.SharingAgreement) (*entities.SharingAgreement, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if agreement.ID == 0 {
+		r.nextId++
+		agreement.ID = r.nextId
+	}
+	if agreement.CreatedAt.IsZero() {
+		agreement.CreatedAt = now
+	}
+	agreement.UpdatedAt = now
+	r.agreements[agreement.ID] = clone(*agreement)
+	return agreement, nil
+}
+
+func (r *MemorySharingRepository) FindAgreement(ctx context.Context, id uint) (*entities.SharingAgreement, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	agreement, ok := r.agreements[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	agreement = clone(agreement)
+	return &agreement, nil
+}
+
+// FindAgreements returns the agreements hospital is a party to, on either side.
+func (r *MemorySharingRepository) FindAgreements(ctx context.Context, hospital string, status string) ([]*entities.SharingAgreement, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	agreements := make([]*entities.SharingAgreement, 0)
+	for _, stored := range r.agreements {
+		party := strings.EqualFold(stored.Hospital, hospital) || strings.EqualFold(stored.PartnerHospital, hospital)
+		if !party || (status != "" && stored.Status != status) {
+			continue
+		}
+		agreement := clone(stored)
+		agreements = append(agreements, &agreement)
+	}
+	sort.Slice(agreements, func(i, j int) bool { return agreements[i].ID > agreements[j].ID })
+	return agreements, nil
+}
+
+// clone copies the fields and roles too, which the database keeps as JSON.
+func clone(agreement entities.SharingAgreement) entities.SharingAgreement {
+	agreement.Fields = maps.Clone(agreement.Fields)
+	agreement.Roles = slices.Clone(agreement.Roles)
+	return agreement
+}
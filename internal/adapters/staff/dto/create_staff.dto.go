@@ -0,0 +1,13 @@
+package dto
+
+// CreateStaffDto is what anyone may send to sign up. It has no role: new
+// accounts are receptionists until an admin changes their role.
+type CreateStaffDto struct {
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
+	Hospital string `json:"hospital" validate:"required"`
+}
+
+type ChangeRoleDto struct {
+	Role string `json:"role" validate:"required,oneof=admin clinician receptionist"`
+}
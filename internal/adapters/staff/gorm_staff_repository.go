@@ -4,6 +4,8 @@ import (
 	"agnos/internal/adapters/staff/dto"
 	"agnos/internal/entities"
 	"agnos/internal/usecases/staff"
+	"agnos/pkg/database"
+	"context"
 	"errors"
 	"fmt"
 
@@ -18,26 +20,63 @@ func NewGormStaffRepository(db *gorm.DB) staff.StaffRepository {
 	return &GormStaffRepository{db: db}
 }
 
-func (r *GormStaffRepository) Save(staff *entities.Staff) (*entities.Staff, error) {
-	if err := r.db.Where("username = ?", staff.Username).First(staff).Error; err == nil {
+func (r *GormStaffRepository) Save(ctx context.Context, staff *entities.Staff) (*entities.Staff, error) {
+	if err := r.db.WithContext(ctx).Where("username = ?", staff.Username).First(staff).Error; err == nil {
 		return nil, fmt.Errorf("username already exist")
 	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, err
 	}
 
-	if err := r.db.Save(staff).Error; err != nil {
+	// The unique index catches a username taken since the check above.
+	if err := r.db.WithContext(ctx).Save(staff).Error; err != nil {
+		if database.IsUniqueViolation(r.db, err) {
+			return nil, fmt.Errorf("username already exist")
+		}
 		return nil, err
 	}
 	return staff, nil
 }
 
-func (r *GormStaffRepository) Login(dto *dto.LoginStaffDto) (*entities.Staff, error) {
-	var staff entities.Staff
-	if err := r.db.Where("username = ?", dto.Username).First(&staff).Error; err != nil {
+func (r *GormStaffRepository) Login(ctx context.Context, dto *dto.LoginStaffDto) (*entities.Staff, error) {
+	var found entities.Staff
+	if err := r.db.WithContext(ctx).Where("username = ?", dto.Username).First(&found).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, fmt.Errorf("user with username %s not found", dto.Username)
+			return nil, fmt.Errorf("user with username %s %w", dto.Username, staff.ErrNotFound)
 		}
 		return nil, err
 	}
-	return &staff, nil
+	return &found, nil
+}
+
+func (r *GormStaffRepository) UpdatePassword(ctx context.Context, username string, password string) error {
+	result := r.db.WithContext(ctx).Model(&entities.Staff{}).Where("username = ?", username).Update("password", password)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("user with username %s %w", username, staff.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *GormStaffRepository) UpdateRole(ctx context.Context, username string, role string) error {
+	result := r.db.WithContext(ctx).Model(&entities.Staff{}).Where("username = ?", username).Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("user with username %s %w", username, staff.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *GormStaffRepository) Delete(ctx context.Context, username string) error {
+	result := r.db.WithContext(ctx).Where("username = ?", username).Delete(&entities.Staff{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("user with username %s %w", username, staff.ErrNotFound)
+	}
+	return nil
 }
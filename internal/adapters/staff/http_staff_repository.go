@@ -4,6 +4,10 @@ import (
 	"agnos/internal/adapters/staff/dto"
 	"agnos/internal/entities"
 	"agnos/internal/usecases/staff"
+	"agnos/pkg/logging"
+	"agnos/pkg/metrics"
+	"agnos/pkg/middleware"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -23,8 +27,10 @@ func NewHttpStaffRepository(usecase staff.StaffUseCase) *HttpStaffHandler {
 	return &HttpStaffHandler{staffUseCase: usecase}
 }
 
+// CreateStaff signs up a receptionist. It is open to anyone, so the role
+// can only be raised afterwards by an admin through ChangeRole.
 func (h *HttpStaffHandler) CreateStaff(c *gin.Context) {
-	var data entities.Staff
+	var data dto.CreateStaffDto
 
 	if err := c.ShouldBindJSON(&data); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -45,16 +51,35 @@ func (h *HttpStaffHandler) CreateStaff(c *gin.Context) {
 		return
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(data.Password), bcrypt.DefaultCost)
-	data.Password = string(hashedPassword)
-
-	hospital, err := h.staffUseCase.CreateStaff(&data)
+	staff := &entities.Staff{Username: data.Username, Password: data.Password, Hospital: data.Hospital, Role: entities.RoleReceptionist}
+	created, err := h.staffUseCase.CreateStaff(c.Request.Context(), staff)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "create success", "statusCode": 201, "data": hospital})
+	c.JSON(http.StatusOK, gin.H{"message": "create success", "statusCode": 201, "data": created})
+}
+
+func (h *HttpStaffHandler) ChangeRole(c *gin.Context) {
+	claims := c.MustGet("payload").(jwt.MapClaims)
+
+	var data dto.ChangeRoleDto
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validator.New().Struct(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be admin, clinician or receptionist"})
+		return
+	}
+
+	username := c.Param("username")
+	if err := h.staffUseCase.ChangeRole(c.Request.Context(), claims["hospital"].(string), username, data.Role); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "role changed", "statusCode": 200, "data": gin.H{"username": username, "role": data.Role}})
 }
 
 func (h *HttpStaffHandler) Login(c *gin.Context) {
@@ -79,31 +104,40 @@ func (h *HttpStaffHandler) Login(c *gin.Context) {
 		return
 	}
 
-	staff, err := h.staffUseCase.Login(&data)
-	if err != nil {
+	account, err := h.staffUseCase.Login(c.Request.Context(), &data)
+	if errors.Is(err, staff.ErrNotFound) {
+		metrics.Logins.WithLabelValues("unknown_user").Inc()
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err != nil {
+		metrics.Logins.WithLabelValues("error").Inc()
+		logging.For("http").ErrorContext(c.Request.Context(), "login lookup failed", "error", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "login failed"})
+		return
+	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(staff.Password), []byte(data.Password))
+	err = bcrypt.CompareHashAndPassword([]byte(account.Password), []byte(data.Password))
 	if err != nil {
+		metrics.Logins.WithLabelValues("wrong_password").Inc()
 		c.JSON(http.StatusBadRequest, gin.H{"error": "password not matched"})
 		return
 	}
 
 	claims := jwt.MapClaims{
-		"username": staff.Username,
+		"username": account.Username,
 		"exp":      time.Now().Add(time.Hour * 24).Unix(),
-		"user_id":  staff.ID,
-		"hospital": staff.Hospital,
+		"user_id":  account.ID,
+		"hospital": account.Hospital,
+		"role":     account.Role,
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	t, err := token.SignedString([]byte("supersecret"))
+	t, err := middleware.SignToken(claims)
 
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusAccepted, gin.H{"data": staff, "token": t, "statusCode": 200})
+	metrics.Logins.WithLabelValues("success").Inc()
+	c.JSON(http.StatusAccepted, gin.H{"data": account, "token": t, "statusCode": 200})
 }
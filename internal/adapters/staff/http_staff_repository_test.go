@@ -0,0 +1,55 @@
+package adapters_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	adapters "agnos/internal/adapters/staff"
+	"agnos/internal/adapters/staff/dto"
+	"agnos/internal/entities"
+	"agnos/internal/usecases/staff"
+	"agnos/pkg/metrics"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// unreachableStaff fails every login as a database that went away would.
+type unreachableStaff struct {
+	staff.StaffUseCase
+}
+
+func (unreachableStaff) Login(ctx context.Context, data *dto.LoginStaffDto) (*entities.Staff, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestLogin_CountsDatabaseErrorsApartFromUnknownUsers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	unknownUser := testutil.ToFloat64(metrics.Logins.WithLabelValues("unknown_user"))
+	failed := testutil.ToFloat64(metrics.Logins.WithLabelValues("error"))
+
+	login := func(usecase staff.StaffUseCase) *httptest.ResponseRecorder {
+		r := gin.New()
+		r.POST("/staff/login", adapters.NewHttpStaffRepository(usecase).Login)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/staff/login", strings.NewReader(`{"username":"somchai","password":"89058905"}`))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := login(unreachableStaff{})
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NotContains(t, w.Body.String(), "connection refused")
+
+	w = login(staff.NewStaffService(adapters.NewMemoryStaffRepository()))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	assert.Equal(t, unknownUser+1, testutil.ToFloat64(metrics.Logins.WithLabelValues("unknown_user")))
+	assert.Equal(t, failed+1, testutil.ToFloat64(metrics.Logins.WithLabelValues("error")))
+}
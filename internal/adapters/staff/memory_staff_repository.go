@@ -0,0 +1,115 @@
+package adapters
+
+import (
+	"agnos/internal/adapters/staff/dto"
+	"agnos/internal/entities"
+	"agnos/internal/usecases/staff"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MemoryStaffRepository keeps staff in memory with the same semantics as
+// GormStaffRepository: usernames are unique among active accounts and
+// deleted accounts can no longer log in.
+type MemoryStaffRepository struct {
+	mu     sync.RWMutex
+	staffs []entities.Staff
+}
+
+func NewMemoryStaffRepository() staff.StaffRepository {
+	return &MemoryStaffRepository{}
+}
+
+func (r *MemoryStaffRepository) Save(ctx context.Context, staff *entities.Staff) (*entities.Staff, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.active(staff.Username) >= 0 {
+		return nil, fmt.Errorf("username already exist")
+	}
+	now := time.Now()
+	staff.ID = uint(len(r.staffs) + 1)
+	staff.CreatedAt, staff.UpdatedAt = now, now
+	if staff.Role == "" {
+		staff.Role = entities.RoleReceptionist
+	}
+	r.staffs = append(r.staffs, *staff)
+	return staff, nil
+}
+
+func (r *MemoryStaffRepository) Login(ctx context.Context, dto *dto.LoginStaffDto) (*entities.Staff, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	i := r.active(dto.Username)
+	if i < 0 {
+		return nil, fmt.Errorf("user with username %s %w", dto.Username, staff.ErrNotFound)
+	}
+	found := r.staffs[i]
+	return &found, nil
+}
+
+func (r *MemoryStaffRepository) UpdatePassword(ctx context.Context, username string, password string) error {
+	return r.update(ctx, username, func(staff *entities.Staff) { staff.Password = password })
+}
+
+func (r *MemoryStaffRepository) UpdateRole(ctx context.Context, username string, role string) error {
+	return r.update(ctx, username, func(staff *entities.Staff) { staff.Role = role })
+}
+
+func (r *MemoryStaffRepository) Delete(ctx context.Context, username string) error {
+	return r.update(ctx, username, func(staff *entities.Staff) {
+		staff.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	})
+}
+
+func (r *MemoryStaffRepository) update(ctx context.Context, username string, change func(staff *entities.Staff)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := r.active(username)
+	if i < 0 {
+		return fmt.Errorf("user with username %s %w", username, staff.ErrNotFound)
+	}
+	change(&r.staffs[i])
+	r.staffs[i].UpdatedAt = time.Now()
+	return nil
+}
+
+// Snapshot returns a function that puts the repository back as it is now,
+// for an in-memory unit of work that rolls back.
+func (r *MemoryStaffRepository) Snapshot() (restore func()) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	staffs := slices.Clone(r.staffs)
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.staffs = staffs
+	}
+}
+
+// active returns the position of the account with username that has not
+// been deleted, or -1. The caller holds mu.
+func (r *MemoryStaffRepository) active(username string) int {
+	for i, staff := range r.staffs {
+		if staff.Username == username && !staff.DeletedAt.Valid {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,28 @@
+package adapters_test
+
+import (
+	"testing"
+
+	adapters "agnos/internal/adapters/staff"
+	"agnos/internal/dbtest"
+	"agnos/internal/entities"
+	"agnos/internal/usecases/staff"
+	"agnos/internal/usecases/staff/stafftest"
+
+	"gorm.io/gorm"
+)
+
+func TestMemoryStaffRepository(t *testing.T) {
+	stafftest.RepositoryContract(t, func(t *testing.T) staff.StaffRepository {
+		return adapters.NewMemoryStaffRepository()
+	})
+}
+
+func TestGormStaffRepository(t *testing.T) {
+	dbtest.Each(t, "staff_repository_test", func(t *testing.T, db *gorm.DB) {
+		stafftest.RepositoryContract(t, func(t *testing.T) staff.StaffRepository {
+			db.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&entities.Staff{})
+			return adapters.NewGormStaffRepository(db)
+		})
+	})
+}
@@ -0,0 +1,97 @@
+package adapters
+
+import (
+	adaptersAudit "agnos/internal/adapters/audit"
+	adaptersConsent "agnos/internal/adapters/consent"
+	adaptersDsar "agnos/internal/adapters/dsar"
+	adaptersMpi "agnos/internal/adapters/mpi"
+	adaptersPatient "agnos/internal/adapters/patient"
+	adaptersStaff "agnos/internal/adapters/staff"
+	"agnos/internal/usecases/audit"
+	"agnos/internal/usecases/consent"
+	"agnos/internal/usecases/dsar"
+	"agnos/internal/usecases/mpi"
+	"agnos/internal/usecases/patient"
+	"agnos/internal/usecases/staff"
+	"agnos/internal/usecases/unitofwork"
+	"agnos/pkg/database"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// maxAttempts bounds how often an outermost unit runs when it keeps losing
+// serialization conflicts; retryBackoff is the wait before the second run,
+// doubled before each one after it.
+const (
+	maxAttempts  = 3
+	retryBackoff = 20 * time.Millisecond
+)
+
+type txKey struct{}
+
+type GormUnitOfWork struct {
+	db *gorm.DB
+}
+
+func NewGormUnitOfWork(db *gorm.DB) unitofwork.UnitOfWork {
+	return &GormUnitOfWork{db: db}
+}
+
+// Do keeps the transaction in the ctx handed to fn. A nested Do finds it
+// there and opens a savepoint in it, which gorm does for a Transaction
+// started on a transaction.
+func (u *GormUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos unitofwork.Repositories) error) error {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return u.run(ctx, tx, fn)
+	}
+
+	backoff := retryBackoff
+	for attempt := 1; ; attempt++ {
+		err := u.run(ctx, u.db, fn)
+		if err == nil || attempt == maxAttempts || !database.IsSerializationFailure(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (u *GormUnitOfWork) run(ctx context.Context, db *gorm.DB, fn func(ctx context.Context, repos unitofwork.Repositories) error) error {
+	return database.Transaction(db.WithContext(ctx), func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx), gormRepositories{tx: tx})
+	})
+}
+
+type gormRepositories struct {
+	tx *gorm.DB
+}
+
+func (r gormRepositories) Patients() patient.PatientRepository {
+	return adaptersPatient.NewGormPatientRepository(r.tx)
+}
+
+func (r gormRepositories) Staff() staff.StaffRepository {
+	return adaptersStaff.NewGormStaffRepository(r.tx)
+}
+
+func (r gormRepositories) Consents() consent.ConsentRepository {
+	return adaptersConsent.NewGormConsentRepository(r.tx)
+}
+
+func (r gormRepositories) Audit() audit.AuditRepository {
+	return adaptersAudit.NewGormAuditRepository(r.tx)
+}
+
+func (r gormRepositories) Mpi() mpi.MpiRepository {
+	return adaptersMpi.NewGormMpiRepository(r.tx)
+}
+
+func (r gormRepositories) Dsar() dsar.DsarRepository {
+	return adaptersDsar.NewGormDsarRepository(r.tx)
+}
@@ -0,0 +1,79 @@
+package adapters
+
+import (
+	"agnos/internal/usecases/audit"
+	"agnos/internal/usecases/consent"
+	"agnos/internal/usecases/dsar"
+	"agnos/internal/usecases/mpi"
+	"agnos/internal/usecases/patient"
+	"agnos/internal/usecases/staff"
+	"agnos/internal/usecases/unitofwork"
+	"context"
+	"sync"
+)
+
+type snapshotter interface {
+	Snapshot() (restore func())
+}
+
+// MemoryUnitOfWork runs units over in-memory repositories, for tests of use
+// cases that should not need a database. Outermost units run one at a time,
+// and every unit rolls back by restoring the repositories as they were when
+// it began. It never sees a serialization conflict, so it never retries.
+type MemoryUnitOfWork struct {
+	patients patient.PatientRepository
+	staff    staff.StaffRepository
+	consents consent.ConsentRepository
+	audit    audit.AuditRepository
+	mpi      mpi.MpiRepository
+	dsar     dsar.DsarRepository
+
+	mu sync.Mutex
+}
+
+// NewMemoryUnitOfWork runs units over the given repositories, which must be
+// the in-memory ones of the adapters packages, so that they can be
+// restored. Those a test does not use may be nil. Writes made around the
+// unit of work, straight through the repositories, are not serialized with
+// it.
+func NewMemoryUnitOfWork(patients patient.PatientRepository, staff staff.StaffRepository, consents consent.ConsentRepository, audit audit.AuditRepository, mpi mpi.MpiRepository, dsar dsar.DsarRepository) unitofwork.UnitOfWork {
+	return &MemoryUnitOfWork{patients: patients, staff: staff, consents: consents, audit: audit, mpi: mpi, dsar: dsar}
+}
+
+func (u *MemoryUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos unitofwork.Repositories) error) (err error) {
+	if ctx.Value(txKey{}) != u {
+		u.mu.Lock()
+		defer u.mu.Unlock()
+		ctx = context.WithValue(ctx, txKey{}, u)
+	}
+
+	var restores []func()
+	for _, repo := range []any{u.patients, u.staff, u.consents, u.audit, u.mpi, u.dsar} {
+		if repo != nil {
+			restores = append(restores, repo.(snapshotter).Snapshot())
+		}
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			rollback(restores)
+			panic(p)
+		}
+		if err != nil {
+			rollback(restores)
+		}
+	}()
+	return fn(ctx, u)
+}
+
+func rollback(restores []func()) {
+	for _, restore := range restores {
+		restore()
+	}
+}
+
+func (u *MemoryUnitOfWork) Patients() patient.PatientRepository { return u.patients }
+func (u *MemoryUnitOfWork) Staff() staff.StaffRepository        { return u.staff }
+func (u *MemoryUnitOfWork) Consents() consent.ConsentRepository { return u.consents }
+func (u *MemoryUnitOfWork) Audit() audit.AuditRepository        { return u.audit }
+func (u *MemoryUnitOfWork) Mpi() mpi.MpiRepository              { return u.mpi }
+func (u *MemoryUnitOfWork) Dsar() dsar.DsarRepository           { return u.dsar }
@@ -0,0 +1,124 @@
+package adapters_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	adaptersAudit "agnos/internal/adapters/audit"
+	adaptersConsent "agnos/internal/adapters/consent"
+	adaptersDsar "agnos/internal/adapters/dsar"
+	adaptersMpi "agnos/internal/adapters/mpi"
+	adaptersPatient "agnos/internal/adapters/patient"
+	adaptersStaff "agnos/internal/adapters/staff"
+	adapters "agnos/internal/adapters/unitofwork"
+	"agnos/internal/dbtest"
+	"agnos/internal/entities"
+	"agnos/internal/routes"
+	"agnos/internal/usecases/unitofwork"
+	"agnos/internal/usecases/unitofwork/unitofworktest"
+	"agnos/pkg/encryption"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func testProvider(t *testing.T) encryption.KeyProvider {
+	provider, err := encryption.NewLocalProvider("test", map[string][]byte{"test": bytes.Repeat([]byte{1}, 32)}, bytes.Repeat([]byte{2}, 32))
+	if err != nil {
+		t.Fatalf("failed to load encryption keys: %v", err)
+	}
+	return provider
+}
+
+func TestMemoryUnitOfWork(t *testing.T) {
+	encryption.SetDefault(encryption.NewKeyring(testProvider(t), nil))
+
+	unitofworktest.Contract(t, func(t *testing.T) unitofwork.UnitOfWork {
+		patients := adaptersPatient.NewMemoryPatientRepository().(*adaptersPatient.MemoryPatientRepository)
+		consents := adaptersConsent.NewMemoryConsentRepository(patients).(*adaptersConsent.MemoryConsentRepository)
+		audit := adaptersAudit.NewMemoryAuditRepository()
+		mpi := adaptersMpi.NewMemoryMpiRepository(patients).(*adaptersMpi.MemoryMpiRepository)
+		return adapters.NewMemoryUnitOfWork(
+			patients,
+			adaptersStaff.NewMemoryStaffRepository(),
+			consents,
+			audit,
+			mpi,
+			adaptersDsar.NewMemoryDsarRepository(patients, consents, mpi, audit),
+		)
+	})
+}
+
+func TestGormUnitOfWork(t *testing.T) {
+	dbtest.Each(t, "unit_of_work_test", func(t *testing.T, db *gorm.DB) {
+		if _, err := routes.EncryptionKeyring(db, testProvider(t)); err != nil {
+			t.Fatalf("failed to load data key: %v", err)
+		}
+		clean := func() {
+			for _, model := range []any{&entities.Consent{}, &entities.AuditLog{}, &entities.Staff{}, &entities.Patient{}} {
+				db.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(model)
+			}
+		}
+
+		unitofworktest.Contract(t, func(t *testing.T) unitofwork.UnitOfWork {
+			clean()
+			return adapters.NewGormUnitOfWork(db)
+		})
+
+		t.Run("RetriesSerializationFailure", func(t *testing.T) {
+			clean()
+			uow := adapters.NewGormUnitOfWork(db)
+			attempts := 0
+			err := uow.Do(context.Background(), func(ctx context.Context, repos unitofwork.Repositories) error {
+				attempts++
+				if _, err := repos.Patients().Save(ctx, newPatient()); err != nil {
+					return err
+				}
+				if attempts == 1 {
+					return &pgconn.PgError{Code: "40001"}
+				}
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, 2, attempts)
+
+			var count int64
+			require.NoError(t, db.Model(&entities.Patient{}).Count(&count).Error)
+			assert.Equal(t, int64(1), count)
+		})
+
+		t.Run("DoesNotRetryOtherErrors", func(t *testing.T) {
+			clean()
+			uow := adapters.NewGormUnitOfWork(db)
+			failed := errors.New("failed")
+			attempts := 0
+			err := uow.Do(context.Background(), func(ctx context.Context, repos unitofwork.Repositories) error {
+				attempts++
+				return failed
+			})
+			assert.ErrorIs(t, err, failed)
+			assert.Equal(t, 1, attempts)
+		})
+
+		t.Run("GivesUpAfterRepeatedConflicts", func(t *testing.T) {
+			clean()
+			uow := adapters.NewGormUnitOfWork(db)
+			attempts := 0
+			err := uow.Do(context.Background(), func(ctx context.Context, repos unitofwork.Repositories) error {
+				attempts++
+				return &pgconn.PgError{Code: "40P01"}
+			})
+			var pgErr *pgconn.PgError
+			assert.ErrorAs(t, err, &pgErr)
+			assert.Equal(t, 3, attempts)
+		})
+	})
+}
+
+func newPatient() *entities.Patient {
+	return &entities.Patient{FirstNameEn: "Somchai", LastNameEn: "Jaidee", PatientHn: "HN-1", NationalId: "1100000000001", Gender: "male", Hospital: "Bangkok Hospital"}
+}
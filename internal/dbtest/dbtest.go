@@ -0,0 +1,125 @@
+// Package dbtest opens a database of each supported dialect for tests, so
+// that repositories and migrations are checked against all of them.
+//
+// SQLite always runs, in a temporary file. Postgres runs only when
+// TEST_POSTGRES_HOST is set, with TEST_POSTGRES_PORT, TEST_POSTGRES_USER,
+// TEST_POSTGRES_PASSWORD and TEST_POSTGRES_DB defaulting to the database of
+// docker compose. MySQL runs only when TEST_MYSQL_HOST is set, with
+// TEST_MYSQL_PORT, TEST_MYSQL_USER and TEST_MYSQL_PASSWORD defaulting to
+// 3306, root and no password.
+package dbtest
+
+import (
+	"agnos/internal/migrations"
+	"agnos/pkg/database"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Dialects lists the dialects Each runs against.
+var Dialects = []string{database.SQLite, database.Postgres, database.MySQL}
+
+// Each runs fn once per dialect, in a subtest named after it, against a
+// migrated database of its own.
+func Each(t *testing.T, name string, fn func(t *testing.T, db *gorm.DB)) {
+	for _, dialect := range Dialects {
+		t.Run(dialect, func(t *testing.T) {
+			fn(t, OpenMigrated(t, dialect, name))
+		})
+	}
+}
+
+// OpenMigrated is OpenEmpty with every migration applied.
+func OpenMigrated(t *testing.T, dialect string, name string) *gorm.DB {
+	db := OpenEmpty(t, dialect, name)
+	migrator, err := migrations.New(db)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	return db
+}
+
+// OpenEmpty connects to a new, empty database or schema called name, which
+// is dropped when the test ends. It skips the test for MySQL unless
+// TEST_MYSQL_HOST is set.
+func OpenEmpty(t *testing.T, dialect string, name string) *gorm.DB {
+	t.Helper()
+	var db *gorm.DB
+	switch dialect {
+	case database.SQLite:
+		config := database.Config{Driver: database.SQLite, Path: filepath.Join(t.TempDir(), name+".db")}
+		db = open(t, config.Dialector())
+	case database.Postgres:
+		if os.Getenv("TEST_POSTGRES_HOST") == "" {
+			t.Skip("TEST_POSTGRES_HOST is not set")
+		}
+		// A schema on the search path keeps the tables apart from those
+		// other packages test against.
+		dbs := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+			os.Getenv("TEST_POSTGRES_HOST"),
+			getEnv("TEST_POSTGRES_PORT", "5433"),
+			getEnv("TEST_POSTGRES_USER", "myuser"),
+			getEnv("TEST_POSTGRES_PASSWORD", "mypassword"),
+			getEnv("TEST_POSTGRES_DB", "mydatabase"))
+		admin := open(t, postgres.Open(dbs))
+		admin.Exec("DROP SCHEMA IF EXISTS " + name + " CASCADE")
+		if err := admin.Exec("CREATE SCHEMA " + name).Error; err != nil {
+			t.Fatalf("failed to create schema: %v", err)
+		}
+		t.Cleanup(func() { admin.Exec("DROP SCHEMA IF EXISTS " + name + " CASCADE") })
+		db = open(t, postgres.Open(dbs+" search_path="+name))
+	case database.MySQL:
+		if os.Getenv("TEST_MYSQL_HOST") == "" {
+			t.Skip("TEST_MYSQL_HOST is not set")
+		}
+		config := database.Config{
+			Driver:   database.MySQL,
+			Host:     os.Getenv("TEST_MYSQL_HOST"),
+			Port:     getEnv("TEST_MYSQL_PORT", "3306"),
+			User:     getEnv("TEST_MYSQL_USER", "root"),
+			Password: os.Getenv("TEST_MYSQL_PASSWORD"),
+		}
+		admin := open(t, config.Dialector())
+		admin.Exec("DROP DATABASE IF EXISTS " + name)
+		if err := admin.Exec("CREATE DATABASE " + name).Error; err != nil {
+			t.Fatalf("failed to create database: %v", err)
+		}
+		t.Cleanup(func() { admin.Exec("DROP DATABASE IF EXISTS " + name) })
+		config.Name = name
+		db = open(t, config.Dialector())
+	default:
+		t.Fatalf("unknown dialect %q", dialect)
+	}
+	return db
+}
+
+func open(t *testing.T, dialector gorm.Dialector) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,62 @@
+package entities
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AuditLog records who did what to which resource. Flagged entries, such as
+// break-the-glass access, need a human review.
+//
+// Entries of each hospital form a hash chain: Hash covers the entry and the
+// Hash of the hospital's previous entry, so editing or deleting an entry in
+// the middle breaks every later link.
+type AuditLog struct {
+	gorm.Model
+	Actor      string `json:"actor" gorm:"index"`
+	ActorRole  string `json:"actor_role"`
+	Hospital   string `json:"hospital" gorm:"index"`
+	Action     string `json:"action" gorm:"index"`
+	Resource   string `json:"resource"`
+	ResourceId string `json:"resource_id" gorm:"index"`
+	Detail     string `json:"detail"`
+	Flagged    bool   `json:"flagged" gorm:"index"`
+	PrevHash   string `json:"prev_hash"`
+	Hash       string `json:"hash"`
+}
+
+// ChainKey names the chain an entry belongs to.
+func (a *AuditLog) ChainKey() string {
+	return strings.ToLower(a.Hospital)
+}
+
+// ComputeHash hashes the entry's content and PrevHash. CreatedAt is hashed
+// at microsecond precision, which is what the database keeps.
+func (a *AuditLog) ComputeHash() string {
+	encoded, _ := json.Marshal([]interface{}{
+		a.PrevHash,
+		a.CreatedAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+		a.Actor,
+		a.ActorRole,
+		a.Hospital,
+		a.Action,
+		a.Resource,
+		a.ResourceId,
+		a.Detail,
+		a.Flagged,
+	})
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+// Seal links the entry after prevHash and stamps its hash.
+func (a *AuditLog) Seal(prevHash string, now time.Time) {
+	a.CreatedAt = now.UTC().Truncate(time.Microsecond)
+	a.PrevHash = prevHash
+	a.Hash = a.ComputeHash()
+}
@@ -0,0 +1,40 @@
+package entities
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ConsentPurposeDataSharing = "data_sharing"
+	ConsentPurposeMarketing   = "marketing"
+	ConsentPurposeResearch    = "research"
+)
+
+const (
+	ConsentChannelWeb    = "web"
+	ConsentChannelPaper  = "paper"
+	ConsentChannelKiosk  = "kiosk"
+	ConsentChannelVerbal = "verbal"
+)
+
+// Consent records what a patient agreed to under PDPA. A consent is active
+// from GrantedAt until WithdrawnAt is set; history is never deleted.
+type Consent struct {
+	gorm.Model
+	PatientID         uint       `json:"patient_id" gorm:"index"`
+	Patient           Patient    `json:"-"`
+	Purpose           string     `json:"purpose" gorm:"index"`
+	Version           string     `json:"version"`
+	Channel           string     `json:"channel"`
+	GrantedAt         time.Time  `json:"granted_at"`
+	WithdrawnAt       *time.Time `json:"withdrawn_at"`
+	WithdrawalChannel string     `json:"withdrawal_channel,omitempty"`
+	Hospital          string     `json:"hospital"`
+	RecordedBy        string     `json:"recorded_by"`
+}
+
+func (c *Consent) Active() bool {
+	return c.WithdrawnAt == nil
+}
@@ -0,0 +1,43 @@
+package entities
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DsarTypeAccess  = "access"
+	DsarTypeErasure = "erasure"
+)
+
+const (
+	DsarStatusPending   = "pending"
+	DsarStatusApproved  = "approved"
+	DsarStatusRejected  = "rejected"
+	DsarStatusCompleted = "completed"
+	DsarStatusOnHold    = "on_hold"
+	DsarStatusFailed    = "failed"
+)
+
+const (
+	ErasureModeDelete    = "delete"
+	ErasureModeAnonymize = "anonymize"
+)
+
+// DataSubjectRequest tracks a patient's PDPA request to access or erase
+// their data. The identifier used to find the patient is not kept.
+type DataSubjectRequest struct {
+	gorm.Model
+	Type        string     `json:"type"`
+	PatientID   uint       `json:"patient_id" gorm:"index"`
+	ErasureMode string     `json:"erasure_mode,omitempty"`
+	Reason      string     `json:"reason"`
+	Status      string     `json:"status" gorm:"default:pending;index"`
+	Hospital    string     `json:"hospital" gorm:"index"`
+	RequestedBy string     `json:"requested_by"`
+	ReviewedBy  string     `json:"reviewed_by"`
+	ReviewedAt  *time.Time `json:"reviewed_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	Message     string     `json:"message"`
+}
@@ -0,0 +1,30 @@
+package entities
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	EmergencyReviewPending     = "pending"
+	EmergencyReviewJustified   = "justified"
+	EmergencyReviewUnjustified = "unjustified"
+)
+
+// EmergencyAccess is a break-the-glass grant that lets a clinician read one
+// patient of another hospital until ExpiresAt. The owning hospital's admins
+// review every grant afterwards.
+type EmergencyAccess struct {
+	gorm.Model
+	Clinician         string     `json:"clinician" gorm:"index"`
+	ClinicianHospital string     `json:"clinician_hospital"`
+	PatientID         uint       `json:"patient_id" gorm:"index"`
+	PatientHospital   string     `json:"patient_hospital" gorm:"index"`
+	Reason            string     `json:"reason"`
+	ExpiresAt         time.Time  `json:"expires_at"`
+	ReviewStatus      string     `json:"review_status" gorm:"default:pending;index"`
+	ReviewedBy        string     `json:"reviewed_by"`
+	ReviewedAt        *time.Time `json:"reviewed_at"`
+	ReviewNote        string     `json:"review_note"`
+}
@@ -0,0 +1,10 @@
+package entities
+
+import "gorm.io/gorm"
+
+type EncryptionKey struct {
+	gorm.Model
+	WrappedKey  []byte `json:"-"`
+	MasterKeyId string `json:"master_key_id"`
+	Active      bool   `json:"active" gorm:"index"`
+}
@@ -0,0 +1,10 @@
+package entities
+
+import "gorm.io/gorm"
+
+// Hospital registers a hospital by the name that staff, patients and
+// agreements refer to.
+type Hospital struct {
+	gorm.Model
+	Name string `json:"name" validate:"required"`
+}
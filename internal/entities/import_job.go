@@ -0,0 +1,32 @@
+package entities
+
+import "gorm.io/gorm"
+
+const (
+	ImportStatusPending   = "pending"
+	ImportStatusRunning   = "running"
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed"
+)
+
+type ImportJob struct {
+	gorm.Model
+	Hospital     string           `json:"hospital"`
+	CreatedBy    string           `json:"created_by"`
+	FileName     string           `json:"file_name"`
+	DryRun       bool             `json:"dry_run"`
+	Status       string           `json:"status" gorm:"default:pending"`
+	TotalRows    int              `json:"total_rows"`
+	ValidRows    int              `json:"valid_rows"`
+	InvalidRows  int              `json:"invalid_rows"`
+	ImportedRows int              `json:"imported_rows"`
+	Message      string           `json:"message"`
+	Errors       []ImportRowError `json:"errors"`
+}
+
+type ImportRowError struct {
+	gorm.Model
+	ImportJobID uint   `json:"import_job_id" gorm:"index"`
+	Row         int    `json:"row" gorm:"column:row_no"`
+	Message     string `json:"message"`
+}
@@ -0,0 +1,22 @@
+package entities
+
+// Models lists every persisted entity, parents before children.
+func Models() []interface{} {
+	return []interface{}{
+		&Patient{},
+		&Staff{},
+		&PatientDuplicate{},
+		&PatientMerge{},
+		&ImportJob{},
+		&ImportRowError{},
+		&AuditLog{},
+		&EncryptionKey{},
+		&Consent{},
+		&DataSubjectRequest{},
+		&RetentionPolicy{},
+		&RetentionRun{},
+		&EmergencyAccess{},
+		&SharingAgreement{},
+		&Hospital{},
+	}
+}
@@ -1,25 +1,97 @@
 package entities
 
 import (
+	"agnos/pkg/encryption"
+	"strings"
 	"time"
+	"unicode"
 
 	"gorm.io/gorm"
 )
 
 type Patient struct {
 	gorm.Model
-	FirstNameTh  string    `json:"first_name_th"`
-	MiddleNameTh string    `json:"middle_name_th"`
-	LastNameTh   string    `json:"last_name_th"`
-	FirstNameEn  string    `json:"first_name_en"`
-	MiddleNameEn string    `json:"middle_name_en"`
-	LastNameEn   string    `json:"last_name_en"`
-	DateBirth    time.Time `json:"date_of_birth"`
-	PatientHn    string    `json:"patient_hn"`
-	NationalId   string    `json:"national_id" validate:"required"`
-	PassportId   string    `json:"passport_id"`
-	PhoneNumber  string    `json:"phone_number"`
-	Email        string    `json:"email"`
-	Gender       string    `json:"gender" binding:"required,oneof=male female"`
-	Hospital     string    `json:"hospital" validate:"required"`
+	FirstNameTh      string     `json:"first_name_th"`
+	MiddleNameTh     string     `json:"middle_name_th"`
+	LastNameTh       string     `json:"last_name_th"`
+	FirstNameEn      string     `json:"first_name_en"`
+	MiddleNameEn     string     `json:"middle_name_en"`
+	LastNameEn       string     `json:"last_name_en"`
+	DateBirth        time.Time  `json:"date_of_birth"`
+	PatientHn        string     `json:"patient_hn"`
+	NationalId       string     `json:"national_id" validate:"required" gorm:"serializer:encrypted"`
+	PassportId       string     `json:"passport_id" gorm:"serializer:encrypted"`
+	PhoneNumber      string     `json:"phone_number" gorm:"serializer:encrypted"`
+	Email            string     `json:"email" gorm:"serializer:encrypted"`
+	Gender           string     `json:"gender" binding:"required,oneof=male female"`
+	Hospital         string     `json:"hospital" validate:"required"`
+	MergedIntoID     *uint      `json:"merged_into_id,omitempty"`
+	LegalHold        bool       `json:"legal_hold"`
+	LegalHoldReason  string     `json:"legal_hold_reason,omitempty"`
+	AnonymizedAt     *time.Time `json:"anonymized_at,omitempty" gorm:"index"`
+	NationalIdIndex  string     `json:"-" gorm:"index"`
+	PassportIdIndex  string     `json:"-" gorm:"index"`
+	PhoneNumberIndex string     `json:"-" gorm:"index"`
+	EmailIndex       string     `json:"-" gorm:"index"`
+}
+
+// BeforeSave refreshes the blind indexes that stand in for the encrypted
+// columns in exact-match lookups.
+func (p *Patient) BeforeSave(tx *gorm.DB) error {
+	var err error
+	if p.NationalIdIndex, err = IdentifierIndex(p.NationalId); err != nil {
+		return err
+	}
+	if p.PassportIdIndex, err = IdentifierIndex(p.PassportId); err != nil {
+		return err
+	}
+	if p.PhoneNumberIndex, err = PhoneIndex(p.PhoneNumber); err != nil {
+		return err
+	}
+	if p.EmailIndex, err = EmailIndex(p.Email); err != nil {
+		return err
+	}
+	return nil
+}
+
+// AnonymizedColumns clears every personal field of a patient, including the
+// blind indexes, and marks it anonymized, for column updates that skip the
+// model hooks.
+func AnonymizedColumns() map[string]interface{} {
+	return map[string]interface{}{
+		"anonymized_at":      time.Now(),
+		"first_name_th":      "",
+		"middle_name_th":     "",
+		"last_name_th":       "",
+		"first_name_en":      "",
+		"middle_name_en":     "",
+		"last_name_en":       "",
+		"date_birth":         time.Time{},
+		"patient_hn":         "",
+		"national_id":        "",
+		"passport_id":        "",
+		"phone_number":       "",
+		"email":              "",
+		"national_id_index":  "",
+		"passport_id_index":  "",
+		"phone_number_index": "",
+		"email_index":        "",
+	}
+}
+
+func IdentifierIndex(value string) (string, error) {
+	return encryption.BlindIndex(strings.ToUpper(strings.TrimSpace(value)))
+}
+
+func PhoneIndex(value string) (string, error) {
+	return encryption.BlindIndex(strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, value))
+}
+
+func EmailIndex(value string) (string, error) {
+	return encryption.BlindIndex(strings.ToLower(strings.TrimSpace(value)))
 }
@@ -0,0 +1,32 @@
+package entities
+
+import "gorm.io/gorm"
+
+const (
+	DuplicateStatusPending   = "pending"
+	DuplicateStatusMerged    = "merged"
+	DuplicateStatusDismissed = "dismissed"
+)
+
+type PatientDuplicate struct {
+	gorm.Model
+	PatientID         uint    `json:"patient_id" gorm:"index"`
+	Patient           Patient `json:"patient"`
+	CandidateID       uint    `json:"candidate_id" gorm:"index"`
+	Candidate         Patient `json:"candidate"`
+	Score             float64 `json:"score"`
+	Likely            bool    `json:"likely"`
+	Status            string  `json:"status" gorm:"default:pending"`
+	Hospital          string  `json:"hospital"`
+	CandidateHospital string  `json:"candidate_hospital"`
+}
+
+type PatientMerge struct {
+	gorm.Model
+	SurvivorID uint    `json:"survivor_id" gorm:"index"`
+	MergedID   uint    `json:"merged_id" gorm:"index"`
+	Score      float64 `json:"score"`
+	MergedBy   string  `json:"merged_by"`
+	Hospital   string  `json:"hospital"`
+	Snapshot   string  `json:"snapshot"`
+}
@@ -0,0 +1,49 @@
+package entities
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	RetentionEntityPatient   = "patient"
+	RetentionEntityAuditLog  = "audit_log"
+	RetentionEntityImportJob = "import_job"
+)
+
+const (
+	RetentionActionDelete    = "delete"
+	RetentionActionAnonymize = "anonymize"
+)
+
+// RetentionPolicy is one retention rule of a hospital. For patients the
+// delete action applies to soft-deleted records and the anonymize action to
+// records not updated for AfterDays; other entities are deleted by age.
+type RetentionPolicy struct {
+	gorm.Model
+	Hospital  string `json:"hospital" gorm:"index"`
+	Entity    string `json:"entity" validate:"required,oneof=patient audit_log import_job"`
+	Action    string `json:"action" validate:"required,oneof=delete anonymize"`
+	AfterDays int    `json:"after_days" validate:"required,min=1"`
+	Enabled   bool   `json:"enabled"`
+	DryRun    bool   `json:"dry_run"`
+}
+
+// RetentionRun reports one application of a policy. Dry runs only count
+// the matching records.
+type RetentionRun struct {
+	gorm.Model
+	PolicyID   uint      `json:"policy_id" gorm:"index"`
+	Hospital   string    `json:"hospital" gorm:"index"`
+	Entity     string    `json:"entity"`
+	Action     string    `json:"action"`
+	DryRun     bool      `json:"dry_run"`
+	Cutoff     time.Time `json:"cutoff"`
+	Matched    int64     `json:"matched"`
+	Processed  int       `json:"processed"`
+	Batches    int       `json:"batches"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Error      string    `json:"error"`
+}
@@ -0,0 +1,68 @@
+package entities
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	SharingDirectionOutbound = "outbound"
+	SharingDirectionInbound  = "inbound"
+	SharingDirectionBoth     = "both"
+)
+
+const (
+	SharingStatusProposed = "proposed"
+	SharingStatusActive   = "active"
+	SharingStatusRevoked  = "revoked"
+)
+
+// SharingAgreement lets staff of one hospital search the patients of
+// another. Hospital proposes it and PartnerHospital accepts it. Direction is
+// seen from Hospital: outbound shares Hospital's patients with the partner.
+// Fields maps each sensitive field to show, mask or omit and Roles lists the
+// receiving roles allowed to search.
+type SharingAgreement struct {
+	gorm.Model
+	Hospital        string            `json:"hospital" gorm:"index"`
+	PartnerHospital string            `json:"partner_hospital" gorm:"index"`
+	Direction       string            `json:"direction"`
+	Fields          map[string]string `json:"fields" gorm:"serializer:json"`
+	Roles           []string          `json:"roles" gorm:"serializer:json"`
+	Status          string            `json:"status" gorm:"default:proposed;index"`
+	ProposedBy      string            `json:"proposed_by"`
+	AcceptedBy      string            `json:"accepted_by"`
+	AcceptedAt      *time.Time        `json:"accepted_at"`
+	RevokedBy       string            `json:"revoked_by"`
+	RevokedAt       *time.Time        `json:"revoked_at"`
+}
+
+// Shares reports whether the agreement lets receiver see owner's patients.
+func (a *SharingAgreement) Shares(owner string, receiver string) bool {
+	switch {
+	case strings.EqualFold(a.Hospital, owner) && strings.EqualFold(a.PartnerHospital, receiver):
+		return a.Direction == SharingDirectionOutbound || a.Direction == SharingDirectionBoth
+	case strings.EqualFold(a.PartnerHospital, owner) && strings.EqualFold(a.Hospital, receiver):
+		return a.Direction == SharingDirectionInbound || a.Direction == SharingDirectionBoth
+	}
+	return false
+}
+
+// Counterpart returns the other hospital of the agreement.
+func (a *SharingAgreement) Counterpart(hospital string) string {
+	if strings.EqualFold(a.Hospital, hospital) {
+		return a.PartnerHospital
+	}
+	return a.Hospital
+}
+
+func (a *SharingAgreement) AllowsRole(role string) bool {
+	for _, allowed := range a.Roles {
+		if allowed == role {
+			return true
+		}
+	}
+	return false
+}
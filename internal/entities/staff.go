@@ -4,9 +4,16 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	RoleAdmin        = "admin"
+	RoleClinician    = "clinician"
+	RoleReceptionist = "receptionist"
+)
+
 type Staff struct {
 	gorm.Model
 	Username string `json:"username" validate:"required"`
 	Password string `json:"password" validate:"required"`
 	Hospital string `json:"hospital" validate:"required"`
+	Role     string `json:"role" validate:"omitempty,oneof=admin clinician receptionist" gorm:"default:receptionist"`
 }
@@ -0,0 +1,41 @@
+// Package migrations embeds the schema migrations for each supported
+// database, one directory per GORM dialect. Each directory holds the same
+// versions so that status and health checks read alike on every database.
+// MySQL commits DDL implicitly, so a migration that fails there midway is
+// not rolled back and must be repaired by hand. SQLite has no migration lock
+// and relies on its single writer, so a migrator started while another runs
+// may fail as busy rather than wait its turn.
+package migrations
+
+import (
+	"agnos/pkg/migrate"
+	"embed"
+	"fmt"
+	"io/fs"
+
+	"gorm.io/gorm"
+)
+
+//go:embed postgres/*.sql sqlite/*.sql mysql/*.sql
+var files embed.FS
+
+// For returns the migrations for a dialect such as "postgres".
+func For(dialect string) (fs.FS, error) {
+	dir, err := fs.Sub(files, dialect)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fs.ReadDir(dir, "."); err != nil {
+		return nil, fmt.Errorf("no migrations for database %q", dialect)
+	}
+	return dir, nil
+}
+
+// New returns a migrator for the dialect of db.
+func New(db *gorm.DB) (*migrate.Migrator, error) {
+	dir, err := For(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(db, dir)
+}
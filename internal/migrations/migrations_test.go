@@ -0,0 +1,72 @@
+package migrations_test
+
+import (
+	"context"
+	"testing"
+
+	"agnos/internal/dbtest"
+	"agnos/internal/entities"
+	"agnos/internal/migrations"
+	"agnos/pkg/migrate"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestMigrations_ApplyFromEmptyAndRollBack(t *testing.T) {
+	for _, dialect := range dbtest.Dialects {
+		t.Run(dialect, func(t *testing.T) {
+			testApplyFromEmptyAndRollBack(t, dbtest.OpenEmpty(t, dialect, "migrations_test"))
+		})
+	}
+}
+
+func testApplyFromEmptyAndRollBack(t *testing.T, db *gorm.DB) {
+	ctx := context.Background()
+	migrator, err := migrations.New(db)
+	assert.NoError(t, err)
+	all := migrator.Migrations()
+	assert.NotEmpty(t, all)
+
+	assert.ErrorIs(t, migrator.Check(ctx), migrate.ErrSchemaBehind)
+
+	applied, err := migrator.Up(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, applied, len(all))
+	assert.NoError(t, migrator.Check(ctx))
+	for _, model := range entities.Models() {
+		assert.True(t, db.Migrator().HasTable(model), "%T", model)
+	}
+
+	// Every column the entities map to must exist.
+	for _, model := range entities.Models() {
+		statement := &gorm.Statement{DB: db}
+		assert.NoError(t, statement.Parse(model))
+		for _, column := range statement.Schema.DBNames {
+			assert.True(t, db.Migrator().HasColumn(model, column), "%T.%s", model, column)
+		}
+	}
+
+	again, err := migrator.Up(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, again)
+
+	statuses, err := migrator.Status(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, statuses, len(all))
+	for _, status := range statuses {
+		assert.True(t, status.Applied, status.Name)
+	}
+
+	reverted, err := migrator.Down(ctx, len(all))
+	assert.NoError(t, err)
+	assert.Len(t, reverted, len(all))
+	for _, model := range entities.Models() {
+		assert.False(t, db.Migrator().HasTable(model), "%T", model)
+	}
+
+	// Down must leave a schema that applies again cleanly.
+	applied, err = migrator.Up(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, applied, len(all))
+}
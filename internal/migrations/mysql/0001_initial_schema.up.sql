@@ -0,0 +1,283 @@
+-- Baseline schema, matching the Postgres baseline. Text compares
+-- case-insensitively under the default collation, except usernames.
+
+CREATE TABLE IF NOT EXISTS `patients` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `first_name_th` longtext,
+    `middle_name_th` longtext,
+    `last_name_th` longtext,
+    `first_name_en` longtext,
+    `middle_name_en` longtext,
+    `last_name_en` longtext,
+    `date_birth` datetime(3) NULL,
+    `patient_hn` varchar(191),
+    `national_id` longtext,
+    `passport_id` longtext,
+    `phone_number` longtext,
+    `email` longtext,
+    `gender` longtext,
+    `hospital` varchar(191),
+    `merged_into_id` bigint unsigned,
+    `legal_hold` boolean,
+    `legal_hold_reason` longtext,
+    `national_id_index` varchar(191),
+    `passport_id_index` varchar(191),
+    `phone_number_index` varchar(191),
+    `email_index` varchar(191),
+    PRIMARY KEY (`id`),
+    INDEX `idx_patients_deleted_at` (`deleted_at`),
+    INDEX `idx_patients_national_id_index` (`national_id_index`),
+    INDEX `idx_patients_passport_id_index` (`passport_id_index`),
+    INDEX `idx_patients_phone_number_index` (`phone_number_index`),
+    INDEX `idx_patients_email_index` (`email_index`)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `staffs` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `username` varchar(191) COLLATE utf8mb4_bin,
+    `password` longtext,
+    `hospital` longtext,
+    `role` varchar(191) DEFAULT 'receptionist',
+    PRIMARY KEY (`id`),
+    INDEX `idx_staffs_deleted_at` (`deleted_at`)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `patient_duplicates` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `patient_id` bigint unsigned,
+    `candidate_id` bigint unsigned,
+    `score` double,
+    `likely` boolean,
+    `status` varchar(191) DEFAULT 'pending',
+    `hospital` longtext,
+    `candidate_hospital` longtext,
+    PRIMARY KEY (`id`),
+    INDEX `idx_patient_duplicates_deleted_at` (`deleted_at`),
+    INDEX `idx_patient_duplicates_patient_id` (`patient_id`),
+    INDEX `idx_patient_duplicates_candidate_id` (`candidate_id`),
+    CONSTRAINT `fk_patient_duplicates_candidate` FOREIGN KEY (`candidate_id`) REFERENCES `patients` (`id`),
+    CONSTRAINT `fk_patient_duplicates_patient` FOREIGN KEY (`patient_id`) REFERENCES `patients` (`id`)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `patient_merges` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `survivor_id` bigint unsigned,
+    `merged_id` bigint unsigned,
+    `score` double,
+    `merged_by` longtext,
+    `hospital` longtext,
+    `snapshot` longtext,
+    PRIMARY KEY (`id`),
+    INDEX `idx_patient_merges_deleted_at` (`deleted_at`),
+    INDEX `idx_patient_merges_survivor_id` (`survivor_id`),
+    INDEX `idx_patient_merges_merged_id` (`merged_id`)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `import_jobs` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `hospital` longtext,
+    `created_by` longtext,
+    `file_name` longtext,
+    `dry_run` boolean,
+    `status` varchar(191) DEFAULT 'pending',
+    `total_rows` bigint,
+    `valid_rows` bigint,
+    `invalid_rows` bigint,
+    `imported_rows` bigint,
+    `message` longtext,
+    PRIMARY KEY (`id`),
+    INDEX `idx_import_jobs_deleted_at` (`deleted_at`)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `import_row_errors` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `import_job_id` bigint unsigned,
+    `row_no` bigint,
+    `message` longtext,
+    PRIMARY KEY (`id`),
+    INDEX `idx_import_row_errors_deleted_at` (`deleted_at`),
+    INDEX `idx_import_row_errors_import_job_id` (`import_job_id`),
+    CONSTRAINT `fk_import_jobs_errors` FOREIGN KEY (`import_job_id`) REFERENCES `import_jobs` (`id`)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `audit_logs` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `actor` varchar(191),
+    `actor_role` longtext,
+    `hospital` varchar(191),
+    `action` varchar(191),
+    `resource` longtext,
+    `resource_id` varchar(191),
+    `detail` longtext,
+    `flagged` boolean,
+    PRIMARY KEY (`id`),
+    INDEX `idx_audit_logs_deleted_at` (`deleted_at`),
+    INDEX `idx_audit_logs_actor` (`actor`),
+    INDEX `idx_audit_logs_hospital` (`hospital`),
+    INDEX `idx_audit_logs_action` (`action`),
+    INDEX `idx_audit_logs_resource_id` (`resource_id`),
+    INDEX `idx_audit_logs_flagged` (`flagged`)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `encryption_keys` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `wrapped_key` longblob,
+    `master_key_id` longtext,
+    `active` boolean,
+    PRIMARY KEY (`id`),
+    INDEX `idx_encryption_keys_deleted_at` (`deleted_at`),
+    INDEX `idx_encryption_keys_active` (`active`)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `consents` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `patient_id` bigint unsigned,
+    `purpose` varchar(191),
+    `version` longtext,
+    `channel` longtext,
+    `granted_at` datetime(3) NULL,
+    `withdrawn_at` datetime(3) NULL,
+    `withdrawal_channel` longtext,
+    `hospital` longtext,
+    `recorded_by` longtext,
+    PRIMARY KEY (`id`),
+    INDEX `idx_consents_deleted_at` (`deleted_at`),
+    INDEX `idx_consents_patient_id` (`patient_id`),
+    INDEX `idx_consents_purpose` (`purpose`),
+    CONSTRAINT `fk_consents_patient` FOREIGN KEY (`patient_id`) REFERENCES `patients` (`id`)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `data_subject_requests` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `type` longtext,
+    `patient_id` bigint unsigned,
+    `erasure_mode` longtext,
+    `reason` longtext,
+    `status` varchar(191) DEFAULT 'pending',
+    `hospital` varchar(191),
+    `requested_by` longtext,
+    `reviewed_by` longtext,
+    `reviewed_at` datetime(3) NULL,
+    `completed_at` datetime(3) NULL,
+    `message` longtext,
+    PRIMARY KEY (`id`),
+    INDEX `idx_data_subject_requests_deleted_at` (`deleted_at`),
+    INDEX `idx_data_subject_requests_patient_id` (`patient_id`),
+    INDEX `idx_data_subject_requests_status` (`status`),
+    INDEX `idx_data_subject_requests_hospital` (`hospital`)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `retention_policies` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `hospital` varchar(191),
+    `entity` longtext,
+    `action` longtext,
+    `after_days` bigint,
+    `enabled` boolean,
+    `dry_run` boolean,
+    PRIMARY KEY (`id`),
+    INDEX `idx_retention_policies_deleted_at` (`deleted_at`),
+    INDEX `idx_retention_policies_hospital` (`hospital`)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `retention_runs` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `policy_id` bigint unsigned,
+    `hospital` varchar(191),
+    `entity` longtext,
+    `action` longtext,
+    `dry_run` boolean,
+    `cutoff` datetime(3) NULL,
+    `matched` bigint,
+    `processed` bigint,
+    `batches` bigint,
+    `started_at` datetime(3) NULL,
+    `finished_at` datetime(3) NULL,
+    `error` longtext,
+    PRIMARY KEY (`id`),
+    INDEX `idx_retention_runs_deleted_at` (`deleted_at`),
+    INDEX `idx_retention_runs_policy_id` (`policy_id`),
+    INDEX `idx_retention_runs_hospital` (`hospital`)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `emergency_accesses` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `clinician` varchar(191),
+    `clinician_hospital` longtext,
+    `patient_id` bigint unsigned,
+    `patient_hospital` varchar(191),
+    `reason` longtext,
+    `expires_at` datetime(3) NULL,
+    `review_status` varchar(191) DEFAULT 'pending',
+    `reviewed_by` longtext,
+    `reviewed_at` datetime(3) NULL,
+    `review_note` longtext,
+    PRIMARY KEY (`id`),
+    INDEX `idx_emergency_accesses_deleted_at` (`deleted_at`),
+    INDEX `idx_emergency_accesses_clinician` (`clinician`),
+    INDEX `idx_emergency_accesses_patient_id` (`patient_id`),
+    INDEX `idx_emergency_accesses_patient_hospital` (`patient_hospital`),
+    INDEX `idx_emergency_accesses_review_status` (`review_status`)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `sharing_agreements` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `hospital` varchar(191),
+    `partner_hospital` varchar(191),
+    `direction` longtext,
+    `fields` longtext,
+    `roles` longtext,
+    `status` varchar(191) DEFAULT 'proposed',
+    `proposed_by` longtext,
+    `accepted_by` longtext,
+    `accepted_at` datetime(3) NULL,
+    `revoked_by` longtext,
+    `revoked_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_sharing_agreements_deleted_at` (`deleted_at`),
+    INDEX `idx_sharing_agreements_hospital` (`hospital`),
+    INDEX `idx_sharing_agreements_partner_hospital` (`partner_hospital`),
+    INDEX `idx_sharing_agreements_status` (`status`)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,5 @@
+DROP INDEX `idx_staffs_username_active` ON `staffs`;
+ALTER TABLE `staffs` DROP COLUMN `active_username`;
+DROP INDEX `idx_consents_patient_id_purpose` ON `consents`;
+DROP INDEX `idx_patients_patient_hn` ON `patients`;
+DROP INDEX `idx_patients_hospital` ON `patients`;
@@ -0,0 +1,11 @@
+-- Text columns compare case-insensitively, so repositories match hospitals
+-- with plain equality and plain indexes serve them.
+CREATE INDEX `idx_patients_hospital` ON `patients` (`hospital`);
+CREATE INDEX `idx_patients_patient_hn` ON `patients` (`patient_hn`);
+CREATE INDEX `idx_consents_patient_id_purpose` ON `consents` (`patient_id`, `purpose`);
+
+-- Usernames are unique among active staff; a deactivated account's name
+-- may be reused. MySQL has no partial indexes, so the unique index is on a
+-- generated column that is NULL for deactivated accounts.
+ALTER TABLE `staffs` ADD COLUMN `active_username` varchar(191) COLLATE utf8mb4_bin AS (IF(`deleted_at` IS NULL, `username`, NULL)) STORED;
+CREATE UNIQUE INDEX `idx_staffs_username_active` ON `staffs` (`active_username`);
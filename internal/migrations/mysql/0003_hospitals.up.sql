@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS `hospitals` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `name` varchar(191),
+    PRIMARY KEY (`id`),
+    INDEX `idx_hospitals_deleted_at` (`deleted_at`)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+ALTER TABLE `hospitals` ADD COLUMN `active_name` varchar(191) AS (IF(`deleted_at` IS NULL, `name`, NULL)) STORED;
+CREATE UNIQUE INDEX `idx_hospitals_name_active` ON `hospitals` (`active_name`);
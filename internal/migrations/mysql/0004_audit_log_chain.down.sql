@@ -0,0 +1,3 @@
+ALTER TABLE `audit_logs` MODIFY COLUMN `created_at` datetime(3) NULL;
+ALTER TABLE `audit_logs` DROP COLUMN `hash`;
+ALTER TABLE `audit_logs` DROP COLUMN `prev_hash`;
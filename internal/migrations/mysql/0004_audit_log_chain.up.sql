@@ -0,0 +1,7 @@
+-- Entries written before this migration stay unsealed; verification starts
+-- each hospital's chain at its first sealed entry.
+ALTER TABLE `audit_logs` ADD COLUMN `prev_hash` longtext;
+ALTER TABLE `audit_logs` ADD COLUMN `hash` longtext;
+-- The hash covers created_at to the microsecond, which datetime(3) would
+-- round away.
+ALTER TABLE `audit_logs` MODIFY COLUMN `created_at` datetime(6) NULL;
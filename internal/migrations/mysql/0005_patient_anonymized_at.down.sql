@@ -0,0 +1,2 @@
+DROP INDEX `idx_patients_anonymized_at` ON `patients`;
+ALTER TABLE `patients` DROP COLUMN `anonymized_at`;
@@ -0,0 +1,14 @@
+DROP TABLE IF EXISTS "sharing_agreements";
+DROP TABLE IF EXISTS "emergency_accesses";
+DROP TABLE IF EXISTS "retention_runs";
+DROP TABLE IF EXISTS "retention_policies";
+DROP TABLE IF EXISTS "data_subject_requests";
+DROP TABLE IF EXISTS "consents";
+DROP TABLE IF EXISTS "encryption_keys";
+DROP TABLE IF EXISTS "audit_logs";
+DROP TABLE IF EXISTS "import_row_errors";
+DROP TABLE IF EXISTS "import_jobs";
+DROP TABLE IF EXISTS "patient_merges";
+DROP TABLE IF EXISTS "patient_duplicates";
+DROP TABLE IF EXISTS "staffs";
+DROP TABLE IF EXISTS "patients";
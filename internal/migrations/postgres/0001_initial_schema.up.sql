@@ -0,0 +1,283 @@
+-- Baseline schema, matching what AutoMigrate created before versioned
+-- migrations. IF NOT EXISTS lets databases created that way adopt it.
+
+CREATE TABLE IF NOT EXISTS "patients" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "first_name_th" text,
+    "middle_name_th" text,
+    "last_name_th" text,
+    "first_name_en" text,
+    "middle_name_en" text,
+    "last_name_en" text,
+    "date_birth" timestamptz,
+    "patient_hn" text,
+    "national_id" text,
+    "passport_id" text,
+    "phone_number" text,
+    "email" text,
+    "gender" text,
+    "hospital" text,
+    "merged_into_id" bigint,
+    "legal_hold" boolean,
+    "legal_hold_reason" text,
+    "national_id_index" text,
+    "passport_id_index" text,
+    "phone_number_index" text,
+    "email_index" text,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_patients_email_index" ON "patients" ("email_index");
+CREATE INDEX IF NOT EXISTS "idx_patients_phone_number_index" ON "patients" ("phone_number_index");
+CREATE INDEX IF NOT EXISTS "idx_patients_passport_id_index" ON "patients" ("passport_id_index");
+CREATE INDEX IF NOT EXISTS "idx_patients_national_id_index" ON "patients" ("national_id_index");
+CREATE INDEX IF NOT EXISTS "idx_patients_deleted_at" ON "patients" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "staffs" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "username" text,
+    "password" text,
+    "hospital" text,
+    "role" text DEFAULT 'receptionist',
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_staffs_deleted_at" ON "staffs" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "patient_duplicates" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "patient_id" bigint,
+    "candidate_id" bigint,
+    "score" decimal,
+    "likely" boolean,
+    "status" text DEFAULT 'pending',
+    "hospital" text,
+    "candidate_hospital" text,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_patient_duplicates_patient" FOREIGN KEY ("patient_id") REFERENCES "patients"("id"),
+    CONSTRAINT "fk_patient_duplicates_candidate" FOREIGN KEY ("candidate_id") REFERENCES "patients"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_patient_duplicates_candidate_id" ON "patient_duplicates" ("candidate_id");
+CREATE INDEX IF NOT EXISTS "idx_patient_duplicates_patient_id" ON "patient_duplicates" ("patient_id");
+CREATE INDEX IF NOT EXISTS "idx_patient_duplicates_deleted_at" ON "patient_duplicates" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "patient_merges" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "survivor_id" bigint,
+    "merged_id" bigint,
+    "score" decimal,
+    "merged_by" text,
+    "hospital" text,
+    "snapshot" text,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_patient_merges_merged_id" ON "patient_merges" ("merged_id");
+CREATE INDEX IF NOT EXISTS "idx_patient_merges_survivor_id" ON "patient_merges" ("survivor_id");
+CREATE INDEX IF NOT EXISTS "idx_patient_merges_deleted_at" ON "patient_merges" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "import_jobs" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "hospital" text,
+    "created_by" text,
+    "file_name" text,
+    "dry_run" boolean,
+    "status" text DEFAULT 'pending',
+    "total_rows" bigint,
+    "valid_rows" bigint,
+    "invalid_rows" bigint,
+    "imported_rows" bigint,
+    "message" text,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_import_jobs_deleted_at" ON "import_jobs" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "import_row_errors" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "import_job_id" bigint,
+    "row_no" bigint,
+    "message" text,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_import_jobs_errors" FOREIGN KEY ("import_job_id") REFERENCES "import_jobs"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_import_row_errors_import_job_id" ON "import_row_errors" ("import_job_id");
+CREATE INDEX IF NOT EXISTS "idx_import_row_errors_deleted_at" ON "import_row_errors" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "audit_logs" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "actor" text,
+    "actor_role" text,
+    "hospital" text,
+    "action" text,
+    "resource" text,
+    "resource_id" text,
+    "detail" text,
+    "flagged" boolean,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_flagged" ON "audit_logs" ("flagged");
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_resource_id" ON "audit_logs" ("resource_id");
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_action" ON "audit_logs" ("action");
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_hospital" ON "audit_logs" ("hospital");
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_actor" ON "audit_logs" ("actor");
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_deleted_at" ON "audit_logs" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "encryption_keys" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "wrapped_key" bytea,
+    "master_key_id" text,
+    "active" boolean,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_encryption_keys_active" ON "encryption_keys" ("active");
+CREATE INDEX IF NOT EXISTS "idx_encryption_keys_deleted_at" ON "encryption_keys" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "consents" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "patient_id" bigint,
+    "purpose" text,
+    "version" text,
+    "channel" text,
+    "granted_at" timestamptz,
+    "withdrawn_at" timestamptz,
+    "withdrawal_channel" text,
+    "hospital" text,
+    "recorded_by" text,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_consents_patient" FOREIGN KEY ("patient_id") REFERENCES "patients"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_consents_purpose" ON "consents" ("purpose");
+CREATE INDEX IF NOT EXISTS "idx_consents_patient_id" ON "consents" ("patient_id");
+CREATE INDEX IF NOT EXISTS "idx_consents_deleted_at" ON "consents" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "data_subject_requests" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "type" text,
+    "patient_id" bigint,
+    "erasure_mode" text,
+    "reason" text,
+    "status" text DEFAULT 'pending',
+    "hospital" text,
+    "requested_by" text,
+    "reviewed_by" text,
+    "reviewed_at" timestamptz,
+    "completed_at" timestamptz,
+    "message" text,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_data_subject_requests_hospital" ON "data_subject_requests" ("hospital");
+CREATE INDEX IF NOT EXISTS "idx_data_subject_requests_status" ON "data_subject_requests" ("status");
+CREATE INDEX IF NOT EXISTS "idx_data_subject_requests_patient_id" ON "data_subject_requests" ("patient_id");
+CREATE INDEX IF NOT EXISTS "idx_data_subject_requests_deleted_at" ON "data_subject_requests" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "retention_policies" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "hospital" text,
+    "entity" text,
+    "action" text,
+    "after_days" bigint,
+    "enabled" boolean,
+    "dry_run" boolean,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_retention_policies_hospital" ON "retention_policies" ("hospital");
+CREATE INDEX IF NOT EXISTS "idx_retention_policies_deleted_at" ON "retention_policies" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "retention_runs" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "policy_id" bigint,
+    "hospital" text,
+    "entity" text,
+    "action" text,
+    "dry_run" boolean,
+    "cutoff" timestamptz,
+    "matched" bigint,
+    "processed" bigint,
+    "batches" bigint,
+    "started_at" timestamptz,
+    "finished_at" timestamptz,
+    "error" text,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_retention_runs_hospital" ON "retention_runs" ("hospital");
+CREATE INDEX IF NOT EXISTS "idx_retention_runs_policy_id" ON "retention_runs" ("policy_id");
+CREATE INDEX IF NOT EXISTS "idx_retention_runs_deleted_at" ON "retention_runs" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "emergency_accesses" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "clinician" text,
+    "clinician_hospital" text,
+    "patient_id" bigint,
+    "patient_hospital" text,
+    "reason" text,
+    "expires_at" timestamptz,
+    "review_status" text DEFAULT 'pending',
+    "reviewed_by" text,
+    "reviewed_at" timestamptz,
+    "review_note" text,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_emergency_accesses_review_status" ON "emergency_accesses" ("review_status");
+CREATE INDEX IF NOT EXISTS "idx_emergency_accesses_patient_hospital" ON "emergency_accesses" ("patient_hospital");
+CREATE INDEX IF NOT EXISTS "idx_emergency_accesses_patient_id" ON "emergency_accesses" ("patient_id");
+CREATE INDEX IF NOT EXISTS "idx_emergency_accesses_clinician" ON "emergency_accesses" ("clinician");
+CREATE INDEX IF NOT EXISTS "idx_emergency_accesses_deleted_at" ON "emergency_accesses" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "sharing_agreements" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "hospital" text,
+    "partner_hospital" text,
+    "direction" text,
+    "fields" text,
+    "roles" text,
+    "status" text DEFAULT 'proposed',
+    "proposed_by" text,
+    "accepted_by" text,
+    "accepted_at" timestamptz,
+    "revoked_by" text,
+    "revoked_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_sharing_agreements_status" ON "sharing_agreements" ("status");
+CREATE INDEX IF NOT EXISTS "idx_sharing_agreements_partner_hospital" ON "sharing_agreements" ("partner_hospital");
+CREATE INDEX IF NOT EXISTS "idx_sharing_agreements_hospital" ON "sharing_agreements" ("hospital");
+CREATE INDEX IF NOT EXISTS "idx_sharing_agreements_deleted_at" ON "sharing_agreements" ("deleted_at");
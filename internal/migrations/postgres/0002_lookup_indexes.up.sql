@@ -0,0 +1,11 @@
+-- Repositories filter by LOWER(hospital), which a plain column index
+-- cannot serve.
+CREATE INDEX IF NOT EXISTS "idx_patients_hospital_lower" ON "patients" (LOWER("hospital"));
+CREATE INDEX IF NOT EXISTS "idx_patients_patient_hn" ON "patients" ("patient_hn");
+CREATE INDEX IF NOT EXISTS "idx_data_subject_requests_hospital_lower" ON "data_subject_requests" (LOWER("hospital"));
+CREATE INDEX IF NOT EXISTS "idx_emergency_accesses_patient_hospital_lower" ON "emergency_accesses" (LOWER("patient_hospital"));
+CREATE INDEX IF NOT EXISTS "idx_consents_patient_id_purpose" ON "consents" ("patient_id", "purpose");
+
+-- Usernames are unique among active staff; a deactivated account's name
+-- may be reused.
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_staffs_username_active" ON "staffs" ("username") WHERE "deleted_at" IS NULL;
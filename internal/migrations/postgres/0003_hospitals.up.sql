@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS "hospitals" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "name" text,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_hospitals_deleted_at" ON "hospitals" ("deleted_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_hospitals_name_active" ON "hospitals" (LOWER("name")) WHERE "deleted_at" IS NULL;
@@ -0,0 +1,4 @@
+-- Entries written before this migration stay unsealed; verification starts
+-- each hospital's chain at its first sealed entry.
+ALTER TABLE "audit_logs" ADD COLUMN IF NOT EXISTS "prev_hash" text;
+ALTER TABLE "audit_logs" ADD COLUMN IF NOT EXISTS "hash" text;
@@ -0,0 +1,5 @@
+-- Retention anonymizes the patients that have no anonymized_at yet. Rows
+-- anonymized before this migration are picked up again, which leaves them
+-- as they are.
+ALTER TABLE "patients" ADD COLUMN IF NOT EXISTS "anonymized_at" timestamptz;
+CREATE INDEX IF NOT EXISTS "idx_patients_anonymized_at" ON "patients" ("anonymized_at");
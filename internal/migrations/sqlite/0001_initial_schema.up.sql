@@ -0,0 +1,268 @@
+-- Baseline schema, matching the Postgres baseline.
+
+CREATE TABLE IF NOT EXISTS "patients" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "created_at" datetime,
+    "updated_at" datetime,
+    "deleted_at" datetime,
+    "first_name_th" text,
+    "middle_name_th" text,
+    "last_name_th" text,
+    "first_name_en" text,
+    "middle_name_en" text,
+    "last_name_en" text,
+    "date_birth" datetime,
+    "patient_hn" text,
+    "national_id" text,
+    "passport_id" text,
+    "phone_number" text,
+    "email" text,
+    "gender" text,
+    "hospital" text,
+    "merged_into_id" integer,
+    "legal_hold" numeric,
+    "legal_hold_reason" text,
+    "national_id_index" text,
+    "passport_id_index" text,
+    "phone_number_index" text,
+    "email_index" text
+);
+CREATE INDEX IF NOT EXISTS "idx_patients_email_index" ON "patients" ("email_index");
+CREATE INDEX IF NOT EXISTS "idx_patients_phone_number_index" ON "patients" ("phone_number_index");
+CREATE INDEX IF NOT EXISTS "idx_patients_passport_id_index" ON "patients" ("passport_id_index");
+CREATE INDEX IF NOT EXISTS "idx_patients_national_id_index" ON "patients" ("national_id_index");
+CREATE INDEX IF NOT EXISTS "idx_patients_deleted_at" ON "patients" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "staffs" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "created_at" datetime,
+    "updated_at" datetime,
+    "deleted_at" datetime,
+    "username" text,
+    "password" text,
+    "hospital" text,
+    "role" text DEFAULT 'receptionist'
+);
+CREATE INDEX IF NOT EXISTS "idx_staffs_deleted_at" ON "staffs" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "patient_duplicates" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "created_at" datetime,
+    "updated_at" datetime,
+    "deleted_at" datetime,
+    "patient_id" integer,
+    "candidate_id" integer,
+    "score" real,
+    "likely" numeric,
+    "status" text DEFAULT 'pending',
+    "hospital" text,
+    "candidate_hospital" text,
+    CONSTRAINT "fk_patient_duplicates_patient" FOREIGN KEY ("patient_id") REFERENCES "patients"("id"),
+    CONSTRAINT "fk_patient_duplicates_candidate" FOREIGN KEY ("candidate_id") REFERENCES "patients"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_patient_duplicates_candidate_id" ON "patient_duplicates" ("candidate_id");
+CREATE INDEX IF NOT EXISTS "idx_patient_duplicates_patient_id" ON "patient_duplicates" ("patient_id");
+CREATE INDEX IF NOT EXISTS "idx_patient_duplicates_deleted_at" ON "patient_duplicates" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "patient_merges" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "created_at" datetime,
+    "updated_at" datetime,
+    "deleted_at" datetime,
+    "survivor_id" integer,
+    "merged_id" integer,
+    "score" real,
+    "merged_by" text,
+    "hospital" text,
+    "snapshot" text
+);
+CREATE INDEX IF NOT EXISTS "idx_patient_merges_merged_id" ON "patient_merges" ("merged_id");
+CREATE INDEX IF NOT EXISTS "idx_patient_merges_survivor_id" ON "patient_merges" ("survivor_id");
+CREATE INDEX IF NOT EXISTS "idx_patient_merges_deleted_at" ON "patient_merges" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "import_jobs" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "created_at" datetime,
+    "updated_at" datetime,
+    "deleted_at" datetime,
+    "hospital" text,
+    "created_by" text,
+    "file_name" text,
+    "dry_run" numeric,
+    "status" text DEFAULT 'pending',
+    "total_rows" integer,
+    "valid_rows" integer,
+    "invalid_rows" integer,
+    "imported_rows" integer,
+    "message" text
+);
+CREATE INDEX IF NOT EXISTS "idx_import_jobs_deleted_at" ON "import_jobs" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "import_row_errors" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "created_at" datetime,
+    "updated_at" datetime,
+    "deleted_at" datetime,
+    "import_job_id" integer,
+    "row_no" integer,
+    "message" text,
+    CONSTRAINT "fk_import_jobs_errors" FOREIGN KEY ("import_job_id") REFERENCES "import_jobs"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_import_row_errors_import_job_id" ON "import_row_errors" ("import_job_id");
+CREATE INDEX IF NOT EXISTS "idx_import_row_errors_deleted_at" ON "import_row_errors" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "audit_logs" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "created_at" datetime,
+    "updated_at" datetime,
+    "deleted_at" datetime,
+    "actor" text,
+    "actor_role" text,
+    "hospital" text,
+    "action" text,
+    "resource" text,
+    "resource_id" text,
+    "detail" text,
+    "flagged" numeric
+);
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_flagged" ON "audit_logs" ("flagged");
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_resource_id" ON "audit_logs" ("resource_id");
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_action" ON "audit_logs" ("action");
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_hospital" ON "audit_logs" ("hospital");
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_actor" ON "audit_logs" ("actor");
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_deleted_at" ON "audit_logs" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "encryption_keys" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "created_at" datetime,
+    "updated_at" datetime,
+    "deleted_at" datetime,
+    "wrapped_key" blob,
+    "master_key_id" text,
+    "active" numeric
+);
+CREATE INDEX IF NOT EXISTS "idx_encryption_keys_active" ON "encryption_keys" ("active");
+CREATE INDEX IF NOT EXISTS "idx_encryption_keys_deleted_at" ON "encryption_keys" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "consents" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "created_at" datetime,
+    "updated_at" datetime,
+    "deleted_at" datetime,
+    "patient_id" integer,
+    "purpose" text,
+    "version" text,
+    "channel" text,
+    "granted_at" datetime,
+    "withdrawn_at" datetime,
+    "withdrawal_channel" text,
+    "hospital" text,
+    "recorded_by" text,
+    CONSTRAINT "fk_consents_patient" FOREIGN KEY ("patient_id") REFERENCES "patients"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_consents_purpose" ON "consents" ("purpose");
+CREATE INDEX IF NOT EXISTS "idx_consents_patient_id" ON "consents" ("patient_id");
+CREATE INDEX IF NOT EXISTS "idx_consents_deleted_at" ON "consents" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "data_subject_requests" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "created_at" datetime,
+    "updated_at" datetime,
+    "deleted_at" datetime,
+    "type" text,
+    "patient_id" integer,
+    "erasure_mode" text,
+    "reason" text,
+    "status" text DEFAULT 'pending',
+    "hospital" text,
+    "requested_by" text,
+    "reviewed_by" text,
+    "reviewed_at" datetime,
+    "completed_at" datetime,
+    "message" text
+);
+CREATE INDEX IF NOT EXISTS "idx_data_subject_requests_hospital" ON "data_subject_requests" ("hospital");
+CREATE INDEX IF NOT EXISTS "idx_data_subject_requests_status" ON "data_subject_requests" ("status");
+CREATE INDEX IF NOT EXISTS "idx_data_subject_requests_patient_id" ON "data_subject_requests" ("patient_id");
+CREATE INDEX IF NOT EXISTS "idx_data_subject_requests_deleted_at" ON "data_subject_requests" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "retention_policies" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "created_at" datetime,
+    "updated_at" datetime,
+    "deleted_at" datetime,
+    "hospital" text,
+    "entity" text,
+    "action" text,
+    "after_days" integer,
+    "enabled" numeric,
+    "dry_run" numeric
+);
+CREATE INDEX IF NOT EXISTS "idx_retention_policies_hospital" ON "retention_policies" ("hospital");
+CREATE INDEX IF NOT EXISTS "idx_retention_policies_deleted_at" ON "retention_policies" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "retention_runs" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "created_at" datetime,
+    "updated_at" datetime,
+    "deleted_at" datetime,
+    "policy_id" integer,
+    "hospital" text,
+    "entity" text,
+    "action" text,
+    "dry_run" numeric,
+    "cutoff" datetime,
+    "matched" integer,
+    "processed" integer,
+    "batches" integer,
+    "started_at" datetime,
+    "finished_at" datetime,
+    "error" text
+);
+CREATE INDEX IF NOT EXISTS "idx_retention_runs_hospital" ON "retention_runs" ("hospital");
+CREATE INDEX IF NOT EXISTS "idx_retention_runs_policy_id" ON "retention_runs" ("policy_id");
+CREATE INDEX IF NOT EXISTS "idx_retention_runs_deleted_at" ON "retention_runs" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "emergency_accesses" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "created_at" datetime,
+    "updated_at" datetime,
+    "deleted_at" datetime,
+    "clinician" text,
+    "clinician_hospital" text,
+    "patient_id" integer,
+    "patient_hospital" text,
+    "reason" text,
+    "expires_at" datetime,
+    "review_status" text DEFAULT 'pending',
+    "reviewed_by" text,
+    "reviewed_at" datetime,
+    "review_note" text
+);
+CREATE INDEX IF NOT EXISTS "idx_emergency_accesses_review_status" ON "emergency_accesses" ("review_status");
+CREATE INDEX IF NOT EXISTS "idx_emergency_accesses_patient_hospital" ON "emergency_accesses" ("patient_hospital");
+CREATE INDEX IF NOT EXISTS "idx_emergency_accesses_patient_id" ON "emergency_accesses" ("patient_id");
+CREATE INDEX IF NOT EXISTS "idx_emergency_accesses_clinician" ON "emergency_accesses" ("clinician");
+CREATE INDEX IF NOT EXISTS "idx_emergency_accesses_deleted_at" ON "emergency_accesses" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "sharing_agreements" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "created_at" datetime,
+    "updated_at" datetime,
+    "deleted_at" datetime,
+    "hospital" text,
+    "partner_hospital" text,
+    "direction" text,
+    "fields" text,
+    "roles" text,
+    "status" text DEFAULT 'proposed',
+    "proposed_by" text,
+    "accepted_by" text,
+    "accepted_at" datetime,
+    "revoked_by" text,
+    "revoked_at" datetime
+);
+CREATE INDEX IF NOT EXISTS "idx_sharing_agreements_status" ON "sharing_agreements" ("status");
+CREATE INDEX IF NOT EXISTS "idx_sharing_agreements_partner_hospital" ON "sharing_agreements" ("partner_hospital");
+CREATE INDEX IF NOT EXISTS "idx_sharing_agreements_hospital" ON "sharing_agreements" ("hospital");
+CREATE INDEX IF NOT EXISTS "idx_sharing_agreements_deleted_at" ON "sharing_agreements" ("deleted_at");
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS "idx_staffs_username_active";
+DROP INDEX IF EXISTS "idx_consents_patient_id_purpose";
+DROP INDEX IF EXISTS "idx_emergency_accesses_patient_hospital_lower";
+DROP INDEX IF EXISTS "idx_data_subject_requests_hospital_lower";
+DROP INDEX IF EXISTS "idx_patients_patient_hn";
+DROP INDEX IF EXISTS "idx_patients_hospital_lower";
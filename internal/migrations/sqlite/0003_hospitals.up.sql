@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS "hospitals" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "created_at" datetime,
+    "updated_at" datetime,
+    "deleted_at" datetime,
+    "name" text
+);
+CREATE INDEX IF NOT EXISTS "idx_hospitals_deleted_at" ON "hospitals" ("deleted_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_hospitals_name_active" ON "hospitals" (LOWER("name")) WHERE "deleted_at" IS NULL;
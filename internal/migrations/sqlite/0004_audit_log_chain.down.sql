@@ -0,0 +1,2 @@
+ALTER TABLE "audit_logs" DROP COLUMN "hash";
+ALTER TABLE "audit_logs" DROP COLUMN "prev_hash";
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS "idx_patients_anonymized_at";
+ALTER TABLE "patients" DROP COLUMN "anonymized_at";
@@ -2,29 +2,125 @@ package routes
 
 import (
 	adaptersStaff "agnos/internal/adapters/staff"
+	"agnos/internal/entities"
+	"agnos/internal/migrations"
 	usecasesStaff "agnos/internal/usecases/staff"
 	"agnos/pkg/middleware"
 
 	adaptersPatient "agnos/internal/adapters/patient"
 	usecasesPatient "agnos/internal/usecases/patient"
 
+	adaptersAudit "agnos/internal/adapters/audit"
+	usecasesAudit "agnos/internal/usecases/audit"
+
+	adaptersConsent "agnos/internal/adapters/consent"
+	usecasesConsent "agnos/internal/usecases/consent"
+
+	adaptersDsar "agnos/internal/adapters/dsar"
+	usecasesDsar "agnos/internal/usecases/dsar"
+
+	adaptersRetention "agnos/internal/adapters/retention"
+	usecasesRetention "agnos/internal/usecases/retention"
+
+	adaptersEmergency "agnos/internal/adapters/emergency"
+	usecasesEmergency "agnos/internal/usecases/emergency"
+
+	adaptersSharing "agnos/internal/adapters/sharing"
+	usecasesSharing "agnos/internal/usecases/sharing"
+
+	adaptersImporter "agnos/internal/adapters/importer"
+	usecasesImporter "agnos/internal/usecases/importer"
+
+	adaptersMpi "agnos/internal/adapters/mpi"
+	usecasesMpi "agnos/internal/usecases/mpi"
+
+	adaptersUnitofwork "agnos/internal/adapters/unitofwork"
+	usecasesUnitofwork "agnos/internal/usecases/unitofwork"
+
+	adaptersFhir "agnos/internal/adapters/fhir"
+
+	adaptersHl7 "agnos/internal/adapters/hl7"
+	usecasesAdt "agnos/internal/usecases/adt"
+
+	adaptersHealth "agnos/internal/adapters/health"
+	"agnos/pkg/database"
+	"agnos/pkg/health"
+
+	adaptersHospital "agnos/internal/adapters/hospital"
+	usecasesHospital "agnos/internal/usecases/hospital"
+
+	adaptersEncryption "agnos/internal/adapters/encryption"
+	usecasesKeys "agnos/internal/usecases/keys"
+	"agnos/pkg/encryption"
+
+	"context"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-func StaffRoutes(router *gin.RouterGroup, db *gorm.DB) {
-	staffRepo := adaptersStaff.NewGormStaffRepository(db)
-	staffService := usecasesStaff.NewStaffService(staffRepo)
+// Repositories are the stores the routes are built on: the database ones
+// in the server, in-memory ones in the route tests. UnitOfWork writes
+// through the same stores.
+type Repositories struct {
+	Staff     usecasesStaff.StaffRepository
+	Patients  usecasesPatient.PatientRepository
+	Audit     usecasesAudit.AuditRepository
+	Consents  usecasesConsent.ConsentRepository
+	Emergency usecasesEmergency.EmergencyRepository
+	Sharing   usecasesSharing.SharingRepository
+	Dsar      usecasesDsar.DsarRepository
+	Retention usecasesRetention.RetentionRepository
+	Mpi       usecasesMpi.MpiRepository
+	Imports   usecasesImporter.ImportRepository
+
+	UnitOfWork usecasesUnitofwork.UnitOfWork
+}
+
+func GormRepositories(db *gorm.DB) Repositories {
+	return Repositories{
+		Staff:     adaptersStaff.NewGormStaffRepository(db),
+		Patients:  adaptersPatient.NewGormPatientRepository(db),
+		Audit:     adaptersAudit.NewGormAuditRepository(db),
+		Consents:  adaptersConsent.NewGormConsentRepository(db),
+		Emergency: adaptersEmergency.NewGormEmergencyRepository(db),
+		Sharing:   adaptersSharing.NewGormSharingRepository(db),
+		Dsar:      adaptersDsar.NewGormDsarRepository(db),
+		Retention: adaptersRetention.NewGormRetentionRepository(db),
+		Mpi:       adaptersMpi.NewGormMpiRepository(db),
+		Imports:   adaptersImporter.NewGormImportRepository(db),
+
+		UnitOfWork: adaptersUnitofwork.NewGormUnitOfWork(db),
+	}
+}
+
+func StaffRoutes(router *gin.RouterGroup, repos Repositories) {
+	staffService := usecasesStaff.NewStaffService(repos.Staff)
 	staffHttp := adaptersStaff.NewHttpStaffRepository(staffService)
 
 	router.POST("/staff/create", staffHttp.CreateStaff)
 	router.POST("/staff/login", staffHttp.Login)
+	router.PUT("/staff/:username/role", middleware.AuthRequired, middleware.RoleRequired(entities.RoleAdmin), staffHttp.ChangeRole)
 }
 
-func PatientRoutes(router *gin.RouterGroup, db *gorm.DB) {
-	patientRepo := adaptersPatient.NewGormPatientRepository(db)
-	patientService := usecasesPatient.NewPatientService(patientRepo)
-	patientHttp := adaptersPatient.NewHttpPatientRepository(patientService)
+// ImportService runs patient imports in the background. The caller waits
+// for it on shutdown.
+func ImportService(repos Repositories) usecasesImporter.ImportUseCase {
+	return usecasesImporter.NewImportService(repos.Imports)
+}
+
+func PatientRoutes(router *gin.RouterGroup, repos Repositories, importService usecasesImporter.ImportUseCase) {
+	patientService := usecasesPatient.NewPatientService(repos.Patients)
+	mpiService := newMpiService(repos)
+	auditService := usecasesAudit.NewAuditService(repos.Audit)
+	consentService := newConsentService(repos)
+	emergencyService := usecasesEmergency.NewEmergencyService(repos.Emergency, patientService, auditService, usecasesEmergency.DefaultAccessDuration)
+	sharingService := usecasesSharing.NewSharingService(repos.Sharing, patientService, consentService, auditService)
+	patientHttp := adaptersPatient.NewHttpPatientRepository(patientService, mpiService, auditService, consentService, emergencyService, sharingService)
+	emergencyHttp := adaptersEmergency.NewHttpEmergencyRepository(emergencyService)
+
+	importHttp := adaptersImporter.NewHttpImportRepository(importService)
 
 	patientGroup := router.Group("/patient")
 	patientGroup.Use(middleware.AuthRequired)
@@ -32,4 +128,241 @@ func PatientRoutes(router *gin.RouterGroup, db *gorm.DB) {
 	patientGroup.POST("/create", patientHttp.CreatePatient)
 	patientGroup.GET("/search", patientHttp.SearchPatient)
 	patientGroup.GET("/search/:id", patientHttp.SearchPatientId)
+	patientGroup.GET("/export", patientHttp.ExportPatient)
+	patientGroup.POST("/reveal/:id", patientHttp.RevealPatient)
+	patientGroup.POST("/emergency-access", middleware.RoleRequired(entities.RoleClinician), emergencyHttp.GrantAccess)
+	patientGroup.GET("/emergency-access", middleware.RoleRequired(entities.RoleAdmin), emergencyHttp.ListAccesses)
+	patientGroup.POST("/emergency-access/:id/review", middleware.RoleRequired(entities.RoleAdmin), emergencyHttp.ReviewAccess)
+	patientGroup.POST("/import", importHttp.ImportPatient)
+	patientGroup.GET("/import/:id", importHttp.GetImportJob)
+}
+
+func ConsentRoutes(router *gin.RouterGroup, repos Repositories) {
+	consentService := newConsentService(repos)
+	consentHttp := adaptersConsent.NewHttpConsentRepository(consentService)
+
+	consentGroup := router.Group("/consent")
+	consentGroup.Use(middleware.AuthRequired)
+
+	consentGroup.POST("/grant", consentHttp.GrantConsent)
+	consentGroup.POST("/withdraw", consentHttp.WithdrawConsent)
+	consentGroup.GET("/:patient_id", consentHttp.ListConsents)
+}
+
+func SharingRoutes(router *gin.RouterGroup, repos Repositories) {
+	patientService := usecasesPatient.NewPatientService(repos.Patients)
+	consentService := newConsentService(repos)
+	auditService := usecasesAudit.NewAuditService(repos.Audit)
+	sharingService := usecasesSharing.NewSharingService(repos.Sharing, patientService, consentService, auditService)
+	sharingHttp := adaptersSharing.NewHttpSharingRepository(sharingService)
+
+	sharingGroup := router.Group("/sharing/agreements")
+	sharingGroup.Use(middleware.AuthRequired, middleware.RoleRequired(entities.RoleAdmin))
+
+	sharingGroup.POST("", sharingHttp.ProposeAgreement)
+	sharingGroup.GET("", sharingHttp.ListAgreements)
+	sharingGroup.POST("/:id/accept", sharingHttp.AcceptAgreement)
+	sharingGroup.POST("/:id/revoke", sharingHttp.RevokeAgreement)
+}
+
+func DsarRoutes(router *gin.RouterGroup, repos Repositories) {
+	uow := usecasesUnitofwork.Scope(repos.UnitOfWork, func(repos usecasesUnitofwork.Repositories) usecasesDsar.Repositories {
+		return repos
+	})
+	dsarService := usecasesDsar.NewDsarService(repos.Dsar, uow)
+	dsarHttp := adaptersDsar.NewHttpDsarRepository(dsarService)
+
+	adminOnly := middleware.RoleRequired(entities.RoleAdmin)
+
+	dsarGroup := router.Group("/dsar")
+	dsarGroup.Use(middleware.AuthRequired)
+
+	dsarGroup.POST("", dsarHttp.CreateRequest)
+	dsarGroup.GET("", dsarHttp.ListRequests)
+	dsarGroup.GET("/:id", dsarHttp.GetRequest)
+	dsarGroup.POST("/:id/approve", adminOnly, dsarHttp.ApproveRequest)
+	dsarGroup.POST("/:id/reject", adminOnly, dsarHttp.RejectRequest)
+	dsarGroup.GET("/:id/export", adminOnly, dsarHttp.ExportRequest)
+	dsarGroup.POST("/:id/erase", adminOnly, dsarHttp.EraseRequest)
+
+	holdGroup := router.Group("/legal-hold")
+	holdGroup.Use(middleware.AuthRequired, adminOnly)
+
+	holdGroup.PUT("/:patient_id", dsarHttp.SetLegalHold)
+}
+
+func RetentionService(repos Repositories) usecasesRetention.RetentionUseCase {
+	return usecasesRetention.NewRetentionService(repos.Retention)
+}
+
+func RetentionRoutes(router *gin.RouterGroup, retentionService usecasesRetention.RetentionUseCase) {
+	retentionHttp := adaptersRetention.NewHttpRetentionRepository(retentionService)
+
+	retentionGroup := router.Group("/retention")
+	retentionGroup.Use(middleware.AuthRequired, middleware.RoleRequired(entities.RoleAdmin))
+
+	retentionGroup.GET("/policies", retentionHttp.ListPolicies)
+	retentionGroup.POST("/policies", retentionHttp.CreatePolicy)
+	retentionGroup.PUT("/policies/:id", retentionHttp.UpdatePolicy)
+	retentionGroup.DELETE("/policies/:id", retentionHttp.DeletePolicy)
+	retentionGroup.POST("/run", retentionHttp.RunPolicies)
+	retentionGroup.GET("/runs", retentionHttp.ListRuns)
+}
+
+func MpiRoutes(router *gin.RouterGroup, repos Repositories) {
+	mpiService := newMpiService(repos)
+	mpiHttp := adaptersMpi.NewHttpMpiRepository(mpiService)
+
+	adminOnly := middleware.RoleRequired(entities.RoleAdmin)
+
+	mpiGroup := router.Group("/mpi")
+	mpiGroup.Use(middleware.AuthRequired)
+
+	mpiGroup.GET("/duplicates", mpiHttp.ListDuplicates)
+	mpiGroup.POST("/duplicates/:id/dismiss", adminOnly, mpiHttp.DismissDuplicate)
+	mpiGroup.POST("/merge", adminOnly, mpiHttp.MergePatient)
+}
+
+func FhirRoutes(router *gin.RouterGroup, repos Repositories) {
+	patientService := usecasesPatient.NewPatientService(repos.Patients)
+	mpiService := newMpiService(repos)
+	fhirHttp := adaptersFhir.NewHttpFhirRepository(patientService, mpiService)
+
+	fhirGroup := router.Group("/fhir")
+	fhirGroup.GET("/metadata", fhirHttp.Metadata)
+
+	patientGroup := fhirGroup.Group("/Patient")
+	patientGroup.Use(fhirHttp.AuthRequired)
+
+	patientGroup.GET("", fhirHttp.SearchPatient)
+	patientGroup.GET("/:id", fhirHttp.ReadPatient)
+	patientGroup.POST("", fhirHttp.CreatePatient)
+}
+
+func newConsentService(repos Repositories) usecasesConsent.ConsentUseCase {
+	uow := usecasesUnitofwork.Scope(repos.UnitOfWork, func(repos usecasesUnitofwork.Repositories) usecasesConsent.Repositories {
+		return repos
+	})
+	return usecasesConsent.NewConsentService(repos.Consents, uow)
+}
+
+func newMpiService(repos Repositories) usecasesMpi.MpiUseCase {
+	uow := usecasesUnitofwork.Scope(repos.UnitOfWork, func(repos usecasesUnitofwork.Repositories) usecasesMpi.Repositories {
+		return repos
+	})
+	return usecasesMpi.NewMpiService(repos.Mpi, uow)
+}
+
+func newAdtService(repos Repositories) usecasesAdt.AdtUseCase {
+	patientService := usecasesPatient.NewPatientService(repos.Patients)
+	mpiService := newMpiService(repos)
+	return usecasesAdt.NewAdtService(patientService, mpiService)
+}
+
+func Hl7Routes(router *gin.RouterGroup, repos Repositories) {
+	hl7Http := adaptersHl7.NewHttpHl7Repository(newAdtService(repos))
+
+	hl7Group := router.Group("/hl7")
+	hl7Group.Use(middleware.AuthRequired)
+
+	hl7Group.POST("/adt", hl7Http.IngestMessage)
+}
+
+func Hl7Listener(repos Repositories, senders []adaptersHl7.MllpSender, messageTimeout time.Duration) *adaptersHl7.MllpHl7Listener {
+	return adaptersHl7.NewMllpHl7Listener(newAdtService(repos), senders, messageTimeout)
+}
+
+// HealthChecker checks the database connection and that no migration is
+// pending. Optional dependencies, such as the MLLP listener, register their
+// own checks on the result.
+func HealthChecker(db *gorm.DB) *health.Checker {
+	checker := health.NewChecker(2 * time.Second)
+	checker.Register("database", func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	})
+	migrator, err := migrations.New(db)
+	checker.Register("schema", func(ctx context.Context) error {
+		if err != nil {
+			return err
+		}
+		return migrator.Check(ctx)
+	})
+	return checker
+}
+
+func HealthRoutes(router *gin.RouterGroup, db *gorm.DB, checker *health.Checker) {
+	healthHttp := adaptersHealth.NewHttpHealthRepository(checker, func() gin.H {
+		details := gin.H{}
+		replicas := gin.H{}
+		for _, pool := range database.Pools(db) {
+			stats := pool.DB.Stats()
+			poolStats := gin.H{
+				"max_open_connections": stats.MaxOpenConnections,
+				"open_connections":     stats.OpenConnections,
+				"in_use":               stats.InUse,
+				"idle":                 stats.Idle,
+				"wait_count":           stats.WaitCount,
+				"wait_duration_ms":     stats.WaitDuration.Milliseconds(),
+			}
+			if pool.Name == "primary" {
+				details["database_pool"] = poolStats
+			} else {
+				replicas[pool.Name] = poolStats
+			}
+		}
+		if len(replicas) > 0 {
+			details["replica_pools"] = replicas
+		}
+		return details
+	})
+
+	router.GET("/healthz", healthHttp.Liveness)
+	router.GET("/readyz", healthHttp.Readiness)
+	router.GET("/health", middleware.AuthRequired, middleware.RoleRequired(entities.RoleAdmin), healthHttp.Health)
+}
+
+// EncryptionKeyring installs the keyring used for the encrypted patient
+// columns. It must run before any route touches a patient.
+func EncryptionKeyring(db *gorm.DB, provider encryption.KeyProvider) (*encryption.Keyring, error) {
+	keyring := encryption.NewKeyring(provider, adaptersEncryption.NewGormKeyRepository(db))
+	if err := keyring.EnsureDataKey(context.Background()); err != nil {
+		return nil, err
+	}
+	encryption.SetDefault(keyring)
+	return keyring, nil
+}
+
+func KeyService(db *gorm.DB, keyring *encryption.Keyring) usecasesKeys.KeyUseCase {
+	rotationRepo := adaptersEncryption.NewGormRotationRepository(db)
+	return usecasesKeys.NewKeyService(rotationRepo, keyring)
+}
+
+// The services below back the admin commands, which run the same use cases
+// as the routes without HTTP.
+
+func StaffService(db *gorm.DB) usecasesStaff.StaffUseCase {
+	staffRepo := adaptersStaff.NewGormStaffRepository(db)
+	return usecasesStaff.NewStaffService(staffRepo)
+}
+
+func PatientRepository(db *gorm.DB) usecasesPatient.PatientRepository {
+	return adaptersPatient.NewGormPatientRepository(db)
+}
+
+func PatientService(db *gorm.DB) usecasesPatient.PatientUseCase {
+	return usecasesPatient.NewPatientService(PatientRepository(db))
+}
+
+func HospitalService(db *gorm.DB) usecasesHospital.HospitalUseCase {
+	hospitalRepo := adaptersHospital.NewGormHospitalRepository(db)
+	return usecasesHospital.NewHospitalService(hospitalRepo)
+}
+
+func AuditService(db *gorm.DB) usecasesAudit.AuditUseCase {
+	auditRepo := adaptersAudit.NewGormAuditRepository(db)
+	return usecasesAudit.NewAuditService(auditRepo)
 }
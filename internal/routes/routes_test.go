@@ -2,52 +2,164 @@ package routes_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
-
+	"time"
+
+	adaptersAudit "agnos/internal/adapters/audit"
+	adaptersConsent "agnos/internal/adapters/consent"
+	adaptersDsar "agnos/internal/adapters/dsar"
+	adaptersEmergency "agnos/internal/adapters/emergency"
+	adaptersImporter "agnos/internal/adapters/importer"
+	adaptersMpi "agnos/internal/adapters/mpi"
+	adaptersPatient "agnos/internal/adapters/patient"
+	patientDto "agnos/internal/adapters/patient/dto"
+	adaptersRetention "agnos/internal/adapters/retention"
+	adaptersSharing "agnos/internal/adapters/sharing"
+	adaptersStaff "agnos/internal/adapters/staff"
+	staffDto "agnos/internal/adapters/staff/dto"
+	adaptersUnitofwork "agnos/internal/adapters/unitofwork"
+	"agnos/internal/dbtest"
 	"agnos/internal/entities"
 	"agnos/internal/routes"
+	usecasesStaff "agnos/internal/usecases/staff"
+	"agnos/internal/usecases/synthetic"
+	"agnos/pkg/encryption"
+	"agnos/pkg/metrics"
+	"agnos/pkg/middleware"
+	"agnos/pkg/migrate"
+	"agnos/pkg/tracing"
+	"agnos/pkg/tracing/tracingtest"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
-	"gorm.io/driver/postgres"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"gorm.io/gorm"
 )
 
-const (
-	host     = "localhost"
-	user     = "myuser"
-	password = "mypassword"
-	dbname   = "mydatabase"
-	port     = 5433
-)
+// memoryRepositories are the in-memory repositories the router is built
+// on, with the concrete ones the tests look into.
+type memoryRepositories struct {
+	routes.Repositories
+	patients *adaptersPatient.MemoryPatientRepository
+	mpi      *adaptersMpi.MemoryMpiRepository
+}
 
-func clearDatabase(db *gorm.DB) {
-	db.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&entities.Staff{})
-	db.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&entities.Patient{})
+func newMemoryRepositories() memoryRepositories {
+	patients := adaptersPatient.NewMemoryPatientRepository().(*adaptersPatient.MemoryPatientRepository)
+	consents := adaptersConsent.NewMemoryConsentRepository(patients).(*adaptersConsent.MemoryConsentRepository)
+	audit := adaptersAudit.NewMemoryAuditRepository().(*adaptersAudit.MemoryAuditRepository)
+	mpi := adaptersMpi.NewMemoryMpiRepository(patients).(*adaptersMpi.MemoryMpiRepository)
+	imports := adaptersImporter.NewMemoryImportRepository(patients).(*adaptersImporter.MemoryImportRepository)
+	staff := adaptersStaff.NewMemoryStaffRepository()
+	dsar := adaptersDsar.NewMemoryDsarRepository(patients, consents, mpi, audit)
+	return memoryRepositories{
+		Repositories: routes.Repositories{
+			Staff:     staff,
+			Patients:  patients,
+			Audit:     audit,
+			Consents:  consents,
+			Emergency: adaptersEmergency.NewMemoryEmergencyRepository(),
+			Sharing:   adaptersSharing.NewMemorySharingRepository(),
+			Dsar:      dsar,
+			Retention: adaptersRetention.NewMemoryRetentionRepository(patients, consents, mpi, audit, imports),
+			Mpi:       mpi,
+			Imports:   imports,
+
+			UnitOfWork: adaptersUnitofwork.NewMemoryUnitOfWork(patients, staff, consents, audit, mpi, dsar),
+		},
+		patients: patients,
+		mpi:      mpi,
+	}
 }
 
-func setupTestRouter() (*gin.Engine, *gorm.DB) {
+func testProvider() encryption.KeyProvider {
+	provider, err := encryption.NewLocalProvider("test", map[string][]byte{"test": bytes.Repeat([]byte{1}, 32)}, bytes.Repeat([]byte{2}, 32))
+	if err != nil {
+		panic("failed to load encryption keys: " + err.Error())
+	}
+	return provider
+}
 
+func newRouter(repos routes.Repositories) (*gin.Engine, *gin.RouterGroup) {
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
+	r.Use(middleware.Tracing, middleware.RequestID)
 	group := r.Group("/")
 
-	dbs := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable", host, port, user, password, dbname)
-	db, err := gorm.Open(postgres.Open(dbs), &gorm.Config{})
-	if err != nil {
-		panic("failed to connect database: " + err.Error())
-	}
+	routes.StaffRoutes(group, repos)
+	routes.PatientRoutes(group, repos, routes.ImportService(repos))
+	routes.ConsentRoutes(group, repos)
+	routes.SharingRoutes(group, repos)
+	routes.DsarRoutes(group, repos)
+	routes.RetentionRoutes(group, routes.RetentionService(repos))
+	routes.MpiRoutes(group, repos)
+	routes.FhirRoutes(group, repos)
+	routes.Hl7Routes(group, repos)
+	return r, group
+}
 
-	db.AutoMigrate(&entities.Staff{}, &entities.Patient{})
+// setupTestRouter builds the routes on in-memory repositories, so that a
+// test starts from empty stores of its own and needs no database.
+func setupTestRouter() (*gin.Engine, memoryRepositories) {
+	encryption.SetDefault(encryption.NewKeyring(testProvider(), nil))
+	repos := newMemoryRepositories()
+	r, _ := newRouter(repos.Repositories)
+	return r, repos
+}
 
-	routes.StaffRoutes(group, db)
-	routes.PatientRoutes(group, db)
+// eachDatabase runs fn against the routes on a migrated database of each
+// dialect, for the tests of what reaches the database itself.
+func eachDatabase(t *testing.T, fn func(t *testing.T, r *gin.Engine, db *gorm.DB)) {
+	dbtest.Each(t, "routes", func(t *testing.T, db *gorm.DB) {
+		db.Use(metrics.GormPlugin{})
+		db.Use(tracing.GormPlugin{})
+		if _, err := routes.EncryptionKeyring(db, testProvider()); err != nil {
+			t.Fatalf("failed to load data key: %v", err)
+		}
+		r, group := newRouter(routes.GormRepositories(db))
+		routes.HealthRoutes(group, db, routes.HealthChecker(db))
+		fn(t, r, db)
+	})
+}
 
-	return r, db
+// findStaff returns the active account with username.
+func findStaff(t *testing.T, repos memoryRepositories, username string) *entities.Staff {
+	found, err := repos.Staff.Login(context.Background(), &staffDto.LoginStaffDto{Username: username})
+	assert.NoError(t, err)
+	return found
+}
+
+// findPatient returns the first live patient whose name contains firstName.
+func findPatient(t *testing.T, repos memoryRepositories, firstName string) *entities.Patient {
+	patients, err := repos.Patients.Findone(context.Background(), &patientDto.SearchPatientDto{FirstName: firstName})
+	assert.NoError(t, err)
+	if !assert.NotEmpty(t, patients) {
+		t.FailNow()
+	}
+	return patients[0]
+}
+
+// auditEntries returns the audit entries that match, in the order recorded.
+func auditEntries(t *testing.T, repos memoryRepositories, matches func(entry *entities.AuditLog) bool) []*entities.AuditLog {
+	var found []*entities.AuditLog
+	err := repos.Audit.FindInBatches(context.Background(), 100, func(entries []*entities.AuditLog) error {
+		for _, entry := range entries {
+			if matches(entry) {
+				found = append(found, entry)
+			}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	return found
 }
 
 func createStaffViaApi(t *testing.T, r *gin.Engine, staffData map[string]string) {
@@ -83,8 +195,7 @@ func createLoginStaffViaApi(t *testing.T, r *gin.Engine, staffData entities.Staf
 }
 
 func TestStaffRoutes_CreateStaff_Success(t *testing.T) {
-	r, db := setupTestRouter()
-	defer clearDatabase(db)
+	r, repos := setupTestRouter()
 
 	inputData := map[string]string{
 		"username": "shinepp",
@@ -104,16 +215,13 @@ func TestStaffRoutes_CreateStaff_Success(t *testing.T) {
 	json.Unmarshal(w.Body.Bytes(), &response)
 	assert.Equal(t, float64(201), response["statusCode"])
 
-	var createdStaff entities.Staff
-	err := db.First(&createdStaff, "username = ?", "shinepp").Error
-	assert.NoError(t, err)
+	createdStaff := findStaff(t, repos, "shinepp")
 	assert.Equal(t, "Bangkok Hospital", createdStaff.Hospital)
 	assert.Equal(t, "shinepp", createdStaff.Username)
 }
 
 func TestStaffRoutes_CreateStaff_FailUsernameAvailable(t *testing.T) {
-	r, db := setupTestRouter()
-	defer clearDatabase(db)
+	r, repos := setupTestRouter()
 
 	firstData := map[string]string{
 		"username": "walawala",
@@ -127,10 +235,7 @@ func TestStaffRoutes_CreateStaff_FailUsernameAvailable(t *testing.T) {
 	req1.Header.Set("Content-Type", "application/json")
 	r.ServeHTTP(w1, req1)
 
-	var staff entities.Staff
-	err := db.First(&staff, "username = ?", "walawala").Error
-	assert.NoError(t, err)
-	assert.Equal(t, "walawala", staff.Username)
+	assert.Equal(t, "walawala", findStaff(t, repos, "walawala").Username)
 
 	inputData := map[string]string{
 		"username": "walawala",
@@ -153,8 +258,7 @@ func TestStaffRoutes_CreateStaff_FailUsernameAvailable(t *testing.T) {
 }
 
 func TestStaffRoutes_CreateStaff_FailByHospitalRequired(t *testing.T) {
-	r, db := setupTestRouter()
-	defer clearDatabase(db)
+	r, _ := setupTestRouter()
 
 	firstData := map[string]string{
 		"username": "walawala",
@@ -174,9 +278,43 @@ func TestStaffRoutes_CreateStaff_FailByHospitalRequired(t *testing.T) {
 	assert.Equal(t, []interface{}{"hospital is required"}, response["error"])
 }
 
+func TestStaffRoutes_CreateStaff_IgnoresRoleUntilAdminChangesIt(t *testing.T) {
+	r, repos := setupTestRouter()
+
+	createStaffViaApi(t, r, map[string]string{
+		"username": "intruder",
+		"password": "89058905",
+		"hospital": "Bangkok Hospital",
+		"role":     "admin",
+	})
+	token := createLoginStaffViaApi(t, r, entities.Staff{Username: "walawala", Password: "89058905", Hospital: "Bangkok Hospital"})
+	w, _ := getViaApi(r, "/retention/policies", token)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, entities.RoleReceptionist, findStaff(t, repos, "intruder").Role)
+
+	change := func(username string, role string, token string) int {
+		body, _ := json.Marshal(map[string]string{"role": role})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", "/staff/"+username+"/role", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusForbidden, change("walawala", "admin", token))
+
+	otherAdmin := loginStaffWithRoleViaApi(t, r, repos.Staff, "siriraj-admin", "Siriraj Hospital", "admin")
+	assert.Equal(t, http.StatusBadRequest, change("walawala", "clinician", otherAdmin))
+
+	admin := loginStaffWithRoleViaApi(t, r, repos.Staff, "boss", "Bangkok Hospital", "admin")
+	assert.Equal(t, http.StatusBadRequest, change("walawala", "superuser", admin))
+	assert.Equal(t, http.StatusOK, change("walawala", "clinician", admin))
+
+	assert.Equal(t, entities.RoleClinician, findStaff(t, repos, "walawala").Role)
+}
+
 func TestStaffRoutes_LoginStaff_Success(t *testing.T) {
-	r, db := setupTestRouter()
-	defer clearDatabase(db)
+	r, _ := setupTestRouter()
 
 	dto := map[string]string{
 		"username": "walawala",
@@ -201,8 +339,7 @@ func TestStaffRoutes_LoginStaff_Success(t *testing.T) {
 }
 
 func TestStaffRoutes_LoginStaff_Fail(t *testing.T) {
-	r, db := setupTestRouter()
-	defer clearDatabase(db)
+	r, _ := setupTestRouter()
 
 	createDto := map[string]string{
 		"username": "walawala12",
@@ -233,8 +370,7 @@ func TestStaffRoutes_LoginStaff_Fail(t *testing.T) {
 }
 
 func TestStaffRoutes_LoginStaff_FailByPasswordRequired(t *testing.T) {
-	r, db := setupTestRouter()
-	defer clearDatabase(db)
+	r, _ := setupTestRouter()
 
 	createDto := map[string]string{
 		"username": "walawala",
@@ -263,8 +399,7 @@ func TestStaffRoutes_LoginStaff_FailByPasswordRequired(t *testing.T) {
 }
 
 func TestStaffRoutes_LoginStaff_FailPasswordWrong(t *testing.T) {
-	r, db := setupTestRouter()
-	defer clearDatabase(db)
+	r, _ := setupTestRouter()
 	createDto := map[string]string{
 		"username": "walawala",
 		"password": "123456",
@@ -292,8 +427,7 @@ func TestStaffRoutes_LoginStaff_FailPasswordWrong(t *testing.T) {
 }
 
 func TestPatient_CreatePatient_Success(t *testing.T) {
-	r, db := setupTestRouter()
-	defer clearDatabase(db)
+	r, _ := setupTestRouter()
 
 	createStaffDto := entities.Staff{
 		Username: "walawala12",
@@ -346,8 +480,7 @@ func createPatientViaApi(t *testing.T, r *gin.Engine, patient map[string]string,
 }
 
 func TestPatient_CreatePatient_FailAvailable(t *testing.T) {
-	r, db := setupTestRouter()
-	defer clearDatabase(db)
+	r, _ := setupTestRouter()
 
 	createStaffDto := entities.Staff{
 		Username: "walawala12",
@@ -389,8 +522,7 @@ func TestPatient_CreatePatient_FailAvailable(t *testing.T) {
 }
 
 func TestPatient_SearchPatient_Success(t *testing.T) {
-	r, db := setupTestRouter()
-	defer clearDatabase(db)
+	r, _ := setupTestRouter()
 
 	createStaffDto := entities.Staff{
 		Username: "walawala12",
@@ -470,8 +602,7 @@ func TestPatient_SearchPatient_Success(t *testing.T) {
 	assert.Equal(t, "กัญชนก", secondObject["first_name_th"])
 }
 func TestPatient_SearchPatientWithQuery_Success(t *testing.T) {
-	r, db := setupTestRouter()
-	defer clearDatabase(db)
+	r, _ := setupTestRouter()
 
 	createStaffDto := entities.Staff{
 		Username: "walawala12",
@@ -550,8 +681,7 @@ func TestPatient_SearchPatientWithQuery_Success(t *testing.T) {
 }
 
 func TestPatient_SearchPatientById_Success(t *testing.T) {
-	r, db := setupTestRouter()
-	defer clearDatabase(db)
+	r, _ := setupTestRouter()
 
 	createStaffDto := entities.Staff{
 		Username: "walawala",
@@ -574,7 +704,7 @@ func TestPatient_SearchPatientById_Success(t *testing.T) {
 		"phone_number":   "0812345678",
 		"email":          "plabpluem@example.com",
 		"gender":         "male",
-		"hospital":       "hua-hin hospital",
+		"hospital":       "Bangkok Hospital",
 	}
 	createPatientViaApi(t, r, firstDto, token)
 
@@ -591,8 +721,7 @@ func TestPatient_SearchPatientById_Success(t *testing.T) {
 	assert.Equal(t, "search success", response["message"])
 }
 func TestPatient_SearchPatientById_Fail(t *testing.T) {
-	r, db := setupTestRouter()
-	defer clearDatabase(db)
+	r, _ := setupTestRouter()
 
 	createStaffDto := entities.Staff{
 		Username: "walawala",
@@ -631,3 +760,1049 @@ func TestPatient_SearchPatientById_Fail(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 	assert.Equal(t, "record not found", response["error"])
 }
+
+func TestMpi_CreatePatient_WarnsLikelyDuplicate(t *testing.T) {
+	r, repos := setupTestRouter()
+
+	createStaffDto := entities.Staff{
+		Username: "walawala",
+		Password: "89058905",
+		Hospital: "Bangkok Hospital",
+	}
+	token := createLoginStaffViaApi(t, r, createStaffDto)
+
+	firstDto := map[string]string{
+		"first_name_th":  "ปลาบปลื้ม",
+		"middle_name_th": "-",
+		"last_name_th":   "ยอดจันทร์",
+		"first_name_en":  "Plabpluem",
+		"middle_name_en": "D",
+		"last_name_en":   "Yodchan",
+		"date_of_birth":  "1995-07-21T00:00:00Z",
+		"patient_hn":     "HN00001",
+		"national_id":    "1234567890123",
+		"passport_id":    "",
+		"phone_number":   "0812345678",
+		"email":          "plabpluem@example.com",
+		"gender":         "male",
+		"hospital":       "Bangkok Hospital",
+	}
+	createPatientViaApi(t, r, firstDto, token)
+
+	secondDto := map[string]string{}
+	for k, v := range firstDto {
+		secondDto[k] = v
+	}
+	secondDto["national_id"] = "1234567890132"
+	secondDto["first_name_en"] = "Plabpleum"
+	secondDto["patient_hn"] = "HN00002"
+	jsonBody, _ := json.Marshal(secondDto)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/patient/create", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	r.ServeHTTP(w, req)
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, float64(201), response["statusCode"])
+	warning := response["warning"].(map[string]interface{})
+	assert.Equal(t, 1, len(warning["duplicates"].([]interface{})))
+
+	w2 := httptest.NewRecorder()
+	req2, _ := http.NewRequest("GET", "/mpi/duplicates", nil)
+	req2.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	r.ServeHTTP(w2, req2)
+
+	var listResponse map[string]interface{}
+	json.Unmarshal(w2.Body.Bytes(), &listResponse)
+	duplicates := listResponse["data"].([]interface{})
+	assert.Equal(t, 1, len(duplicates))
+
+	duplicate := duplicates[0].(map[string]interface{})
+	mergeBody, _ := json.Marshal(map[string]interface{}{
+		"survivor_id": duplicate["candidate_id"],
+		"merged_id":   duplicate["patient_id"],
+	})
+
+	// Only admins merge.
+	w, _ = postJsonViaApi(r, "/mpi/merge", json.RawMessage(mergeBody), token)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	admin := loginStaffWithRoleViaApi(t, r, repos.Staff, "mpi-admin", "Bangkok Hospital", "admin")
+	w3 := httptest.NewRecorder()
+	req3, _ := http.NewRequest("POST", "/mpi/merge", bytes.NewBuffer(mergeBody))
+	req3.Header.Set("Content-Type", "application/json")
+	req3.Header.Set("Authorization", fmt.Sprintf("Bearer %s", admin))
+	r.ServeHTTP(w3, req3)
+
+	var mergeResponse map[string]interface{}
+	json.Unmarshal(w3.Body.Bytes(), &mergeResponse)
+	assert.Equal(t, http.StatusOK, w3.Code)
+	assert.Equal(t, "merge success", mergeResponse["message"])
+
+	merges := repos.mpi.Merges()
+	assert.Equal(t, 1, len(merges))
+	assert.Equal(t, uint(duplicate["candidate_id"].(float64)), merges[0].SurvivorID)
+
+	recorded := auditEntries(t, repos, func(entry *entities.AuditLog) bool { return entry.Action == "patient.merge" })
+	assert.Equal(t, 1, len(recorded))
+	assert.Equal(t, "mpi-admin", recorded[0].Actor)
+	assert.Equal(t, fmt.Sprint(merges[0].SurvivorID), recorded[0].ResourceId)
+
+	live, err := repos.Patients.Findone(context.Background(), &patientDto.SearchPatientDto{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(live))
+}
+
+func TestMpi_ListDuplicates_HidesOtherHospitalsPatients(t *testing.T) {
+	r, repos := setupTestRouter()
+
+	bangkok := loginStaffWithRoleViaApi(t, r, repos.Staff, "bangkok-doctor", "Bangkok Hospital", "clinician")
+	siriraj := loginStaffWithRoleViaApi(t, r, repos.Staff, "siriraj-doctor", "Siriraj Hospital", "clinician")
+
+	patient := map[string]string{
+		"first_name_en": "Plabpluem",
+		"last_name_en":  "Yodchan",
+		"date_of_birth": "1995-07-21T00:00:00Z",
+		"national_id":   "1234567890123",
+		"phone_number":  "0812345678",
+		"email":         "plabpluem@example.com",
+		"gender":        "male",
+		"hospital":      "Bangkok Hospital",
+	}
+	createPatientViaApi(t, r, patient, bangkok)
+	patient["national_id"] = "1234567890132"
+	patient["hospital"] = "Siriraj Hospital"
+	createPatientViaApi(t, r, patient, siriraj)
+
+	for _, token := range []string{bangkok, siriraj} {
+		w, response := getViaApi(r, "/mpi/duplicates", token)
+		assert.Equal(t, http.StatusOK, w.Code)
+		duplicates := response["data"].([]interface{})
+		assert.Equal(t, 1, len(duplicates))
+
+		duplicate := duplicates[0].(map[string]interface{})
+		own, foreign := duplicate["candidate"].(map[string]interface{}), duplicate["patient"].(map[string]interface{})
+		if token == siriraj {
+			own, foreign = foreign, own
+		}
+		assert.Equal(t, "Plabpluem", own["first_name_en"])
+		assert.Equal(t, "", foreign["first_name_en"])
+		assert.Equal(t, "", foreign["national_id"])
+		assert.Equal(t, "", foreign["phone_number"])
+		assert.Equal(t, "", foreign["email"])
+		assert.NotZero(t, foreign["ID"])
+		assert.NotEmpty(t, foreign["hospital"])
+		assert.NotZero(t, duplicate["score"])
+	}
+}
+
+func TestFhir_CreateAndSearchPatient_Success(t *testing.T) {
+	r, _ := setupTestRouter()
+
+	createStaffDto := entities.Staff{
+		Username: "walawala",
+		Password: "89058905",
+		Hospital: "Bangkok Hospital",
+	}
+	token := createLoginStaffViaApi(t, r, createStaffDto)
+
+	resource := map[string]interface{}{
+		"resourceType": "Patient",
+		"identifier": []map[string]string{
+			{"system": "https://terms.sil-th.org/id/th-cid", "value": "1234567890123"},
+		},
+		"name": []map[string]interface{}{
+			{"family": "ชวนศรี", "given": []string{"สมศักดิ์"}},
+			{"family": "Chunsri", "given": []string{"Somsak"}},
+		},
+		"gender":    "male",
+		"birthDate": "1990-02-03",
+	}
+	jsonBody, _ := json.Marshal(resource)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/fhir/Patient", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/fhir+json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Location"))
+
+	w2 := httptest.NewRecorder()
+	req2, _ := http.NewRequest("GET", "/fhir/Patient?identifier=https://terms.sil-th.org/id/th-cid|1234567890123&birthdate=1990-02-03", nil)
+	req2.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	r.ServeHTTP(w2, req2)
+
+	var bundle map[string]interface{}
+	json.Unmarshal(w2.Body.Bytes(), &bundle)
+
+	assert.Equal(t, http.StatusOK, w2.Code)
+	assert.Equal(t, "Bundle", bundle["resourceType"])
+	assert.Equal(t, float64(1), bundle["total"])
+
+	w3 := httptest.NewRecorder()
+	req3, _ := http.NewRequest("GET", "/fhir/Patient/999999", nil)
+	req3.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	r.ServeHTTP(w3, req3)
+
+	var outcome map[string]interface{}
+	json.Unmarshal(w3.Body.Bytes(), &outcome)
+
+	assert.Equal(t, http.StatusNotFound, w3.Code)
+	assert.Equal(t, "OperationOutcome", outcome["resourceType"])
+}
+
+func TestFhir_CreatePatient_UsesPatientValidation(t *testing.T) {
+	r, repos := setupTestRouter()
+	token := loginStaffWithRoleViaApi(t, r, repos.Staff, "fhir-doctor", "Bangkok Hospital", "clinician")
+
+	for _, test := range []struct {
+		resource    map[string]interface{}
+		diagnostics string
+	}{
+		{map[string]interface{}{"resourceType": "Patient", "gender": "other", "identifier": []map[string]string{{"system": "https://terms.sil-th.org/id/th-cid", "value": "1234567890123"}}}, "gender is oneof"},
+		{map[string]interface{}{"resourceType": "Patient", "gender": "male"}, "nationalid is required"},
+	} {
+		w, outcome := postJsonViaApi(r, "/fhir/Patient", test.resource, token)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		issue := outcome["issue"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, test.diagnostics, issue["diagnostics"])
+	}
+}
+
+func importPatientsViaApi(t *testing.T, r *gin.Engine, token string, csv string, fields map[string]string) map[string]interface{} {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", "patients.csv")
+	part.Write([]byte(csv))
+	for k, v := range fields {
+		writer.WriteField(k, v)
+	}
+	writer.Close()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/patient/import", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	id := response["data"].(map[string]interface{})["ID"]
+
+	for i := 0; i < 50; i++ {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/patient/import/%v", id), nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		r.ServeHTTP(w, req)
+
+		var status map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &status)
+		job := status["data"].(map[string]interface{})
+		if job["status"] == "completed" || job["status"] == "failed" {
+			return job
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("import did not finish")
+	return nil
+}
+
+func TestPatient_ImportPatient_DryRunThenImport(t *testing.T) {
+	r, repos := setupTestRouter()
+
+	createStaffDto := entities.Staff{
+		Username: "walawala",
+		Password: "89058905",
+		Hospital: "Bangkok Hospital",
+	}
+	token := createLoginStaffViaApi(t, r, createStaffDto)
+
+	csv := "CID,first_name_en,last_name_en,date_of_birth,gender\n" +
+		"1100000000001,Somsak,Chunsri,1990-02-03,male\n" +
+		"1100000000002,Kanchanok,Chunsri,1992-04-05,female\n" +
+		"1100000000001,Somsak,Duplicate,1990-02-03,male\n" +
+		",Nobody,Missing,1990-02-03,unknown\n"
+	fields := map[string]string{"dry_run": "true", "mapping": `{"national_id":"CID"}`}
+
+	job := importPatientsViaApi(t, r, token, csv, fields)
+	assert.Equal(t, "completed", job["status"])
+	assert.Equal(t, float64(4), job["total_rows"])
+	assert.Equal(t, float64(2), job["valid_rows"])
+	assert.Equal(t, float64(0), job["imported_rows"])
+	assert.Equal(t, 3, len(job["errors"].([]interface{})))
+
+	imported, err := repos.Patients.Findone(context.Background(), &patientDto.SearchPatientDto{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(imported))
+
+	fields["dry_run"] = "false"
+	job = importPatientsViaApi(t, r, token, csv, fields)
+	assert.Equal(t, "completed", job["status"])
+	assert.Equal(t, float64(2), job["imported_rows"])
+
+	imported, err = repos.Patients.Findone(context.Background(), &patientDto.SearchPatientDto{Hospital: "Bangkok Hospital"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(imported))
+}
+
+func TestPatient_ExportPatient_MasksForReceptionist(t *testing.T) {
+	r, repos := setupTestRouter()
+
+	createStaffDto := entities.Staff{
+		Username: "walawala",
+		Password: "89058905",
+		Hospital: "Bangkok Hospital",
+	}
+	token := createLoginStaffViaApi(t, r, createStaffDto)
+
+	patientDto := map[string]string{
+		"first_name_th": "สมศักดิ์",
+		"last_name_th":  "ชวนศรี",
+		"first_name_en": "Somsak",
+		"last_name_en":  "Chunsri",
+		"date_of_birth": "1995-07-21T00:00:00Z",
+		"national_id":   "1234567890123",
+		"phone_number":  "0812345678",
+		"gender":        "male",
+		"hospital":      "Bangkok Hospital",
+	}
+	createPatientViaApi(t, r, patientDto, token)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/patient/export?format=csv&first_name=Somsak", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "1-2345-XXXXX-12-3")
+	assert.NotContains(t, w.Body.String(), "1234567890123")
+
+	entries := auditEntries(t, repos, func(entry *entities.AuditLog) bool { return entry.Action == "patient.export" })
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "walawala", entries[0].Actor)
+}
+
+func TestPatient_ExportPatient_FhirNdjsonForClinician(t *testing.T) {
+	r, repos := setupTestRouter()
+
+	token := loginStaffWithRoleViaApi(t, r, repos.Staff, "doctor", "Bangkok Hospital", "clinician")
+
+	patientDto := map[string]string{
+		"first_name_en": "Somsak",
+		"last_name_en":  "Chunsri",
+		"national_id":   "1234567890123",
+		"gender":        "male",
+		"hospital":      "Bangkok Hospital",
+	}
+	createPatientViaApi(t, r, patientDto, token)
+
+	w2 := httptest.NewRecorder()
+	req2, _ := http.NewRequest("GET", "/patient/export?format=ndjson", nil)
+	req2.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	r.ServeHTTP(w2, req2)
+
+	var resource map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w2.Body.Bytes(), &resource))
+	assert.Equal(t, "application/fhir+ndjson", w2.Header().Get("Content-Type"))
+	assert.Equal(t, "Patient", resource["resourceType"])
+	assert.Contains(t, w2.Body.String(), "1234567890123")
+}
+
+func TestPatient_CreatePatient_EncryptsIdentifiersAtRest(t *testing.T) {
+	eachDatabase(t, func(t *testing.T, r *gin.Engine, db *gorm.DB) {
+		createStaffDto := entities.Staff{
+			Username: "walawala",
+			Password: "89058905",
+			Hospital: "Bangkok Hospital",
+		}
+		token := createLoginStaffViaApi(t, r, createStaffDto)
+
+		patientDto := map[string]string{
+			"first_name_en": "Somsak",
+			"last_name_en":  "Chunsri",
+			"date_of_birth": "1995-07-21T00:00:00Z",
+			"national_id":   "1234567890123",
+			"phone_number":  "081-234-5678",
+			"email":         "Somsak@Example.com",
+			"gender":        "male",
+			"hospital":      "Bangkok Hospital",
+		}
+		createPatientViaApi(t, r, patientDto, token)
+
+		var raw struct {
+			NationalId  string
+			PhoneNumber string
+			Email       string
+		}
+		assert.NoError(t, db.Table("patients").Select("national_id, phone_number, email").Take(&raw).Error)
+		assert.True(t, strings.HasPrefix(raw.NationalId, "enc:v2:"))
+		assert.NotContains(t, raw.PhoneNumber, "5678")
+		assert.NotContains(t, raw.Email, "Example")
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/patient/search?phone_number=0812345678&email=somsak@example.com", nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		r.ServeHTTP(w, req)
+
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+
+		dataArray := response["data"].([]interface{})
+		assert.Equal(t, 1, len(dataArray))
+		assert.Equal(t, "1-2345-XXXXX-12-3", dataArray[0].(map[string]interface{})["national_id"])
+
+		// A ciphertext moved to another column no longer opens.
+		assert.NoError(t, db.Table("patients").Where("1 = 1").Update("email", raw.NationalId).Error)
+		var moved entities.Patient
+		assert.ErrorContains(t, db.Take(&moved).Error, "decrypt Email")
+	})
+}
+
+// loginStaffWithRoleViaApi creates the account as the staff command does,
+// since sign-up through the API only makes receptionists, then logs in.
+func loginStaffWithRoleViaApi(t *testing.T, r *gin.Engine, staff usecasesStaff.StaffRepository, username string, hospital string, role string) string {
+	_, err := usecasesStaff.NewStaffService(staff).CreateStaff(context.Background(), &entities.Staff{Username: username, Password: "89058905", Hospital: hospital, Role: role})
+	assert.NoError(t, err)
+	jsonBody, _ := json.Marshal(map[string]string{"username": username, "password": "89058905"})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/staff/login", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	var login map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &login)
+	return login["token"].(string)
+}
+
+func TestPatient_SearchPatient_MasksByRoleAndRevealIsAudited(t *testing.T) {
+	r, repos := setupTestRouter()
+
+	receptionist := loginStaffWithRoleViaApi(t, r, repos.Staff, "front", "Bangkok Hospital", "receptionist")
+	clinician := loginStaffWithRoleViaApi(t, r, repos.Staff, "doctor", "Bangkok Hospital", "clinician")
+
+	patientDto := map[string]string{
+		"first_name_en": "Somsak",
+		"last_name_en":  "Chunsri",
+		"national_id":   "1234567890123",
+		"phone_number":  "0812345678",
+		"gender":        "male",
+		"hospital":      "Bangkok Hospital",
+	}
+	createPatientViaApi(t, r, patientDto, clinician)
+
+	search := func(token string) map[string]interface{} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/patient/search?first_name=Somsak", nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		r.ServeHTTP(w, req)
+
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response["data"].([]interface{})[0].(map[string]interface{})
+	}
+
+	masked := search(receptionist)
+	assert.Equal(t, "1-2345-XXXXX-12-3", masked["national_id"])
+	assert.Equal(t, "081XXXXX78", masked["phone_number"])
+	assert.Equal(t, "1234567890123", search(clinician)["national_id"])
+
+	reveal := func(body map[string]string) *httptest.ResponseRecorder {
+		jsonBody, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", fmt.Sprintf("/patient/reveal/%v", masked["ID"]), bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", receptionist))
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusBadRequest, reveal(map[string]string{"field": "national_id"}).Code)
+	assert.Equal(t, http.StatusBadRequest, reveal(map[string]string{"field": "first_name_en", "reason": "check-in"}).Code)
+
+	w := reveal(map[string]string{"field": "national_id", "reason": "check-in"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "1234567890123", response["data"].(map[string]interface{})["value"])
+
+	entries := auditEntries(t, repos, func(entry *entities.AuditLog) bool { return entry.Action == "patient.reveal" })
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "front", entries[0].Actor)
+	assert.Contains(t, entries[0].Detail, "check-in")
+	assert.NotContains(t, entries[0].Detail, "1234567890123")
+}
+
+func postJsonViaApi(r *gin.Engine, path string, body interface{}, token string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	jsonBody, _ := json.Marshal(body)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", path, bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	r.ServeHTTP(w, req)
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w, response
+}
+
+func TestConsent_GrantWithdrawAndExportByPurpose(t *testing.T) {
+	r, repos := setupTestRouter()
+
+	token := loginStaffWithRoleViaApi(t, r, repos.Staff, "doctor", "Bangkok Hospital", "clinician")
+
+	createPatientViaApi(t, r, map[string]string{
+		"first_name_en": "Somsak",
+		"national_id":   "1234567890123",
+		"gender":        "male",
+		"hospital":      "Bangkok Hospital",
+	}, token)
+	createPatientViaApi(t, r, map[string]string{
+		"first_name_en": "Somchai",
+		"national_id":   "1234567890124",
+		"gender":        "male",
+		"hospital":      "Bangkok Hospital",
+	}, token)
+
+	consenting, other := findPatient(t, repos, "Somsak"), findPatient(t, repos, "Somchai")
+
+	w, _ := postJsonViaApi(r, "/consent/grant", map[string]interface{}{"patient_id": consenting.ID, "purpose": "research", "channel": "paper"}, token)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w, response := postJsonViaApi(r, "/consent/grant", map[string]interface{}{"patient_id": consenting.ID, "purpose": "research", "version": "v1", "channel": "paper"}, token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "v1", response["data"].(map[string]interface{})["version"])
+
+	export := func(purpose string) string {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/patient/export?format=csv&purpose="+purpose, nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		return w.Body.String()
+	}
+
+	body := export("research")
+	assert.Contains(t, body, "Somsak")
+	assert.NotContains(t, body, "Somchai")
+
+	// Without a purpose the export is not filtered by consent.
+	body = export("")
+	assert.Contains(t, body, "Somsak")
+	assert.Contains(t, body, "Somchai")
+
+	w, _ = postJsonViaApi(r, "/consent/withdraw", map[string]interface{}{"patient_id": consenting.ID, "purpose": "research", "channel": "web"}, token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w, _ = postJsonViaApi(r, "/consent/withdraw", map[string]interface{}{"patient_id": consenting.ID, "purpose": "research", "channel": "web"}, token)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.NotContains(t, export("research"), "Somsak")
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/consent/%d", consenting.ID), nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	r.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	consents := response["data"].([]interface{})
+	assert.Equal(t, 1, len(consents))
+	assert.NotNil(t, consents[0].(map[string]interface{})["withdrawn_at"])
+
+	recorded := auditEntries(t, repos, func(entry *entities.AuditLog) bool { return entry.Resource == "consent" })
+	assert.Equal(t, 2, len(recorded))
+	assert.Equal(t, "consent.grant", recorded[0].Action)
+	assert.Equal(t, "consent.withdraw", recorded[1].Action)
+	assert.Equal(t, "doctor", recorded[1].Actor)
+
+	outsider := loginStaffWithRoleViaApi(t, r, repos.Staff, "nurse", "Siriraj Hospital", "clinician")
+	w, _ = postJsonViaApi(r, "/consent/grant", map[string]interface{}{"patient_id": other.ID, "purpose": "research", "version": "v1", "channel": "paper"}, outsider)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestDsar_AccessAndErasureWithLegalHold(t *testing.T) {
+	r, repos := setupTestRouter()
+
+	front := loginStaffWithRoleViaApi(t, r, repos.Staff, "front", "Bangkok Hospital", "receptionist")
+	admin := loginStaffWithRoleViaApi(t, r, repos.Staff, "boss", "Bangkok Hospital", "admin")
+
+	createPatientViaApi(t, r, map[string]string{
+		"first_name_en": "Somsak",
+		"national_id":   "1234567890123",
+		"email":         "somsak@example.com",
+		"gender":        "male",
+		"hospital":      "Bangkok Hospital",
+	}, front)
+	patient := findPatient(t, repos, "Somsak")
+
+	w, _ := postJsonViaApi(r, "/consent/grant", map[string]interface{}{"patient_id": patient.ID, "purpose": "research", "version": "v1", "channel": "paper"}, front)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w, response := postJsonViaApi(r, "/dsar", map[string]string{"type": "access", "identifier": "1234567890123"}, front)
+	assert.Equal(t, http.StatusOK, w.Code)
+	accessId := response["data"].(map[string]interface{})["ID"]
+
+	w, _ = postJsonViaApi(r, fmt.Sprintf("/dsar/%v/approve", accessId), nil, front)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w, _ = postJsonViaApi(r, fmt.Sprintf("/dsar/%v/approve", accessId), nil, admin)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/dsar/%v/export", accessId), nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", front))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/dsar/%v/export", accessId), nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", admin))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var subject map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &subject)
+	assert.Equal(t, "somsak@example.com", subject["patient"].(map[string]interface{})["email"])
+	assert.Equal(t, 1, len(subject["consents"].([]interface{})))
+
+	w, _ = postJsonViaApi(r, "/dsar", map[string]string{"type": "erasure", "identifier": "1234567890123"}, front)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w, response = postJsonViaApi(r, "/dsar", map[string]string{"type": "erasure", "identifier": "1234567890123", "erasure_mode": "delete"}, front)
+	assert.Equal(t, http.StatusOK, w.Code)
+	erasureId := response["data"].(map[string]interface{})["ID"]
+	postJsonViaApi(r, fmt.Sprintf("/dsar/%v/approve", erasureId), nil, admin)
+
+	hold := func(body map[string]interface{}) {
+		jsonBody, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", fmt.Sprintf("/legal-hold/%d", patient.ID), bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", admin))
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	hold(map[string]interface{}{"hold": true, "reason": "pending litigation"})
+
+	w, response = postJsonViaApi(r, fmt.Sprintf("/dsar/%v/erase", erasureId), nil, admin)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "on_hold", response["data"].(map[string]interface{})["status"])
+	_, err := repos.Patients.FindById(context.Background(), patient.ID)
+	assert.NoError(t, err)
+
+	hold(map[string]interface{}{"hold": false})
+
+	w, response = postJsonViaApi(r, fmt.Sprintf("/dsar/%v/erase", erasureId), nil, admin)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "completed", response["data"].(map[string]interface{})["status"])
+	assert.Empty(t, repos.patients.Unscoped())
+
+	consents, err := repos.Consents.FindByPatient(context.Background(), patient.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, consents)
+
+	var actions []string
+	for _, entry := range auditEntries(t, repos, func(entry *entities.AuditLog) bool { return entry.Resource == "data_subject_request" }) {
+		actions = append(actions, entry.Action)
+	}
+	assert.Equal(t, []string{"dsar.create", "dsar.approve", "dsar.export", "dsar.create", "dsar.approve", "dsar.erase.blocked", "dsar.erase"}, actions)
+}
+
+func TestRetention_DryRunThenPurgeKeepsLegalHold(t *testing.T) {
+	r, repos := setupTestRouter()
+
+	admin := loginStaffWithRoleViaApi(t, r, repos.Staff, "boss", "Bangkok Hospital", "admin")
+
+	for _, nationalId := range []string{"1234567890123", "1234567890124", "1234567890125"} {
+		createPatientViaApi(t, r, map[string]string{
+			"first_name_en": "Somsak",
+			"national_id":   nationalId,
+			"gender":        "male",
+			"hospital":      "Bangkok Hospital",
+		}, admin)
+	}
+	patients := repos.patients.Unscoped()
+	old := gorm.DeletedAt{Time: time.Now().AddDate(0, 0, -40), Valid: true}
+	patients[0].DeletedAt = old
+	patients[1].DeletedAt, patients[1].LegalHold = old, true
+	repos.patients.Put(patients[0])
+	repos.patients.Put(patients[1])
+
+	w, _ := postJsonViaApi(r, "/retention/policies", map[string]interface{}{"entity": "patient", "action": "delete", "after_days": 30, "enabled": true}, admin)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w, _ = postJsonViaApi(r, "/retention/policies", map[string]interface{}{"entity": "audit_log", "action": "anonymize", "after_days": 30, "enabled": true}, admin)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w, response := postJsonViaApi(r, "/retention/run?dry_run=true", nil, admin)
+	assert.Equal(t, http.StatusOK, w.Code)
+	run := response["data"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, true, run["dry_run"])
+	assert.Equal(t, float64(1), run["matched"])
+	assert.Equal(t, float64(0), run["processed"])
+
+	assert.Equal(t, 3, len(repos.patients.Unscoped()))
+
+	w, response = postJsonViaApi(r, "/retention/run", nil, admin)
+	assert.Equal(t, http.StatusOK, w.Code)
+	run = response["data"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, float64(1), run["processed"])
+
+	var remaining []uint
+	for _, p := range repos.patients.Unscoped() {
+		remaining = append(remaining, p.ID)
+	}
+	assert.Equal(t, []uint{patients[1].ID, patients[2].ID}, remaining)
+
+	receptionist := loginStaffWithRoleViaApi(t, r, repos.Staff, "front", "Bangkok Hospital", "receptionist")
+	w, _ = postJsonViaApi(r, "/retention/run", nil, receptionist)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func getViaApi(r *gin.Engine, path string, token string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", path, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	r.ServeHTTP(w, req)
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w, response
+}
+
+func TestEmergencyAccess_BreakTheGlassIsScopedAndReported(t *testing.T) {
+	r, repos := setupTestRouter()
+
+	owner := loginStaffWithRoleViaApi(t, r, repos.Staff, "siriraj-doctor", "Siriraj Hospital", "clinician")
+	ownerAdmin := loginStaffWithRoleViaApi(t, r, repos.Staff, "siriraj-admin", "Siriraj Hospital", "admin")
+	doctor := loginStaffWithRoleViaApi(t, r, repos.Staff, "er-doctor", "Bangkok Hospital", "clinician")
+	front := loginStaffWithRoleViaApi(t, r, repos.Staff, "front", "Bangkok Hospital", "receptionist")
+
+	createPatientViaApi(t, r, map[string]string{
+		"first_name_en": "Somsak",
+		"national_id":   "1234567890123",
+		"passport_id":   "AA1234567",
+		"gender":        "male",
+		"hospital":      "Siriraj Hospital",
+	}, owner)
+	// Passports are only unique within a hospital.
+	createPatientViaApi(t, r, map[string]string{
+		"first_name_en": "Somchai",
+		"national_id":   "1234567890124",
+		"passport_id":   "AA1234567",
+		"gender":        "male",
+		"hospital":      "Bangkok Hospital",
+	}, doctor)
+
+	w, _ := getViaApi(r, "/patient/search/1234567890123", doctor)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, response := getViaApi(r, "/patient/search/AA1234567", doctor)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Somchai", response["data"].(map[string]interface{})["first_name_en"])
+
+	w, _ = postJsonViaApi(r, "/patient/emergency-access", map[string]string{"identifier": "1234567890123", "hospital": "Siriraj Hospital", "reason": "unconscious patient in ER"}, front)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w, _ = postJsonViaApi(r, "/patient/emergency-access", map[string]string{"identifier": "1234567890123", "hospital": "Siriraj Hospital"}, doctor)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = postJsonViaApi(r, "/patient/emergency-access", map[string]string{"identifier": "AA1234567", "hospital": "Bangkok Hospital", "reason": "unconscious patient in ER"}, doctor)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w, response = postJsonViaApi(r, "/patient/emergency-access", map[string]string{"identifier": "AA1234567", "hospital": "Siriraj Hospital", "reason": "unconscious patient in ER"}, doctor)
+	assert.Equal(t, http.StatusOK, w.Code)
+	emergencyToken := response["token"].(string)
+	accessId := response["data"].(map[string]interface{})["ID"]
+
+	w, response = getViaApi(r, "/patient/search/1234567890123", emergencyToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotNil(t, response["emergency_access"])
+	assert.Equal(t, "1234567890123", response["data"].(map[string]interface{})["national_id"])
+
+	// The caller's own patient still comes first for a shared passport.
+	w, response = getViaApi(r, "/patient/search/AA1234567", emergencyToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Somchai", response["data"].(map[string]interface{})["first_name_en"])
+	assert.Nil(t, response["emergency_access"])
+
+	var flagged []string
+	for _, entry := range auditEntries(t, repos, func(entry *entities.AuditLog) bool { return entry.Flagged && entry.Hospital == "Siriraj Hospital" }) {
+		flagged = append(flagged, entry.Action)
+	}
+	assert.Equal(t, []string{"patient.break_glass", "patient.break_glass.read"}, flagged)
+
+	access, err := repos.Emergency.FindAccess(context.Background(), uint(accessId.(float64)))
+	assert.NoError(t, err)
+	access.ExpiresAt = time.Now().Add(-time.Minute)
+	_, err = repos.Emergency.SaveAccess(context.Background(), access)
+	assert.NoError(t, err)
+	w, _ = getViaApi(r, "/patient/search/1234567890123", emergencyToken)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w, response = getViaApi(r, "/patient/emergency-access?status=pending", ownerAdmin)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, len(response["data"].([]interface{})))
+
+	w, _ = postJsonViaApi(r, fmt.Sprintf("/patient/emergency-access/%v/review", accessId), map[string]string{"status": "justified", "note": "confirmed with ER log"}, ownerAdmin)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w, response = getViaApi(r, "/patient/emergency-access?status=pending", ownerAdmin)
+	assert.Equal(t, 0, len(response["data"].([]interface{})))
+}
+
+func TestSharing_PartnerSearchFollowsAgreementAndConsent(t *testing.T) {
+	r, repos := setupTestRouter()
+
+	owner := loginStaffWithRoleViaApi(t, r, repos.Staff, "siriraj-doctor", "Siriraj Hospital", "clinician")
+	ownerAdmin := loginStaffWithRoleViaApi(t, r, repos.Staff, "siriraj-admin", "Siriraj Hospital", "admin")
+	doctor := loginStaffWithRoleViaApi(t, r, repos.Staff, "bangkok-doctor", "Bangkok Hospital", "clinician")
+	admin := loginStaffWithRoleViaApi(t, r, repos.Staff, "bangkok-admin", "Bangkok Hospital", "admin")
+	front := loginStaffWithRoleViaApi(t, r, repos.Staff, "front", "Bangkok Hospital", "receptionist")
+
+	createPatientViaApi(t, r, map[string]string{
+		"first_name_en": "Somsak",
+		"national_id":   "1234567890123",
+		"phone_number":  "0812345678",
+		"gender":        "male",
+		"hospital":      "Siriraj Hospital",
+	}, owner)
+	createPatientViaApi(t, r, map[string]string{
+		"first_name_en": "Somchai",
+		"national_id":   "1234567890124",
+		"gender":        "male",
+		"hospital":      "Siriraj Hospital",
+	}, owner)
+
+	consenting := findPatient(t, repos, "Somsak")
+	w, _ := postJsonViaApi(r, "/consent/grant", map[string]interface{}{"patient_id": consenting.ID, "purpose": "data_sharing", "version": "v1", "channel": "paper"}, owner)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	partners := func(token string) []interface{} {
+		w, response := getViaApi(r, "/patient/search", token)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 0, len(response["data"].([]interface{})))
+		return response["partner_data"].([]interface{})
+	}
+	assert.Equal(t, 0, len(partners(doctor)))
+
+	agreement := map[string]interface{}{
+		"partner_hospital": "Siriraj Hospital",
+		"direction":        "inbound",
+		"fields":           map[string]string{"national_id": "mask", "phone_number": "omit"},
+		"roles":            []string{"clinician"},
+	}
+	w, _ = postJsonViaApi(r, "/sharing/agreements", agreement, doctor)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w, _ = postJsonViaApi(r, "/sharing/agreements", map[string]interface{}{"partner_hospital": "Siriraj Hospital", "direction": "inbound", "fields": map[string]string{"first_name": "show"}, "roles": []string{"clinician"}}, admin)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w, response := postJsonViaApi(r, "/sharing/agreements", agreement, admin)
+	assert.Equal(t, http.StatusOK, w.Code)
+	agreementId := response["data"].(map[string]interface{})["ID"]
+	assert.Equal(t, 0, len(partners(doctor)))
+
+	w, _ = postJsonViaApi(r, fmt.Sprintf("/sharing/agreements/%v/accept", agreementId), nil, admin)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = postJsonViaApi(r, fmt.Sprintf("/sharing/agreements/%v/accept", agreementId), nil, ownerAdmin)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	results := partners(doctor)
+	assert.Equal(t, 1, len(results))
+	result := results[0].(map[string]interface{})
+	assert.Equal(t, "Siriraj Hospital", result["source_hospital"])
+	assert.Equal(t, "1-2345-XXXXX-12-3", result["patient"].(map[string]interface{})["national_id"])
+	assert.Equal(t, "", result["patient"].(map[string]interface{})["phone_number"])
+	assert.Equal(t, 0, len(partners(front)))
+
+	audited := auditEntries(t, repos, func(entry *entities.AuditLog) bool {
+		return entry.Action == "patient.partner_search" && entry.Hospital == "Siriraj Hospital"
+	})
+	assert.Equal(t, 1, len(audited))
+
+	w, _ = postJsonViaApi(r, fmt.Sprintf("/sharing/agreements/%v/revoke", agreementId), nil, ownerAdmin)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 0, len(partners(doctor)))
+}
+
+func TestRequestID_EchoesOrGenerates(t *testing.T) {
+	r, _ := setupTestRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/patient/search", nil)
+	req.Header.Set("X-Request-ID", "abc-123")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, "abc-123", w.Header().Get("X-Request-ID"))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/patient/search", nil)
+	req.Header.Set("X-Request-ID", "bad id\ninjected")
+	r.ServeHTTP(w, req)
+	assert.Len(t, w.Header().Get("X-Request-ID"), 32)
+}
+
+func TestMetrics_CountsLoginsAndQueries(t *testing.T) {
+	eachDatabase(t, func(t *testing.T, r *gin.Engine, db *gorm.DB) {
+		success := testutil.ToFloat64(metrics.Logins.WithLabelValues("success"))
+		wrongPassword := testutil.ToFloat64(metrics.Logins.WithLabelValues("wrong_password"))
+
+		loginStaffWithRoleViaApi(t, r, routes.GormRepositories(db).Staff, "counted", "Bangkok Hospital", "clinician")
+		w, _ := postJsonViaApi(r, "/staff/login", map[string]string{"username": "counted", "password": "wrong-password", "hospital": "Bangkok Hospital"}, "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		assert.Equal(t, success+1, testutil.ToFloat64(metrics.Logins.WithLabelValues("success")))
+		assert.Equal(t, wrongPassword+1, testutil.ToFloat64(metrics.Logins.WithLabelValues("wrong_password")))
+		assert.Greater(t, testutil.CollectAndCount(metrics.DbQueries, "agnos_db_query_duration_seconds"), 0)
+	})
+}
+
+func TestTracing_SearchPatientSpansHandlerServiceAndQuery(t *testing.T) {
+	eachDatabase(t, func(t *testing.T, r *gin.Engine, db *gorm.DB) {
+		token := loginStaffWithRoleViaApi(t, r, routes.GormRepositories(db).Staff, "traced", "Bangkok Hospital", "clinician")
+		exporter := tracingtest.SetupInMemory()
+
+		w, _ := getViaApi(r, "/patient/search?national_id=1234567890123", token)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		spans := make(map[string]tracetest.SpanStub)
+		var queries []tracetest.SpanStub
+		for _, span := range exporter.GetSpans() {
+			spans[span.Name] = span
+			if span.Name == "gorm.query" {
+				queries = append(queries, span)
+			}
+		}
+		server, service := spans["GET /patient/search"], spans["PatientService.SearchPatient"]
+		assert.Equal(t, server.SpanContext.SpanID(), service.Parent.SpanID())
+
+		var searched bool
+		for _, query := range queries {
+			if query.Parent.SpanID() != service.SpanContext.SpanID() {
+				continue
+			}
+			searched = true
+			for _, attr := range query.Attributes {
+				if attr.Key == "db.statement" {
+					assert.Contains(t, attr.Value.AsString(), "national_id_index")
+					assert.NotContains(t, attr.Value.AsString(), "1234567890123")
+				}
+			}
+		}
+		assert.True(t, searched)
+	})
+}
+
+func TestHealth_ProbesAndAdminReport(t *testing.T) {
+	eachDatabase(t, func(t *testing.T, r *gin.Engine, db *gorm.DB) {
+		w, response := getViaApi(r, "/healthz", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "up", response["status"])
+
+		w, response = getViaApi(r, "/readyz", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, map[string]interface{}{"database": "up", "schema": "up"}, response["checks"])
+
+		w, _ = getViaApi(r, "/health", "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		clinician := loginStaffWithRoleViaApi(t, r, routes.GormRepositories(db).Staff, "health-doctor", "Bangkok Hospital", "clinician")
+		w, _ = getViaApi(r, "/health", clinician)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		admin := loginStaffWithRoleViaApi(t, r, routes.GormRepositories(db).Staff, "health-admin", "Bangkok Hospital", "admin")
+		w, response = getViaApi(r, "/health", admin)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotNil(t, response["database_pool"])
+		assert.Equal(t, "up", response["checks"].(map[string]interface{})["database"].(map[string]interface{})["status"])
+
+		// Forgetting the latest migration makes the schema look behind.
+		var latest struct {
+			Version   int64
+			Name      string
+			AppliedAt time.Time
+		}
+		db.Table(migrate.Table).Order("version DESC").First(&latest)
+		db.Exec("DELETE FROM "+migrate.Table+" WHERE version = ?", latest.Version)
+		defer db.Table(migrate.Table).Create(&latest)
+		w, response = getViaApi(r, "/readyz", "")
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, "down", response["checks"].(map[string]interface{})["schema"])
+		assert.Nil(t, response["error"])
+	})
+}
+
+func TestStaff_ResetPasswordAndDeactivate(t *testing.T) {
+	r, repos := setupTestRouter()
+	ctx := context.Background()
+	staffService := usecasesStaff.NewStaffService(repos.Staff)
+
+	loginStaffWithRoleViaApi(t, r, repos.Staff, "night-nurse", "Bangkok Hospital", "clinician")
+
+	assert.NoError(t, staffService.ResetPassword(ctx, "night-nurse", "a-new-secret"))
+	w, _ := postJsonViaApi(r, "/staff/login", map[string]string{"username": "night-nurse", "password": "89058905"}, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = postJsonViaApi(r, "/staff/login", map[string]string{"username": "night-nurse", "password": "a-new-secret"}, "")
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	assert.NoError(t, staffService.DeactivateStaff(ctx, "night-nurse"))
+	w, _ = postJsonViaApi(r, "/staff/login", map[string]string{"username": "night-nurse", "password": "a-new-secret"}, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	assert.Error(t, staffService.DeactivateStaff(ctx, "night-nurse"))
+	assert.Error(t, staffService.ResetPassword(ctx, "nobody", "secret"))
+}
+
+func TestAudit_VerifyDetectsEditedAndDeletedEntries(t *testing.T) {
+	eachDatabase(t, func(t *testing.T, _ *gin.Engine, db *gorm.DB) {
+		auditService := routes.AuditService(db)
+
+		var ids []uint
+		for i, hospital := range []string{"Bangkok Hospital", "Siriraj Hospital", "bangkok hospital", "Bangkok Hospital"} {
+			entry, err := auditService.Record(context.Background(), &entities.AuditLog{Actor: "admin", Hospital: hospital, Action: "patient.reveal", Resource: "patient", ResourceId: fmt.Sprint(i)}, map[string]string{"field": "national_id"})
+			assert.NoError(t, err)
+			ids = append(ids, entry.ID)
+		}
+
+		result, err := auditService.Verify(context.Background())
+		assert.NoError(t, err)
+		assert.True(t, result.Valid())
+		assert.Equal(t, 4, result.Checked)
+
+		// Editing an entry breaks its own hash.
+		db.Model(&entities.AuditLog{}).Where("id = ?", ids[2]).Update("detail", `{"field":"email"}`)
+		result, _ = auditService.Verify(context.Background())
+		assert.Equal(t, 1, len(result.Broken))
+		assert.Equal(t, ids[2], result.Broken[0].Id)
+
+		// Deleting one breaks the link of the next entry of the same hospital.
+		db.Unscoped().Delete(&entities.AuditLog{}, ids[2])
+		result, _ = auditService.Verify(context.Background())
+		assert.Equal(t, 1, len(result.Broken))
+		assert.Equal(t, ids[3], result.Broken[0].Id)
+		assert.Equal(t, "previous entry is missing or changed", result.Broken[0].Reason)
+	})
+}
+
+func TestSynthetic_GeneratedPatientsSaveThroughRepository(t *testing.T) {
+	r, repos := setupTestRouter()
+
+	generator, err := synthetic.NewGenerator(synthetic.Options{Seed: 2024, Hospitals: []string{"Bangkok Hospital", "Siriraj Hospital"}})
+	assert.NoError(t, err)
+	writer := synthetic.NewRepositoryWriter(context.Background(), repos.Patients)
+	patients := generator.Patients(30)
+	for _, patient := range patients {
+		assert.NoError(t, writer.Write(patient))
+	}
+	assert.NoError(t, writer.Close())
+
+	var indexed int
+	for _, p := range repos.patients.Unscoped() {
+		if p.NationalIdIndex != "" {
+			indexed++
+		}
+	}
+	assert.Equal(t, 30, indexed)
+
+	clinician := loginStaffWithRoleViaApi(t, r, repos.Staff, "synthetic-doctor", patients[0].Hospital, "clinician")
+	w, response := getViaApi(r, "/patient/search/"+patients[0].NationalId, clinician)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, patients[0].FirstNameTh, response["data"].(map[string]interface{})["first_name_th"])
+}
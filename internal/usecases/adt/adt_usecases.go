@@ -0,0 +1,216 @@
+package adt
+
+import (
+	"agnos/internal/adapters/patient/dto"
+	"agnos/internal/entities"
+	"agnos/internal/usecases/mpi"
+	"agnos/internal/usecases/patient"
+	"agnos/pkg/hl7"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// hl7Null is the HL7 explicit null: the sender asks for the value to be cleared.
+const hl7Null = `""`
+
+var supportedEvents = map[string]bool{"A04": true, "A08": true, "A28": true, "A31": true}
+
+type AdtUseCase interface {
+	Ingest(ctx context.Context, raw []byte, hospital string) ([]byte, error)
+}
+
+type AdtService struct {
+	patientUseCase patient.PatientUseCase
+	mpiUseCase     mpi.MpiUseCase
+}
+
+func NewAdtService(patientUseCase patient.PatientUseCase, mpiUseCase mpi.MpiUseCase) AdtUseCase {
+	return &AdtService{patientUseCase: patientUseCase, mpiUseCase: mpiUseCase}
+}
+
+// Ingest registers or updates the patient carried in an ADT message and
+// returns the ACK to send back. The error is set whenever the ACK is a NAK.
+// hospital overrides the sending facility when the caller is authenticated.
+func (s *AdtService) Ingest(ctx context.Context, raw []byte, hospital string) ([]byte, error) {
+	message, err := hl7.Parse(raw)
+	if err != nil {
+		return hl7.BuildAck(nil, hl7.AckReject, err.Error()), err
+	}
+
+	if messageType := message.Get("MSH-9.1"); messageType != "ADT" {
+		err := fmt.Errorf("message type %s is not supported", messageType)
+		return hl7.BuildAck(message, hl7.AckReject, err.Error()), err
+	}
+	if event := message.Get("MSH-9.2"); !supportedEvents[event] {
+		err := fmt.Errorf("event %s is not supported", event)
+		return hl7.BuildAck(message, hl7.AckReject, err.Error()), err
+	}
+
+	if _, err := s.upsertPatient(ctx, message, hospital); err != nil {
+		return hl7.BuildAck(message, hl7.AckError, err.Error()), err
+	}
+	return hl7.BuildAck(message, hl7.AckAccept, ""), nil
+}
+
+func (s *AdtService) upsertPatient(ctx context.Context, message *hl7.Message, hospital string) (*entities.Patient, error) {
+	if message.Segment("PID") == nil {
+		return nil, fmt.Errorf("PID segment is required")
+	}
+
+	if hospital == "" {
+		hospital = message.Get("PV1-3.4")
+	}
+	if hospital == "" {
+		hospital = message.Get("MSH-4.1")
+	}
+	if hospital == "" {
+		return nil, fmt.Errorf("hospital is required")
+	}
+
+	incoming := &entities.Patient{}
+	if err := applyPid(incoming, message); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.findExisting(ctx, incoming, hospital)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing != nil {
+		if err := applyPid(existing, message); err != nil {
+			return nil, err
+		}
+		return s.patientUseCase.UpdatePatient(ctx, existing)
+	}
+
+	incoming.Hospital = hospital
+	if incoming.NationalId == "" {
+		return nil, fmt.Errorf("national_id is required")
+	}
+	if incoming.Gender == "" {
+		return nil, fmt.Errorf("gender is required")
+	}
+
+	created, err := s.patientUseCase.CreatePatient(ctx, incoming)
+	if err != nil {
+		return nil, err
+	}
+	s.mpiUseCase.DetectDuplicates(ctx, created)
+	return created, nil
+}
+
+func (s *AdtService) findExisting(ctx context.Context, incoming *entities.Patient, hospital string) (*entities.Patient, error) {
+	for _, identifier := range []string{incoming.NationalId, incoming.PassportId, incoming.PatientHn} {
+		if identifier == "" {
+			continue
+		}
+		patients, err := s.patientUseCase.SearchPatient(ctx, &dto.SearchPatientDto{Identifier: identifier, Hospital: hospital})
+		if err != nil {
+			return nil, err
+		}
+		if len(patients) > 0 {
+			return patients[0], nil
+		}
+	}
+	return nil, nil
+}
+
+// applyPid copies the PID fields present in message onto patient. Empty
+// fields are left unchanged and explicit nulls clear the value.
+func applyPid(patient *entities.Patient, message *hl7.Message) error {
+	pid := message.Segment("PID")
+
+	for _, rep := range message.Repetitions(pid, 3) {
+		value := rep.Component(1, 1)
+		switch strings.ToUpper(rep.Component(5, 1)) {
+		case "NI", "CZ", "NNTHA":
+			set(&patient.NationalId, value)
+		case "PPN":
+			set(&patient.PassportId, value)
+		case "MR", "PI", "PT":
+			set(&patient.PatientHn, value)
+		}
+	}
+
+	for _, rep := range message.Repetitions(pid, 5) {
+		family, given, middle := rep.Component(1, 1), rep.Component(2, 1), rep.Component(3, 1)
+		if isThai(family + given + middle) {
+			set(&patient.LastNameTh, family)
+			set(&patient.FirstNameTh, given)
+			set(&patient.MiddleNameTh, middle)
+		} else {
+			set(&patient.LastNameEn, family)
+			set(&patient.FirstNameEn, given)
+			set(&patient.MiddleNameEn, middle)
+		}
+	}
+
+	if birth := message.Get("PID-7"); birth == hl7Null {
+		patient.DateBirth = time.Time{}
+	} else if birth != "" {
+		if len(birth) < 8 {
+			return fmt.Errorf("date of birth %s is invalid", birth)
+		}
+		date, err := time.Parse("20060102", birth[:8])
+		if err != nil {
+			return fmt.Errorf("date of birth %s is invalid", birth)
+		}
+		patient.DateBirth = date
+	}
+
+	switch sex := message.Get("PID-8"); sex {
+	case "":
+	case "M":
+		patient.Gender = "male"
+	case "F":
+		patient.Gender = "female"
+	default:
+		return fmt.Errorf("administrative sex %s is not supported", sex)
+	}
+
+	phoneSet := false
+	for _, field := range []int{13, 14} {
+		for _, rep := range message.Repetitions(pid, field) {
+			if rep.Component(2, 1) == "NET" || strings.EqualFold(rep.Component(3, 1), "Internet") {
+				email := rep.Component(4, 1)
+				if email == "" {
+					email = rep.Component(1, 1)
+				}
+				set(&patient.Email, email)
+				continue
+			}
+			number := rep.Component(12, 1)
+			if number == "" {
+				number = rep.Component(1, 1)
+			}
+			if !phoneSet && number != "" {
+				set(&patient.PhoneNumber, number)
+				phoneSet = true
+			}
+		}
+	}
+	return nil
+}
+
+func set(target *string, value string) {
+	switch value {
+	case "":
+	case hl7Null:
+		*target = ""
+	default:
+		*target = value
+	}
+}
+
+func isThai(value string) bool {
+	for _, r := range value {
+		if unicode.Is(unicode.Thai, r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,11 @@
+package audit
+
+import (
+	"agnos/internal/entities"
+	"context"
+)
+
+type AuditRepository interface {
+	Save(ctx context.Context, entry *entities.AuditLog) (*entities.AuditLog, error)
+	FindInBatches(ctx context.Context, batchSize int, fn func(entries []*entities.AuditLog) error) error
+}
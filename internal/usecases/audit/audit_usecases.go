@@ -0,0 +1,81 @@
+package audit
+
+import (
+	"agnos/internal/entities"
+	"context"
+	"encoding/json"
+)
+
+const verifyBatchSize = 1000
+
+type AuditUseCase interface {
+	Record(ctx context.Context, entry *entities.AuditLog, detail interface{}) (*entities.AuditLog, error)
+	Verify(ctx context.Context) (*VerifyResult, error)
+}
+
+// BrokenLink is an entry whose hash or link to the previous entry of its
+// hospital does not match.
+type BrokenLink struct {
+	Id       uint   `json:"id"`
+	Hospital string `json:"hospital"`
+	Reason   string `json:"reason"`
+}
+
+type VerifyResult struct {
+	Checked  int          `json:"checked"`
+	Unsealed int          `json:"unsealed"`
+	Broken   []BrokenLink `json:"broken"`
+}
+
+func (r *VerifyResult) Valid() bool {
+	return len(r.Broken) == 0
+}
+
+type AuditService struct {
+	repo AuditRepository
+}
+
+func NewAuditService(repo AuditRepository) AuditUseCase {
+	return &AuditService{repo: repo}
+}
+
+// Record stores entry with detail encoded as JSON.
+func (s *AuditService) Record(ctx context.Context, entry *entities.AuditLog, detail interface{}) (*entities.AuditLog, error) {
+	if detail != nil {
+		encoded, err := json.Marshal(detail)
+		if err != nil {
+			return nil, err
+		}
+		entry.Detail = string(encoded)
+	}
+	return s.repo.Save(ctx, entry)
+}
+
+// Verify walks every hospital's chain in order. Entries written before the
+// chain existed are counted as unsealed and skipped. The first entry of a
+// chain is not linked backwards, since retention may have purged what came
+// before it; a deleted tail cannot be detected.
+func (s *AuditService) Verify(ctx context.Context) (*VerifyResult, error) {
+	result := &VerifyResult{Broken: make([]BrokenLink, 0)}
+	last := map[string]string{}
+	err := s.repo.FindInBatches(ctx, verifyBatchSize, func(entries []*entities.AuditLog) error {
+		for _, entry := range entries {
+			if entry.Hash == "" {
+				result.Unsealed++
+				continue
+			}
+			result.Checked++
+			if entry.ComputeHash() != entry.Hash {
+				result.Broken = append(result.Broken, BrokenLink{Id: entry.ID, Hospital: entry.Hospital, Reason: "content does not match its hash"})
+			} else if prev, ok := last[entry.ChainKey()]; ok && entry.PrevHash != prev {
+				result.Broken = append(result.Broken, BrokenLink{Id: entry.ID, Hospital: entry.Hospital, Reason: "previous entry is missing or changed"})
+			}
+			last[entry.ChainKey()] = entry.Hash
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
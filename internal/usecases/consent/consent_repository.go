@@ -0,0 +1,28 @@
+package consent
+
+import (
+	"agnos/internal/entities"
+	"agnos/internal/usecases/audit"
+	"context"
+)
+
+type ConsentRepository interface {
+	Save(ctx context.Context, consent *entities.Consent) (*entities.Consent, error)
+	FindActive(ctx context.Context, patientId uint, purpose string) (*entities.Consent, error)
+	FindByPatient(ctx context.Context, patientId uint) ([]*entities.Consent, error)
+	ConsentedPatientIds(ctx context.Context, patientIds []uint, purpose string) ([]uint, error)
+	FindPatient(ctx context.Context, id uint) (*entities.Patient, error)
+}
+
+// Repositories are what a consent change is written through, bound to one
+// unit of work.
+type Repositories interface {
+	Consents() ConsentRepository
+	Audit() audit.AuditRepository
+}
+
+// UnitOfWork is unitofwork.UnitOfWork as the consent changes see it; see
+// unitofwork.Scope.
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) error
+}
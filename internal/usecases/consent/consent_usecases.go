@@ -0,0 +1,167 @@
+package consent
+
+import (
+	"agnos/internal/adapters/consent/dto"
+	"agnos/internal/entities"
+	"agnos/internal/usecases/audit"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrConsentRequired = errors.New("patient has not consented to this purpose")
+
+type ConsentUseCase interface {
+	GrantConsent(ctx context.Context, data *dto.GrantConsentDto) (*entities.Consent, error)
+	WithdrawConsent(ctx context.Context, data *dto.WithdrawConsentDto) (*entities.Consent, error)
+	ListConsents(ctx context.Context, hospital string, patientId uint) ([]*entities.Consent, error)
+	CheckConsent(ctx context.Context, patientId uint, purpose string) error
+	FilterConsented(ctx context.Context, patientIds []uint, purpose string) (map[uint]bool, error)
+}
+
+type ConsentService struct {
+	repo ConsentRepository
+	uow  UnitOfWork
+}
+
+func NewConsentService(repo ConsentRepository, uow UnitOfWork) ConsentUseCase {
+	return &ConsentService{repo: repo, uow: uow}
+}
+
+// GrantConsent records a new consent. An active consent for the same
+// purpose is closed first, in the same unit of work as the new one and its
+// audit entry, so that only one is active at a time and the version history
+// is kept.
+func (s *ConsentService) GrantConsent(ctx context.Context, data *dto.GrantConsentDto) (*entities.Consent, error) {
+	if _, err := s.findPatient(ctx, data.Hospital, data.PatientId); err != nil {
+		return nil, err
+	}
+
+	var granted *entities.Consent
+	err := s.uow.Do(ctx, func(ctx context.Context, repos Repositories) error {
+		now := time.Now()
+		active, err := repos.Consents().FindActive(ctx, data.PatientId, data.Purpose)
+		if err != nil {
+			return err
+		}
+		if active != nil {
+			active.WithdrawnAt = &now
+			active.WithdrawalChannel = data.Channel
+			if _, err := repos.Consents().Save(ctx, active); err != nil {
+				return err
+			}
+		}
+
+		granted, err = repos.Consents().Save(ctx, &entities.Consent{
+			PatientID:  data.PatientId,
+			Purpose:    data.Purpose,
+			Version:    data.Version,
+			Channel:    data.Channel,
+			GrantedAt:  now,
+			Hospital:   data.Hospital,
+			RecordedBy: data.RecordedBy,
+		})
+		if err != nil {
+			return err
+		}
+		return record(ctx, repos, granted, data.RecordedBy, data.Role, "consent.grant")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return granted, nil
+}
+
+func (s *ConsentService) WithdrawConsent(ctx context.Context, data *dto.WithdrawConsentDto) (*entities.Consent, error) {
+	if _, err := s.findPatient(ctx, data.Hospital, data.PatientId); err != nil {
+		return nil, err
+	}
+
+	var withdrawn *entities.Consent
+	err := s.uow.Do(ctx, func(ctx context.Context, repos Repositories) error {
+		active, err := repos.Consents().FindActive(ctx, data.PatientId, data.Purpose)
+		if err != nil {
+			return err
+		}
+		if active == nil {
+			return fmt.Errorf("no active %s consent", data.Purpose)
+		}
+
+		now := time.Now()
+		active.WithdrawnAt = &now
+		active.WithdrawalChannel = data.Channel
+		if withdrawn, err = repos.Consents().Save(ctx, active); err != nil {
+			return err
+		}
+		return record(ctx, repos, withdrawn, data.RecordedBy, data.Role, "consent.withdraw")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return withdrawn, nil
+}
+
+func (s *ConsentService) ListConsents(ctx context.Context, hospital string, patientId uint) ([]*entities.Consent, error) {
+	if _, err := s.findPatient(ctx, hospital, patientId); err != nil {
+		return nil, err
+	}
+	return s.repo.FindByPatient(ctx, patientId)
+}
+
+// CheckConsent returns ErrConsentRequired unless the patient has an active
+// consent for purpose. Cross-hospital sharing and exports call it before
+// processing a patient's data.
+func (s *ConsentService) CheckConsent(ctx context.Context, patientId uint, purpose string) error {
+	active, err := s.repo.FindActive(ctx, patientId, purpose)
+	if err != nil {
+		return err
+	}
+	if active == nil {
+		return ErrConsentRequired
+	}
+	return nil
+}
+
+// FilterConsented is the batch form of CheckConsent. It returns the ids of
+// the patients that have an active consent for purpose.
+func (s *ConsentService) FilterConsented(ctx context.Context, patientIds []uint, purpose string) (map[uint]bool, error) {
+	consented := make(map[uint]bool, len(patientIds))
+	if len(patientIds) == 0 {
+		return consented, nil
+	}
+
+	ids, err := s.repo.ConsentedPatientIds(ctx, patientIds, purpose)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		consented[id] = true
+	}
+	return consented, nil
+}
+
+func (s *ConsentService) findPatient(ctx context.Context, hospital string, id uint) (*entities.Patient, error) {
+	patient, err := s.repo.FindPatient(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(patient.Hospital, hospital) {
+		return nil, fmt.Errorf("patient not found")
+	}
+	return patient, nil
+}
+
+func record(ctx context.Context, repos Repositories, consent *entities.Consent, actor string, role string, action string) error {
+	_, err := audit.NewAuditService(repos.Audit()).Record(ctx, &entities.AuditLog{
+		Actor:      actor,
+		ActorRole:  role,
+		Hospital:   consent.Hospital,
+		Action:     action,
+		Resource:   "consent",
+		ResourceId: strconv.FormatUint(uint64(consent.ID), 10),
+	}, map[string]interface{}{"patient_id": consent.PatientID, "purpose": consent.Purpose, "version": consent.Version})
+	return err
+}
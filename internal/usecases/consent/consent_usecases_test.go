@@ -0,0 +1,79 @@
+package consent_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	adaptersAudit "agnos/internal/adapters/audit"
+	adaptersConsent "agnos/internal/adapters/consent"
+	"agnos/internal/adapters/consent/dto"
+	adaptersPatient "agnos/internal/adapters/patient"
+	adaptersUnitofwork "agnos/internal/adapters/unitofwork"
+	"agnos/internal/entities"
+	"agnos/internal/usecases/consent"
+	"agnos/internal/usecases/unitofwork"
+	"agnos/pkg/encryption"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingInsert fails to save a new consent, after any update to an
+// existing one has gone through.
+type failingInsert struct {
+	*adaptersConsent.MemoryConsentRepository
+}
+
+func (r failingInsert) Save(ctx context.Context, saved *entities.Consent) (*entities.Consent, error) {
+	if saved.ID == 0 {
+		return nil, errors.New("disk full")
+	}
+	return r.MemoryConsentRepository.Save(ctx, saved)
+}
+
+func newConsentService(patients *adaptersPatient.MemoryPatientRepository, consents consent.ConsentRepository, audit *adaptersAudit.MemoryAuditRepository) consent.ConsentUseCase {
+	uow := adaptersUnitofwork.NewMemoryUnitOfWork(patients, nil, consents, audit, nil, nil)
+	return consent.NewConsentService(consents, unitofwork.Scope(uow, func(repos unitofwork.Repositories) consent.Repositories { return repos }))
+}
+
+func auditActions(t *testing.T, audit *adaptersAudit.MemoryAuditRepository) []string {
+	var actions []string
+	err := audit.FindInBatches(context.Background(), 100, func(entries []*entities.AuditLog) error {
+		for _, entry := range entries {
+			actions = append(actions, entry.Action)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	return actions
+}
+
+func TestConsentService_GrantRollsBackWithdrawalWhenItFails(t *testing.T) {
+	ctx := context.Background()
+	provider, err := encryption.NewLocalProvider("test", map[string][]byte{"test": bytes.Repeat([]byte{1}, 32)}, bytes.Repeat([]byte{2}, 32))
+	require.NoError(t, err)
+	encryption.SetDefault(encryption.NewKeyring(provider, nil))
+
+	patients := adaptersPatient.NewMemoryPatientRepository().(*adaptersPatient.MemoryPatientRepository)
+	patient, err := patients.Save(ctx, &entities.Patient{NationalId: "1234567890123", Hospital: "Bangkok Hospital"})
+	require.NoError(t, err)
+	repo := adaptersConsent.NewMemoryConsentRepository(patients).(*adaptersConsent.MemoryConsentRepository)
+	audit := adaptersAudit.NewMemoryAuditRepository().(*adaptersAudit.MemoryAuditRepository)
+
+	grant := &dto.GrantConsentDto{PatientId: patient.ID, Purpose: entities.ConsentPurposeResearch, Version: "v1", Channel: "paper", Hospital: "Bangkok Hospital"}
+	first, err := newConsentService(patients, repo, audit).GrantConsent(ctx, grant)
+	require.NoError(t, err)
+
+	grant.Version = "v2"
+	_, err = newConsentService(patients, failingInsert{repo}, audit).GrantConsent(ctx, grant)
+	assert.EqualError(t, err, "disk full")
+	assert.Equal(t, []string{"consent.grant"}, auditActions(t, audit))
+
+	active, err := repo.FindActive(ctx, patient.ID, entities.ConsentPurposeResearch)
+	require.NoError(t, err)
+	require.NotNil(t, active)
+	assert.Equal(t, first.ID, active.ID)
+	assert.Nil(t, active.WithdrawnAt)
+}
@@ -0,0 +1,40 @@
+package dsar
+
+import (
+	"agnos/internal/adapters/dsar/dto"
+	"agnos/internal/entities"
+	"agnos/internal/usecases/audit"
+	"context"
+	"errors"
+)
+
+var ErrLegalHold = errors.New("patient is under legal hold")
+
+type DsarRepository interface {
+	SaveRequest(ctx context.Context, request *entities.DataSubjectRequest) (*entities.DataSubjectRequest, error)
+	FindRequest(ctx context.Context, id uint) (*entities.DataSubjectRequest, error)
+	FindRequests(ctx context.Context, hospital string, status string) ([]*entities.DataSubjectRequest, error)
+	FindPatientByIdentifier(ctx context.Context, hospital string, identifier string) (*entities.Patient, error)
+	FindPatient(ctx context.Context, id uint) (*entities.Patient, error)
+	CollectSubjectData(ctx context.Context, patientId uint) (*dto.SubjectData, error)
+	// ErasePatient returns ErrLegalHold, having erased nothing, when the
+	// patient or a record merged into it is under a legal hold. The holds
+	// are read with the rows locked, so that one set meanwhile waits for the
+	// erasure rather than being missed. Its writes only commit together
+	// inside a unit of work.
+	ErasePatient(ctx context.Context, patientId uint, mode string) error
+	SetLegalHold(ctx context.Context, patientId uint, hold bool, reason string) error
+}
+
+// Repositories are what a request and its audit entries are written
+// through, bound to one unit of work.
+type Repositories interface {
+	Dsar() DsarRepository
+	Audit() audit.AuditRepository
+}
+
+// UnitOfWork is unitofwork.UnitOfWork as the requests see it; see
+// unitofwork.Scope.
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) error
+}
@@ -0,0 +1,236 @@
+package dsar
+
+import (
+	"agnos/internal/adapters/dsar/dto"
+	"agnos/internal/entities"
+	"agnos/internal/usecases/audit"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type DsarUseCase interface {
+	CreateRequest(ctx context.Context, data *dto.CreateDsarDto) (*entities.DataSubjectRequest, error)
+	ListRequests(ctx context.Context, hospital string, status string) ([]*entities.DataSubjectRequest, error)
+	GetRequest(ctx context.Context, hospital string, id uint) (*entities.DataSubjectRequest, error)
+	ReviewRequest(ctx context.Context, data *dto.ReviewDsarDto) (*entities.DataSubjectRequest, error)
+	ExportRequest(ctx context.Context, data *dto.ExecuteDsarDto) (*dto.SubjectData, error)
+	EraseRequest(ctx context.Context, data *dto.ExecuteDsarDto) (*entities.DataSubjectRequest, error)
+	SetLegalHold(ctx context.Context, data *dto.LegalHoldDto) (*entities.Patient, error)
+}
+
+type DsarService struct {
+	repo DsarRepository
+	uow  UnitOfWork
+}
+
+func NewDsarService(repo DsarRepository, uow UnitOfWork) DsarUseCase {
+	return &DsarService{repo: repo, uow: uow}
+}
+
+func (s *DsarService) CreateRequest(ctx context.Context, data *dto.CreateDsarDto) (*entities.DataSubjectRequest, error) {
+	patient, err := s.repo.FindPatientByIdentifier(ctx, data.Hospital, data.Identifier)
+	if err != nil {
+		return nil, fmt.Errorf("patient not found")
+	}
+
+	request := &entities.DataSubjectRequest{
+		Type:        data.Type,
+		PatientID:   patient.ID,
+		Reason:      data.Reason,
+		Status:      entities.DsarStatusPending,
+		Hospital:    patient.Hospital,
+		RequestedBy: data.RequestedBy,
+	}
+	if data.Type == entities.DsarTypeErasure {
+		request.ErasureMode = data.ErasureMode
+	}
+	err = s.save(ctx, request, data.RequestedBy, data.Role, "dsar.create", map[string]interface{}{"type": request.Type, "patient_id": request.PatientID})
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func (s *DsarService) ListRequests(ctx context.Context, hospital string, status string) ([]*entities.DataSubjectRequest, error) {
+	return s.repo.FindRequests(ctx, hospital, status)
+}
+
+func (s *DsarService) GetRequest(ctx context.Context, hospital string, id uint) (*entities.DataSubjectRequest, error) {
+	request, err := s.repo.FindRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(request.Hospital, hospital) {
+		return nil, fmt.Errorf("request not found")
+	}
+	return request, nil
+}
+
+// ReviewRequest approves or rejects a pending request. The reviewer must
+// not be the staff member who raised it.
+func (s *DsarService) ReviewRequest(ctx context.Context, data *dto.ReviewDsarDto) (*entities.DataSubjectRequest, error) {
+	request, err := s.GetRequest(ctx, data.Hospital, data.Id)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != entities.DsarStatusPending {
+		return nil, fmt.Errorf("request already %s", request.Status)
+	}
+	if request.RequestedBy == data.ReviewedBy {
+		return nil, fmt.Errorf("request must be reviewed by another staff")
+	}
+
+	now := time.Now()
+	request.ReviewedBy, request.ReviewedAt, request.Message = data.ReviewedBy, &now, data.Message
+	action := "dsar.approve"
+	request.Status = entities.DsarStatusApproved
+	if !data.Approve {
+		action = "dsar.reject"
+		request.Status = entities.DsarStatusRejected
+	}
+	if err := s.save(ctx, request, data.ReviewedBy, data.Role, action, map[string]interface{}{"message": data.Message}); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// ExportRequest collects everything linked to the patient of an approved
+// access request. The request stays downloadable once completed.
+func (s *DsarService) ExportRequest(ctx context.Context, data *dto.ExecuteDsarDto) (*dto.SubjectData, error) {
+	request, err := s.approvedRequest(ctx, data, entities.DsarTypeAccess)
+	if err != nil {
+		return nil, err
+	}
+
+	subject, err := s.repo.CollectSubjectData(ctx, request.PatientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Status != entities.DsarStatusCompleted {
+		now := time.Now()
+		request.Status, request.CompletedAt = entities.DsarStatusCompleted, &now
+	}
+	err = s.save(ctx, request, data.ExecutedBy, data.Role, "dsar.export", map[string]interface{}{
+		"merged_records": len(subject.MergedRecords),
+		"consents":       len(subject.Consents),
+		"audit_entries":  len(subject.AuditTrail),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return subject, nil
+}
+
+// EraseRequest runs an approved erasure request. Patients under a legal
+// hold are kept and the request is parked until the hold is lifted. An
+// erasure that fails is rolled back and the request parked as failed.
+func (s *DsarService) EraseRequest(ctx context.Context, data *dto.ExecuteDsarDto) (*entities.DataSubjectRequest, error) {
+	request, err := s.approvedRequest(ctx, data, entities.DsarTypeErasure)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status == entities.DsarStatusCompleted {
+		return nil, fmt.Errorf("request already %s", request.Status)
+	}
+
+	err = s.uow.Do(ctx, func(ctx context.Context, repos Repositories) error {
+		action := "dsar.erase"
+		if err := repos.Dsar().ErasePatient(ctx, request.PatientID, request.ErasureMode); errors.Is(err, ErrLegalHold) {
+			action = "dsar.erase.blocked"
+			request.Status, request.Message = entities.DsarStatusOnHold, err.Error()
+		} else if err != nil {
+			return err
+		} else {
+			now := time.Now()
+			request.Status, request.CompletedAt, request.Message = entities.DsarStatusCompleted, &now, ""
+		}
+		return saveIn(ctx, repos, request, data.ExecutedBy, data.Role, action, map[string]interface{}{"mode": request.ErasureMode, "status": request.Status})
+	})
+	if err != nil {
+		request.Status, request.CompletedAt, request.Message = entities.DsarStatusFailed, nil, err.Error()
+		err = s.save(ctx, request, data.ExecutedBy, data.Role, "dsar.erase.failed", map[string]interface{}{"mode": request.ErasureMode, "status": request.Status})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func (s *DsarService) SetLegalHold(ctx context.Context, data *dto.LegalHoldDto) (*entities.Patient, error) {
+	patient, err := s.repo.FindPatient(ctx, data.PatientId)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(patient.Hospital, data.Hospital) {
+		return nil, fmt.Errorf("patient not found")
+	}
+
+	reason := data.Reason
+	if !data.Hold {
+		reason = ""
+	}
+	err = s.uow.Do(ctx, func(ctx context.Context, repos Repositories) error {
+		if err := repos.Dsar().SetLegalHold(ctx, patient.ID, data.Hold, reason); err != nil {
+			return err
+		}
+		_, err := audit.NewAuditService(repos.Audit()).Record(ctx, &entities.AuditLog{
+			Actor:      data.SetBy,
+			ActorRole:  data.Role,
+			Hospital:   patient.Hospital,
+			Action:     "patient.legal_hold",
+			Resource:   "patient",
+			ResourceId: strconv.FormatUint(uint64(patient.ID), 10),
+		}, map[string]interface{}{"hold": data.Hold, "reason": data.Reason})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	patient.LegalHold, patient.LegalHoldReason = data.Hold, reason
+	return patient, nil
+}
+
+// approvedRequest loads a request of kind that is ready to run. Requests
+// parked by a legal hold can be run again.
+func (s *DsarService) approvedRequest(ctx context.Context, data *dto.ExecuteDsarDto, kind string) (*entities.DataSubjectRequest, error) {
+	request, err := s.GetRequest(ctx, data.Hospital, data.Id)
+	if err != nil {
+		return nil, err
+	}
+	if request.Type != kind {
+		return nil, fmt.Errorf("request is not an %s request", kind)
+	}
+	switch request.Status {
+	case entities.DsarStatusApproved, entities.DsarStatusCompleted, entities.DsarStatusOnHold, entities.DsarStatusFailed:
+		return request, nil
+	}
+	return nil, fmt.Errorf("request is %s", request.Status)
+}
+
+// save stores request and records action on it in the audit log, in one
+// unit of work.
+func (s *DsarService) save(ctx context.Context, request *entities.DataSubjectRequest, actor string, role string, action string, detail interface{}) error {
+	return s.uow.Do(ctx, func(ctx context.Context, repos Repositories) error {
+		return saveIn(ctx, repos, request, actor, role, action, detail)
+	})
+}
+
+func saveIn(ctx context.Context, repos Repositories, request *entities.DataSubjectRequest, actor string, role string, action string, detail interface{}) error {
+	if _, err := repos.Dsar().SaveRequest(ctx, request); err != nil {
+		return err
+	}
+	_, err := audit.NewAuditService(repos.Audit()).Record(ctx, &entities.AuditLog{
+		Actor:      actor,
+		ActorRole:  role,
+		Hospital:   request.Hospital,
+		Action:     action,
+		Resource:   "data_subject_request",
+		ResourceId: strconv.FormatUint(uint64(request.ID), 10),
+	}, detail)
+	return err
+}
@@ -0,0 +1,12 @@
+package emergency
+
+import (
+	"agnos/internal/entities"
+	"context"
+)
+
+type EmergencyRepository interface {
+	SaveAccess(ctx context.Context, access *entities.EmergencyAccess) (*entities.EmergencyAccess, error)
+	FindAccess(ctx context.Context, id uint) (*entities.EmergencyAccess, error)
+	FindAccesses(ctx context.Context, patientHospital string, status string) ([]*entities.EmergencyAccess, error)
+}
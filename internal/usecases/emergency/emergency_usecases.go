@@ -0,0 +1,152 @@
+package emergency
+
+import (
+	"agnos/internal/adapters/emergency/dto"
+	"agnos/internal/entities"
+	"agnos/internal/usecases/audit"
+	"agnos/internal/usecases/patient"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DefaultAccessDuration = time.Hour
+
+var ErrAccessDenied = errors.New("emergency access is not valid for this patient")
+
+type EmergencyUseCase interface {
+	GrantAccess(ctx context.Context, data *dto.GrantEmergencyDto) (*entities.EmergencyAccess, error)
+	FindPatient(ctx context.Context, id uint, clinician string, identifier string) (*entities.Patient, *entities.EmergencyAccess, error)
+	RecordRead(ctx context.Context, access *entities.EmergencyAccess, role string) error
+	ListAccesses(ctx context.Context, hospital string, status string) ([]*entities.EmergencyAccess, error)
+	ReviewAccess(ctx context.Context, data *dto.ReviewEmergencyDto) (*entities.EmergencyAccess, error)
+}
+
+type EmergencyService struct {
+	repo           EmergencyRepository
+	patientUseCase patient.PatientUseCase
+	auditUseCase   audit.AuditUseCase
+	duration       time.Duration
+}
+
+func NewEmergencyService(repo EmergencyRepository, patientUseCase patient.PatientUseCase, auditUseCase audit.AuditUseCase, duration time.Duration) EmergencyUseCase {
+	return &EmergencyService{repo: repo, patientUseCase: patientUseCase, auditUseCase: auditUseCase, duration: duration}
+}
+
+// GrantAccess breaks the glass for one patient of another hospital, named
+// by the caller as identifiers are only unique within a hospital. The grant
+// is audited as flagged under the owning hospital so that its admins see it
+// in their review queue.
+func (s *EmergencyService) GrantAccess(ctx context.Context, data *dto.GrantEmergencyDto) (*entities.EmergencyAccess, error) {
+	if strings.EqualFold(data.Hospital, data.ClinicianHospital) {
+		return nil, fmt.Errorf("patient belongs to your hospital")
+	}
+	found, err := s.patientUseCase.SearchPatientId(ctx, data.Identifier, data.Hospital)
+	if err != nil {
+		return nil, err
+	}
+
+	access := &entities.EmergencyAccess{
+		Clinician:         data.Clinician,
+		ClinicianHospital: data.ClinicianHospital,
+		PatientID:         found.ID,
+		PatientHospital:   found.Hospital,
+		Reason:            data.Reason,
+		ExpiresAt:         time.Now().Add(s.duration),
+		ReviewStatus:      entities.EmergencyReviewPending,
+	}
+	if _, err := s.repo.SaveAccess(ctx, access); err != nil {
+		return nil, err
+	}
+
+	_, err = s.auditUseCase.Record(ctx, &entities.AuditLog{
+		Actor:      data.Clinician,
+		ActorRole:  data.Role,
+		Hospital:   found.Hospital,
+		Action:     "patient.break_glass",
+		Resource:   "patient",
+		ResourceId: strconv.FormatUint(uint64(found.ID), 10),
+		Flagged:    true,
+	}, map[string]interface{}{
+		"access_id":          access.ID,
+		"clinician_hospital": data.ClinicianHospital,
+		"reason":             data.Reason,
+		"expires_at":         access.ExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return access, nil
+}
+
+// FindPatient looks identifier up in the hospital of the grant id and
+// returns the patient with the grant when it still lets clinician read them.
+func (s *EmergencyService) FindPatient(ctx context.Context, id uint, clinician string, identifier string) (*entities.Patient, *entities.EmergencyAccess, error) {
+	access, err := s.repo.FindAccess(ctx, id)
+	if err != nil {
+		return nil, nil, ErrAccessDenied
+	}
+	if access.Clinician != clinician || time.Now().After(access.ExpiresAt) {
+		return nil, nil, ErrAccessDenied
+	}
+	found, err := s.patientUseCase.SearchPatientId(ctx, identifier, access.PatientHospital)
+	if err != nil {
+		return nil, nil, err
+	}
+	if found.ID != access.PatientID {
+		return nil, nil, ErrAccessDenied
+	}
+	return found, access, nil
+}
+
+func (s *EmergencyService) RecordRead(ctx context.Context, access *entities.EmergencyAccess, role string) error {
+	_, err := s.auditUseCase.Record(ctx, &entities.AuditLog{
+		Actor:      access.Clinician,
+		ActorRole:  role,
+		Hospital:   access.PatientHospital,
+		Action:     "patient.break_glass.read",
+		Resource:   "patient",
+		ResourceId: strconv.FormatUint(uint64(access.PatientID), 10),
+		Flagged:    true,
+	}, map[string]interface{}{"access_id": access.ID, "clinician_hospital": access.ClinicianHospital})
+	return err
+}
+
+func (s *EmergencyService) ListAccesses(ctx context.Context, hospital string, status string) ([]*entities.EmergencyAccess, error) {
+	return s.repo.FindAccesses(ctx, hospital, status)
+}
+
+func (s *EmergencyService) ReviewAccess(ctx context.Context, data *dto.ReviewEmergencyDto) (*entities.EmergencyAccess, error) {
+	access, err := s.repo.FindAccess(ctx, data.Id)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(access.PatientHospital, data.Hospital) {
+		return nil, fmt.Errorf("emergency access not found")
+	}
+	if access.ReviewStatus != entities.EmergencyReviewPending {
+		return nil, fmt.Errorf("emergency access already reviewed")
+	}
+
+	now := time.Now()
+	access.ReviewStatus, access.ReviewedBy, access.ReviewedAt, access.ReviewNote = data.Status, data.ReviewedBy, &now, data.Note
+	if _, err := s.repo.SaveAccess(ctx, access); err != nil {
+		return nil, err
+	}
+
+	_, err = s.auditUseCase.Record(ctx, &entities.AuditLog{
+		Actor:      data.ReviewedBy,
+		ActorRole:  data.Role,
+		Hospital:   data.Hospital,
+		Action:     "patient.break_glass.review",
+		Resource:   "emergency_access",
+		ResourceId: strconv.FormatUint(uint64(access.ID), 10),
+	}, map[string]interface{}{"status": data.Status, "note": data.Note})
+	if err != nil {
+		return nil, err
+	}
+	return access, nil
+}
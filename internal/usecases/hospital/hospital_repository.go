@@ -0,0 +1,11 @@
+package hospital
+
+import (
+	"agnos/internal/entities"
+	"context"
+)
+
+type HospitalRepository interface {
+	Save(ctx context.Context, hospital *entities.Hospital) (*entities.Hospital, error)
+	FindAll(ctx context.Context) ([]*entities.Hospital, error)
+}
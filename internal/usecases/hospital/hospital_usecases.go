@@ -0,0 +1,29 @@
+package hospital
+
+import (
+	"agnos/internal/entities"
+	"context"
+	"strings"
+)
+
+type HospitalUseCase interface {
+	CreateHospital(ctx context.Context, hospital *entities.Hospital) (*entities.Hospital, error)
+	ListHospitals(ctx context.Context) ([]*entities.Hospital, error)
+}
+
+type HospitalService struct {
+	repo HospitalRepository
+}
+
+func NewHospitalService(repo HospitalRepository) HospitalUseCase {
+	return &HospitalService{repo: repo}
+}
+
+func (s *HospitalService) CreateHospital(ctx context.Context, hospital *entities.Hospital) (*entities.Hospital, error) {
+	hospital.Name = strings.TrimSpace(hospital.Name)
+	return s.repo.Save(ctx, hospital)
+}
+
+func (s *HospitalService) ListHospitals(ctx context.Context) ([]*entities.Hospital, error) {
+	return s.repo.FindAll(ctx)
+}
@@ -0,0 +1,15 @@
+package importer
+
+import (
+	"agnos/internal/entities"
+	"context"
+)
+
+type ImportRepository interface {
+	SaveJob(ctx context.Context, job *entities.ImportJob) error
+	UpdateJob(ctx context.Context, job *entities.ImportJob) error
+	FindJob(ctx context.Context, id uint) (*entities.ImportJob, error)
+	SaveRowErrors(ctx context.Context, rowErrors []*entities.ImportRowError) error
+	ExistingNationalIds(ctx context.Context, nationalIds []string) (map[string]bool, error)
+	InsertPatients(ctx context.Context, patients []*entities.Patient) error
+}
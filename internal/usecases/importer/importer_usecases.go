@@ -0,0 +1,258 @@
+package importer
+
+import (
+	"agnos/internal/adapters/importer/dto"
+	"agnos/internal/entities"
+	"agnos/internal/usecases/patient"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	batchSize  = 500
+	lookupSize = 1000
+)
+
+var dateLayouts = []string{"2006-01-02", time.RFC3339, "02/01/2006", "2006/01/02"}
+
+var columns = map[string]func(p *entities.Patient, value string) error{
+	"first_name_th":  func(p *entities.Patient, v string) error { p.FirstNameTh = v; return nil },
+	"middle_name_th": func(p *entities.Patient, v string) error { p.MiddleNameTh = v; return nil },
+	"last_name_th":   func(p *entities.Patient, v string) error { p.LastNameTh = v; return nil },
+	"first_name_en":  func(p *entities.Patient, v string) error { p.FirstNameEn = v; return nil },
+	"middle_name_en": func(p *entities.Patient, v string) error { p.MiddleNameEn = v; return nil },
+	"last_name_en":   func(p *entities.Patient, v string) error { p.LastNameEn = v; return nil },
+	"patient_hn":     func(p *entities.Patient, v string) error { p.PatientHn = v; return nil },
+	"national_id":    func(p *entities.Patient, v string) error { p.NationalId = v; return nil },
+	"passport_id":    func(p *entities.Patient, v string) error { p.PassportId = v; return nil },
+	"phone_number":   func(p *entities.Patient, v string) error { p.PhoneNumber = v; return nil },
+	"email":          func(p *entities.Patient, v string) error { p.Email = v; return nil },
+	"gender":         func(p *entities.Patient, v string) error { p.Gender = strings.ToLower(v); return nil },
+	"date_of_birth":  setDateBirth,
+}
+
+type ImportUseCase interface {
+	StartImport(ctx context.Context, data *dto.ImportPatientDto) (*entities.ImportJob, error)
+	GetImportJob(ctx context.Context, hospital string, id uint) (*entities.ImportJob, error)
+	Wait()
+}
+
+type ImportService struct {
+	repo ImportRepository
+	wg   sync.WaitGroup
+}
+
+func NewImportService(repo ImportRepository) ImportUseCase {
+	return &ImportService{repo: repo}
+}
+
+type importRow struct {
+	row     int
+	patient *entities.Patient
+}
+
+// StartImport checks the column mapping, records the job and processes the
+// rows in the background. The returned job can be polled with GetImportJob.
+func (s *ImportService) StartImport(ctx context.Context, data *dto.ImportPatientDto) (*entities.ImportJob, error) {
+	index, err := columnIndex(data.Header, data.Mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &entities.ImportJob{
+		Hospital:  data.Hospital,
+		CreatedBy: data.CreatedBy,
+		FileName:  data.FileName,
+		DryRun:    data.DryRun,
+		Status:    entities.ImportStatusPending,
+		TotalRows: len(data.Rows),
+	}
+	if err := s.repo.SaveJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	// The import outlives the request that started it, so it keeps the
+	// request's values but not its cancellation.
+	s.wg.Add(1)
+	go s.run(context.WithoutCancel(ctx), *job, data.Rows, index)
+	return job, nil
+}
+
+func (s *ImportService) GetImportJob(ctx context.Context, hospital string, id uint) (*entities.ImportJob, error) {
+	job, err := s.repo.FindJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(job.Hospital, hospital) {
+		return nil, fmt.Errorf("import job not found")
+	}
+	return job, nil
+}
+
+// Wait blocks until every running import has finished.
+func (s *ImportService) Wait() {
+	s.wg.Wait()
+}
+
+func (s *ImportService) run(ctx context.Context, job entities.ImportJob, rows [][]string, index map[string]int) {
+	defer s.wg.Done()
+
+	job.Status = entities.ImportStatusRunning
+	if err := s.repo.UpdateJob(ctx, &job); err != nil {
+		return
+	}
+
+	valid, rowErrors, err := s.validate(ctx, &job, rows, index)
+	if err == nil && !job.DryRun {
+		rowErrors = append(rowErrors, s.insert(ctx, &job, valid)...)
+	}
+
+	if err == nil {
+		err = s.repo.SaveRowErrors(ctx, rowErrors)
+	}
+	if err != nil {
+		job.Status = entities.ImportStatusFailed
+		job.Message = err.Error()
+	} else {
+		job.Status = entities.ImportStatusCompleted
+	}
+	s.repo.UpdateJob(ctx, &job)
+}
+
+func (s *ImportService) validate(ctx context.Context, job *entities.ImportJob, rows [][]string, index map[string]int) ([]importRow, []*entities.ImportRowError, error) {
+	valid := make([]importRow, 0, len(rows))
+	rowErrors := make([]*entities.ImportRowError, 0)
+	rowError := func(row int, message string) {
+		rowErrors = append(rowErrors, &entities.ImportRowError{ImportJobID: job.ID, Row: row, Message: message})
+	}
+
+	seen := make(map[string]int)
+	for i, values := range rows {
+		// Row 1 is the header, so data rows start at 2 like in a spreadsheet.
+		row := i + 2
+		p := &entities.Patient{Hospital: job.Hospital}
+
+		messages := make([]string, 0)
+		for field, column := range index {
+			if column >= len(values) {
+				continue
+			}
+			if err := columns[field](p, strings.TrimSpace(values[column])); err != nil {
+				messages = append(messages, err.Error())
+			}
+		}
+		messages = append(messages, patient.ValidatePatient(p)...)
+
+		if first, ok := seen[p.NationalId]; ok && p.NationalId != "" {
+			messages = append(messages, fmt.Sprintf("national_id duplicates row %d", first))
+		} else {
+			seen[p.NationalId] = row
+		}
+
+		if len(messages) > 0 {
+			for _, message := range messages {
+				rowError(row, message)
+			}
+			continue
+		}
+		valid = append(valid, importRow{row: row, patient: p})
+	}
+
+	existing := make(map[string]bool)
+	for start := 0; start < len(valid); start += lookupSize {
+		end := min(start+lookupSize, len(valid))
+		ids := make([]string, 0, end-start)
+		for _, r := range valid[start:end] {
+			ids = append(ids, r.patient.NationalId)
+		}
+		found, err := s.repo.ExistingNationalIds(ctx, ids)
+		if err != nil {
+			return nil, nil, err
+		}
+		for id := range found {
+			existing[id] = true
+		}
+	}
+
+	unique := valid[:0]
+	for _, r := range valid {
+		if existing[r.patient.NationalId] {
+			rowError(r.row, "national_id already exist")
+			continue
+		}
+		unique = append(unique, r)
+	}
+
+	job.ValidRows = len(unique)
+	job.InvalidRows = job.TotalRows - len(unique)
+	return unique, rowErrors, nil
+}
+
+// insert writes the valid rows in batches, each batch in its own
+// transaction. A failed batch is reported against all of its rows.
+func (s *ImportService) insert(ctx context.Context, job *entities.ImportJob, rows []importRow) []*entities.ImportRowError {
+	rowErrors := make([]*entities.ImportRowError, 0)
+
+	for start := 0; start < len(rows); start += batchSize {
+		batch := rows[start:min(start+batchSize, len(rows))]
+		patients := make([]*entities.Patient, 0, len(batch))
+		for _, r := range batch {
+			patients = append(patients, r.patient)
+		}
+
+		if err := s.repo.InsertPatients(ctx, patients); err != nil {
+			for _, r := range batch {
+				rowErrors = append(rowErrors, &entities.ImportRowError{ImportJobID: job.ID, Row: r.row, Message: err.Error()})
+			}
+			continue
+		}
+		job.ImportedRows += len(batch)
+		s.repo.UpdateJob(ctx, job)
+	}
+	return rowErrors
+}
+
+func columnIndex(header []string, mapping map[string]string) (map[string]int, error) {
+	for field := range mapping {
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("mapping field %s is not supported", field)
+		}
+	}
+
+	positions := make(map[string]int)
+	for i, name := range header {
+		positions[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	index := make(map[string]int)
+	for field := range columns {
+		name := field
+		if mapped, ok := mapping[field]; ok {
+			name = mapped
+		}
+		if i, ok := positions[strings.ToLower(strings.TrimSpace(name))]; ok {
+			index[field] = i
+		}
+	}
+
+	if _, ok := index["national_id"]; !ok {
+		return nil, fmt.Errorf("column national_id not found")
+	}
+	return index, nil
+}
+
+func setDateBirth(p *entities.Patient, value string) error {
+	if value == "" {
+		return nil
+	}
+	for _, layout := range dateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			p.DateBirth = date
+			return nil
+		}
+	}
+	return fmt.Errorf("date_of_birth %s is invalid", value)
+}
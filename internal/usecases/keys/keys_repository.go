@@ -0,0 +1,8 @@
+package keys
+
+import "context"
+
+type KeyRepository interface {
+	ReencryptPatients(ctx context.Context, batchSize int) (int, error)
+	BackfillIndexes(ctx context.Context, batchSize int) (int, error)
+}
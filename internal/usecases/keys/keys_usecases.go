@@ -0,0 +1,54 @@
+package keys
+
+import (
+	"agnos/pkg/encryption"
+	"context"
+)
+
+const reencryptBatchSize = 500
+
+type RotationResult struct {
+	DataKeyId           uint `json:"data_key_id"`
+	RewrappedKeys       int  `json:"rewrapped_keys"`
+	ReencryptedPatients int  `json:"reencrypted_patients"`
+}
+
+type KeyUseCase interface {
+	RotateKeys(ctx context.Context) (*RotationResult, error)
+	BackfillIndexes(ctx context.Context) (int, error)
+}
+
+type KeyService struct {
+	repo    KeyRepository
+	keyring *encryption.Keyring
+}
+
+func NewKeyService(repo KeyRepository, keyring *encryption.Keyring) KeyUseCase {
+	return &KeyService{repo: repo, keyring: keyring}
+}
+
+// RotateKeys creates a new data key, re-wraps the older data keys under the
+// current master key and rewrites every patient so that their encrypted
+// fields use the new data key. It can be run again after a failure.
+func (s *KeyService) RotateKeys(ctx context.Context) (*RotationResult, error) {
+	key, err := s.keyring.RotateDataKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RotationResult{DataKeyId: key.Id}
+	if result.RewrappedKeys, err = s.keyring.RewrapDataKeys(ctx); err != nil {
+		return result, err
+	}
+	if result.ReencryptedPatients, err = s.repo.ReencryptPatients(ctx, reencryptBatchSize); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// BackfillIndexes fills the blind indexes of patients saved without them
+// and returns how many patients it rewrote. Searches match identifiers and
+// contacts only through these indexes.
+func (s *KeyService) BackfillIndexes(ctx context.Context) (int, error) {
+	return s.repo.BackfillIndexes(ctx, reencryptBatchSize)
+}
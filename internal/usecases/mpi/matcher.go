@@ -0,0 +1,279 @@
+package mpi
+
+import (
+	"math"
+	"strings"
+	"unicode"
+
+	"agnos/internal/entities"
+)
+
+// FieldWeight holds the Fellegi–Sunter m and u probabilities of a field:
+// M is P(fields agree | same person), U is P(fields agree | different people).
+type FieldWeight struct {
+	M float64
+	U float64
+}
+
+func (w FieldWeight) Agree() float64 {
+	return math.Log2(w.M / w.U)
+}
+
+func (w FieldWeight) Disagree() float64 {
+	return math.Log2((1 - w.M) / (1 - w.U))
+}
+
+// partial interpolates between the disagreement and agreement weights.
+func (w FieldWeight) partial(level float64) float64 {
+	return w.Disagree() + (w.Agree()-w.Disagree())*level
+}
+
+type Weights struct {
+	NationalId  FieldWeight
+	PassportId  FieldWeight
+	FirstName   FieldWeight
+	LastName    FieldWeight
+	DateBirth   FieldWeight
+	PhoneNumber FieldWeight
+	Email       FieldWeight
+	Gender      FieldWeight
+}
+
+var DefaultWeights = Weights{
+	NationalId:  FieldWeight{M: 0.98, U: 0.0001},
+	PassportId:  FieldWeight{M: 0.95, U: 0.0001},
+	FirstName:   FieldWeight{M: 0.95, U: 0.01},
+	LastName:    FieldWeight{M: 0.95, U: 0.005},
+	DateBirth:   FieldWeight{M: 0.97, U: 0.003},
+	PhoneNumber: FieldWeight{M: 0.90, U: 0.001},
+	Email:       FieldWeight{M: 0.90, U: 0.001},
+	Gender:      FieldWeight{M: 0.98, U: 0.5},
+}
+
+const (
+	// ReviewThreshold is the score above which a pair is kept for review.
+	ReviewThreshold = 8.0
+	// MatchThreshold is the score above which a pair is a likely duplicate.
+	MatchThreshold = 15.0
+
+	nameAgreeSimilarity   = 0.92
+	namePartialSimilarity = 0.80
+)
+
+type Matcher struct {
+	weights Weights
+}
+
+func NewMatcher(weights Weights) *Matcher {
+	return &Matcher{weights: weights}
+}
+
+// Score returns the total match weight of two patient records. Fields that
+// are empty on either side contribute nothing.
+func (m *Matcher) Score(a, b *entities.Patient) float64 {
+	w := m.weights
+	score := 0.0
+
+	score += compareId(w.NationalId, a.NationalId, b.NationalId)
+	score += compareId(w.PassportId, a.PassportId, b.PassportId)
+	score += compareName(w.FirstName, [][2]string{{a.FirstNameTh, b.FirstNameTh}, {a.FirstNameEn, b.FirstNameEn}})
+	score += compareName(w.LastName, [][2]string{{a.LastNameTh, b.LastNameTh}, {a.LastNameEn, b.LastNameEn}})
+	score += compareDate(w.DateBirth, a, b)
+	score += compareExact(w.PhoneNumber, normalizePhone(a.PhoneNumber), normalizePhone(b.PhoneNumber))
+	score += compareExact(w.Email, normalize(a.Email), normalize(b.Email))
+	score += compareExact(w.Gender, normalize(a.Gender), normalize(b.Gender))
+
+	return score
+}
+
+func compareExact(w FieldWeight, a, b string) float64 {
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return w.Agree()
+	}
+	return w.Disagree()
+}
+
+func compareId(w FieldWeight, a, b string) float64 {
+	a, b = normalizeId(a), normalizeId(b)
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return w.Agree()
+	}
+	if oneEditApart([]rune(a), []rune(b)) {
+		return w.partial(0.5)
+	}
+	return w.Disagree()
+}
+
+func compareName(w FieldWeight, pairs [][2]string) float64 {
+	best := -1.0
+	for _, pair := range pairs {
+		a, b := normalize(pair[0]), normalize(pair[1])
+		if a == "" || b == "" {
+			continue
+		}
+		if sim := JaroWinkler(a, b); sim > best {
+			best = sim
+		}
+	}
+
+	switch {
+	case best < 0:
+		return 0
+	case best >= nameAgreeSimilarity:
+		return w.Agree()
+	case best >= namePartialSimilarity:
+		return w.partial((best - namePartialSimilarity) / (nameAgreeSimilarity - namePartialSimilarity))
+	default:
+		return w.Disagree()
+	}
+}
+
+func compareDate(w FieldWeight, a, b *entities.Patient) float64 {
+	if a.DateBirth.IsZero() || b.DateBirth.IsZero() {
+		return 0
+	}
+	ay, am, ad := a.DateBirth.Date()
+	by, bm, bd := b.DateBirth.Date()
+
+	same := 0
+	if ay == by {
+		same++
+	}
+	if am == bm {
+		same++
+	}
+	if ad == bd {
+		same++
+	}
+
+	switch {
+	case same == 3:
+		return w.Agree()
+	case same == 2, ay == by && int(am) == bd && ad == int(bm):
+		return w.partial(0.5)
+	default:
+		return w.Disagree()
+	}
+}
+
+func normalize(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "-" {
+		return ""
+	}
+	return s
+}
+
+func normalizeId(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return -1
+	}, s)
+}
+
+func normalizePhone(s string) string {
+	digits := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, s)
+	if strings.HasPrefix(digits, "66") && len(digits) == 11 {
+		digits = "0" + digits[2:]
+	}
+	return digits
+}
+
+// oneEditApart reports whether a and b differ by a single substitution or a
+// single transposition of adjacent characters, the common ID typing errors.
+func oneEditApart(a, b []rune) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	diff := make([]int, 0, 2)
+	for i := range a {
+		if a[i] != b[i] {
+			diff = append(diff, i)
+			if len(diff) > 2 {
+				return false
+			}
+		}
+	}
+	switch len(diff) {
+	case 1:
+		return true
+	case 2:
+		i, j := diff[0], diff[1]
+		return j == i+1 && a[i] == b[j] && a[j] == b[i]
+	}
+	return false
+}
+
+// JaroWinkler returns the Jaro–Winkler similarity of two strings in [0, 1].
+func JaroWinkler(s1, s2 string) float64 {
+	a, b := []rune(s1), []rune(s2)
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	window := max(len(a), len(b))/2 - 1
+	if window < 0 {
+		window = 0
+	}
+
+	matchedA := make([]bool, len(a))
+	matchedB := make([]bool, len(b))
+	matches := 0
+	for i := range a {
+		lo, hi := max(0, i-window), min(len(b), i+window+1)
+		for j := lo; j < hi; j++ {
+			if matchedB[j] || a[i] != b[j] {
+				continue
+			}
+			matchedA[i], matchedB[j] = true, true
+			matches++
+			break
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions := 0
+	j := 0
+	for i := range a {
+		if !matchedA[i] {
+			continue
+		}
+		for !matchedB[j] {
+			j++
+		}
+		if a[i] != b[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(a)) + m/float64(len(b)) + (m-float64(transpositions)/2)/m) / 3
+
+	prefix := 0
+	for i := 0; i < min(4, len(a), len(b)); i++ {
+		if a[i] != b[i] {
+			break
+		}
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
@@ -0,0 +1,71 @@
+package mpi_test
+
+import (
+	"testing"
+	"time"
+
+	"agnos/internal/entities"
+	"agnos/internal/usecases/mpi"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func basePatient() *entities.Patient {
+	return &entities.Patient{
+		FirstNameTh: "ปลาบปลื้ม",
+		LastNameTh:  "ยอดจันทร์",
+		FirstNameEn: "Plabpluem",
+		LastNameEn:  "Yodchan",
+		DateBirth:   time.Date(1995, 7, 21, 0, 0, 0, 0, time.UTC),
+		NationalId:  "1234567890123",
+		PhoneNumber: "0812345678",
+		Email:       "plabpluem@example.com",
+		Gender:      "male",
+	}
+}
+
+func TestMatcher_Score_TypoIsLikelyDuplicate(t *testing.T) {
+	matcher := mpi.NewMatcher(mpi.DefaultWeights)
+
+	a := basePatient()
+	b := basePatient()
+	b.NationalId = "1234567890132"
+	b.FirstNameEn = "Plabpleum"
+	b.PhoneNumber = "+66 81 234 5678"
+
+	assert.GreaterOrEqual(t, matcher.Score(a, b), mpi.MatchThreshold)
+}
+
+func TestMatcher_Score_PassportRegistrationIsLikelyDuplicate(t *testing.T) {
+	matcher := mpi.NewMatcher(mpi.DefaultWeights)
+
+	a := basePatient()
+	b := basePatient()
+	b.NationalId = ""
+	b.PassportId = "AA1234567"
+
+	assert.GreaterOrEqual(t, matcher.Score(a, b), mpi.MatchThreshold)
+}
+
+func TestMatcher_Score_DifferentPeopleAreNotDuplicates(t *testing.T) {
+	matcher := mpi.NewMatcher(mpi.DefaultWeights)
+
+	a := basePatient()
+	b := &entities.Patient{
+		FirstNameEn: "Somsak",
+		LastNameEn:  "Chunsri",
+		DateBirth:   time.Date(1980, 1, 2, 0, 0, 0, 0, time.UTC),
+		NationalId:  "9876543210987",
+		PhoneNumber: "0899999999",
+		Gender:      "male",
+	}
+
+	assert.Less(t, matcher.Score(a, b), mpi.ReviewThreshold)
+}
+
+func TestJaroWinkler(t *testing.T) {
+	assert.InDelta(t, 0.961, mpi.JaroWinkler("MARTHA", "MARHTA"), 0.001)
+	assert.InDelta(t, 0.840, mpi.JaroWinkler("DWAYNE", "DUANE"), 0.001)
+	assert.Equal(t, 1.0, mpi.JaroWinkler("สมศักดิ์", "สมศักดิ์"))
+	assert.Equal(t, 0.0, mpi.JaroWinkler("abc", ""))
+}
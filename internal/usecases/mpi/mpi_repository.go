@@ -0,0 +1,34 @@
+package mpi
+
+import (
+	"agnos/internal/entities"
+	"agnos/internal/usecases/audit"
+	"context"
+)
+
+type MpiRepository interface {
+	// FindCandidates returns at most limit patients that may be patient,
+	// always the same ones for the same data.
+	FindCandidates(ctx context.Context, patient *entities.Patient, limit int) ([]*entities.Patient, error)
+	FindPatient(ctx context.Context, id uint) (*entities.Patient, error)
+	SaveDuplicates(ctx context.Context, duplicates []*entities.PatientDuplicate) error
+	FindDuplicates(ctx context.Context, hospital string, status string) ([]*entities.PatientDuplicate, error)
+	FindDuplicate(ctx context.Context, id uint) (*entities.PatientDuplicate, error)
+	UpdateDuplicateStatus(ctx context.Context, id uint, status string) error
+	// Merge folds merged into survivor and records lineage. Its writes only
+	// commit together inside a unit of work.
+	Merge(ctx context.Context, survivor *entities.Patient, merged *entities.Patient, lineage *entities.PatientMerge) (*entities.Patient, error)
+}
+
+// Repositories are what a merge is written through, bound to one unit of
+// work.
+type Repositories interface {
+	Mpi() MpiRepository
+	Audit() audit.AuditRepository
+}
+
+// UnitOfWork is unitofwork.UnitOfWork as the merge sees it; see
+// unitofwork.Scope.
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) error
+}
@@ -0,0 +1,192 @@
+package mpi
+
+import (
+	"agnos/internal/adapters/mpi/dto"
+	"agnos/internal/entities"
+	"agnos/internal/usecases/audit"
+	"agnos/pkg/logging"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CandidateLimit caps how many candidates are scored against a patient.
+const CandidateLimit = 100
+
+type MpiUseCase interface {
+	DetectDuplicates(ctx context.Context, patient *entities.Patient) ([]*entities.PatientDuplicate, error)
+	ListDuplicates(ctx context.Context, hospital string, status string) ([]*entities.PatientDuplicate, error)
+	DismissDuplicate(ctx context.Context, hospital string, id uint) error
+	MergePatient(ctx context.Context, merge *dto.MergePatientDto) (*entities.Patient, error)
+}
+
+type MpiService struct {
+	repo    MpiRepository
+	uow     UnitOfWork
+	matcher *Matcher
+}
+
+func NewMpiService(repo MpiRepository, uow UnitOfWork) MpiUseCase {
+	return &MpiService{repo: repo, uow: uow, matcher: NewMatcher(DefaultWeights)}
+}
+
+func (s *MpiService) DetectDuplicates(ctx context.Context, patient *entities.Patient) ([]*entities.PatientDuplicate, error) {
+	// One candidate past the limit tells that the blocks held more than get
+	// scored, so that duplicates may have been missed.
+	candidates, err := s.repo.FindCandidates(ctx, patient, CandidateLimit+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) > CandidateLimit {
+		candidates = candidates[:CandidateLimit]
+		logging.For("mpi").WarnContext(ctx, "candidate limit reached, duplicates may be missed", "patient_id", patient.ID, "limit", CandidateLimit)
+	}
+
+	duplicates := make([]*entities.PatientDuplicate, 0)
+	for _, candidate := range candidates {
+		score := s.matcher.Score(patient, candidate)
+		if score < ReviewThreshold {
+			continue
+		}
+		duplicates = append(duplicates, &entities.PatientDuplicate{
+			PatientID:         patient.ID,
+			CandidateID:       candidate.ID,
+			Score:             score,
+			Likely:            score >= MatchThreshold,
+			Status:            entities.DuplicateStatusPending,
+			Hospital:          patient.Hospital,
+			CandidateHospital: candidate.Hospital,
+		})
+	}
+	sort.Slice(duplicates, func(i, j int) bool { return duplicates[i].Score > duplicates[j].Score })
+
+	if len(duplicates) == 0 {
+		return duplicates, nil
+	}
+	if err := s.repo.SaveDuplicates(ctx, duplicates); err != nil {
+		return nil, err
+	}
+	return duplicates, nil
+}
+
+// ListDuplicates returns the duplicates hospital is party to. A side from
+// another hospital is cut down to its id and hospital, since a duplicate
+// list is no way around sharing agreements and consent.
+func (s *MpiService) ListDuplicates(ctx context.Context, hospital string, status string) ([]*entities.PatientDuplicate, error) {
+	if status == "" {
+		status = entities.DuplicateStatusPending
+	}
+	duplicates, err := s.repo.FindDuplicates(ctx, hospital, status)
+	if err != nil {
+		return nil, err
+	}
+	for _, duplicate := range duplicates {
+		if !strings.EqualFold(duplicate.Hospital, hospital) {
+			duplicate.Patient = foreignPatient(duplicate.PatientID, duplicate.Hospital)
+		}
+		if !strings.EqualFold(duplicate.CandidateHospital, hospital) {
+			duplicate.Candidate = foreignPatient(duplicate.CandidateID, duplicate.CandidateHospital)
+		}
+	}
+	return duplicates, nil
+}
+
+func foreignPatient(id uint, hospital string) entities.Patient {
+	patient := entities.Patient{Hospital: hospital}
+	patient.ID = id
+	return patient
+}
+
+func (s *MpiService) DismissDuplicate(ctx context.Context, hospital string, id uint) error {
+	duplicate, err := s.repo.FindDuplicate(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(duplicate.Hospital, hospital) && !strings.EqualFold(duplicate.CandidateHospital, hospital) {
+		return fmt.Errorf("duplicate not found")
+	}
+	if duplicate.Status != entities.DuplicateStatusPending {
+		return fmt.Errorf("duplicate already %s", duplicate.Status)
+	}
+	return s.repo.UpdateDuplicateStatus(ctx, id, entities.DuplicateStatusDismissed)
+}
+
+// MergePatient folds the merged patient into the survivor and records it
+// in the audit log, in one unit of work.
+func (s *MpiService) MergePatient(ctx context.Context, merge *dto.MergePatientDto) (*entities.Patient, error) {
+	var result *entities.Patient
+	err := s.uow.Do(ctx, func(ctx context.Context, repos Repositories) error {
+		survivor, err := repos.Mpi().FindPatient(ctx, merge.SurvivorId)
+		if err != nil {
+			return err
+		}
+		merged, err := repos.Mpi().FindPatient(ctx, merge.MergedId)
+		if err != nil {
+			return err
+		}
+
+		if !strings.EqualFold(survivor.Hospital, merge.Hospital) || !strings.EqualFold(merged.Hospital, merge.Hospital) {
+			return fmt.Errorf("cannot merge patients from another hospital")
+		}
+
+		snapshot, err := json.Marshal(merged)
+		if err != nil {
+			return err
+		}
+		lineage := &entities.PatientMerge{
+			SurvivorID: survivor.ID,
+			MergedID:   merged.ID,
+			Score:      s.matcher.Score(survivor, merged),
+			MergedBy:   merge.MergedBy,
+			Hospital:   survivor.Hospital,
+			Snapshot:   string(snapshot),
+		}
+
+		fillBlanks(survivor, merged)
+		if result, err = repos.Mpi().Merge(ctx, survivor, merged, lineage); err != nil {
+			return err
+		}
+
+		_, err = audit.NewAuditService(repos.Audit()).Record(ctx, &entities.AuditLog{
+			Actor:      merge.MergedBy,
+			ActorRole:  merge.Role,
+			Hospital:   survivor.Hospital,
+			Action:     "patient.merge",
+			Resource:   "patient",
+			ResourceId: strconv.FormatUint(uint64(survivor.ID), 10),
+		}, map[string]interface{}{"merged_id": merged.ID, "score": lineage.Score})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// fillBlanks keeps the survivor's values and only takes fields from the
+// merged record where the survivor has none.
+func fillBlanks(survivor, merged *entities.Patient) {
+	fields := []struct{ dst, src *string }{
+		{&survivor.FirstNameTh, &merged.FirstNameTh},
+		{&survivor.MiddleNameTh, &merged.MiddleNameTh},
+		{&survivor.LastNameTh, &merged.LastNameTh},
+		{&survivor.FirstNameEn, &merged.FirstNameEn},
+		{&survivor.MiddleNameEn, &merged.MiddleNameEn},
+		{&survivor.LastNameEn, &merged.LastNameEn},
+		{&survivor.PatientHn, &merged.PatientHn},
+		{&survivor.PassportId, &merged.PassportId},
+		{&survivor.PhoneNumber, &merged.PhoneNumber},
+		{&survivor.Email, &merged.Email},
+	}
+	for _, f := range fields {
+		if normalize(*f.dst) == "" && normalize(*f.src) != "" {
+			*f.dst = *f.src
+		}
+	}
+	if survivor.DateBirth.IsZero() {
+		survivor.DateBirth = merged.DateBirth
+	}
+}
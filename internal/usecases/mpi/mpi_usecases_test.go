@@ -0,0 +1,58 @@
+package mpi_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	adaptersAudit "agnos/internal/adapters/audit"
+	adaptersMpi "agnos/internal/adapters/mpi"
+	"agnos/internal/adapters/mpi/dto"
+	adaptersPatient "agnos/internal/adapters/patient"
+	adaptersUnitofwork "agnos/internal/adapters/unitofwork"
+	"agnos/internal/entities"
+	"agnos/internal/usecases/mpi"
+	"agnos/internal/usecases/unitofwork"
+	"agnos/pkg/encryption"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingAudit fails to record any entry.
+type failingAudit struct {
+	*adaptersAudit.MemoryAuditRepository
+}
+
+func (failingAudit) Save(ctx context.Context, entry *entities.AuditLog) (*entities.AuditLog, error) {
+	return nil, errors.New("disk full")
+}
+
+func TestMpiService_MergeRollsBackWhenAuditFails(t *testing.T) {
+	ctx := context.Background()
+	provider, err := encryption.NewLocalProvider("test", map[string][]byte{"test": bytes.Repeat([]byte{1}, 32)}, bytes.Repeat([]byte{2}, 32))
+	require.NoError(t, err)
+	encryption.SetDefault(encryption.NewKeyring(provider, nil))
+
+	patients := adaptersPatient.NewMemoryPatientRepository().(*adaptersPatient.MemoryPatientRepository)
+	survivor, err := patients.Save(ctx, basePatient())
+	require.NoError(t, err)
+	merged := basePatient()
+	merged.NationalId = "1234567890132"
+	merged, err = patients.Save(ctx, merged)
+	require.NoError(t, err)
+
+	repo := adaptersMpi.NewMemoryMpiRepository(patients).(*adaptersMpi.MemoryMpiRepository)
+	audit := failingAudit{adaptersAudit.NewMemoryAuditRepository().(*adaptersAudit.MemoryAuditRepository)}
+	uow := adaptersUnitofwork.NewMemoryUnitOfWork(patients, nil, nil, audit, repo, nil)
+	service := mpi.NewMpiService(repo, unitofwork.Scope(uow, func(repos unitofwork.Repositories) mpi.Repositories { return repos }))
+
+	_, err = service.MergePatient(ctx, &dto.MergePatientDto{SurvivorId: survivor.ID, MergedId: merged.ID, MergedBy: "admin", Hospital: survivor.Hospital})
+	assert.EqualError(t, err, "disk full")
+
+	assert.Empty(t, repo.Merges())
+	found, err := repo.FindPatient(ctx, merged.ID)
+	require.NoError(t, err)
+	assert.Nil(t, found.MergedIntoID)
+}
@@ -0,0 +1,44 @@
+package patient
+
+import (
+	"agnos/internal/entities"
+	"agnos/pkg/mask"
+	"fmt"
+)
+
+// MaskPatient returns a copy of p with its sensitive fields shaped by policy.
+// Omitted fields are returned empty.
+func MaskPatient(p *entities.Patient, policy mask.Policy) *entities.Patient {
+	if p == nil {
+		return nil
+	}
+	masked := *p
+	masked.NationalId = policy.Apply(mask.FieldNationalId, p.NationalId)
+	masked.PassportId = policy.Apply(mask.FieldPassportId, p.PassportId)
+	masked.PhoneNumber = policy.Apply(mask.FieldPhoneNumber, p.PhoneNumber)
+	masked.Email = policy.Apply(mask.FieldEmail, p.Email)
+	return &masked
+}
+
+func MaskPatients(patients []*entities.Patient, policy mask.Policy) []*entities.Patient {
+	masked := make([]*entities.Patient, 0, len(patients))
+	for _, p := range patients {
+		masked = append(masked, MaskPatient(p, policy))
+	}
+	return masked
+}
+
+// PatientField returns the unmasked value of one sensitive field.
+func PatientField(p *entities.Patient, field string) (string, error) {
+	switch field {
+	case mask.FieldNationalId:
+		return p.NationalId, nil
+	case mask.FieldPassportId:
+		return p.PassportId, nil
+	case mask.FieldPhoneNumber:
+		return p.PhoneNumber, nil
+	case mask.FieldEmail:
+		return p.Email, nil
+	}
+	return "", fmt.Errorf("field %s can not be revealed", field)
+}
@@ -3,10 +3,16 @@ package patient
 import (
 	"agnos/internal/adapters/patient/dto"
 	"agnos/internal/entities"
+	"context"
 )
 
 type PatientRepository interface {
-	Save(patient *entities.Patient) (*entities.Patient, error)
-	Findone(query *dto.SearchPatientDto) ([]*entities.Patient, error)
-	FindoneId(id string) (*entities.Patient, error)
+	Save(ctx context.Context, patient *entities.Patient) (*entities.Patient, error)
+	Update(ctx context.Context, patient *entities.Patient) (*entities.Patient, error)
+	Findone(ctx context.Context, query *dto.SearchPatientDto) ([]*entities.Patient, error)
+	FindInBatches(ctx context.Context, query *dto.SearchPatientDto, batchSize int, fn func(patients []*entities.Patient) error) error
+	// FindoneId finds the patient of hospital with id as their national ID
+	// or passport. Passports are only unique within a hospital.
+	FindoneId(ctx context.Context, id string, hospital string) (*entities.Patient, error)
+	FindById(ctx context.Context, id uint) (*entities.Patient, error)
 }
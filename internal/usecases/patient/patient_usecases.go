@@ -3,12 +3,19 @@ package patient
 import (
 	"agnos/internal/adapters/patient/dto"
 	"agnos/internal/entities"
+	"agnos/pkg/tracing"
+	"context"
 )
 
+const exportBatchSize = 500
+
 type PatientUseCase interface {
-	CreatePatient(patient *entities.Patient) (*entities.Patient, error)
-	SearchPatient(query *dto.SearchPatientDto) ([]*entities.Patient, error)
-	SearchPatientId(id string) (*entities.Patient, error)
+	CreatePatient(ctx context.Context, patient *entities.Patient) (*entities.Patient, error)
+	UpdatePatient(ctx context.Context, patient *entities.Patient) (*entities.Patient, error)
+	SearchPatient(ctx context.Context, query *dto.SearchPatientDto) ([]*entities.Patient, error)
+	ExportPatient(ctx context.Context, query *dto.SearchPatientDto, fn func(patients []*entities.Patient) error) error
+	SearchPatientId(ctx context.Context, id string, hospital string) (*entities.Patient, error)
+	GetPatient(ctx context.Context, id uint) (*entities.Patient, error)
 }
 
 type PatientService struct {
@@ -19,14 +26,46 @@ func NewPatientService(repo PatientRepository) PatientUseCase {
 	return &PatientService{repo: repo}
 }
 
-func (s *PatientService) CreatePatient(patient *entities.Patient) (*entities.Patient, error) {
-	return s.repo.Save(patient)
+func (s *PatientService) CreatePatient(ctx context.Context, patient *entities.Patient) (created *entities.Patient, err error) {
+	ctx, span := tracing.Tracer("patient").Start(ctx, "PatientService.CreatePatient")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.Save(ctx, patient)
 }
 
-func (s *PatientService) SearchPatient(query *dto.SearchPatientDto) ([]*entities.Patient, error) {
-	return s.repo.Findone(query)
+func (s *PatientService) UpdatePatient(ctx context.Context, patient *entities.Patient) (updated *entities.Patient, err error) {
+	ctx, span := tracing.Tracer("patient").Start(ctx, "PatientService.UpdatePatient")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.Update(ctx, patient)
 }
 
-func (s *PatientService) SearchPatientId(id string) (*entities.Patient, error) {
-	return s.repo.FindoneId(id)
+func (s *PatientService) SearchPatient(ctx context.Context, query *dto.SearchPatientDto) (patients []*entities.Patient, err error) {
+	ctx, span := tracing.Tracer("patient").Start(ctx, "PatientService.SearchPatient")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.Findone(ctx, query)
+}
+
+// ExportPatient hands the matching patients to fn in batches so that large
+// exports never hold the whole result in memory.
+func (s *PatientService) ExportPatient(ctx context.Context, query *dto.SearchPatientDto, fn func(patients []*entities.Patient) error) (err error) {
+	ctx, span := tracing.Tracer("patient").Start(ctx, "PatientService.ExportPatient")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.FindInBatches(ctx, query, exportBatchSize, fn)
+}
+
+func (s *PatientService) SearchPatientId(ctx context.Context, id string, hospital string) (found *entities.Patient, err error) {
+	ctx, span := tracing.Tracer("patient").Start(ctx, "PatientService.SearchPatientId")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.FindoneId(ctx, id, hospital)
+}
+
+func (s *PatientService) GetPatient(ctx context.Context, id uint) (found *entities.Patient, err error) {
+	ctx, span := tracing.Tracer("patient").Start(ctx, "PatientService.GetPatient")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.FindById(ctx, id)
 }
@@ -0,0 +1,38 @@
+package patient
+
+import (
+	"agnos/internal/entities"
+	"fmt"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var (
+	validate        = validator.New()
+	validateBinding = newBindingValidator()
+)
+
+func newBindingValidator() *validator.Validate {
+	v := validator.New()
+	v.SetTagName("binding")
+	return v
+}
+
+// ValidatePatient applies the same rules as POST /patient/create: the
+// `binding` tags enforced by gin and the `validate` tags checked afterwards.
+func ValidatePatient(patient *entities.Patient) []string {
+	messages := make([]string, 0)
+	for _, v := range []*validator.Validate{validateBinding, validate} {
+		if err := v.Struct(patient); err != nil {
+			errs, ok := err.(validator.ValidationErrors)
+			if !ok {
+				return append(messages, err.Error())
+			}
+			for _, e := range errs {
+				messages = append(messages, fmt.Sprintf("%s is %s", strings.ToLower(e.Field()), strings.ToLower(e.Tag())))
+			}
+		}
+	}
+	return messages
+}
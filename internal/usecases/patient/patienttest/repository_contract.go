@@ -0,0 +1,253 @@
+// Package patienttest holds the behaviour every patient.PatientRepository
+// must share, so that tests written against one implementation hold for
+// all of them.
+package patienttest
+
+import (
+	"agnos/internal/adapters/patient/dto"
+	"agnos/internal/entities"
+	"agnos/internal/usecases/patient"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// RepositoryContract runs the contract against repositories made by
+// newRepository, which must return an empty repository on every call. The
+// default encryption keyring must be set, as the blind indexes need it.
+func RepositoryContract(t *testing.T, newRepository func(t *testing.T) patient.PatientRepository) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repo patient.PatientRepository)
+	}{
+		{"SaveAndFindById", testSaveAndFindById},
+		{"SaveRejectsDuplicateNationalId", testSaveRejectsDuplicateNationalId},
+		{"UpdateRejectsAnotherPatientsNationalId", testUpdateRejectsAnotherPatientsNationalId},
+		{"FindoneIdMatchesNationalIdOrPassport", testFindoneId},
+		{"FindoneIdScopesByHospital", testFindoneIdScopesByHospital},
+		{"FindoneFilters", testFindoneFilters},
+		{"FindoneScopesByHospital", testFindoneScopesByHospital},
+		{"FindInBatches", testFindInBatches},
+		{"CancelledContext", testCancelledContext},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.run(t, newRepository(t))
+		})
+	}
+}
+
+func newPatient(nationalId string, hospital string) *entities.Patient {
+	return &entities.Patient{
+		FirstNameTh: "สมชาย",
+		LastNameTh:  "ใจดี",
+		FirstNameEn: "Somchai",
+		LastNameEn:  "Jaidee",
+		DateBirth:   time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC),
+		PatientHn:   "HN-" + nationalId,
+		NationalId:  nationalId,
+		PhoneNumber: "0812345678",
+		Gender:      "male",
+		Hospital:    hospital,
+	}
+}
+
+func save(t *testing.T, repo patient.PatientRepository, patients ...*entities.Patient) {
+	t.Helper()
+	for _, p := range patients {
+		_, err := repo.Save(context.Background(), p)
+		require.NoError(t, err)
+	}
+}
+
+func ids(patients []*entities.Patient) []uint {
+	ids := make([]uint, 0, len(patients))
+	for _, p := range patients {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
+
+func testSaveAndFindById(t *testing.T, repo patient.PatientRepository) {
+	ctx := context.Background()
+	saved, err := repo.Save(ctx, newPatient("1100000000001", "Bangkok Hospital"))
+	require.NoError(t, err)
+	assert.NotZero(t, saved.ID)
+	assert.NotEmpty(t, saved.NationalIdIndex)
+
+	found, err := repo.FindById(ctx, saved.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "1100000000001", found.NationalId)
+	assert.Equal(t, "Somchai", found.FirstNameEn)
+	assert.Equal(t, "Bangkok Hospital", found.Hospital)
+
+	_, err = repo.FindById(ctx, saved.ID+1000)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func testSaveRejectsDuplicateNationalId(t *testing.T, repo patient.PatientRepository) {
+	save(t, repo, newPatient("1100000000001", "Bangkok Hospital"))
+
+	_, err := repo.Save(context.Background(), newPatient("1100000000001", "Siriraj Hospital"))
+	assert.EqualError(t, err, "national_id already exist")
+}
+
+func testUpdateRejectsAnotherPatientsNationalId(t *testing.T, repo patient.PatientRepository) {
+	ctx := context.Background()
+	first, second := newPatient("1100000000001", "Bangkok Hospital"), newPatient("1100000000002", "Bangkok Hospital")
+	save(t, repo, first, second)
+
+	second.FirstNameEn = "Somsak"
+	_, err := repo.Update(ctx, second)
+	assert.NoError(t, err)
+	found, err := repo.FindById(ctx, second.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Somsak", found.FirstNameEn)
+
+	second.NationalId = first.NationalId
+	_, err = repo.Update(ctx, second)
+	assert.EqualError(t, err, "national_id already exist")
+}
+
+func testFindoneId(t *testing.T, repo patient.PatientRepository) {
+	ctx := context.Background()
+	withPassport := newPatient("1100000000001", "Bangkok Hospital")
+	withPassport.PassportId = "AA1234567"
+	save(t, repo, withPassport, newPatient("1100000000002", "Bangkok Hospital"))
+
+	found, err := repo.FindoneId(ctx, "1100000000002", "Bangkok Hospital")
+	require.NoError(t, err)
+	assert.Equal(t, "1100000000002", found.NationalId)
+
+	found, err = repo.FindoneId(ctx, "AA1234567", "bangkok hospital")
+	require.NoError(t, err)
+	assert.Equal(t, withPassport.ID, found.ID)
+
+	_, err = repo.FindoneId(ctx, "1100000000009", "Bangkok Hospital")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, err = repo.FindoneId(ctx, "1100000000002", "Siriraj Hospital")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+// Passports are only unique within a hospital, so each hospital finds its
+// own patient.
+func testFindoneIdScopesByHospital(t *testing.T, repo patient.PatientRepository) {
+	ctx := context.Background()
+	bangkok := newPatient("1100000000001", "Bangkok Hospital")
+	bangkok.PassportId = "AA1234567"
+	siriraj := newPatient("1100000000002", "Siriraj Hospital")
+	siriraj.PassportId = "AA1234567"
+	save(t, repo, bangkok, siriraj)
+
+	found, err := repo.FindoneId(ctx, "AA1234567", "Siriraj Hospital")
+	require.NoError(t, err)
+	assert.Equal(t, siriraj.ID, found.ID)
+
+	found, err = repo.FindoneId(ctx, "AA1234567", "Bangkok Hospital")
+	require.NoError(t, err)
+	assert.Equal(t, bangkok.ID, found.ID)
+}
+
+func testFindoneFilters(t *testing.T, repo patient.PatientRepository) {
+	somchai := newPatient("1100000000001", "Bangkok Hospital")
+	somchai.Email = "somchai@example.com"
+	malee := newPatient("1100000000002", "Bangkok Hospital")
+	malee.FirstNameTh, malee.FirstNameEn, malee.MiddleNameEn = "มาลี", "Malee", "Kanya"
+	malee.LastNameTh, malee.LastNameEn = "มีสุข", "Meesuk"
+	malee.Gender = "female"
+	malee.DateBirth = time.Date(1985, 2, 3, 0, 0, 0, 0, time.UTC)
+	malee.PhoneNumber = "0898765432"
+	malee.PassportId = "AB7654321"
+	save(t, repo, somchai, malee)
+
+	tests := []struct {
+		name  string
+		query dto.SearchPatientDto
+		want  []uint
+	}{
+		{"everything", dto.SearchPatientDto{}, []uint{somchai.ID, malee.ID}},
+		{"name in English ignores case", dto.SearchPatientDto{Name: "MALE"}, []uint{malee.ID}},
+		{"name in Thai", dto.SearchPatientDto{Name: "ใจดี"}, []uint{somchai.ID}},
+		{"name matches middle names", dto.SearchPatientDto{Name: "kanya"}, []uint{malee.ID}},
+		{"first name", dto.SearchPatientDto{FirstName: "som"}, []uint{somchai.ID}},
+		{"first name is not a last name", dto.SearchPatientDto{FirstName: "jaidee"}, nil},
+		{"first name combines with other filters", dto.SearchPatientDto{FirstName: "somchai", Gender: "female"}, nil},
+		{"last name", dto.SearchPatientDto{LastName: "มีสุข"}, []uint{malee.ID}},
+		{"last name is not a first name", dto.SearchPatientDto{LastName: "malee"}, nil},
+		{"middle name", dto.SearchPatientDto{MiddleName: "KAN"}, []uint{malee.ID}},
+		{"identifier as national ID", dto.SearchPatientDto{Identifier: "1100000000001"}, []uint{somchai.ID}},
+		{"identifier as passport", dto.SearchPatientDto{Identifier: "AB7654321"}, []uint{malee.ID}},
+		{"identifier as HN", dto.SearchPatientDto{Identifier: "HN-1100000000002"}, []uint{malee.ID}},
+		{"date of birth", dto.SearchPatientDto{DateofBirth: time.Date(1985, 2, 3, 0, 0, 0, 0, time.UTC)}, []uint{malee.ID}},
+		{"gender ignores case", dto.SearchPatientDto{Gender: "Female"}, []uint{malee.ID}},
+		{"passport", dto.SearchPatientDto{PassportId: "AB7654321"}, []uint{malee.ID}},
+		{"email", dto.SearchPatientDto{Email: "somchai@example.com"}, []uint{somchai.ID}},
+		{"phone number", dto.SearchPatientDto{PhoneNumber: "0898765432"}, []uint{malee.ID}},
+		{"national ID", dto.SearchPatientDto{NationalId: "1100000000002"}, []uint{malee.ID}},
+		{"filters combine", dto.SearchPatientDto{Name: "somchai", Gender: "female"}, nil},
+		{"wildcards match literally", dto.SearchPatientDto{Name: "%"}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			found, err := repo.Findone(context.Background(), &test.query)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, test.want, ids(found))
+		})
+	}
+}
+
+func testFindoneScopesByHospital(t *testing.T, repo patient.PatientRepository) {
+	bangkok := newPatient("1100000000001", "Bangkok Hospital")
+	siriraj := newPatient("1100000000002", "Siriraj Hospital")
+	save(t, repo, bangkok, siriraj)
+
+	found, err := repo.Findone(context.Background(), &dto.SearchPatientDto{Hospital: "bangkok hospital"})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{bangkok.ID}, ids(found))
+
+	found, err = repo.Findone(context.Background(), &dto.SearchPatientDto{Hospital: "Chulalongkorn Hospital"})
+	require.NoError(t, err)
+	assert.Empty(t, found)
+}
+
+func testFindInBatches(t *testing.T, repo patient.PatientRepository) {
+	var saved []uint
+	for _, nationalId := range []string{"1100000000001", "1100000000002", "1100000000003", "1100000000004", "1100000000005"} {
+		p := newPatient(nationalId, "Bangkok Hospital")
+		save(t, repo, p)
+		saved = append(saved, p.ID)
+	}
+	save(t, repo, newPatient("1100000000006", "Siriraj Hospital"))
+
+	var sizes []int
+	var seen []uint
+	err := repo.FindInBatches(context.Background(), &dto.SearchPatientDto{Hospital: "Bangkok Hospital"}, 2, func(patients []*entities.Patient) error {
+		sizes = append(sizes, len(patients))
+		seen = append(seen, ids(patients)...)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 2, 1}, sizes)
+	assert.Equal(t, saved, seen)
+}
+
+func testCancelledContext(t *testing.T, repo patient.PatientRepository) {
+	save(t, repo, newPatient("1100000000001", "Bangkok Hospital"))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := repo.Save(ctx, newPatient("1100000000002", "Bangkok Hospital"))
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.Findone(ctx, &dto.SearchPatientDto{})
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.FindoneId(ctx, "1100000000001", "Bangkok Hospital")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	found, err := repo.Findone(context.Background(), &dto.SearchPatientDto{})
+	require.NoError(t, err)
+	assert.Len(t, found, 1)
+}
@@ -0,0 +1,21 @@
+package retention
+
+import (
+	"agnos/internal/entities"
+	"context"
+	"time"
+)
+
+type RetentionRepository interface {
+	SavePolicy(ctx context.Context, policy *entities.RetentionPolicy) (*entities.RetentionPolicy, error)
+	FindPolicy(ctx context.Context, id uint) (*entities.RetentionPolicy, error)
+	FindPolicies(ctx context.Context, hospital string) ([]*entities.RetentionPolicy, error)
+	DeletePolicy(ctx context.Context, id uint) error
+	SaveRun(ctx context.Context, run *entities.RetentionRun) error
+	FindRuns(ctx context.Context, hospital string, limit int) ([]*entities.RetentionRun, error)
+	CountEligible(ctx context.Context, policy *entities.RetentionPolicy, cutoff time.Time) (int64, error)
+	ApplyBatch(ctx context.Context, policy *entities.RetentionPolicy, cutoff time.Time, batchSize int) (int, error)
+	// TryLock takes the lock that keeps replicas from running the purge at
+	// the same time. ok is false when another process holds it.
+	TryLock(ctx context.Context) (unlock func(), ok bool, err error)
+}
@@ -0,0 +1,222 @@
+package retention
+
+import (
+	"agnos/internal/entities"
+	"agnos/pkg/logging"
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	batchSize = 500
+	runsLimit = 100
+)
+
+var ErrLocked = errors.New("retention purge is already running")
+
+// Metrics are published through expvar under "retention".
+var metrics = expvar.NewMap("retention")
+
+type RetentionUseCase interface {
+	CreatePolicy(ctx context.Context, policy *entities.RetentionPolicy) (*entities.RetentionPolicy, error)
+	UpdatePolicy(ctx context.Context, policy *entities.RetentionPolicy) (*entities.RetentionPolicy, error)
+	DeletePolicy(ctx context.Context, hospital string, id uint) error
+	ListPolicies(ctx context.Context, hospital string) ([]*entities.RetentionPolicy, error)
+	ListRuns(ctx context.Context, hospital string) ([]*entities.RetentionRun, error)
+	Run(ctx context.Context, hospital string, dryRun bool) ([]*entities.RetentionRun, error)
+	Start(interval time.Duration, dryRun bool)
+	Stop()
+}
+
+type RetentionService struct {
+	repo RetentionRepository
+	now  func() time.Time
+
+	mu   sync.Mutex
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewRetentionService(repo RetentionRepository) RetentionUseCase {
+	return &RetentionService{repo: repo, now: time.Now}
+}
+
+func (s *RetentionService) CreatePolicy(ctx context.Context, policy *entities.RetentionPolicy) (*entities.RetentionPolicy, error) {
+	if err := checkPolicy(policy); err != nil {
+		return nil, err
+	}
+	policy.ID = 0
+	return s.repo.SavePolicy(ctx, policy)
+}
+
+func (s *RetentionService) UpdatePolicy(ctx context.Context, policy *entities.RetentionPolicy) (*entities.RetentionPolicy, error) {
+	if err := checkPolicy(policy); err != nil {
+		return nil, err
+	}
+	existing, err := s.findPolicy(ctx, policy.Hospital, policy.ID)
+	if err != nil {
+		return nil, err
+	}
+	policy.CreatedAt = existing.CreatedAt
+	return s.repo.SavePolicy(ctx, policy)
+}
+
+func (s *RetentionService) DeletePolicy(ctx context.Context, hospital string, id uint) error {
+	if _, err := s.findPolicy(ctx, hospital, id); err != nil {
+		return err
+	}
+	return s.repo.DeletePolicy(ctx, id)
+}
+
+func (s *RetentionService) ListPolicies(ctx context.Context, hospital string) ([]*entities.RetentionPolicy, error) {
+	return s.repo.FindPolicies(ctx, hospital)
+}
+
+func (s *RetentionService) ListRuns(ctx context.Context, hospital string) ([]*entities.RetentionRun, error) {
+	return s.repo.FindRuns(ctx, hospital, runsLimit)
+}
+
+// Run applies the enabled policies of hospital, or of every hospital when
+// hospital is empty. A policy marked as dry run, or a dry run request, only
+// reports how many records would be processed.
+func (s *RetentionService) Run(ctx context.Context, hospital string, dryRun bool) ([]*entities.RetentionRun, error) {
+	unlock, ok, err := s.repo.TryLock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		metrics.Add("lock_skipped", 1)
+		return nil, ErrLocked
+	}
+	defer unlock()
+
+	policies, err := s.repo.FindPolicies(ctx, hospital)
+	if err != nil {
+		return nil, err
+	}
+
+	runs := make([]*entities.RetentionRun, 0, len(policies))
+	for _, policy := range policies {
+		if !policy.Enabled {
+			continue
+		}
+		run := s.apply(ctx, policy, dryRun || policy.DryRun)
+		if err := s.repo.SaveRun(ctx, run); err != nil {
+			return runs, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+func (s *RetentionService) apply(ctx context.Context, policy *entities.RetentionPolicy, dryRun bool) *entities.RetentionRun {
+	run := &entities.RetentionRun{
+		PolicyID:  policy.ID,
+		Hospital:  policy.Hospital,
+		Entity:    policy.Entity,
+		Action:    policy.Action,
+		DryRun:    dryRun,
+		Cutoff:    s.now().AddDate(0, 0, -policy.AfterDays),
+		StartedAt: s.now(),
+	}
+	defer func() { run.FinishedAt = s.now() }()
+
+	metric := policy.Entity + "." + policy.Action
+	if dryRun {
+		metrics.Add("dry_runs", 1)
+	} else {
+		metrics.Add("runs", 1)
+	}
+
+	matched, err := s.repo.CountEligible(ctx, policy, run.Cutoff)
+	if err != nil {
+		metrics.Add("errors", 1)
+		run.Error = err.Error()
+		return run
+	}
+	run.Matched = matched
+	if dryRun {
+		metrics.Add(metric+".matched", matched)
+		return run
+	}
+
+	for {
+		processed, err := s.repo.ApplyBatch(ctx, policy, run.Cutoff, batchSize)
+		if err != nil {
+			metrics.Add("errors", 1)
+			run.Error = err.Error()
+			return run
+		}
+		if processed == 0 {
+			return run
+		}
+		run.Batches++
+		run.Processed += processed
+		metrics.Add(metric+".processed", int64(processed))
+		if processed < batchSize {
+			return run
+		}
+	}
+}
+
+// Start runs every hospital's policies on interval in the background until
+// Stop is called. Replicas that lose the lock skip the tick.
+func (s *RetentionService) Start(interval time.Duration, dryRun bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		return
+	}
+	s.stop = make(chan struct{})
+
+	s.wg.Add(1)
+	go func(stop chan struct{}) {
+		defer s.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if _, err := s.Run(context.Background(), "", dryRun); err != nil && !errors.Is(err, ErrLocked) {
+					logging.For("retention").Error("scheduled purge failed", "error", err.Error())
+				}
+			}
+		}
+	}(s.stop)
+}
+
+// Stop ends the background worker and waits for a running purge to finish.
+func (s *RetentionService) Stop() {
+	s.mu.Lock()
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+func (s *RetentionService) findPolicy(ctx context.Context, hospital string, id uint) (*entities.RetentionPolicy, error) {
+	policy, err := s.repo.FindPolicy(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(policy.Hospital, hospital) {
+		return nil, fmt.Errorf("retention policy not found")
+	}
+	return policy, nil
+}
+
+func checkPolicy(policy *entities.RetentionPolicy) error {
+	if policy.Action == entities.RetentionActionAnonymize && policy.Entity != entities.RetentionEntityPatient {
+		return fmt.Errorf("action anonymize only applies to patient")
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package retention
+
+import (
+	"agnos/internal/entities"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeRetentionRepository struct {
+	RetentionRepository
+	policies  []*entities.RetentionPolicy
+	remaining int
+	runs      []*entities.RetentionRun
+	locked    bool
+}
+
+func (r *fakeRetentionRepository) FindPolicies(ctx context.Context, hospital string) ([]*entities.RetentionPolicy, error) {
+	return r.policies, nil
+}
+
+func (r *fakeRetentionRepository) SaveRun(ctx context.Context, run *entities.RetentionRun) error {
+	r.runs = append(r.runs, run)
+	return nil
+}
+
+func (r *fakeRetentionRepository) CountEligible(ctx context.Context, policy *entities.RetentionPolicy, cutoff time.Time) (int64, error) {
+	return int64(r.remaining), nil
+}
+
+func (r *fakeRetentionRepository) ApplyBatch(ctx context.Context, policy *entities.RetentionPolicy, cutoff time.Time, size int) (int, error) {
+	processed := min(size, r.remaining)
+	r.remaining -= processed
+	return processed, nil
+}
+
+func (r *fakeRetentionRepository) TryLock(ctx context.Context) (func(), bool, error) {
+	if r.locked {
+		return nil, false, nil
+	}
+	r.locked = true
+	return func() { r.locked = false }, true, nil
+}
+
+func TestRunAppliesPoliciesInBatches(t *testing.T) {
+	repo := &fakeRetentionRepository{
+		policies: []*entities.RetentionPolicy{
+			{Entity: entities.RetentionEntityAuditLog, Action: entities.RetentionActionDelete, AfterDays: 30, Enabled: true},
+			{Entity: entities.RetentionEntityImportJob, Action: entities.RetentionActionDelete, AfterDays: 30},
+		},
+		remaining: 2*batchSize + 10,
+	}
+	service := NewRetentionService(repo)
+
+	runs, err := service.Run(context.Background(), "", true)
+	assert.NoError(t, err)
+	assert.Len(t, runs, 1)
+	assert.Equal(t, int64(2*batchSize+10), runs[0].Matched)
+	assert.Equal(t, 2*batchSize+10, repo.remaining)
+
+	runs, err = service.Run(context.Background(), "", false)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, runs[0].Batches)
+	assert.Equal(t, 2*batchSize+10, runs[0].Processed)
+	assert.Equal(t, 0, repo.remaining)
+	assert.False(t, repo.locked)
+}
+
+func TestRunSkipsWhenLocked(t *testing.T) {
+	repo := &fakeRetentionRepository{locked: true}
+	service := NewRetentionService(repo)
+
+	_, err := service.Run(context.Background(), "", false)
+	assert.ErrorIs(t, err, ErrLocked)
+	assert.Empty(t, repo.runs)
+}
@@ -0,0 +1,12 @@
+package sharing
+
+import (
+	"agnos/internal/entities"
+	"context"
+)
+
+type SharingRepository interface {
+	SaveAgreement(ctx context.Context, agreement *entities.SharingAgreement) (*entities.SharingAgreement, error)
+	FindAgreement(ctx context.Context, id uint) (*entities.SharingAgreement, error)
+	FindAgreements(ctx context.Context, hospital string, status string) ([]*entities.SharingAgreement, error)
+}
@@ -0,0 +1,210 @@
+package sharing
+
+import (
+	patientDto "agnos/internal/adapters/patient/dto"
+	"agnos/internal/adapters/sharing/dto"
+	"agnos/internal/entities"
+	"agnos/internal/usecases/audit"
+	"agnos/internal/usecases/consent"
+	"agnos/internal/usecases/patient"
+	"agnos/pkg/mask"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type SharingUseCase interface {
+	ProposeAgreement(ctx context.Context, data *dto.ProposeAgreementDto) (*entities.SharingAgreement, error)
+	AcceptAgreement(ctx context.Context, data *dto.AgreementActionDto) (*entities.SharingAgreement, error)
+	RevokeAgreement(ctx context.Context, data *dto.AgreementActionDto) (*entities.SharingAgreement, error)
+	ListAgreements(ctx context.Context, hospital string, status string) ([]*entities.SharingAgreement, error)
+	SearchPartners(ctx context.Context, query *patientDto.SearchPatientDto, actor string, role string) ([]*dto.PartnerResult, error)
+}
+
+type SharingService struct {
+	repo           SharingRepository
+	patientUseCase patient.PatientUseCase
+	consentUseCase consent.ConsentUseCase
+	auditUseCase   audit.AuditUseCase
+}
+
+func NewSharingService(repo SharingRepository, patientUseCase patient.PatientUseCase, consentUseCase consent.ConsentUseCase, auditUseCase audit.AuditUseCase) SharingUseCase {
+	return &SharingService{repo: repo, patientUseCase: patientUseCase, consentUseCase: consentUseCase, auditUseCase: auditUseCase}
+}
+
+// ProposeAgreement records an agreement for the partner to accept. Nothing
+// is shared until the partner hospital's admin accepts it.
+func (s *SharingService) ProposeAgreement(ctx context.Context, data *dto.ProposeAgreementDto) (*entities.SharingAgreement, error) {
+	if strings.EqualFold(data.PartnerHospital, data.Hospital) {
+		return nil, fmt.Errorf("partner hospital must be another hospital")
+	}
+	if _, err := mask.ParsePolicy(data.Fields); err != nil {
+		return nil, err
+	}
+
+	agreement := &entities.SharingAgreement{
+		Hospital:        data.Hospital,
+		PartnerHospital: data.PartnerHospital,
+		Direction:       data.Direction,
+		Fields:          data.Fields,
+		Roles:           data.Roles,
+		Status:          entities.SharingStatusProposed,
+		ProposedBy:      data.ProposedBy,
+	}
+	if _, err := s.repo.SaveAgreement(ctx, agreement); err != nil {
+		return nil, err
+	}
+	s.record(ctx, agreement, data.ProposedBy, data.Role, data.Hospital, "sharing.propose")
+	return agreement, nil
+}
+
+func (s *SharingService) AcceptAgreement(ctx context.Context, data *dto.AgreementActionDto) (*entities.SharingAgreement, error) {
+	agreement, err := s.findAgreement(ctx, data.Id, data.Hospital)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(agreement.PartnerHospital, data.Hospital) {
+		return nil, fmt.Errorf("only the partner hospital can accept the agreement")
+	}
+	if agreement.Status != entities.SharingStatusProposed {
+		return nil, fmt.Errorf("agreement is %s", agreement.Status)
+	}
+
+	now := time.Now()
+	agreement.Status, agreement.AcceptedBy, agreement.AcceptedAt = entities.SharingStatusActive, data.Actor, &now
+	if _, err := s.repo.SaveAgreement(ctx, agreement); err != nil {
+		return nil, err
+	}
+	s.record(ctx, agreement, data.Actor, data.Role, data.Hospital, "sharing.accept")
+	return agreement, nil
+}
+
+// RevokeAgreement ends the agreement. Either hospital may revoke it.
+func (s *SharingService) RevokeAgreement(ctx context.Context, data *dto.AgreementActionDto) (*entities.SharingAgreement, error) {
+	agreement, err := s.findAgreement(ctx, data.Id, data.Hospital)
+	if err != nil {
+		return nil, err
+	}
+	if agreement.Status == entities.SharingStatusRevoked {
+		return nil, fmt.Errorf("agreement is %s", agreement.Status)
+	}
+
+	now := time.Now()
+	agreement.Status, agreement.RevokedBy, agreement.RevokedAt = entities.SharingStatusRevoked, data.Actor, &now
+	if _, err := s.repo.SaveAgreement(ctx, agreement); err != nil {
+		return nil, err
+	}
+	s.record(ctx, agreement, data.Actor, data.Role, data.Hospital, "sharing.revoke")
+	return agreement, nil
+}
+
+func (s *SharingService) ListAgreements(ctx context.Context, hospital string, status string) ([]*entities.SharingAgreement, error) {
+	return s.repo.FindAgreements(ctx, hospital, status)
+}
+
+// SearchPartners runs query against every hospital sharing with the
+// caller's hospital under an active agreement that allows role. Only
+// patients with an active data sharing consent are returned and each is
+// masked by the stricter of the role policy and the agreement's fields.
+// Every partner search that returns patients is audited under the partner.
+func (s *SharingService) SearchPartners(ctx context.Context, query *patientDto.SearchPatientDto, actor string, role string) ([]*dto.PartnerResult, error) {
+	hospital := query.Hospital
+	agreements, err := s.repo.FindAgreements(ctx, hospital, entities.SharingStatusActive)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*dto.PartnerResult, 0)
+	searched := make(map[string]bool)
+	for _, agreement := range agreements {
+		partner := agreement.Counterpart(hospital)
+		if searched[strings.ToLower(partner)] || !agreement.Shares(partner, hospital) || !agreement.AllowsRole(role) {
+			continue
+		}
+		searched[strings.ToLower(partner)] = true
+
+		fields, err := mask.ParsePolicy(agreement.Fields)
+		if err != nil {
+			return nil, err
+		}
+		policy := mask.PolicyFor(role).Restrict(fields)
+
+		partnerQuery := *query
+		partnerQuery.Hospital = partner
+		found, err := s.patientUseCase.SearchPatient(ctx, &partnerQuery)
+		if err != nil {
+			return nil, err
+		}
+		if len(found) == 0 {
+			continue
+		}
+
+		ids := make([]uint, 0, len(found))
+		for _, p := range found {
+			ids = append(ids, p.ID)
+		}
+		consented, err := s.consentUseCase.FilterConsented(ctx, ids, entities.ConsentPurposeDataSharing)
+		if err != nil {
+			return nil, err
+		}
+
+		shared := make([]string, 0)
+		for _, p := range found {
+			if !consented[p.ID] {
+				continue
+			}
+			results = append(results, &dto.PartnerResult{
+				SourceHospital: p.Hospital,
+				AgreementId:    agreement.ID,
+				Patient:        patient.MaskPatient(p, policy),
+			})
+			shared = append(shared, strconv.FormatUint(uint64(p.ID), 10))
+		}
+		if len(shared) == 0 {
+			continue
+		}
+
+		_, err = s.auditUseCase.Record(ctx, &entities.AuditLog{
+			Actor:      actor,
+			ActorRole:  role,
+			Hospital:   partner,
+			Action:     "patient.partner_search",
+			Resource:   "sharing_agreement",
+			ResourceId: strconv.FormatUint(uint64(agreement.ID), 10),
+		}, map[string]interface{}{"actor_hospital": hospital, "patient_ids": shared})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+func (s *SharingService) findAgreement(ctx context.Context, id uint, hospital string) (*entities.SharingAgreement, error) {
+	agreement, err := s.repo.FindAgreement(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(agreement.Hospital, hospital) && !strings.EqualFold(agreement.PartnerHospital, hospital) {
+		return nil, fmt.Errorf("agreement not found")
+	}
+	return agreement, nil
+}
+
+func (s *SharingService) record(ctx context.Context, agreement *entities.SharingAgreement, actor string, role string, hospital string, action string) {
+	s.auditUseCase.Record(ctx, &entities.AuditLog{
+		Actor:      actor,
+		ActorRole:  role,
+		Hospital:   hospital,
+		Action:     action,
+		Resource:   "sharing_agreement",
+		ResourceId: strconv.FormatUint(uint64(agreement.ID), 10),
+	}, map[string]interface{}{
+		"hospital":         agreement.Hospital,
+		"partner_hospital": agreement.PartnerHospital,
+		"direction":        agreement.Direction,
+		"fields":           agreement.Fields,
+		"roles":            agreement.Roles,
+	})
+}
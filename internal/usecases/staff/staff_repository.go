@@ -3,9 +3,13 @@ package staff
 import (
 	"agnos/internal/adapters/staff/dto"
 	"agnos/internal/entities"
+	"context"
 )
 
 type StaffRepository interface {
-	Save(staff *entities.Staff) (*entities.Staff, error)
-	Login(staff *dto.LoginStaffDto) (*entities.Staff, error)
+	Save(ctx context.Context, staff *entities.Staff) (*entities.Staff, error)
+	Login(ctx context.Context, staff *dto.LoginStaffDto) (*entities.Staff, error)
+	UpdatePassword(ctx context.Context, username string, password string) error
+	UpdateRole(ctx context.Context, username string, role string) error
+	Delete(ctx context.Context, username string) error
 }
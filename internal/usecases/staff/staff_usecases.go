@@ -3,11 +3,25 @@ package staff
 import (
 	"agnos/internal/adapters/staff/dto"
 	"agnos/internal/entities"
+	"agnos/pkg/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
+// ErrNotFound is wrapped by the errors for a username that no active staff
+// has, which read "user with username X not found".
+var ErrNotFound = errors.New("not found")
+
 type StaffUseCase interface {
-	CreateStaff(staff *entities.Staff) (*entities.Staff, error)
-	Login(staff *dto.LoginStaffDto) (*entities.Staff, error)
+	CreateStaff(ctx context.Context, staff *entities.Staff) (*entities.Staff, error)
+	Login(ctx context.Context, staff *dto.LoginStaffDto) (*entities.Staff, error)
+	ResetPassword(ctx context.Context, username string, password string) error
+	ChangeRole(ctx context.Context, hospital string, username string, role string) error
+	DeactivateStaff(ctx context.Context, username string) error
 }
 
 type StaffService struct {
@@ -18,10 +32,63 @@ func NewStaffService(repo StaffRepository) StaffUseCase {
 	return &StaffService{repo: repo}
 }
 
-func (s *StaffService) CreateStaff(staff *entities.Staff) (*entities.Staff, error) {
-	return s.repo.Save(staff)
+// CreateStaff stores staff with its plain text password replaced by a
+// bcrypt hash. Staff without a role become receptionists.
+func (s *StaffService) CreateStaff(ctx context.Context, staff *entities.Staff) (created *entities.Staff, err error) {
+	ctx, span := tracing.Tracer("staff").Start(ctx, "StaffService.CreateStaff")
+	defer func() { tracing.End(span, err) }()
+
+	if staff.Role == "" {
+		staff.Role = entities.RoleReceptionist
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(staff.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	staff.Password = string(hashedPassword)
+
+	return s.repo.Save(ctx, staff)
+}
+
+func (s *StaffService) Login(ctx context.Context, staff *dto.LoginStaffDto) (found *entities.Staff, err error) {
+	ctx, span := tracing.Tracer("staff").Start(ctx, "StaffService.Login")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.Login(ctx, staff)
+}
+
+func (s *StaffService) ResetPassword(ctx context.Context, username string, password string) (err error) {
+	ctx, span := tracing.Tracer("staff").Start(ctx, "StaffService.ResetPassword")
+	defer func() { tracing.End(span, err) }()
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return s.repo.UpdatePassword(ctx, username, string(hashedPassword))
 }
 
-func (s *StaffService) Login(staff *dto.LoginStaffDto) (*entities.Staff, error) {
-	return s.repo.Login(staff)
+// ChangeRole gives username another role. Only staff of hospital can be
+// changed, so that an admin cannot promote accounts of other hospitals.
+func (s *StaffService) ChangeRole(ctx context.Context, hospital string, username string, role string) (err error) {
+	ctx, span := tracing.Tracer("staff").Start(ctx, "StaffService.ChangeRole")
+	defer func() { tracing.End(span, err) }()
+
+	staff, err := s.repo.Login(ctx, &dto.LoginStaffDto{Username: username})
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(staff.Hospital, hospital) {
+		return fmt.Errorf("user with username %s %w", username, ErrNotFound)
+	}
+	return s.repo.UpdateRole(ctx, username, role)
+}
+
+// DeactivateStaff soft deletes the account so that it can no longer log in.
+// Tokens already issued stay valid until they expire.
+func (s *StaffService) DeactivateStaff(ctx context.Context, username string) (err error) {
+	ctx, span := tracing.Tracer("staff").Start(ctx, "StaffService.DeactivateStaff")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.Delete(ctx, username)
 }
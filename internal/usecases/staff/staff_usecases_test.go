@@ -0,0 +1,47 @@
+package staff_test
+
+import (
+	"context"
+	"testing"
+
+	adapters "agnos/internal/adapters/staff"
+	"agnos/internal/adapters/staff/dto"
+	"agnos/internal/entities"
+	"agnos/internal/usecases/staff"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestStaffService_CreateResetAndDeactivate(t *testing.T) {
+	ctx := context.Background()
+	service := staff.NewStaffService(adapters.NewMemoryStaffRepository())
+
+	created, err := service.CreateStaff(ctx, &entities.Staff{Username: "somchai", Password: "89058905", Hospital: "Bangkok Hospital"})
+	require.NoError(t, err)
+	assert.Equal(t, entities.RoleReceptionist, created.Role)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(created.Password), []byte("89058905")))
+
+	require.NoError(t, service.ResetPassword(ctx, "somchai", "changed"))
+	found, err := service.Login(ctx, &dto.LoginStaffDto{Username: "somchai"})
+	require.NoError(t, err)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(found.Password), []byte("changed")))
+
+	require.NoError(t, service.DeactivateStaff(ctx, "somchai"))
+	_, err = service.Login(ctx, &dto.LoginStaffDto{Username: "somchai"})
+	assert.EqualError(t, err, "user with username somchai not found")
+}
+
+func TestStaffService_ChangeRoleStaysInHospital(t *testing.T) {
+	ctx := context.Background()
+	service := staff.NewStaffService(adapters.NewMemoryStaffRepository())
+	_, err := service.CreateStaff(ctx, &entities.Staff{Username: "somchai", Password: "89058905", Hospital: "Bangkok Hospital"})
+	require.NoError(t, err)
+
+	assert.EqualError(t, service.ChangeRole(ctx, "Siriraj Hospital", "somchai", entities.RoleAdmin), "user with username somchai not found")
+	require.NoError(t, service.ChangeRole(ctx, "bangkok hospital", "somchai", entities.RoleClinician))
+	found, err := service.Login(ctx, &dto.LoginStaffDto{Username: "somchai"})
+	require.NoError(t, err)
+	assert.Equal(t, entities.RoleClinician, found.Role)
+}
@@ -0,0 +1,123 @@
+// Package stafftest holds the behaviour every staff.StaffRepository must
+// share, so that tests written against one implementation hold for all of
+// them.
+package stafftest
+
+import (
+	"agnos/internal/adapters/staff/dto"
+	"agnos/internal/entities"
+	"agnos/internal/usecases/staff"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RepositoryContract runs the contract against repositories made by
+// newRepository, which must return an empty repository on every call.
+func RepositoryContract(t *testing.T, newRepository func(t *testing.T) staff.StaffRepository) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repo staff.StaffRepository)
+	}{
+		{"SaveAndLogin", testSaveAndLogin},
+		{"SaveRejectsDuplicateUsername", testSaveRejectsDuplicateUsername},
+		{"UpdatePassword", testUpdatePassword},
+		{"UpdateRole", testUpdateRole},
+		{"DeleteDeactivates", testDeleteDeactivates},
+		{"UnknownUsername", testUnknownUsername},
+		{"CancelledContext", testCancelledContext},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.run(t, newRepository(t))
+		})
+	}
+}
+
+func newStaff(username string) *entities.Staff {
+	return &entities.Staff{Username: username, Password: "hash", Hospital: "Bangkok Hospital", Role: entities.RoleClinician}
+}
+
+func testSaveAndLogin(t *testing.T, repo staff.StaffRepository) {
+	ctx := context.Background()
+	saved, err := repo.Save(ctx, newStaff("somchai"))
+	require.NoError(t, err)
+	assert.NotZero(t, saved.ID)
+
+	found, err := repo.Login(ctx, &dto.LoginStaffDto{Username: "somchai"})
+	require.NoError(t, err)
+	assert.Equal(t, saved.ID, found.ID)
+	assert.Equal(t, "hash", found.Password)
+	assert.Equal(t, "Bangkok Hospital", found.Hospital)
+	assert.Equal(t, entities.RoleClinician, found.Role)
+
+	// Usernames match exactly, whatever the database's collation.
+	_, err = repo.Login(ctx, &dto.LoginStaffDto{Username: "Somchai"})
+	assert.EqualError(t, err, "user with username Somchai not found")
+}
+
+func testSaveRejectsDuplicateUsername(t *testing.T, repo staff.StaffRepository) {
+	_, err := repo.Save(context.Background(), newStaff("somchai"))
+	require.NoError(t, err)
+
+	_, err = repo.Save(context.Background(), newStaff("somchai"))
+	assert.EqualError(t, err, "username already exist")
+}
+
+func testUpdatePassword(t *testing.T, repo staff.StaffRepository) {
+	ctx := context.Background()
+	_, err := repo.Save(ctx, newStaff("somchai"))
+	require.NoError(t, err)
+
+	assert.NoError(t, repo.UpdatePassword(ctx, "somchai", "new hash"))
+	found, err := repo.Login(ctx, &dto.LoginStaffDto{Username: "somchai"})
+	require.NoError(t, err)
+	assert.Equal(t, "new hash", found.Password)
+}
+
+func testUpdateRole(t *testing.T, repo staff.StaffRepository) {
+	ctx := context.Background()
+	_, err := repo.Save(ctx, newStaff("somchai"))
+	require.NoError(t, err)
+
+	assert.NoError(t, repo.UpdateRole(ctx, "somchai", entities.RoleAdmin))
+	found, err := repo.Login(ctx, &dto.LoginStaffDto{Username: "somchai"})
+	require.NoError(t, err)
+	assert.Equal(t, entities.RoleAdmin, found.Role)
+}
+
+func testDeleteDeactivates(t *testing.T, repo staff.StaffRepository) {
+	ctx := context.Background()
+	_, err := repo.Save(ctx, newStaff("somchai"))
+	require.NoError(t, err)
+
+	assert.NoError(t, repo.Delete(ctx, "somchai"))
+	_, err = repo.Login(ctx, &dto.LoginStaffDto{Username: "somchai"})
+	assert.EqualError(t, err, "user with username somchai not found")
+	assert.EqualError(t, repo.Delete(ctx, "somchai"), "user with username somchai not found")
+
+	// The username is free again once its account is deactivated.
+	_, err = repo.Save(ctx, newStaff("somchai"))
+	assert.NoError(t, err)
+}
+
+func testUnknownUsername(t *testing.T, repo staff.StaffRepository) {
+	ctx := context.Background()
+	_, err := repo.Login(ctx, &dto.LoginStaffDto{Username: "nobody"})
+	assert.EqualError(t, err, "user with username nobody not found")
+	assert.EqualError(t, repo.UpdatePassword(ctx, "nobody", "hash"), "user with username nobody not found")
+	assert.EqualError(t, repo.UpdateRole(ctx, "nobody", entities.RoleAdmin), "user with username nobody not found")
+	assert.EqualError(t, repo.Delete(ctx, "nobody"), "user with username nobody not found")
+}
+
+func testCancelledContext(t *testing.T, repo staff.StaffRepository) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := repo.Save(ctx, newStaff("somchai"))
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.Login(ctx, &dto.LoginStaffDto{Username: "somchai"})
+	assert.ErrorIs(t, err, context.Canceled)
+}
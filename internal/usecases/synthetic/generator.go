@@ -0,0 +1,231 @@
+// Package synthetic generates realistic, fake Thai patients for demos and
+// load tests.
+package synthetic
+
+import (
+	"agnos/internal/entities"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// DefaultAsOf anchors ages when Options.AsOf is unset, so that a seed gives
+// the same patients on every day.
+var DefaultAsOf = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type Options struct {
+	// Seed makes the output reproducible: the same options always give the
+	// same patients in the same order.
+	Seed uint64
+	// Hospitals are picked uniformly for each patient.
+	Hospitals []string
+	// AsOf is the date ages are counted from.
+	AsOf time.Time
+}
+
+type Generator struct {
+	rand      *rand.Rand
+	hospitals []string
+	asOf      time.Time
+	// issued keeps national IDs and passports unique within a run.
+	issued map[string]bool
+	hn     map[string]int
+}
+
+func NewGenerator(options Options) (*Generator, error) {
+	if len(options.Hospitals) == 0 {
+		return nil, fmt.Errorf("at least one hospital is required")
+	}
+	for _, hospital := range options.Hospitals {
+		if strings.TrimSpace(hospital) == "" {
+			return nil, fmt.Errorf("hospital names must not be empty")
+		}
+	}
+	asOf := options.AsOf
+	if asOf.IsZero() {
+		asOf = DefaultAsOf
+	}
+	return &Generator{
+		rand:      rand.New(rand.NewPCG(options.Seed, 0x6167_6e6f_7353)),
+		hospitals: options.Hospitals,
+		asOf:      asOf,
+		issued:    map[string]bool{},
+		hn:        map[string]int{},
+	}, nil
+}
+
+// Patient returns the next patient.
+func (g *Generator) Patient() *entities.Patient {
+	gender := "male"
+	firstName := pick(g.rand, maleFirstNames)
+	if g.rand.IntN(100) < 51 {
+		gender = "female"
+		firstName = pick(g.rand, femaleFirstNames)
+	}
+	lastName := pick(g.rand, lastNames)
+	dateBirth := g.dateBirth()
+	hospital := g.hospitals[g.rand.IntN(len(g.hospitals))]
+
+	patient := &entities.Patient{
+		FirstNameTh: firstName.th,
+		LastNameTh:  lastName.th,
+		FirstNameEn: firstName.en,
+		LastNameEn:  lastName.en,
+		DateBirth:   dateBirth,
+		PatientHn:   g.patientHn(hospital),
+		NationalId:  g.nationalId(dateBirth),
+		PhoneNumber: g.phoneNumber(),
+		Gender:      gender,
+		Hospital:    hospital,
+	}
+	// About one in six Thais holds a passport, and adults more often give
+	// an email address.
+	if g.rand.IntN(6) == 0 {
+		patient.PassportId = g.passportId()
+	}
+	if age(dateBirth, g.asOf) >= 15 && g.rand.IntN(100) < 45 {
+		patient.Email = g.email(firstName.en, lastName.en)
+	}
+	return patient
+}
+
+// Patients returns the next n patients.
+func (g *Generator) Patients(n int) []*entities.Patient {
+	patients := make([]*entities.Patient, 0, n)
+	for i := 0; i < n; i++ {
+		patients = append(patients, g.Patient())
+	}
+	return patients
+}
+
+func (g *Generator) dateBirth() time.Time {
+	total := 0
+	for _, band := range ageBands {
+		total += band.weight
+	}
+	n := g.rand.IntN(total)
+	for _, band := range ageBands {
+		if n < band.weight {
+			years := band.from + g.rand.IntN(band.to-band.from+1)
+			birthday := g.asOf.AddDate(-years-1, 0, 1)
+			return birthday.AddDate(0, 0, g.rand.IntN(365)).Truncate(24 * time.Hour)
+		}
+		n -= band.weight
+	}
+	return g.asOf
+}
+
+// nationalId follows the layout of a real ID: a category digit, the
+// province and district of registration, a serial and the check digit.
+// Category 1 is for people registered at birth after 1984 and category 3
+// for those already registered then.
+func (g *Generator) nationalId(dateBirth time.Time) string {
+	category := "1"
+	if dateBirth.Year() < 1984 {
+		category = "3"
+	}
+	total := 0
+	for _, province := range provinces {
+		total += province.weight
+	}
+	for {
+		n := g.rand.IntN(total)
+		code := provinces[0].code
+		for _, province := range provinces {
+			if n < province.weight {
+				code = province.code
+				break
+			}
+			n -= province.weight
+		}
+		digits := fmt.Sprintf("%s%s%02d%07d", category, code, 1+g.rand.IntN(30), g.rand.IntN(10_000_000))
+		id := digits + strconv.Itoa(CheckDigit(digits))
+		if !g.issued[id] {
+			g.issued[id] = true
+			return id
+		}
+	}
+}
+
+// passportId uses the current Thai format of two letters and seven digits.
+func (g *Generator) passportId() string {
+	for {
+		id := fmt.Sprintf("%c%c%07d", 'A'+rune(g.rand.IntN(26)), 'A'+rune(g.rand.IntN(26)), g.rand.IntN(10_000_000))
+		if !g.issued[id] {
+			g.issued[id] = true
+			return id
+		}
+	}
+}
+
+// phoneNumber returns a Thai mobile number, which starts 06, 08 or 09.
+func (g *Generator) phoneNumber() string {
+	prefixes := []string{"06", "08", "09"}
+	return fmt.Sprintf("%s%08d", prefixes[g.rand.IntN(len(prefixes))], g.rand.IntN(100_000_000))
+}
+
+func (g *Generator) email(firstName string, lastName string) string {
+	local := strings.ToLower(firstName)
+	switch g.rand.IntN(3) {
+	case 0:
+		local += "." + strings.ToLower(lastName)
+	case 1:
+		local += "." + strings.ToLower(lastName[:1])
+	}
+	if g.rand.IntN(2) == 0 {
+		local += strconv.Itoa(g.rand.IntN(100))
+	}
+	return local + "@" + emailDomains[g.rand.IntN(len(emailDomains))]
+}
+
+// patientHn numbers patients per hospital behind the hospital's initials,
+// such as BH-0000001 for Bangkok Hospital.
+func (g *Generator) patientHn(hospital string) string {
+	g.hn[hospital]++
+	initials := ""
+	for _, word := range strings.Fields(hospital) {
+		first := []rune(word)[0]
+		if unicode.IsLetter(first) || unicode.IsDigit(first) {
+			initials += string(unicode.ToUpper(first))
+		}
+	}
+	return fmt.Sprintf("%s-%07d", initials, g.hn[hospital])
+}
+
+// CheckDigit computes the 13th digit of a Thai national ID from the first
+// twelve.
+func CheckDigit(digits string) int {
+	sum := 0
+	for i, digit := range digits[:12] {
+		sum += int(digit-'0') * (13 - i)
+	}
+	return (11 - sum%11) % 10
+}
+
+// ValidNationalId reports whether id is 13 digits with a correct check digit.
+func ValidNationalId(id string) bool {
+	if len(id) != 13 {
+		return false
+	}
+	for _, digit := range id {
+		if digit < '0' || digit > '9' {
+			return false
+		}
+	}
+	return CheckDigit(id) == int(id[12]-'0')
+}
+
+func pick(r *rand.Rand, names []name) name {
+	return names[r.IntN(len(names))]
+}
+
+func age(dateBirth time.Time, asOf time.Time) int {
+	years := asOf.Year() - dateBirth.Year()
+	if asOf.YearDay() < dateBirth.YearDay() {
+		years--
+	}
+	return years
+}
@@ -0,0 +1,130 @@
+package synthetic_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"agnos/internal/entities"
+	"agnos/internal/usecases/patient"
+	"agnos/internal/usecases/synthetic"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var hospitals = []string{"Bangkok Hospital", "Siriraj Hospital"}
+
+func TestGenerator_SameSeedSamePatients(t *testing.T) {
+	a, err := synthetic.NewGenerator(synthetic.Options{Seed: 42, Hospitals: hospitals})
+	assert.NoError(t, err)
+	b, _ := synthetic.NewGenerator(synthetic.Options{Seed: 42, Hospitals: hospitals})
+	c, _ := synthetic.NewGenerator(synthetic.Options{Seed: 43, Hospitals: hospitals})
+
+	first, second, other := a.Patients(50), b.Patients(50), c.Patients(50)
+	assert.Equal(t, first, second)
+	assert.NotEqual(t, first[0].NationalId, other[0].NationalId)
+}
+
+func TestGenerator_PatientsAreValidAndVaried(t *testing.T) {
+	generator, _ := synthetic.NewGenerator(synthetic.Options{Seed: 7, Hospitals: hospitals})
+	patients := generator.Patients(2000)
+
+	ids := map[string]bool{}
+	genders := map[string]int{}
+	perHospital := map[string]int{}
+	passports, emails, seniors, children := 0, 0, 0, 0
+	for _, p := range patients {
+		assert.Empty(t, patient.ValidatePatient(p))
+		assert.True(t, synthetic.ValidNationalId(p.NationalId), p.NationalId)
+		assert.False(t, ids[p.NationalId], "duplicate national id %s", p.NationalId)
+		ids[p.NationalId] = true
+		assert.Regexp(t, `^0[689]\d{8}$`, p.PhoneNumber)
+		assert.NotEmpty(t, p.FirstNameTh)
+		assert.NotEmpty(t, p.LastNameEn)
+
+		genders[p.Gender]++
+		perHospital[p.Hospital]++
+		if p.PassportId != "" {
+			assert.Regexp(t, `^[A-Z]{2}\d{7}$`, p.PassportId)
+			passports++
+		}
+		if p.Email != "" {
+			assert.Regexp(t, `@example\.(com|net|org)$`, p.Email)
+			emails++
+		}
+		age := synthetic.DefaultAsOf.Sub(p.DateBirth).Hours() / 24 / 365.25
+		assert.True(t, age >= 0 && age < 97, "age %f", age)
+		if age >= 60 {
+			seniors++
+		}
+		if age < 15 {
+			children++
+		}
+	}
+
+	assert.InDelta(t, 1000, genders["female"], 150)
+	assert.InDelta(t, 1000, perHospital["Bangkok Hospital"], 150)
+	assert.InDelta(t, 330, passports, 100)
+	assert.Greater(t, emails, 400)
+	assert.InDelta(t, 400, seniors, 120)
+	assert.InDelta(t, 320, children, 120)
+	assert.Equal(t, "BH-0000001", firstHn(patients, "Bangkok Hospital"))
+}
+
+func firstHn(patients []*entities.Patient, hospital string) string {
+	for _, p := range patients {
+		if p.Hospital == hospital {
+			return p.PatientHn
+		}
+	}
+	return ""
+}
+
+func TestNationalIdCheckDigit(t *testing.T) {
+	assert.True(t, synthetic.ValidNationalId("1101700012344"))
+	assert.False(t, synthetic.ValidNationalId("1101700012345"))
+	assert.False(t, synthetic.ValidNationalId("110170001234"))
+	assert.False(t, synthetic.ValidNationalId("11017000123a4"))
+}
+
+func TestGenerator_RequiresHospitals(t *testing.T) {
+	_, err := synthetic.NewGenerator(synthetic.Options{Seed: 1})
+	assert.Error(t, err)
+	_, err = synthetic.NewGenerator(synthetic.Options{Seed: 1, Hospitals: []string{" "}})
+	assert.Error(t, err)
+}
+
+func TestWriters_CsvAndJson(t *testing.T) {
+	generator, _ := synthetic.NewGenerator(synthetic.Options{Seed: 1, Hospitals: hospitals, AsOf: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)})
+	patients := generator.Patients(3)
+
+	var csv bytes.Buffer
+	writer := synthetic.NewCsvWriter(&csv)
+	for _, p := range patients {
+		assert.NoError(t, writer.Write(p))
+	}
+	assert.NoError(t, writer.Close())
+	lines := strings.Split(strings.TrimSpace(csv.String()), "\n")
+	assert.Equal(t, 4, len(lines))
+	assert.Equal(t, strings.Join(synthetic.Columns, ","), lines[0])
+	assert.Contains(t, lines[1], patients[0].NationalId)
+
+	var out bytes.Buffer
+	jsonWriter := synthetic.NewJsonWriter(&out)
+	for _, p := range patients {
+		assert.NoError(t, jsonWriter.Write(p))
+	}
+	assert.NoError(t, jsonWriter.Close())
+	var decoded []map[string]string
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.Equal(t, 3, len(decoded))
+	assert.Equal(t, patients[2].NationalId, decoded[2]["national_id"])
+	assert.Equal(t, patients[2].DateBirth.Format("2006-01-02"), decoded[2]["date_of_birth"])
+
+	out.Reset()
+	empty := synthetic.NewJsonWriter(&out)
+	assert.NoError(t, empty.Close())
+	assert.Equal(t, "[]\n", out.String())
+}
@@ -0,0 +1,82 @@
+package synthetic
+
+// name is a Thai name with its usual romanisation.
+type name struct {
+	th string
+	en string
+}
+
+var maleFirstNames = []name{
+	{"สมชาย", "Somchai"}, {"สมศักดิ์", "Somsak"}, {"สมพงษ์", "Somphong"}, {"ประเสริฐ", "Prasert"},
+	{"วิชัย", "Wichai"}, {"สุรชัย", "Surachai"}, {"ธนากร", "Thanakorn"}, {"อนันต์", "Anan"},
+	{"ณัฐวุฒิ", "Nattawut"}, {"กิตติพงษ์", "Kittiphong"}, {"ชัยวัฒน์", "Chaiwat"}, {"ปิยะพงษ์", "Piyaphong"},
+	{"วีระพงษ์", "Weeraphong"}, {"อภิชาติ", "Aphichat"}, {"ธีรวัฒน์", "Thirawat"}, {"พงศกร", "Phongsakorn"},
+	{"ศุภชัย", "Supachai"}, {"บุญมี", "Boonmee"}, {"สุทธิพงษ์", "Sutthiphong"}, {"เอกชัย", "Ekkachai"},
+	{"ภาณุวัฒน์", "Phanuwat"}, {"จักรพันธ์", "Chakkaphan"}, {"ธนวัฒน์", "Thanawat"}, {"ปกรณ์", "Pakorn"},
+	{"วรเชษฐ์", "Worachet"}, {"ชนาธิป", "Chanathip"}, {"กฤษดา", "Kritsada"}, {"นพดล", "Noppadon"},
+	{"สมบัติ", "Sombat"}, {"อิทธิพล", "Itthiphon"},
+}
+
+var femaleFirstNames = []name{
+	{"สมหญิง", "Somying"}, {"มาลี", "Malee"}, {"สุภาพร", "Supaporn"}, {"วันเพ็ญ", "Wanphen"},
+	{"นภัสสร", "Napatsorn"}, {"กาญจนา", "Kanchana"}, {"ปราณี", "Pranee"}, {"อรุณี", "Arunee"},
+	{"ศิริพร", "Siriporn"}, {"พิมพ์ชนก", "Pimchanok"}, {"ณัฐธิดา", "Natthida"}, {"จันทร์เพ็ญ", "Chanphen"},
+	{"รัตนา", "Rattana"}, {"สุดารัตน์", "Sudarat"}, {"วิไลวรรณ", "Wilaiwan"}, {"ปิยะนุช", "Piyanut"},
+	{"ชลธิชา", "Chonthicha"}, {"เบญจวรรณ", "Benjawan"}, {"อัญชลี", "Anchalee"}, {"ธิดารัตน์", "Thidarat"},
+	{"กมลชนก", "Kamonchanok"}, {"ลำดวน", "Lamduan"}, {"บุษบา", "Butsaba"}, {"พรทิพย์", "Phonthip"},
+	{"ศศิธร", "Sasithon"}, {"ขวัญใจ", "Khwanjai"}, {"เยาวลักษณ์", "Yaowalak"}, {"นันทนา", "Nantana"},
+	{"จิราพร", "Jiraporn"}, {"ปวีณา", "Paweena"},
+}
+
+var lastNames = []name{
+	{"ใจดี", "Jaidee"}, {"รักไทย", "Rakthai"}, {"มีสุข", "Meesuk"}, {"ศรีสวัสดิ์", "Srisawat"},
+	{"แสงทอง", "Saengthong"}, {"บุญมา", "Boonma"}, {"สุขสวัสดิ์", "Suksawat"}, {"วงศ์สวัสดิ์", "Wongsawat"},
+	{"ทองดี", "Thongdee"}, {"พรหมมา", "Phromma"}, {"จันทร์แก้ว", "Chankaew"}, {"ศรีทอง", "Srithong"},
+	{"แก้วมณี", "Kaewmanee"}, {"สมบูรณ์", "Somboon"}, {"ประเสริฐสุข", "Prasertsuk"}, {"ชัยมงคล", "Chaimongkol"},
+	{"เพชรรัตน์", "Phetcharat"}, {"กิตติศักดิ์", "Kittisak"}, {"อินทร์แก้ว", "Inkaew"}, {"บุญเรือง", "Boonrueang"},
+	{"สายสุวรรณ", "Saisuwan"}, {"ธรรมวงศ์", "Thammawong"}, {"รุ่งเรือง", "Rungrueang"}, {"วิเศษศักดิ์", "Wisetsak"},
+	{"นาคสุข", "Naksuk"}, {"พึ่งบุญ", "Phuengboon"}, {"หอมจันทร์", "Homchan"}, {"ศักดิ์ดี", "Sakdee"},
+	{"มั่นคง", "Mankhong"}, {"เจริญผล", "Charoenphon"}, {"ปัญญาดี", "Panyadee"}, {"สุวรรณรัตน์", "Suwannarat"},
+	{"คำแหง", "Khamhaeng"}, {"ทองประเสริฐ", "Thongprasert"}, {"วัฒนกุล", "Wattanakul"}, {"ลิ้มเจริญ", "Limcharoen"},
+	{"ตั้งใจ", "Tangjai"}, {"อุดมสุข", "Udomsuk"}, {"ชื่นชม", "Chuenchom"}, {"บัวทอง", "Buathong"},
+}
+
+// provinces are the first two digits after the category digit of a
+// national ID, weighted roughly by population.
+var provinces = []struct {
+	code   string
+	weight int
+}{
+	{"10", 16}, // Bangkok
+	{"30", 6},  // Nakhon Ratchasima
+	{"40", 4},  // Khon Kaen
+	{"41", 4},  // Udon Thani
+	{"50", 4},  // Chiang Mai
+	{"20", 4},  // Chon Buri
+	{"90", 3},  // Songkhla
+	{"34", 4},  // Ubon Ratchathani
+	{"12", 3},  // Nonthaburi
+	{"13", 3},  // Pathum Thani
+	{"11", 3},  // Samut Prakan
+	{"80", 3},  // Nakhon Si Thammarat
+	{"83", 1},  // Phuket
+	{"57", 2},  // Chiang Rai
+	{"65", 2},  // Phitsanulok
+}
+
+// ageBands approximate the Thai population pyramid.
+var ageBands = []struct {
+	from, to int
+	weight   int
+}{
+	{0, 14, 16},
+	{15, 29, 19},
+	{30, 44, 22},
+	{45, 59, 23},
+	{60, 74, 15},
+	{75, 95, 5},
+}
+
+// emailDomains are reserved for examples so that generated addresses never
+// reach a real mailbox.
+var emailDomains = []string{"example.com", "example.net", "example.org"}
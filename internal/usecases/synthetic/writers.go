@@ -0,0 +1,119 @@
+package synthetic
+
+import (
+	"agnos/internal/entities"
+	"agnos/internal/usecases/patient"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+)
+
+// Columns are the CSV header. They match the patient import, which ignores
+// the hospital column and files rows under the uploader's hospital.
+var Columns = []string{
+	"patient_hn", "national_id", "passport_id",
+	"first_name_th", "middle_name_th", "last_name_th",
+	"first_name_en", "middle_name_en", "last_name_en",
+	"date_of_birth", "gender", "phone_number", "email", "hospital",
+}
+
+// Writer receives generated patients one at a time.
+type Writer interface {
+	Write(patient *entities.Patient) error
+	Close() error
+}
+
+func record(p *entities.Patient) []string {
+	return []string{
+		p.PatientHn, p.NationalId, p.PassportId,
+		p.FirstNameTh, p.MiddleNameTh, p.LastNameTh,
+		p.FirstNameEn, p.MiddleNameEn, p.LastNameEn,
+		p.DateBirth.Format("2006-01-02"), p.Gender, p.PhoneNumber, p.Email, p.Hospital,
+	}
+}
+
+type CsvWriter struct {
+	csv    *csv.Writer
+	header bool
+}
+
+func NewCsvWriter(w io.Writer) *CsvWriter {
+	return &CsvWriter{csv: csv.NewWriter(w)}
+}
+
+func (w *CsvWriter) Write(patient *entities.Patient) error {
+	if !w.header {
+		w.header = true
+		if err := w.csv.Write(Columns); err != nil {
+			return err
+		}
+	}
+	return w.csv.Write(record(patient))
+}
+
+func (w *CsvWriter) Close() error {
+	if !w.header {
+		w.header = true
+		w.csv.Write(Columns)
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+// JsonWriter streams a JSON array of objects keyed by Columns.
+type JsonWriter struct {
+	w     io.Writer
+	count int
+}
+
+func NewJsonWriter(w io.Writer) *JsonWriter {
+	return &JsonWriter{w: w}
+}
+
+func (w *JsonWriter) Write(patient *entities.Patient) error {
+	object := make(map[string]string, len(Columns))
+	for i, value := range record(patient) {
+		object[Columns[i]] = value
+	}
+	encoded, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+	separator := ",\n"
+	if w.count == 0 {
+		separator = "[\n"
+	}
+	w.count++
+	_, err = io.WriteString(w.w, separator+string(encoded))
+	return err
+}
+
+func (w *JsonWriter) Close() error {
+	closing := "\n]\n"
+	if w.count == 0 {
+		closing = "[]\n"
+	}
+	_, err := io.WriteString(w.w, closing)
+	return err
+}
+
+// RepositoryWriter saves each patient through the patient repository, so
+// that identifiers are encrypted and indexed as for any other patient.
+type RepositoryWriter struct {
+	ctx  context.Context
+	repo patient.PatientRepository
+}
+
+func NewRepositoryWriter(ctx context.Context, repo patient.PatientRepository) *RepositoryWriter {
+	return &RepositoryWriter{ctx: ctx, repo: repo}
+}
+
+func (w *RepositoryWriter) Write(patient *entities.Patient) error {
+	_, err := w.repo.Save(w.ctx, patient)
+	return err
+}
+
+func (w *RepositoryWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,54 @@
+// Package unitofwork lets a use case make writes through several
+// repositories that commit or fail together.
+package unitofwork
+
+import (
+	"agnos/internal/usecases/audit"
+	"agnos/internal/usecases/consent"
+	"agnos/internal/usecases/dsar"
+	"agnos/internal/usecases/mpi"
+	"agnos/internal/usecases/patient"
+	"agnos/internal/usecases/staff"
+	"context"
+)
+
+// Repositories are bound to one unit of work. They must not be kept after
+// the function they were handed to returns.
+type Repositories interface {
+	Patients() patient.PatientRepository
+	Staff() staff.StaffRepository
+	Consents() consent.ConsentRepository
+	Audit() audit.AuditRepository
+	Mpi() mpi.MpiRepository
+	Dsar() dsar.DsarRepository
+}
+
+type UnitOfWork interface {
+	// Do runs fn in a transaction, committed when fn returns nil and rolled
+	// back otherwise. fn must make its calls with the ctx it is given. When
+	// that ctx reaches a nested Do, the nested unit is a savepoint of the
+	// outer one: its failure only undoes its own writes, and nothing commits
+	// before the outer unit does.
+	//
+	// An outermost unit that loses a serialization conflict is run again,
+	// so fn must not have effects outside its repositories.
+	Do(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) error
+}
+
+// Scoped hands its units the repositories of a unit of work as R. A use case
+// that writes through a unit of work declares the repositories it needs as
+// its own R, since this package imports the use cases' repositories.
+type Scoped[R any] struct {
+	uow   UnitOfWork
+	scope func(repos Repositories) R
+}
+
+func Scope[R any](uow UnitOfWork, scope func(repos Repositories) R) Scoped[R] {
+	return Scoped[R]{uow: uow, scope: scope}
+}
+
+func (s Scoped[R]) Do(ctx context.Context, fn func(ctx context.Context, repos R) error) error {
+	return s.uow.Do(ctx, func(ctx context.Context, repos Repositories) error {
+		return fn(ctx, s.scope(repos))
+	})
+}
@@ -0,0 +1,260 @@
+// Package unitofworktest holds the behaviour every unitofwork.UnitOfWork
+// must share, so that use cases tested against one implementation hold for
+// all of them.
+package unitofworktest
+
+import (
+	staffDto "agnos/internal/adapters/staff/dto"
+	"agnos/internal/entities"
+	"agnos/internal/usecases/audit"
+	"agnos/internal/usecases/unitofwork"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+var errFailed = errors.New("failed")
+
+// Contract runs the contract against units of work made by newUnitOfWork,
+// which must start from empty repositories on every call. The default
+// encryption keyring must be set, as saving patients needs it.
+func Contract(t *testing.T, newUnitOfWork func(t *testing.T) unitofwork.UnitOfWork) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, uow unitofwork.UnitOfWork)
+	}{
+		{"CommitsTogether", testCommitsTogether},
+		{"RollsBackTogether", testRollsBackTogether},
+		{"PanicRollsBack", testPanicRollsBack},
+		{"NestedFailureRollsBackToSavepoint", testNestedFailureRollsBackToSavepoint},
+		{"NestedFailureFailsOuter", testNestedFailureFailsOuter},
+		{"CancelledContext", testCancelledContext},
+		{"ConcurrentAuditEntriesStayChained", testConcurrentAuditEntriesStayChained},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.run(t, newUnitOfWork(t))
+		})
+	}
+}
+
+func newPatient(nationalId string) *entities.Patient {
+	return &entities.Patient{
+		FirstNameEn: "Somchai",
+		LastNameEn:  "Jaidee",
+		DateBirth:   time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC),
+		PatientHn:   "HN-" + nationalId,
+		NationalId:  nationalId,
+		Gender:      "male",
+		Hospital:    "Bangkok Hospital",
+	}
+}
+
+// admit saves a patient with a consent and an audit entry, the writes a use
+// case would want to land together.
+func admit(ctx context.Context, repos unitofwork.Repositories, nationalId string) (*entities.Patient, error) {
+	patient, err := repos.Patients().Save(ctx, newPatient(nationalId))
+	if err != nil {
+		return nil, err
+	}
+	_, err = repos.Consents().Save(ctx, &entities.Consent{
+		PatientID: patient.ID,
+		Purpose:   entities.ConsentPurposeDataSharing,
+		Channel:   entities.ConsentChannelWeb,
+		GrantedAt: time.Now(),
+		Hospital:  patient.Hospital,
+	})
+	if err != nil {
+		return nil, err
+	}
+	_, err = repos.Audit().Save(ctx, &entities.AuditLog{Action: "patient.create", Hospital: patient.Hospital})
+	if err != nil {
+		return nil, err
+	}
+	return patient, nil
+}
+
+// exists tells whether the patient with nationalId was committed.
+func exists(t *testing.T, uow unitofwork.UnitOfWork, nationalId string) bool {
+	t.Helper()
+	var found bool
+	err := uow.Do(context.Background(), func(ctx context.Context, repos unitofwork.Repositories) error {
+		_, err := repos.Patients().FindoneId(ctx, nationalId, "Bangkok Hospital")
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		found = err == nil
+		return err
+	})
+	require.NoError(t, err)
+	return found
+}
+
+func auditEntries(t *testing.T, uow unitofwork.UnitOfWork) int {
+	t.Helper()
+	var count int
+	err := uow.Do(context.Background(), func(ctx context.Context, repos unitofwork.Repositories) error {
+		return repos.Audit().FindInBatches(ctx, 100, func(entries []*entities.AuditLog) error {
+			count += len(entries)
+			return nil
+		})
+	})
+	require.NoError(t, err)
+	return count
+}
+
+func testCommitsTogether(t *testing.T, uow unitofwork.UnitOfWork) {
+	var patient *entities.Patient
+	err := uow.Do(context.Background(), func(ctx context.Context, repos unitofwork.Repositories) (err error) {
+		patient, err = admit(ctx, repos, "1100000000001")
+		return err
+	})
+	require.NoError(t, err)
+
+	err = uow.Do(context.Background(), func(ctx context.Context, repos unitofwork.Repositories) error {
+		found, err := repos.Patients().FindById(ctx, patient.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "1100000000001", found.NationalId)
+
+		consent, err := repos.Consents().FindActive(ctx, patient.ID, entities.ConsentPurposeDataSharing)
+		require.NoError(t, err)
+		assert.NotNil(t, consent)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, auditEntries(t, uow))
+}
+
+func testRollsBackTogether(t *testing.T, uow unitofwork.UnitOfWork) {
+	var patient *entities.Patient
+	err := uow.Do(context.Background(), func(ctx context.Context, repos unitofwork.Repositories) (err error) {
+		if patient, err = admit(ctx, repos, "1100000000001"); err != nil {
+			return err
+		}
+		if _, err = repos.Staff().Save(ctx, &entities.Staff{Username: "somchai", Password: "hash", Hospital: "Bangkok Hospital", Role: entities.RoleClinician}); err != nil {
+			return err
+		}
+		return errFailed
+	})
+	require.ErrorIs(t, err, errFailed)
+
+	assert.False(t, exists(t, uow, "1100000000001"))
+	assert.Zero(t, auditEntries(t, uow))
+	err = uow.Do(context.Background(), func(ctx context.Context, repos unitofwork.Repositories) error {
+		consents, err := repos.Consents().FindByPatient(ctx, patient.ID)
+		require.NoError(t, err)
+		assert.Empty(t, consents)
+
+		_, err = repos.Staff().Login(ctx, &staffDto.LoginStaffDto{Username: "somchai"})
+		assert.EqualError(t, err, "user with username somchai not found")
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func testPanicRollsBack(t *testing.T, uow unitofwork.UnitOfWork) {
+	assert.Panics(t, func() {
+		_ = uow.Do(context.Background(), func(ctx context.Context, repos unitofwork.Repositories) error {
+			if _, err := admit(ctx, repos, "1100000000001"); err != nil {
+				return err
+			}
+			panic(errFailed)
+		})
+	})
+	assert.False(t, exists(t, uow, "1100000000001"))
+}
+
+func testNestedFailureRollsBackToSavepoint(t *testing.T, uow unitofwork.UnitOfWork) {
+	err := uow.Do(context.Background(), func(ctx context.Context, repos unitofwork.Repositories) error {
+		if _, err := admit(ctx, repos, "1100000000001"); err != nil {
+			return err
+		}
+		err := uow.Do(ctx, func(ctx context.Context, repos unitofwork.Repositories) error {
+			if _, err := admit(ctx, repos, "1100000000002"); err != nil {
+				return err
+			}
+			return errFailed
+		})
+		assert.ErrorIs(t, err, errFailed)
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.True(t, exists(t, uow, "1100000000001"))
+	assert.False(t, exists(t, uow, "1100000000002"))
+	assert.Equal(t, 1, auditEntries(t, uow))
+}
+
+func testNestedFailureFailsOuter(t *testing.T, uow unitofwork.UnitOfWork) {
+	err := uow.Do(context.Background(), func(ctx context.Context, repos unitofwork.Repositories) error {
+		if _, err := admit(ctx, repos, "1100000000001"); err != nil {
+			return err
+		}
+		return uow.Do(ctx, func(ctx context.Context, repos unitofwork.Repositories) error {
+			if _, err := admit(ctx, repos, "1100000000002"); err != nil {
+				return err
+			}
+			return errFailed
+		})
+	})
+	assert.ErrorIs(t, err, errFailed)
+
+	assert.False(t, exists(t, uow, "1100000000001"))
+	assert.False(t, exists(t, uow, "1100000000002"))
+	assert.Zero(t, auditEntries(t, uow))
+}
+
+func testCancelledContext(t *testing.T, uow unitofwork.UnitOfWork) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := uow.Do(ctx, func(ctx context.Context, repos unitofwork.Repositories) error {
+		_, err := admit(ctx, repos, "1100000000001")
+		return err
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, exists(t, uow, "1100000000001"))
+}
+
+// testConcurrentAuditEntriesStayChained runs units that each append to the
+// same chain and keep writing before they commit, so that a chain lock
+// released before the commit would let two of them link to the same entry.
+func testConcurrentAuditEntriesStayChained(t *testing.T, uow unitofwork.UnitOfWork) {
+	const units = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, units)
+	for i := 0; i < units; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- uow.Do(context.Background(), func(ctx context.Context, repos unitofwork.Repositories) error {
+				if _, err := repos.Audit().Save(ctx, &entities.AuditLog{Action: "patient.view", Hospital: "Bangkok Hospital"}); err != nil {
+					return err
+				}
+				_, err := admit(ctx, repos, fmt.Sprintf("11000000000%02d", i))
+				return err
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	err := uow.Do(context.Background(), func(ctx context.Context, repos unitofwork.Repositories) error {
+		result, err := audit.NewAuditService(repos.Audit()).Verify(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 2*units, result.Checked)
+		assert.Empty(t, result.Broken)
+		return nil
+	})
+	require.NoError(t, err)
+}
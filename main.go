@@ -1,19 +1,39 @@
 package main
 
 import (
-	"agnos/internal/entities"
+	"agnos/internal/migrations"
 	"agnos/internal/routes"
+	"agnos/pkg/database"
+	"agnos/pkg/encryption"
+	"agnos/pkg/health"
+	"agnos/pkg/logging"
+	"agnos/pkg/metrics"
+	"agnos/pkg/tracing"
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/gin-gonic/gin"
-	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
+const usage = `usage: server [command]
+
+commands:
+  serve                                  run the API (default)
+  migrate up|down [steps]|status         apply, roll back or list migrations
+  staff create|reset-password|deactivate manage staff accounts
+  hospital create                        register a hospital
+  seed                                   load demo data for a hospital
+  generate                               write synthetic patients to the database, CSV or JSON
+  keys rotate                            rotate the data key and re-encrypt patients
+  audit verify                           check the audit log hash chains
+
+Run "server <command> -h" for the flags of a command.`
+
 func getEnv(key, fallback string) string {
 	if value, ok := os.LookupEnv(key); ok && value != "" {
 		return value
@@ -22,41 +42,137 @@ func getEnv(key, fallback string) string {
 }
 
 func main() {
-	host := getEnv("DB_HOST", "localhost")
-	port := getEnv("DB_PORT", "5433")
-	user := getEnv("DB_USER", "myuser")
-	password := getEnv("DB_PASSWORD", "mypassword")
-	dbname := getEnv("DB_NAME", "mydatabase")
-
-	dbs := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", host, port, user, password, dbname)
-
-	newLogger := logger.New(
-		log.New(os.Stdout, "\r\n", log.LstdFlags),
-		logger.Config{
-			SlowThreshold: time.Second,
-			LogLevel:      logger.Info,
-			Colorful:      true,
-		},
-	)
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
 
-	db, err := gorm.Open(postgres.Open(dbs), &gorm.Config{Logger: newLogger})
+	logConfig, err := logging.ParseConfig(getEnv("LOG_LEVEL", "info"), getEnv("LOG_LEVELS", ""))
+	if err != nil {
+		panic("LOG_LEVEL is invalid: " + err.Error())
+	}
+	// Commands print their results on stdout, so their logs go to stderr.
+	logOutput := os.Stderr
+	if command == "serve" {
+		logOutput = os.Stdout
+	}
+	logging.Setup(logOutput, logConfig)
 
+	ctx := context.Background()
+	switch command {
+	case "serve":
+		serve()
+		return
+	case "migrate":
+		err = runMigrate(ctx, openDatabase(), args)
+	case "staff":
+		err = runStaff(ctx, openMigratedDatabase(), args)
+	case "hospital":
+		err = runHospital(ctx, openMigratedDatabase(), args)
+	case "seed":
+		db := openMigratedDatabase()
+		loadKeyring(db)
+		err = runSeed(ctx, db, args)
+	case "generate":
+		err = runGenerate(ctx, args)
+	case "keys":
+		db := openMigratedDatabase()
+		err = runKeys(ctx, db, loadKeyring(db), args)
+	case "audit":
+		err = runAudit(ctx, openMigratedDatabase(), args)
+	case "help", "-h", "--help":
+		fmt.Println(usage)
+		return
+	default:
+		err = fmt.Errorf("unknown command %q\n\n%s", command, usage)
+	}
 	if err != nil {
-		panic("Can't connect database")
+		slog.Error(command+" failed", "error", err.Error())
+		os.Exit(1)
 	}
+}
 
-	db.AutoMigrate(&entities.Patient{}, &entities.Staff{})
-	router := gin.Default()
+// openDatabase connects with the DB_* settings. The database may still be
+// starting, as under docker compose, so the connection is retried with
+// backoff before giving up.
+func openDatabase() *gorm.DB {
+	config, err := database.ConfigFromEnv(getEnv)
+	if err != nil {
+		panic("Database settings are invalid: " + err.Error())
+	}
 
-	routes.StaffRoutes(&router.RouterGroup, db)
+	attempts, err := strconv.Atoi(getEnv("DB_CONNECT_ATTEMPTS", "10"))
+	if err != nil {
+		panic("DB_CONNECT_ATTEMPTS is invalid: " + err.Error())
+	}
+	var db *gorm.DB
+	err = health.WaitFor(context.Background(), health.Backoff{Attempts: attempts, Initial: time.Second, Max: 30 * time.Second}, func() error {
+		db, err = database.Open(config, &gorm.Config{Logger: logging.NewGormLogger(logging.For("gorm"), time.Second)})
+		return err
+	}, func(attempt int, err error, wait time.Duration) {
+		slog.Warn("database not reachable, retrying", "driver", config.Driver, "attempt", attempt, "wait", wait.String(), "error", err.Error())
+	})
+	if err != nil {
+		panic("Can't connect database: " + err.Error())
+	}
+	if err := db.Use(metrics.GormPlugin{}); err != nil {
+		panic("Can't register metrics plugin: " + err.Error())
+	}
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		panic("Can't register tracing plugin: " + err.Error())
+	}
+	for _, pool := range database.Pools(db) {
+		name := config.Label()
+		if pool.Name != "primary" {
+			name += " " + pool.Name
+		}
+		if err := metrics.RegisterDB(pool.DB, name); err != nil {
+			panic("Can't register database metrics: " + err.Error())
+		}
+	}
+	return db
+}
 
-	routes.PatientRoutes(&router.RouterGroup, db)
+// openMigratedDatabase connects and refuses to go on against a schema older
+// than this build expects, which would fail at the first query that touches
+// a new column or table.
+func openMigratedDatabase() *gorm.DB {
+	db := openDatabase()
+	migrator, err := migrations.New(db)
+	if err != nil {
+		panic("Can't load migrations: " + err.Error())
+	}
+	if err := migrator.Check(context.Background()); err != nil {
+		panic("Can't start: " + err.Error() + "; run `server migrate up`")
+	}
+	return db
+}
 
-	router.GET("/hello", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"message": "test",
-		})
-	})
+// loadKeyring installs the keyring for the encrypted patient columns.
+func loadKeyring(db *gorm.DB) *encryption.Keyring {
+	provider, err := encryption.NewProviderFromEnv()
+	if err != nil {
+		panic("Can't load encryption keys: " + err.Error())
+	}
+	keyring, err := routes.EncryptionKeyring(db, provider)
+	if err != nil {
+		panic("Can't load data key: " + err.Error())
+	}
+	return keyring
+}
 
-	router.Run(":8080")
+// waitContext runs fn, which blocks until some work is done, and gives up
+// when ctx ends first.
+func waitContext(ctx context.Context, fn func()) error {
+	done := make(chan struct{})
+	go func() {
+		fn()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
@@ -0,0 +1,168 @@
+// Package database opens the supported databases from the environment and
+// builds the few query fragments whose SQL differs between them.
+package database
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// The supported drivers, named as their GORM dialects.
+const (
+	Postgres = "postgres"
+	MySQL    = "mysql"
+	SQLite   = "sqlite"
+)
+
+type Config struct {
+	Driver   string
+	Host     string
+	Port     string
+	User     string
+	Password string
+	Name     string
+
+	// Path is the database file for SQLite, which ignores the settings
+	// above.
+	Path string
+
+	// DSN, when set, replaces the settings above with a connection string
+	// in the driver's own format.
+	DSN string
+
+	// Replicas are the connection strings of read replicas of the
+	// primary, in the same format as DSN. See ReadReplica.
+	Replicas []string
+
+	// ReadYourWrites is how long the reads of a user who has just written
+	// stay on the primary, so that they see their own writes however far
+	// the replicas lag.
+	ReadYourWrites time.Duration
+
+	Pool Pool
+}
+
+// Pool sizes the connection pool of the primary and of each replica. Zero
+// values keep the database/sql defaults.
+type Pool struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// ConfigFromEnv reads the DB_* variables through getEnv, which returns the
+// fallback when a variable is unset.
+func ConfigFromEnv(getEnv func(key, fallback string) string) (Config, error) {
+	config := Config{
+		Driver:   getEnv("DB_DRIVER", Postgres),
+		Host:     getEnv("DB_HOST", "localhost"),
+		User:     getEnv("DB_USER", "myuser"),
+		Password: getEnv("DB_PASSWORD", "mypassword"),
+		Name:     getEnv("DB_NAME", "mydatabase"),
+		Path:     getEnv("DB_PATH", "agnos.db"),
+		DSN:      getEnv("DB_DSN", ""),
+	}
+	for _, replica := range strings.Split(getEnv("DB_REPLICAS", ""), ",") {
+		if replica = strings.TrimSpace(replica); replica != "" {
+			config.Replicas = append(config.Replicas, replica)
+		}
+	}
+
+	var err error
+	ints := []struct {
+		key, fallback string
+		value         *int
+	}{
+		{"DB_MAX_OPEN_CONNS", "0", &config.Pool.MaxOpenConns},
+		{"DB_MAX_IDLE_CONNS", "2", &config.Pool.MaxIdleConns},
+	}
+	for _, setting := range ints {
+		if *setting.value, err = strconv.Atoi(getEnv(setting.key, setting.fallback)); err != nil {
+			return Config{}, fmt.Errorf("%s is invalid: %w", setting.key, err)
+		}
+	}
+	durations := []struct {
+		key, fallback string
+		value         *time.Duration
+	}{
+		{"DB_CONN_MAX_LIFETIME", "0", &config.Pool.ConnMaxLifetime},
+		{"DB_CONN_MAX_IDLE_TIME", "0", &config.Pool.ConnMaxIdleTime},
+		{"DB_READ_YOUR_WRITES", "5s", &config.ReadYourWrites},
+	}
+	for _, setting := range durations {
+		if *setting.value, err = time.ParseDuration(getEnv(setting.key, setting.fallback)); err != nil {
+			return Config{}, fmt.Errorf("%s is invalid: %w", setting.key, err)
+		}
+	}
+
+	switch config.Driver {
+	case Postgres:
+		config.Port = getEnv("DB_PORT", "5433")
+	case MySQL:
+		config.Port = getEnv("DB_PORT", "3306")
+	case SQLite:
+	default:
+		return Config{}, fmt.Errorf("DB_DRIVER %q is not one of %s, %s or %s", config.Driver, Postgres, MySQL, SQLite)
+	}
+	return config, nil
+}
+
+// Label names the database in logs and metrics without its credentials.
+func (c Config) Label() string {
+	if c.Driver == SQLite {
+		return c.Path
+	}
+	return c.Name
+}
+
+// Dialector connects to the primary.
+func (c Config) Dialector() gorm.Dialector {
+	if c.DSN != "" {
+		return c.open(c.DSN)
+	}
+	switch c.Driver {
+	case MySQL:
+		// Times are stored in UTC and parsed back into time.Time, as the
+		// Postgres driver does.
+		dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=true&loc=UTC", c.User, c.Password, c.Host, c.Port, c.Name)
+		return mysql.Open(dsn)
+	case SQLite:
+		// Writers wait for each other instead of failing with SQLITE_BUSY,
+		// and transactions take the write lock up front so that one which
+		// reads before writing cannot deadlock with another.
+		query := url.Values{}
+		query.Add("_pragma", "foreign_keys(1)")
+		query.Add("_pragma", "busy_timeout(5000)")
+		query.Add("_pragma", "journal_mode(WAL)")
+		query.Add("_txlock", "immediate")
+		separator := "?"
+		if strings.Contains(c.Path, "?") {
+			separator = "&"
+		}
+		return sqlite.Open(c.Path + separator + query.Encode())
+	default:
+		dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", c.Host, c.Port, c.User, c.Password, c.Name)
+		return postgres.Open(dsn)
+	}
+}
+
+// open connects to dsn as is, without the settings Dialector adds.
+func (c Config) open(dsn string) gorm.Dialector {
+	switch c.Driver {
+	case MySQL:
+		return mysql.Open(dsn)
+	case SQLite:
+		return sqlite.Open(dsn)
+	default:
+		return postgres.Open(dsn)
+	}
+}
@@ -0,0 +1,141 @@
+package database_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"agnos/pkg/database"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func envOf(values map[string]string) func(string, string) string {
+	return func(key, fallback string) string {
+		if value, ok := values[key]; ok {
+			return value
+		}
+		return fallback
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	config, err := database.ConfigFromEnv(envOf(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, database.Postgres, config.Driver)
+	assert.Equal(t, "5433", config.Port)
+	assert.Equal(t, "mydatabase", config.Label())
+
+	config, err = database.ConfigFromEnv(envOf(map[string]string{"DB_DRIVER": "mysql"}))
+	assert.NoError(t, err)
+	assert.Equal(t, "3306", config.Port)
+	assert.Equal(t, "mysql", config.Dialector().Name())
+
+	config, err = database.ConfigFromEnv(envOf(map[string]string{"DB_DRIVER": "sqlite", "DB_PATH": "/data/agnos.db"}))
+	assert.NoError(t, err)
+	assert.Equal(t, "/data/agnos.db", config.Label())
+	assert.Equal(t, "sqlite", config.Dialector().Name())
+
+	_, err = database.ConfigFromEnv(envOf(map[string]string{"DB_DRIVER": "oracle"}))
+	assert.Error(t, err)
+}
+
+func TestConfigFromEnvReplicasAndPool(t *testing.T) {
+	config, err := database.ConfigFromEnv(envOf(nil))
+	assert.NoError(t, err)
+	assert.Empty(t, config.Replicas)
+	assert.Equal(t, database.Pool{MaxIdleConns: 2}, config.Pool)
+	assert.Equal(t, 5*time.Second, config.ReadYourWrites)
+
+	config, err = database.ConfigFromEnv(envOf(map[string]string{
+		"DB_REPLICAS":          "host=replica1 dbname=mydatabase, host=replica2 dbname=mydatabase",
+		"DB_MAX_OPEN_CONNS":    "20",
+		"DB_MAX_IDLE_CONNS":    "5",
+		"DB_CONN_MAX_LIFETIME": "30m",
+		"DB_READ_YOUR_WRITES":  "10s",
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"host=replica1 dbname=mydatabase", "host=replica2 dbname=mydatabase"}, config.Replicas)
+	assert.Equal(t, database.Pool{MaxOpenConns: 20, MaxIdleConns: 5, ConnMaxLifetime: 30 * time.Minute}, config.Pool)
+	assert.Equal(t, 10*time.Second, config.ReadYourWrites)
+
+	_, err = database.ConfigFromEnv(envOf(map[string]string{"DB_MAX_OPEN_CONNS": "many"}))
+	assert.ErrorContains(t, err, "DB_MAX_OPEN_CONNS")
+	_, err = database.ConfigFromEnv(envOf(map[string]string{"DB_CONN_MAX_IDLE_TIME": "5"}))
+	assert.ErrorContains(t, err, "DB_CONN_MAX_IDLE_TIME")
+}
+
+type item struct {
+	ID   uint
+	Name string `gorm:"uniqueIndex"`
+}
+
+func openSqlite(t *testing.T) *gorm.DB {
+	config := database.Config{Driver: database.SQLite, Path: filepath.Join(t.TempDir(), "test.db")}
+	db, err := gorm.Open(config.Dialector(), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	require.NoError(t, db.AutoMigrate(&item{}))
+	return db
+}
+
+func names(t *testing.T, db *gorm.DB, condition interface{}) []string {
+	var found []string
+	require.NoError(t, db.Model(&item{}).Where(condition).Order("id").Pluck("name", &found).Error)
+	return found
+}
+
+func TestContainsFold(t *testing.T) {
+	db := openSqlite(t)
+	require.NoError(t, db.Create(&[]item{{Name: "Bangkok Hospital"}, {Name: "50% off"}, {Name: "500 beds"}, {Name: `a\b`}}).Error)
+
+	assert.Equal(t, []string{"Bangkok Hospital"}, names(t, db, database.ContainsFold(db, "name", "HOSP")))
+	assert.Equal(t, []string{"50% off"}, names(t, db, database.ContainsFold(db, "name", "50%")))
+	assert.Empty(t, names(t, db, database.ContainsFold(db, "name", "5_0")))
+	assert.Equal(t, []string{`a\b`}, names(t, db, database.ContainsFold(db, "name", `\`)))
+}
+
+func TestHasPrefixFold(t *testing.T) {
+	db := openSqlite(t)
+	require.NoError(t, db.Create(&[]item{{Name: "Bangkok Hospital"}, {Name: "Siriraj Bangkok"}, {Name: "50% off"}, {Name: "500 beds"}}).Error)
+
+	assert.Equal(t, []string{"Bangkok Hospital"}, names(t, db, database.HasPrefixFold(db, "name", "BANG")))
+	assert.Equal(t, []string{"50% off"}, names(t, db, database.HasPrefixFold(db, "name", "50%")))
+}
+
+func TestEqualFold(t *testing.T) {
+	db := openSqlite(t)
+	require.NoError(t, db.Create(&[]item{{Name: "Bangkok Hospital"}, {Name: "Bangkok"}}).Error)
+
+	assert.Equal(t, []string{"Bangkok Hospital"}, names(t, db, database.EqualFold(db, "name", "bangkok HOSPITAL")))
+}
+
+func TestIsUniqueViolation(t *testing.T) {
+	db := openSqlite(t)
+	require.NoError(t, db.Create(&item{Name: "Bangkok Hospital"}).Error)
+
+	err := db.Create(&item{Name: "Bangkok Hospital"}).Error
+	assert.True(t, database.IsUniqueViolation(db, err))
+	assert.False(t, database.IsUniqueViolation(db, gorm.ErrRecordNotFound))
+	assert.False(t, database.IsUniqueViolation(db, nil))
+}
+
+func TestIsSerializationFailure(t *testing.T) {
+	assert.True(t, database.IsSerializationFailure(&pgconn.PgError{Code: "40001"}))
+	assert.True(t, database.IsSerializationFailure(fmt.Errorf("commit: %w", &pgconn.PgError{Code: "40P01"})))
+	assert.False(t, database.IsSerializationFailure(&pgconn.PgError{Code: "23505"}))
+	assert.True(t, database.IsSerializationFailure(&mysql.MySQLError{Number: 1213}))
+	assert.False(t, database.IsSerializationFailure(&mysql.MySQLError{Number: 1062}))
+	assert.False(t, database.IsSerializationFailure(gorm.ErrRecordNotFound))
+	assert.False(t, database.IsSerializationFailure(nil))
+}
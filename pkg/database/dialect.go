@@ -0,0 +1,109 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	gosqlite "github.com/glebarez/go-sqlite"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// likeEscaper escapes the LIKE wildcards in user input, so that a search
+// for "50%" matches that text rather than everything starting with "50".
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// ContainsFold matches rows whose column contains value, ignoring case.
+func ContainsFold(db *gorm.DB, column string, value string) clause.Expr {
+	pattern := "%" + likeEscaper.Replace(strings.ToLower(value)) + "%"
+	switch db.Dialector.Name() {
+	case Postgres:
+		return clause.Expr{SQL: "? ILIKE ?", Vars: []interface{}{clause.Column{Name: column}, pattern}}
+	case MySQL:
+		// The tables' collation already compares case-insensitively.
+		return clause.Expr{SQL: "? LIKE ?", Vars: []interface{}{clause.Column{Name: column}, pattern}}
+	default:
+		// SQLite has no default escape character.
+		return clause.Expr{SQL: `LOWER(?) LIKE ? ESCAPE '\'`, Vars: []interface{}{clause.Column{Name: column}, pattern}}
+	}
+}
+
+// HasPrefixFold matches rows whose column starts with value, ignoring case.
+func HasPrefixFold(db *gorm.DB, column string, value string) clause.Expr {
+	pattern := likeEscaper.Replace(strings.ToLower(value)) + "%"
+	switch db.Dialector.Name() {
+	case Postgres:
+		return clause.Expr{SQL: "? ILIKE ?", Vars: []interface{}{clause.Column{Name: column}, pattern}}
+	case MySQL:
+		return clause.Expr{SQL: "? LIKE ?", Vars: []interface{}{clause.Column{Name: column}, pattern}}
+	default:
+		return clause.Expr{SQL: `LOWER(?) LIKE ? ESCAPE '\'`, Vars: []interface{}{clause.Column{Name: column}, pattern}}
+	}
+}
+
+// EqualFold matches rows whose column equals value, ignoring case. On
+// Postgres and SQLite it compares LOWER(column), which the migrations index
+// where it matters.
+func EqualFold(db *gorm.DB, column string, value string) clause.Expr {
+	if db.Dialector.Name() == MySQL {
+		return clause.Expr{SQL: "? = ?", Vars: []interface{}{clause.Column{Name: column}, value}}
+	}
+	return clause.Expr{SQL: "LOWER(?) = ?", Vars: []interface{}{clause.Column{Name: column}, strings.ToLower(value)}}
+}
+
+// IsUniqueViolation reports whether err comes from a unique index, whatever
+// the database. Drivers report these with their own error codes, which
+// their dialects translate to gorm.ErrDuplicatedKey.
+func IsUniqueViolation(db *gorm.DB, err error) bool {
+	if err == nil {
+		return false
+	}
+	if translator, ok := db.Dialector.(gorm.ErrorTranslator); ok {
+		err = translator.Translate(err)
+	}
+	return errors.Is(err, gorm.ErrDuplicatedKey)
+}
+
+// IsSerializationFailure reports whether err aborted a transaction that may
+// succeed if run again: a serialization failure or deadlock on Postgres, a
+// deadlock on MySQL, or a busy database on SQLite.
+func IsSerializationFailure(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "40001" || pgErr.Code == "40P01"
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 1213
+	}
+	var sqliteErr *gosqlite.Error
+	if errors.As(err, &sqliteErr) {
+		// Extended codes such as SQLITE_BUSY_SNAPSHOT keep SQLITE_BUSY in
+		// their low byte.
+		return sqliteErr.Code()&0xff == 5
+	}
+	return false
+}
+
+// Transaction runs fn in a transaction on db, or in a savepoint when db is
+// already in one. MySQL holds locks taken with GET_LOCK until the session
+// releases them rather than until the commit, so a transaction started there
+// runs on a connection of its own that releases its named locks once the
+// transaction has ended.
+func Transaction(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	if _, inTransaction := db.Statement.ConnPool.(gorm.TxCommitter); inTransaction || db.Dialector.Name() != MySQL {
+		return db.Transaction(fn)
+	}
+	return db.Connection(func(conn *gorm.DB) error {
+		err := conn.Transaction(fn)
+		// Release even when the context has ended, or the connection would
+		// go back to the pool still holding the locks.
+		if releaseErr := conn.WithContext(context.Background()).Exec("SELECT RELEASE_ALL_LOCKS()").Error; err == nil {
+			err = releaseErr
+		}
+		return err
+	})
+}
@@ -0,0 +1,170 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// replicaResolver names the dbresolver configuration of the replicas. Only
+// the queries that ask for it by name leave the primary, so that a
+// uniqueness check or a read just after a write never sees stale rows.
+const replicaResolver = "replicas"
+
+// Open connects to the primary and the replicas of config and sizes their
+// pools.
+func Open(config Config, gormConfig *gorm.Config) (*gorm.DB, error) {
+	db, err := gorm.Open(config.Dialector(), gormConfig)
+	if err != nil {
+		return nil, err
+	}
+	primary, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	config.Pool.apply(primary)
+	if len(config.Replicas) == 0 {
+		return db, nil
+	}
+
+	dialectors := make([]gorm.Dialector, 0, len(config.Replicas))
+	for _, dsn := range config.Replicas {
+		dialectors = append(dialectors, config.open(dsn))
+	}
+	resolver := dbresolver.Register(dbresolver.Config{Replicas: dialectors}, replicaResolver)
+	if err := db.Use(resolver); err != nil {
+		return nil, fmt.Errorf("can't connect replicas: %w", err)
+	}
+
+	tracker := &readYourWrites{window: config.ReadYourWrites, writes: map[string]time.Time{}}
+	resolver.Call(func(pool gorm.ConnPool) error {
+		if sqlDB, ok := pool.(*sql.DB); ok && sqlDB != primary {
+			config.Pool.apply(sqlDB)
+			tracker.replicas = append(tracker.replicas, sqlDB)
+		}
+		return nil
+	})
+	if err := db.Use(tracker); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+func (p Pool) apply(db *sql.DB) {
+	db.SetMaxOpenConns(p.MaxOpenConns)
+	db.SetMaxIdleConns(p.MaxIdleConns)
+	db.SetConnMaxLifetime(p.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(p.ConnMaxIdleTime)
+}
+
+// ReadReplica sends the queries of db to a replica, unless the actor in its
+// context wrote within the read-your-writes window. Without replicas, and
+// inside a transaction, the queries stay where they were.
+func ReadReplica(db *gorm.DB) *gorm.DB {
+	tracker, ok := db.Config.Plugins[readYourWritesName].(*readYourWrites)
+	if !ok || tracker.wroteRecently(Actor(db.Statement.Context)) {
+		return db
+	}
+	return db.Clauses(dbresolver.Use(replicaResolver))
+}
+
+// NamedPool is a connection pool of the database, named "primary" or
+// "replica-N".
+type NamedPool struct {
+	Name string
+	DB   *sql.DB
+}
+
+// Pools returns the pools of the primary and of each replica, in order,
+// for their stats.
+func Pools(db *gorm.DB) []NamedPool {
+	var pools []NamedPool
+	if primary, err := db.DB(); err == nil {
+		pools = append(pools, NamedPool{Name: "primary", DB: primary})
+	}
+	if tracker, ok := db.Config.Plugins[readYourWritesName].(*readYourWrites); ok {
+		for i, replica := range tracker.replicas {
+			pools = append(pools, NamedPool{Name: fmt.Sprintf("replica-%d", i+1), DB: replica})
+		}
+	}
+	return pools
+}
+
+const readYourWritesName = "agnos:read_your_writes"
+
+// readYourWrites is a gorm plugin that remembers when each actor last
+// wrote. It only knows about the writes of this process, so instances
+// behind a load balancer need sticky sessions for the guarantee to hold.
+type readYourWrites struct {
+	window   time.Duration
+	replicas []*sql.DB
+
+	mu     sync.Mutex
+	writes map[string]time.Time
+}
+
+func (p *readYourWrites) Name() string {
+	return readYourWritesName
+}
+
+func (p *readYourWrites) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Create().After("gorm:create").Register(readYourWritesName, p.record); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:update").Register(readYourWritesName, p.record); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().After("gorm:delete").Register(readYourWritesName, p.record); err != nil {
+		return err
+	}
+	return callbacks.Raw().After("gorm:raw").Register(readYourWritesName, p.record)
+}
+
+func (p *readYourWrites) record(db *gorm.DB) {
+	actor := Actor(db.Statement.Context)
+	if actor == "" || db.Error != nil {
+		return
+	}
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for other, at := range p.writes {
+		if now.Sub(at) >= p.window {
+			delete(p.writes, other)
+		}
+	}
+	p.writes[actor] = now
+}
+
+func (p *readYourWrites) wroteRecently(actor string) bool {
+	if actor == "" {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	at, ok := p.writes[actor]
+	return ok && time.Since(at) < p.window
+}
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx carrying the username of the caller, whose
+// writes ReadReplica keeps track of.
+func WithActor(ctx context.Context, username string) context.Context {
+	return context.WithValue(ctx, actorKey{}, username)
+}
+
+// Actor returns the username carried by ctx, or "".
+func Actor(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
@@ -0,0 +1,93 @@
+package database_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"agnos/pkg/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openReplicated opens a primary and a replica that start out with
+// different rows, so that each read shows where it went.
+func openReplicated(t *testing.T, replicas bool) *gorm.DB {
+	dir := t.TempDir()
+	replicaPath := filepath.Join(dir, "replica.db")
+	replica, err := gorm.Open(database.Config{Driver: database.SQLite, Path: replicaPath}.Dialector(), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, replica.AutoMigrate(&item{}))
+	require.NoError(t, replica.Create(&item{Name: "on the replica"}).Error)
+	if sqlDB, err := replica.DB(); err == nil {
+		sqlDB.Close()
+	}
+
+	config := database.Config{
+		Driver:         database.SQLite,
+		Path:           filepath.Join(dir, "primary.db"),
+		ReadYourWrites: time.Hour,
+		Pool:           database.Pool{MaxOpenConns: 3, MaxIdleConns: 1},
+	}
+	if replicas {
+		config.Replicas = []string{replicaPath}
+	}
+	db, err := database.Open(config, &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		for _, pool := range database.Pools(db) {
+			pool.DB.Close()
+		}
+	})
+	require.NoError(t, db.AutoMigrate(&item{}))
+	return db
+}
+
+func readNames(t *testing.T, db *gorm.DB) []string {
+	var found []string
+	require.NoError(t, database.ReadReplica(db).Model(&item{}).Order("id").Pluck("name", &found).Error)
+	return found
+}
+
+func TestReadReplica(t *testing.T) {
+	db := openReplicated(t, true)
+	somchai := database.WithActor(context.Background(), "somchai")
+	malee := database.WithActor(context.Background(), "malee")
+
+	assert.Equal(t, []string{"on the replica"}, readNames(t, db.WithContext(somchai)))
+
+	require.NoError(t, db.WithContext(somchai).Create(&item{Name: "on the primary"}).Error)
+	assert.Equal(t, []string{"on the primary"}, readNames(t, db.WithContext(somchai)), "the writer reads its own write")
+	assert.Equal(t, []string{"on the replica"}, readNames(t, db.WithContext(malee)))
+	assert.Equal(t, []string{"on the replica"}, readNames(t, db.WithContext(context.Background())))
+
+	var count int64
+	require.NoError(t, db.WithContext(malee).Model(&item{}).Count(&count).Error)
+	assert.EqualValues(t, 1, count, "queries that do not ask for a replica stay on the primary")
+
+	require.NoError(t, db.WithContext(malee).Transaction(func(tx *gorm.DB) error {
+		assert.Equal(t, []string{"on the primary"}, readNames(t, tx))
+		return nil
+	}))
+}
+
+func TestReadReplicaWithoutReplicas(t *testing.T) {
+	db := openReplicated(t, false)
+	require.NoError(t, db.Create(&item{Name: "on the primary"}).Error)
+
+	assert.Equal(t, []string{"on the primary"}, readNames(t, db.WithContext(context.Background())))
+}
+
+func TestPools(t *testing.T) {
+	pools := database.Pools(openReplicated(t, true))
+	require.Len(t, pools, 2)
+	assert.Equal(t, "primary", pools[0].Name)
+	assert.Equal(t, "replica-1", pools[1].Name)
+	for _, pool := range pools {
+		assert.Equal(t, 3, pool.DB.Stats().MaxOpenConnections)
+	}
+}
@@ -0,0 +1,288 @@
+package encryption
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Values sealed as v2 are bound to where they are stored; v1 values predate
+// that and are read until the rotation command rewrites them.
+const (
+	ciphertextPrefix       = "enc:v2:"
+	legacyCiphertextPrefix = "enc:v1:"
+	blindIndexLength       = 32
+)
+
+// DataKey is an AES-256 key used to encrypt field values, stored wrapped by
+// the master key it was created or last rotated under.
+type DataKey struct {
+	Id          uint
+	WrappedKey  []byte
+	MasterKeyId string
+	Active      bool
+}
+
+type KeyStore interface {
+	ActiveDataKey(ctx context.Context) (*DataKey, error)
+	FindDataKey(ctx context.Context, id uint) (*DataKey, error)
+	ListDataKeys(ctx context.Context) ([]*DataKey, error)
+	SaveDataKey(ctx context.Context, key *DataKey) error
+}
+
+type Keyring struct {
+	provider KeyProvider
+	store    KeyStore
+
+	mu     sync.RWMutex
+	keys   map[uint][]byte
+	active uint
+}
+
+func NewKeyring(provider KeyProvider, store KeyStore) *Keyring {
+	return &Keyring{provider: provider, store: store, keys: make(map[uint][]byte)}
+}
+
+// Encrypt seals value with the active data key, creating the first data key
+// on demand. Empty values are stored as they are. The ciphertext is bound to
+// location, such as "patients.national_id", and opens only there, so that it
+// cannot be copied into another column.
+func (k *Keyring) Encrypt(ctx context.Context, value string, location string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	id, key, err := k.activeKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := seal(key, []byte(value), []byte(location))
+	if err != nil {
+		return "", err
+	}
+	return ciphertextPrefix + strconv.FormatUint(uint64(id), 10) + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value produced by Encrypt for the same location. Values
+// without a ciphertext prefix are rows written before encryption was enabled
+// and are returned as they are until the rotation command rewrites them.
+func (k *Keyring) Decrypt(ctx context.Context, value string, location string) (string, error) {
+	var additional []byte
+	switch {
+	case strings.HasPrefix(value, ciphertextPrefix):
+		value, additional = strings.TrimPrefix(value, ciphertextPrefix), []byte(location)
+	case strings.HasPrefix(value, legacyCiphertextPrefix):
+		value = strings.TrimPrefix(value, legacyCiphertextPrefix)
+	default:
+		return value, nil
+	}
+
+	idPart, encoded, ok := strings.Cut(value, ":")
+	if !ok {
+		return "", fmt.Errorf("ciphertext is malformed")
+	}
+	id, err := strconv.ParseUint(idPart, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("ciphertext is malformed")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("ciphertext is malformed")
+	}
+
+	key, err := k.dataKey(ctx, uint(id))
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(key, sealed, additional)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// BlindIndex returns a deterministic keyed hash of value so that encrypted
+// columns can still be matched exactly. Callers normalize value first.
+func (k *Keyring) BlindIndex(value string) string {
+	if value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, k.provider.BlindIndexKey())
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))[:blindIndexLength]
+}
+
+// EnsureDataKey loads the active data key, creating the first one if the
+// store is empty, so that it is not created lazily inside a transaction.
+func (k *Keyring) EnsureDataKey(ctx context.Context) error {
+	_, _, err := k.activeKey(ctx)
+	return err
+}
+
+// RotateDataKey creates a new active data key. Existing values stay readable
+// with their old key until they are written again.
+func (k *Keyring) RotateDataKey(ctx context.Context) (*DataKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.rotateLocked(ctx)
+}
+
+func (k *Keyring) rotateLocked(ctx context.Context) (*DataKey, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	masterKeyId := k.provider.CurrentKeyId()
+	wrapped, err := k.provider.Wrap(masterKeyId, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := k.store.ListDataKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if key.Active {
+			key.Active = false
+			if err := k.store.SaveDataKey(ctx, key); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	key := &DataKey{WrappedKey: wrapped, MasterKeyId: masterKeyId, Active: true}
+	if err := k.store.SaveDataKey(ctx, key); err != nil {
+		return nil, err
+	}
+	k.keys[key.Id] = raw
+	k.active = key.Id
+	return key, nil
+}
+
+// RewrapDataKeys re-wraps every data key under the current master key so
+// that retired master keys can be removed from the provider.
+func (k *Keyring) RewrapDataKeys(ctx context.Context) (int, error) {
+	keys, err := k.store.ListDataKeys(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	current := k.provider.CurrentKeyId()
+	rewrapped := 0
+	for _, key := range keys {
+		if key.MasterKeyId == current {
+			continue
+		}
+		raw, err := k.provider.Unwrap(key.MasterKeyId, key.WrappedKey)
+		if err != nil {
+			return rewrapped, err
+		}
+		wrapped, err := k.provider.Wrap(current, raw)
+		if err != nil {
+			return rewrapped, err
+		}
+		key.WrappedKey, key.MasterKeyId = wrapped, current
+		if err := k.store.SaveDataKey(ctx, key); err != nil {
+			return rewrapped, err
+		}
+		rewrapped++
+	}
+	return rewrapped, nil
+}
+
+func (k *Keyring) activeKey(ctx context.Context) (uint, []byte, error) {
+	k.mu.RLock()
+	if k.active != 0 {
+		id, key := k.active, k.keys[k.active]
+		k.mu.RUnlock()
+		return id, key, nil
+	}
+	k.mu.RUnlock()
+
+	active, err := k.store.ActiveDataKey(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	if active == nil {
+		k.mu.Lock()
+		defer k.mu.Unlock()
+		if k.active != 0 {
+			return k.active, k.keys[k.active], nil
+		}
+		created, err := k.rotateLocked(ctx)
+		if err != nil {
+			return 0, nil, err
+		}
+		return created.Id, k.keys[created.Id], nil
+	}
+
+	key, err := k.dataKey(ctx, active.Id)
+	if err != nil {
+		return 0, nil, err
+	}
+	k.mu.Lock()
+	k.active = active.Id
+	k.mu.Unlock()
+	return active.Id, key, nil
+}
+
+func (k *Keyring) dataKey(ctx context.Context, id uint) ([]byte, error) {
+	k.mu.RLock()
+	key, ok := k.keys[id]
+	k.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+
+	stored, err := k.store.FindDataKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	key, err = k.provider.Unwrap(stored.MasterKeyId, stored.WrappedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	k.mu.Lock()
+	k.keys[id] = key
+	k.mu.Unlock()
+	return key, nil
+}
+
+var (
+	defaultMu      sync.RWMutex
+	defaultKeyring *Keyring
+)
+
+// SetDefault installs the keyring used by the "encrypted" serializer and by
+// BlindIndex.
+func SetDefault(keyring *Keyring) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultKeyring = keyring
+}
+
+func Default() (*Keyring, error) {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	if defaultKeyring == nil {
+		return nil, fmt.Errorf("encryption keyring is not configured")
+	}
+	return defaultKeyring, nil
+}
+
+func BlindIndex(value string) (string, error) {
+	keyring, err := Default()
+	if err != nil {
+		return "", err
+	}
+	return keyring.BlindIndex(value), nil
+}
@@ -0,0 +1,165 @@
+package encryption_test
+
+import (
+	"agnos/pkg/encryption"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryKeyStore struct {
+	keys []*encryption.DataKey
+}
+
+func (s *memoryKeyStore) ActiveDataKey(ctx context.Context) (*encryption.DataKey, error) {
+	for i := len(s.keys) - 1; i >= 0; i-- {
+		if s.keys[i].Active {
+			key := *s.keys[i]
+			return &key, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *memoryKeyStore) FindDataKey(ctx context.Context, id uint) (*encryption.DataKey, error) {
+	if id == 0 || int(id) > len(s.keys) {
+		return nil, fmt.Errorf("data key %d not found", id)
+	}
+	key := *s.keys[id-1]
+	return &key, nil
+}
+
+func (s *memoryKeyStore) ListDataKeys(ctx context.Context) ([]*encryption.DataKey, error) {
+	keys := make([]*encryption.DataKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		copied := *key
+		keys = append(keys, &copied)
+	}
+	return keys, nil
+}
+
+func (s *memoryKeyStore) SaveDataKey(ctx context.Context, key *encryption.DataKey) error {
+	copied := *key
+	if key.Id == 0 {
+		copied.Id = uint(len(s.keys) + 1)
+		key.Id = copied.Id
+		s.keys = append(s.keys, &copied)
+		return nil
+	}
+	s.keys[key.Id-1] = &copied
+	return nil
+}
+
+func newProvider(t *testing.T, current string) encryption.KeyProvider {
+	provider, err := encryption.NewLocalProvider(current, map[string][]byte{
+		"k1": bytes.Repeat([]byte{1}, 32),
+		"k2": bytes.Repeat([]byte{2}, 32),
+	}, bytes.Repeat([]byte{3}, 32))
+	assert.NoError(t, err)
+	return provider
+}
+
+func TestKeyringEncryptDecrypt(t *testing.T) {
+	keyring := encryption.NewKeyring(newProvider(t, "k1"), &memoryKeyStore{})
+
+	sealed, err := keyring.Encrypt(context.Background(), "1234567890123", "patients.national_id")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(sealed, "enc:v2:1:"))
+	assert.NotContains(t, sealed, "1234567890123")
+
+	opened, err := keyring.Decrypt(context.Background(), sealed, "patients.national_id")
+	assert.NoError(t, err)
+	assert.Equal(t, "1234567890123", opened)
+
+	empty, err := keyring.Encrypt(context.Background(), "", "patients.national_id")
+	assert.NoError(t, err)
+	assert.Equal(t, "", empty)
+
+	legacy, err := keyring.Decrypt(context.Background(), "plain value", "patients.national_id")
+	assert.NoError(t, err)
+	assert.Equal(t, "plain value", legacy)
+
+	_, err = keyring.Decrypt(context.Background(), "enc:v2:1:not-base64", "patients.national_id")
+	assert.Error(t, err)
+}
+
+func TestKeyringBindsValuesToTheirLocation(t *testing.T) {
+	store := &memoryKeyStore{}
+	provider := newProvider(t, "k1")
+	keyring := encryption.NewKeyring(provider, store)
+
+	sealed, err := keyring.Encrypt(context.Background(), "1234567890123", "patients.national_id")
+	assert.NoError(t, err)
+	_, err = keyring.Decrypt(context.Background(), sealed, "patients.email")
+	assert.Error(t, err)
+
+	// Values sealed before binding are read wherever they are.
+	raw, err := provider.Unwrap(store.keys[0].MasterKeyId, store.keys[0].WrappedKey)
+	assert.NoError(t, err)
+	block, err := aes.NewCipher(raw)
+	assert.NoError(t, err)
+	gcm, err := cipher.NewGCM(block)
+	assert.NoError(t, err)
+	nonce := make([]byte, gcm.NonceSize())
+	legacy := "enc:v1:1:" + base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte("1234567890123"), nil))
+	opened, err := keyring.Decrypt(context.Background(), legacy, "patients.email")
+	assert.NoError(t, err)
+	assert.Equal(t, "1234567890123", opened)
+}
+
+func TestKeyringRotateAndRewrap(t *testing.T) {
+	store := &memoryKeyStore{}
+	keyring := encryption.NewKeyring(newProvider(t, "k1"), store)
+
+	old, err := keyring.Encrypt(context.Background(), "AA1234567", "patients.national_id")
+	assert.NoError(t, err)
+
+	rotated := encryption.NewKeyring(newProvider(t, "k2"), store)
+	key, err := rotated.RotateDataKey(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2), key.Id)
+
+	count, err := rotated.RewrapDataKeys(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	for _, stored := range store.keys {
+		assert.Equal(t, "k2", stored.MasterKeyId)
+	}
+
+	sealed, err := rotated.Encrypt(context.Background(), "AA1234567", "patients.national_id")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(sealed, "enc:v2:2:"))
+
+	// A keyring that only knows k2 still reads values sealed before rotation.
+	provider, err := encryption.NewLocalProvider("k2", map[string][]byte{"k2": bytes.Repeat([]byte{2}, 32)}, bytes.Repeat([]byte{3}, 32))
+	assert.NoError(t, err)
+	fresh := encryption.NewKeyring(provider, store)
+	opened, err := fresh.Decrypt(context.Background(), old, "patients.national_id")
+	assert.NoError(t, err)
+	assert.Equal(t, "AA1234567", opened)
+}
+
+func TestKeyringBlindIndex(t *testing.T) {
+	keyring := encryption.NewKeyring(newProvider(t, "k1"), &memoryKeyStore{})
+
+	index := keyring.BlindIndex("1234567890123")
+	assert.Len(t, index, 32)
+	assert.Equal(t, index, keyring.BlindIndex("1234567890123"))
+	assert.NotEqual(t, index, keyring.BlindIndex("1234567890124"))
+	assert.Equal(t, "", keyring.BlindIndex(""))
+}
+
+func TestNewLocalProviderRejectsShortKeys(t *testing.T) {
+	_, err := encryption.NewLocalProvider("k1", map[string][]byte{"k1": []byte("short")}, bytes.Repeat([]byte{3}, 32))
+	assert.Error(t, err)
+
+	_, err = encryption.NewLocalProvider("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)}, []byte("short"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,171 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeyProvider holds the master keys that wrap data keys. The local provider
+// keeps them in memory; a KMS backed provider would call out instead.
+type KeyProvider interface {
+	CurrentKeyId() string
+	Wrap(keyId string, dataKey []byte) ([]byte, error)
+	Unwrap(keyId string, wrapped []byte) ([]byte, error)
+	BlindIndexKey() []byte
+}
+
+type LocalProvider struct {
+	current  string
+	keys     map[string][]byte
+	indexKey []byte
+}
+
+func NewLocalProvider(current string, keys map[string][]byte, indexKey []byte) (*LocalProvider, error) {
+	for id, key := range keys {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("master key %s must be 32 bytes", id)
+		}
+	}
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("master key %s not found", current)
+	}
+	if len(indexKey) < 32 {
+		return nil, fmt.Errorf("blind index key must be at least 32 bytes")
+	}
+	return &LocalProvider{current: current, keys: keys, indexKey: indexKey}, nil
+}
+
+// NewEnvProvider reads MASTER_KEYS ("id:base64,id:base64"), MASTER_KEY_ID
+// (defaults to the first key) and BLIND_INDEX_KEY (base64).
+func NewEnvProvider() (*LocalProvider, error) {
+	keys := make(map[string][]byte)
+	current := os.Getenv("MASTER_KEY_ID")
+	for _, entry := range strings.Split(os.Getenv("MASTER_KEYS"), ",") {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("master key %s is not valid base64", id)
+		}
+		keys[id] = key
+		if current == "" {
+			current = id
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("MASTER_KEYS is required")
+	}
+
+	indexKey, err := base64.StdEncoding.DecodeString(os.Getenv("BLIND_INDEX_KEY"))
+	if err != nil {
+		return nil, fmt.Errorf("BLIND_INDEX_KEY is not valid base64")
+	}
+	return NewLocalProvider(current, keys, indexKey)
+}
+
+type keyFile struct {
+	Current       string            `json:"current"`
+	Keys          map[string]string `json:"keys"`
+	BlindIndexKey string            `json:"blind_index_key"`
+}
+
+// NewFileProvider reads master keys from a JSON file of the form
+// {"current": "k2", "keys": {"k1": "base64", "k2": "base64"}, "blind_index_key": "base64"}.
+func NewFileProvider(path string) (*LocalProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file keyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string][]byte)
+	for id, encoded := range file.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("master key %s is not valid base64", id)
+		}
+		keys[id] = key
+	}
+	indexKey, err := base64.StdEncoding.DecodeString(file.BlindIndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("blind_index_key is not valid base64")
+	}
+	return NewLocalProvider(file.Current, keys, indexKey)
+}
+
+// NewProviderFromEnv uses ENCRYPTION_KEY_FILE when set and falls back to
+// the environment variables read by NewEnvProvider.
+func NewProviderFromEnv() (KeyProvider, error) {
+	if path := os.Getenv("ENCRYPTION_KEY_FILE"); path != "" {
+		return NewFileProvider(path)
+	}
+	return NewEnvProvider()
+}
+
+func (p *LocalProvider) CurrentKeyId() string {
+	return p.current
+}
+
+func (p *LocalProvider) Wrap(keyId string, dataKey []byte) ([]byte, error) {
+	key, ok := p.keys[keyId]
+	if !ok {
+		return nil, fmt.Errorf("master key %s not found", keyId)
+	}
+	return seal(key, dataKey, nil)
+}
+
+func (p *LocalProvider) Unwrap(keyId string, wrapped []byte) ([]byte, error) {
+	key, ok := p.keys[keyId]
+	if !ok {
+		return nil, fmt.Errorf("master key %s not found", keyId)
+	}
+	return open(key, wrapped, nil)
+}
+
+func (p *LocalProvider) BlindIndexKey() []byte {
+	return p.indexKey
+}
+
+func seal(key []byte, plaintext []byte, additional []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+func open(key []byte, sealed []byte, additional []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext is too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, additional)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
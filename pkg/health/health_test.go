@@ -0,0 +1,59 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"agnos/pkg/health"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker_Run(t *testing.T) {
+	checker := health.NewChecker(20 * time.Millisecond)
+	checker.Register("database", func(ctx context.Context) error { return nil })
+	report := checker.Run(context.Background())
+	assert.Equal(t, health.StatusUp, report.Status)
+	assert.Equal(t, health.StatusUp, report.Checks["database"].Status)
+
+	checker.Register("mllp", func(ctx context.Context) error { return errors.New("not listening") })
+	checker.Register("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	report = checker.Run(context.Background())
+	assert.Equal(t, health.StatusDown, report.Status)
+	assert.Equal(t, health.StatusUp, report.Checks["database"].Status)
+	assert.Equal(t, "not listening", report.Checks["mllp"].Error)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["slow"].Error)
+	assert.Equal(t, []string{"database", "mllp", "slow"}, checker.Names())
+}
+
+func TestWaitFor(t *testing.T) {
+	backoff := health.Backoff{Attempts: 4, Initial: time.Millisecond, Max: 2 * time.Millisecond}
+
+	calls := 0
+	var waits []time.Duration
+	err := health.WaitFor(context.Background(), backoff, func() error {
+		calls++
+		if calls < 4 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}, func(attempt int, err error, wait time.Duration) {
+		waits = append(waits, wait)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, calls)
+	assert.Equal(t, []time.Duration{time.Millisecond, 2 * time.Millisecond, 2 * time.Millisecond}, waits)
+
+	calls = 0
+	err = health.WaitFor(context.Background(), backoff, func() error {
+		calls++
+		return errors.New("connection refused")
+	}, nil)
+	assert.EqualError(t, err, "connection refused")
+	assert.Equal(t, 4, calls)
+}
@@ -0,0 +1,43 @@
+package health
+
+import (
+	"context"
+	"time"
+)
+
+// Backoff describes how WaitFor spaces its attempts: the delay starts at
+// Initial and doubles up to Max.
+type Backoff struct {
+	Attempts int
+	Initial  time.Duration
+	Max      time.Duration
+}
+
+// WaitFor calls fn until it succeeds, the attempts run out or ctx ends.
+// onRetry, when set, is told about every failed attempt and the wait before
+// the next one. The last error is returned.
+func WaitFor(ctx context.Context, backoff Backoff, fn func() error, onRetry func(attempt int, err error, wait time.Duration)) error {
+	delay := backoff.Initial
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if attempt >= backoff.Attempts {
+			return err
+		}
+		if onRetry != nil {
+			onRetry(attempt, err, delay)
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if backoff.Max > 0 && delay > backoff.Max {
+			delay = backoff.Max
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package hl7
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	AckAccept = "AA"
+	AckError  = "AE"
+	AckReject = "AR"
+)
+
+// BuildAck answers message with an ACK carrying the given acknowledgment
+// code. Sender and receiver are swapped from the original MSH.
+func BuildAck(message *Message, code string, text string) []byte {
+	d := DefaultDelimiters
+	get := func(path string) string { return "" }
+	if message != nil {
+		d = message.Delimiters
+		get = func(path string) string { return Escape(message.Get(path), d) }
+	}
+
+	version := get("MSH-12")
+	if version == "" {
+		version = "2.5"
+	}
+	trigger := get("MSH-9.2")
+
+	f := string(d.Field)
+	encoding := string([]byte{d.Component, d.Repetition, d.Escape, d.Subcomponent})
+	now := time.Now().Format("20060102150405")
+
+	msh := strings.Join([]string{
+		"MSH", encoding,
+		get("MSH-5"), get("MSH-6"), get("MSH-3"), get("MSH-4"),
+		now, "",
+		"ACK" + string(d.Component) + trigger + string(d.Component) + "ACK",
+		fmt.Sprintf("ACK%s", now), get("MSH-11"), version,
+	}, f)
+	msa := strings.Join([]string{"MSA", code, get("MSH-10"), Escape(text, d)}, f)
+
+	segments := []string{msh, msa}
+	if code != AckAccept {
+		severity := "E"
+		segments = append(segments, strings.Join([]string{"ERR", "", "", "", severity, "", "", "", Escape(text, d)}, f))
+	}
+	return []byte(strings.Join(segments, "\r") + "\r")
+}
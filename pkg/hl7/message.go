@@ -0,0 +1,224 @@
+package hl7
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Delimiters struct {
+	Field        byte
+	Component    byte
+	Repetition   byte
+	Escape       byte
+	Subcomponent byte
+}
+
+var DefaultDelimiters = Delimiters{Field: '|', Component: '^', Repetition: '~', Escape: '\\', Subcomponent: '&'}
+
+type Segment struct {
+	Name   string
+	fields []string
+}
+
+type Message struct {
+	Delimiters Delimiters
+	Segments   []*Segment
+}
+
+// Parse reads a pipe-delimited HL7 v2 message. Segments may be separated by
+// CR, LF or CRLF so that fixtures written with an ordinary editor also parse.
+func Parse(raw []byte) (*Message, error) {
+	text := strings.ReplaceAll(string(raw), "\r\n", "\r")
+	text = strings.ReplaceAll(text, "\n", "\r")
+	text = strings.Trim(text, "\r\x0b\x1c ")
+
+	if !strings.HasPrefix(text, "MSH") || len(text) < 8 {
+		return nil, fmt.Errorf("message must start with an MSH segment")
+	}
+
+	d := Delimiters{
+		Field:        text[3],
+		Component:    text[4],
+		Repetition:   text[5],
+		Escape:       text[6],
+		Subcomponent: text[7],
+	}
+	if text[7] == d.Field {
+		d.Subcomponent = DefaultDelimiters.Subcomponent
+	}
+
+	message := &Message{Delimiters: d}
+	for _, line := range strings.Split(text, "\r") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Split(line, string(d.Field))
+		segment := &Segment{Name: fields[0]}
+		if segment.Name == "MSH" {
+			// MSH-1 is the field separator itself, so MSH-2 starts at fields[1].
+			segment.fields = append([]string{string(d.Field)}, fields[1:]...)
+		} else {
+			segment.fields = fields[1:]
+		}
+		message.Segments = append(message.Segments, segment)
+	}
+	return message, nil
+}
+
+func (m *Message) Segment(name string) *Segment {
+	for _, segment := range m.Segments {
+		if segment.Name == name {
+			return segment
+		}
+	}
+	return nil
+}
+
+// Get returns a decoded value by terser-style path such as "PID-5.1",
+// "PID-3(2).1" for the second repetition, or "MSH-9.2".
+func (m *Message) Get(path string) string {
+	name, rest, ok := strings.Cut(path, "-")
+	if !ok {
+		return ""
+	}
+	segment := m.Segment(name)
+	if segment == nil {
+		return ""
+	}
+
+	fieldPart, componentPart, _ := strings.Cut(rest, ".")
+	repetition := 1
+	if open := strings.Index(fieldPart, "("); open >= 0 {
+		repetition, _ = strconv.Atoi(strings.TrimSuffix(fieldPart[open+1:], ")"))
+		fieldPart = fieldPart[:open]
+	}
+	field, _ := strconv.Atoi(fieldPart)
+
+	component, subcomponent := 1, 1
+	if componentPart != "" {
+		comp, sub, _ := strings.Cut(componentPart, ".")
+		component, _ = strconv.Atoi(comp)
+		if sub != "" {
+			subcomponent, _ = strconv.Atoi(sub)
+		}
+	}
+
+	reps := m.Repetitions(segment, field)
+	if repetition < 1 || repetition > len(reps) {
+		return ""
+	}
+	return reps[repetition-1].Component(component, subcomponent)
+}
+
+type Repetition struct {
+	value      string
+	delimiters Delimiters
+}
+
+// Repetitions splits a field into its repetitions. MSH-2 holds the encoding
+// characters and is returned as a single raw value.
+func (m *Message) Repetitions(segment *Segment, field int) []Repetition {
+	if field < 1 || field > len(segment.fields) {
+		return nil
+	}
+	raw := segment.fields[field-1]
+	if segment.Name == "MSH" && field <= 2 {
+		return []Repetition{{value: raw, delimiters: Delimiters{}}}
+	}
+	if raw == "" {
+		return nil
+	}
+
+	reps := make([]Repetition, 0)
+	for _, value := range strings.Split(raw, string(m.Delimiters.Repetition)) {
+		reps = append(reps, Repetition{value: value, delimiters: m.Delimiters})
+	}
+	return reps
+}
+
+func (r Repetition) Component(component int, subcomponent int) string {
+	if r.delimiters == (Delimiters{}) {
+		return r.value
+	}
+	components := strings.Split(r.value, string(r.delimiters.Component))
+	if component < 1 || component > len(components) {
+		return ""
+	}
+	subcomponents := strings.Split(components[component-1], string(r.delimiters.Subcomponent))
+	if subcomponent < 1 || subcomponent > len(subcomponents) {
+		return ""
+	}
+	return Unescape(subcomponents[subcomponent-1], r.delimiters)
+}
+
+// Unescape decodes the standard HL7 escape sequences. Formatting sequences
+// other than line breaks are dropped.
+func Unescape(value string, d Delimiters) string {
+	esc := string(d.Escape)
+	if !strings.Contains(value, esc) {
+		return value
+	}
+
+	var b strings.Builder
+	for {
+		start := strings.Index(value, esc)
+		if start < 0 {
+			b.WriteString(value)
+			break
+		}
+		end := strings.Index(value[start+1:], esc)
+		if end < 0 {
+			b.WriteString(value)
+			break
+		}
+		b.WriteString(value[:start])
+		sequence := value[start+1 : start+1+end]
+		value = value[start+end+2:]
+
+		switch {
+		case sequence == "F":
+			b.WriteByte(d.Field)
+		case sequence == "S":
+			b.WriteByte(d.Component)
+		case sequence == "T":
+			b.WriteByte(d.Subcomponent)
+		case sequence == "R":
+			b.WriteByte(d.Repetition)
+		case sequence == "E":
+			b.WriteByte(d.Escape)
+		case sequence == ".br":
+			b.WriteByte('\n')
+		case strings.HasPrefix(sequence, "X"):
+			for i := 1; i+1 < len(sequence); i += 2 {
+				if v, err := strconv.ParseUint(sequence[i:i+2], 16, 8); err == nil {
+					b.WriteByte(byte(v))
+				}
+			}
+		}
+	}
+	return b.String()
+}
+
+func Escape(value string, d Delimiters) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case d.Escape:
+			b.WriteString(string(d.Escape) + "E" + string(d.Escape))
+		case d.Field:
+			b.WriteString(string(d.Escape) + "F" + string(d.Escape))
+		case d.Component:
+			b.WriteString(string(d.Escape) + "S" + string(d.Escape))
+		case d.Subcomponent:
+			b.WriteString(string(d.Escape) + "T" + string(d.Escape))
+		case d.Repetition:
+			b.WriteString(string(d.Escape) + "R" + string(d.Escape))
+		case '\r', '\n':
+			b.WriteString(string(d.Escape) + ".br" + string(d.Escape))
+		default:
+			b.WriteByte(value[i])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,80 @@
+package hl7_test
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"agnos/pkg/hl7"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const sample = "MSH|^~\\&|HOSXP|HUAHIN|AGNOS|AGNOS|20240101120000||ADT^A04^ADT_A01|MSG00001|P|2.5\r" +
+	"PID|1||1234567890123^^^TH-CID^NI~HN00001^^^HUAHIN^MR||O\\S\\Brien\\T\\Smith^Mary||19950721|F\r"
+
+func TestParse_FieldsComponentsAndRepetitions(t *testing.T) {
+	message, err := hl7.Parse([]byte(sample))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "|", message.Get("MSH-1"))
+	assert.Equal(t, "^~\\&", message.Get("MSH-2"))
+	assert.Equal(t, "HOSXP", message.Get("MSH-3"))
+	assert.Equal(t, "ADT", message.Get("MSH-9.1"))
+	assert.Equal(t, "A04", message.Get("MSH-9.2"))
+	assert.Equal(t, "MSG00001", message.Get("MSH-10"))
+
+	assert.Equal(t, "1234567890123", message.Get("PID-3.1"))
+	assert.Equal(t, "HN00001", message.Get("PID-3(2).1"))
+	assert.Equal(t, "MR", message.Get("PID-3(2).5"))
+	assert.Equal(t, "", message.Get("PID-3(3).1"))
+	assert.Equal(t, 2, len(message.Repetitions(message.Segment("PID"), 3)))
+}
+
+func TestParse_EscapeSequences(t *testing.T) {
+	message, err := hl7.Parse([]byte(sample))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "O^Brien&Smith", message.Get("PID-5.1"))
+	assert.Equal(t, "a|b", hl7.Unescape("a\\F\\b", hl7.DefaultDelimiters))
+	assert.Equal(t, "line1\nline2", hl7.Unescape("line1\\.br\\line2", hl7.DefaultDelimiters))
+	assert.Equal(t, "A", hl7.Unescape("\\X41\\", hl7.DefaultDelimiters))
+
+	value := "x|y^z~w&v\\u"
+	assert.Equal(t, value, hl7.Unescape(hl7.Escape(value, hl7.DefaultDelimiters), hl7.DefaultDelimiters))
+}
+
+func TestParse_RejectsMissingMsh(t *testing.T) {
+	_, err := hl7.Parse([]byte("PID|1||123"))
+	assert.Error(t, err)
+}
+
+func TestBuildAck(t *testing.T) {
+	message, _ := hl7.Parse([]byte(sample))
+
+	ack, err := hl7.Parse(hl7.BuildAck(message, hl7.AckError, "national_id already exist"))
+	assert.NoError(t, err)
+	assert.Equal(t, "AGNOS", ack.Get("MSH-3"))
+	assert.Equal(t, "HOSXP", ack.Get("MSH-5"))
+	assert.Equal(t, "ACK", ack.Get("MSH-9.1"))
+	assert.Equal(t, "A04", ack.Get("MSH-9.2"))
+	assert.Equal(t, "AE", ack.Get("MSA-1"))
+	assert.Equal(t, "MSG00001", ack.Get("MSA-2"))
+	assert.NotNil(t, ack.Segment("ERR"))
+}
+
+func TestMllpFrame_RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, hl7.WriteFrame(&buf, []byte(sample)))
+	assert.NoError(t, hl7.WriteFrame(&buf, []byte("MSH|^~\\&|SECOND")))
+
+	reader := bufio.NewReader(&buf)
+	first, err := hl7.ReadFrame(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, sample, string(first))
+
+	second, err := hl7.ReadFrame(reader)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(string(second), "SECOND"))
+}
@@ -0,0 +1,60 @@
+package hl7
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+const (
+	mllpStart = 0x0b
+	mllpEnd   = 0x1c
+	mllpCR    = 0x0d
+
+	maxMessageSize = 1 << 20
+)
+
+// ReadFrame reads one MLLP frame (<VT> message <FS><CR>) and returns the
+// message without its envelope.
+func ReadFrame(r *bufio.Reader) ([]byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b == mllpStart {
+			break
+		}
+	}
+
+	var message bytes.Buffer
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		if b == mllpEnd {
+			if next, err := r.ReadByte(); err == nil && next != mllpCR {
+				r.UnreadByte()
+			}
+			return message.Bytes(), nil
+		}
+		if message.Len() >= maxMessageSize {
+			return nil, fmt.Errorf("mllp frame exceeds %d bytes", maxMessageSize)
+		}
+		message.WriteByte(b)
+	}
+}
+
+func WriteFrame(w io.Writer, message []byte) error {
+	frame := make([]byte, 0, len(message)+3)
+	frame = append(frame, mllpStart)
+	frame = append(frame, message...)
+	frame = append(frame, mllpEnd, mllpCR)
+	_, err := w.Write(frame)
+	return err
+}
@@ -0,0 +1,113 @@
+// Package logging builds the service's structured JSON loggers. Every
+// logger belongs to a subsystem whose level can be set on its own, carries
+// the request ID and trace found in the context and has PII scrubbed before
+// output.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Config holds the default level and the per-subsystem overrides.
+type Config struct {
+	Level  slog.Level
+	Levels map[string]slog.Level
+}
+
+// LevelFor returns the level of subsystem.
+func (c Config) LevelFor(subsystem string) slog.Level {
+	if level, ok := c.Levels[subsystem]; ok {
+		return level
+	}
+	return c.Level
+}
+
+// ParseConfig reads a default level such as "info" and overrides such as
+// "gorm=warn,http=info".
+func ParseConfig(level string, levels string) (Config, error) {
+	config := Config{Level: slog.LevelInfo, Levels: map[string]slog.Level{}}
+	if level != "" {
+		if err := config.Level.UnmarshalText([]byte(level)); err != nil {
+			return config, fmt.Errorf("log level %s is invalid", level)
+		}
+	}
+
+	for _, override := range strings.Split(levels, ",") {
+		override = strings.TrimSpace(override)
+		if override == "" {
+			continue
+		}
+		subsystem, name, ok := strings.Cut(override, "=")
+		if !ok {
+			return config, fmt.Errorf("log level %s is not subsystem=level", override)
+		}
+		var subsystemLevel slog.Level
+		if err := subsystemLevel.UnmarshalText([]byte(name)); err != nil {
+			return config, fmt.Errorf("log level %s is invalid", name)
+		}
+		config.Levels[strings.TrimSpace(subsystem)] = subsystemLevel
+	}
+	return config, nil
+}
+
+type root struct {
+	handler slog.Handler
+	config  Config
+}
+
+var current atomic.Pointer[root]
+
+func init() {
+	Setup(os.Stderr, Config{Level: slog.LevelInfo})
+}
+
+// Setup sends every logger obtained afterwards to w as JSON and makes the
+// default slog logger use it too.
+func Setup(w io.Writer, config Config) {
+	handler := slog.NewJSONHandler(w, &slog.HandlerOptions{Level: slog.LevelDebug, ReplaceAttr: Redact})
+	current.Store(&root{handler: handler, config: config})
+	slog.SetDefault(For("app"))
+}
+
+// For returns the logger of subsystem.
+func For(subsystem string) *slog.Logger {
+	r := current.Load()
+	return slog.New(&handler{next: r.handler, level: r.config.LevelFor(subsystem)}).With("subsystem", subsystem)
+}
+
+// handler filters records below its subsystem's level and adds the request
+// ID and trace carried by the context.
+type handler struct {
+	next  slog.Handler
+	level slog.Level
+}
+
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *handler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		record.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+	}
+	return h.next.Handle(ctx, record)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &handler{next: h.next.WithAttrs(attrs), level: h.level}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{next: h.next.WithGroup(name), level: h.level}
+}
@@ -0,0 +1,97 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"agnos/pkg/logging"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeLines(t *testing.T, out *bytes.Buffer) []map[string]interface{} {
+	lines := make([]map[string]interface{}, 0)
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &entry))
+		lines = append(lines, entry)
+	}
+	return lines
+}
+
+func TestParseConfig(t *testing.T) {
+	config, err := logging.ParseConfig("warn", "gorm=debug, http=error")
+	assert.NoError(t, err)
+	assert.Equal(t, slog.LevelWarn, config.LevelFor("retention"))
+	assert.Equal(t, slog.LevelDebug, config.LevelFor("gorm"))
+	assert.Equal(t, slog.LevelError, config.LevelFor("http"))
+
+	_, err = logging.ParseConfig("loud", "")
+	assert.Error(t, err)
+	_, err = logging.ParseConfig("info", "gorm")
+	assert.Error(t, err)
+}
+
+func TestFor_FiltersBySubsystemAndAddsRequestId(t *testing.T) {
+	var out bytes.Buffer
+	logging.Setup(&out, logging.Config{Level: slog.LevelInfo, Levels: map[string]slog.Level{"gorm": slog.LevelWarn}})
+	defer logging.Setup(&bytes.Buffer{}, logging.Config{Level: slog.LevelInfo})
+
+	ctx := logging.WithRequestID(context.Background(), "req-1")
+	logging.For("gorm").InfoContext(ctx, "hidden")
+	logging.For("http").InfoContext(ctx, "shown")
+
+	lines := decodeLines(t, &out)
+	assert.Equal(t, 1, len(lines))
+	assert.Equal(t, "shown", lines[0]["msg"])
+	assert.Equal(t, "http", lines[0]["subsystem"])
+	assert.Equal(t, "req-1", lines[0]["request_id"])
+}
+
+func TestRedact(t *testing.T) {
+	var out bytes.Buffer
+	logging.Setup(&out, logging.Config{Level: slog.LevelInfo})
+	defer logging.Setup(&bytes.Buffer{}, logging.Config{Level: slog.LevelInfo})
+
+	logging.For("app").Info("patient 1-2345-67890-12-3 contacted somsak@example.com",
+		"national_id", "1234567890123",
+		"password", "secret",
+		"hash", "$2a$10$abcdefghijklmnopqrstuuABCDEFGHIJKLMNOPQRSTUVWXYZ01234",
+		"hospital", "Bangkok Hospital")
+
+	entry := decodeLines(t, &out)[0]
+	assert.Equal(t, "patient [REDACTED] contacted [REDACTED]", entry["msg"])
+	assert.Equal(t, "[REDACTED]", entry["national_id"])
+	assert.Equal(t, "[REDACTED]", entry["password"])
+	assert.Equal(t, "[REDACTED]", entry["hash"])
+	assert.Equal(t, "Bangkok Hospital", entry["hospital"])
+}
+
+func TestGormLogger_LogsStatementsWithoutParameters(t *testing.T) {
+	var out bytes.Buffer
+	logging.Setup(&out, logging.Config{Level: slog.LevelDebug})
+	defer logging.Setup(&bytes.Buffer{}, logging.Config{Level: slog.LevelInfo})
+
+	gormLogger := logging.NewGormLogger(logging.For("gorm"), time.Second)
+	sql, vars := gormLogger.ParamsFilter(context.Background(), "SELECT * FROM patients WHERE national_id_index = $1", "secret-index")
+	assert.Nil(t, vars)
+
+	gormLogger.Trace(context.Background(), time.Now(), func() (string, int64) { return sql, 1 }, nil)
+	gormLogger.Trace(context.Background(), time.Now(), func() (string, int64) { return sql, 0 }, errors.New("boom"))
+
+	lines := decodeLines(t, &out)
+	assert.Equal(t, 2, len(lines))
+	assert.Equal(t, "DEBUG", lines[0]["level"])
+	assert.Equal(t, "SELECT * FROM patients WHERE national_id_index = $1", lines[0]["sql"])
+	assert.Equal(t, "ERROR", lines[1]["level"])
+	assert.Equal(t, "boom", lines[1]["error"])
+}
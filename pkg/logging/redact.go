@@ -0,0 +1,53 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute keys whose values are never logged.
+var sensitiveKeys = map[string]bool{
+	"national_id":    true,
+	"passport_id":    true,
+	"phone_number":   true,
+	"email":          true,
+	"date_of_birth":  true,
+	"first_name_th":  true,
+	"middle_name_th": true,
+	"last_name_th":   true,
+	"first_name_en":  true,
+	"middle_name_en": true,
+	"last_name_en":   true,
+	"password":       true,
+	"token":          true,
+	"authorization":  true,
+}
+
+var (
+	// nationalIds matches 13 digit Thai national IDs, with or without dashes.
+	nationalIds = regexp.MustCompile(`\b\d{1}-?\d{4}-?\d{5}-?\d{2}-?\d{1}\b`)
+	emails      = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	bcrypts     = regexp.MustCompile(`\$2[aby]?\$\d{2}\$[./A-Za-z0-9]{53}`)
+)
+
+// Redact is a slog ReplaceAttr function. It drops the values of sensitive
+// keys and scrubs national IDs, emails and password hashes from strings.
+func Redact(groups []string, attr slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(attr.Key)] {
+		return slog.String(attr.Key, redacted)
+	}
+	if attr.Value.Kind() == slog.KindString {
+		return slog.String(attr.Key, Scrub(attr.Value.String()))
+	}
+	return attr
+}
+
+// Scrub replaces PII found in free text.
+func Scrub(text string) string {
+	text = nationalIds.ReplaceAllString(text, redacted)
+	text = emails.ReplaceAllString(text, redacted)
+	return bcrypts.ReplaceAllString(text, redacted)
+}
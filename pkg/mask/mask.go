@@ -0,0 +1,46 @@
+package mask
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const maskChar = "X"
+
+// NationalId masks the middle of a 13 digit Thai ID and formats it the way
+// it is printed on the card, e.g. 1-2345-XXXXX-12-3.
+func NationalId(value string) string {
+	if len(value) != 13 {
+		return Keep(value, 2, 1)
+	}
+	return value[:1] + "-" + value[1:5] + "-" + strings.Repeat(maskChar, 5) + "-" + value[10:12] + "-" + value[12:]
+}
+
+func Passport(value string) string {
+	return Keep(value, 2, 2)
+}
+
+func Phone(value string) string {
+	return Keep(value, 3, 2)
+}
+
+func Email(value string) string {
+	local, domain, ok := strings.Cut(value, "@")
+	if !ok {
+		return Keep(value, 1, 0)
+	}
+	return Keep(local, 1, 0) + "@" + domain
+}
+
+// Keep replaces everything but the first and last characters of value.
+func Keep(value string, first int, last int) string {
+	n := utf8.RuneCountInString(value)
+	if n == 0 {
+		return ""
+	}
+	if first+last >= n {
+		return strings.Repeat(maskChar, n)
+	}
+	runes := []rune(value)
+	return string(runes[:first]) + strings.Repeat(maskChar, n-first-last) + string(runes[n-last:])
+}
@@ -0,0 +1,38 @@
+package mask_test
+
+import (
+	"testing"
+
+	"agnos/pkg/mask"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMask(t *testing.T) {
+	assert.Equal(t, "1-2345-XXXXX-12-3", mask.NationalId("1234567890123"))
+	assert.Equal(t, "89XXXXXXX5", mask.NationalId("8905890585"))
+	assert.Equal(t, "AAXXXXX67", mask.Passport("AA1234567"))
+	assert.Equal(t, "081XXXXX78", mask.Phone("0812345678"))
+	assert.Equal(t, "pXXXXXXXX@example.com", mask.Email("plabpluem@example.com"))
+	assert.Equal(t, "สXXXX", mask.Keep("สมชาย", 1, 0))
+	assert.Equal(t, "", mask.Phone(""))
+	assert.Equal(t, "XX", mask.Phone("12"))
+}
+
+func TestPolicy(t *testing.T) {
+	clinician := mask.PolicyFor("clinician")
+	assert.Equal(t, "1234567890123", clinician.Apply(mask.FieldNationalId, "1234567890123"))
+	assert.False(t, clinician.Masked())
+
+	receptionist := mask.PolicyFor("receptionist")
+	assert.Equal(t, "1-2345-XXXXX-12-3", receptionist.Apply(mask.FieldNationalId, "1234567890123"))
+	assert.Equal(t, "081XXXXX78", receptionist.Apply(mask.FieldPhoneNumber, "0812345678"))
+	assert.True(t, receptionist.Masked())
+
+	unknown := mask.PolicyFor("intern")
+	assert.Equal(t, "", unknown.Apply(mask.FieldEmail, "plabpluem@example.com"))
+
+	restricted := clinician.Restrict(mask.MaskAll)
+	assert.Equal(t, mask.Mask, restricted.Action(mask.FieldEmail))
+	assert.Equal(t, mask.Omit, unknown.Restrict(mask.MaskAll).Action(mask.FieldEmail))
+}
@@ -0,0 +1,116 @@
+package mask
+
+import "fmt"
+
+// Action is what a policy does with a sensitive field. Actions are ordered
+// from least to most restrictive.
+type Action int
+
+const (
+	Show Action = iota
+	Mask
+	Omit
+)
+
+const (
+	FieldNationalId  = "national_id"
+	FieldPassportId  = "passport_id"
+	FieldPhoneNumber = "phone_number"
+	FieldEmail       = "email"
+)
+
+var maskers = map[string]func(string) string{
+	FieldNationalId:  NationalId,
+	FieldPassportId:  Passport,
+	FieldPhoneNumber: Phone,
+	FieldEmail:       Email,
+}
+
+// Fields lists the sensitive fields that policies apply to.
+var Fields = []string{FieldNationalId, FieldPassportId, FieldPhoneNumber, FieldEmail}
+
+// Policy maps a sensitive field to the action taken on it. Fields that are
+// not listed are omitted, so a new sensitive field is hidden until a policy
+// says otherwise.
+type Policy map[string]Action
+
+var (
+	ShowAll = Policy{FieldNationalId: Show, FieldPassportId: Show, FieldPhoneNumber: Show, FieldEmail: Show}
+	MaskAll = Policy{FieldNationalId: Mask, FieldPassportId: Mask, FieldPhoneNumber: Mask, FieldEmail: Mask}
+	OmitAll = Policy{}
+)
+
+// Policies holds the policy for each staff role. Only clinicians see full
+// values; everyone else has to go through the audited reveal endpoint.
+var Policies = map[string]Policy{
+	"clinician":    ShowAll,
+	"admin":        MaskAll,
+	"receptionist": MaskAll,
+}
+
+// PolicyFor returns the policy of role. Unknown roles see no sensitive
+// field at all.
+func PolicyFor(role string) Policy {
+	if policy, ok := Policies[role]; ok {
+		return policy
+	}
+	return OmitAll
+}
+
+func (p Policy) Action(field string) Action {
+	if action, ok := p[field]; ok {
+		return action
+	}
+	return Omit
+}
+
+// Apply returns value as the policy allows field to be seen.
+func (p Policy) Apply(field string, value string) string {
+	switch p.Action(field) {
+	case Show:
+		return value
+	case Mask:
+		if masker, ok := maskers[field]; ok {
+			return masker(value)
+		}
+	}
+	return ""
+}
+
+// Restrict combines p with other, keeping the stricter action per field.
+func (p Policy) Restrict(other Policy) Policy {
+	restricted := make(Policy, len(Fields))
+	for _, field := range Fields {
+		restricted[field] = max(p.Action(field), other.Action(field))
+	}
+	return restricted
+}
+
+// Masked reports whether any sensitive field is hidden by the policy.
+func (p Policy) Masked() bool {
+	for _, field := range Fields {
+		if p.Action(field) != Show {
+			return true
+		}
+	}
+	return false
+}
+
+var actionNames = map[string]Action{"show": Show, "mask": Mask, "omit": Omit}
+
+// ParsePolicy reads a policy from field to action names, e.g.
+// {"national_id": "mask", "email": "omit"}.
+func ParsePolicy(actions map[string]string) (Policy, error) {
+	policy := make(Policy, len(actions))
+	for field, name := range actions {
+		if _, ok := maskers[field]; !ok {
+			return nil, fmt.Errorf("field %s is not a sensitive field", field)
+		}
+		action, ok := actionNames[name]
+		if !ok {
+			return nil, fmt.Errorf("action %s is not one of show, mask, omit", name)
+		}
+		policy[field] = action
+	}
+	return policy, nil
+}
@@ -0,0 +1,80 @@
+// Package metrics holds the service's Prometheus collectors. They are
+// registered on Registry, which is served on the admin port rather than on
+// the public API.
+package metrics
+
+import (
+	"database/sql"
+	"expvar"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "agnos"
+
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HttpRequests is labelled by route template, never by raw path, so
+	// that IDs in the URL do not create a series each.
+	HttpRequests = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route template and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	Logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_logins_total",
+		Help:      "Staff login attempts by result: success, unknown_user, wrong_password or error.",
+	}, []string{"result"})
+
+	TokenFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_token_failures_total",
+		Help:      "Rejected JWTs by reason: missing, expired or invalid.",
+	}, []string{"reason"})
+
+	DbQueries = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "GORM statement latency by operation and table.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table", "status"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HttpRequests,
+		Logins,
+		TokenFailures,
+		DbQueries,
+	)
+}
+
+// RegisterDB exposes the connection pool stats of db.
+func RegisterDB(db *sql.DB, name string) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// RegisterExpvar bridges an expvar map of counters, such as the retention
+// worker's, into gauges labelled by key.
+func RegisterExpvar(name string, help string) error {
+	if expvar.Get(name) == nil {
+		return nil
+	}
+	return Registry.Register(collectors.NewExpvarCollector(map[string]*prometheus.Desc{
+		name: prometheus.NewDesc(prometheus.BuildFQName(namespace, name, "total"), help, []string{"key"}, nil),
+	}))
+}
+
+// Handler serves the registry in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
@@ -0,0 +1,64 @@
+package metrics_test
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"agnos/pkg/metrics"
+	"agnos/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics_LabelsByRouteTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.Metrics)
+	r.GET("/patient/search/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, path := range []string{"/patient/search/1234567890123", "/patient/search/1234567890124", "/nowhere"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	body := scrape(t)
+	assert.Contains(t, body, `route="/patient/search/:id",status="200"} 2`)
+	assert.Contains(t, body, `route="unmatched",status="404"} 1`)
+	assert.NotContains(t, body, "1234567890123")
+}
+
+func TestMetrics_TokenFailures(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/private", middleware.AuthRequired, func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	missing := testutil.ToFloat64(metrics.TokenFailures.WithLabelValues("missing"))
+	invalid := testutil.ToFloat64(metrics.TokenFailures.WithLabelValues("invalid"))
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/private", nil))
+	req := httptest.NewRequest("GET", "/private", nil)
+	req.Header.Set("Authorization", "Bearer nonsense")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, missing+1, testutil.ToFloat64(metrics.TokenFailures.WithLabelValues("missing")))
+	assert.Equal(t, invalid+1, testutil.ToFloat64(metrics.TokenFailures.WithLabelValues("invalid")))
+}
+
+func TestRegisterExpvar(t *testing.T) {
+	counters := expvar.NewMap("metrics_test")
+	counters.Add("runs", 3)
+
+	assert.NoError(t, metrics.RegisterExpvar("metrics_test", "Test counters."))
+	assert.NoError(t, metrics.RegisterExpvar("missing_map", "Not published."))
+	assert.Contains(t, scrape(t), `agnos_metrics_test_total{key="runs"} 3`)
+}
+
+func scrape(t *testing.T) string {
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	return w.Body.String()
+}
@@ -1,6 +1,10 @@
 package middleware
 
 import (
+	"agnos/pkg/database"
+	"agnos/pkg/metrics"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 
@@ -8,21 +12,84 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func AuthRequired(c *gin.Context) {
+const secretKey = "supersecret"
+
+func SignToken(claims jwt.MapClaims) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secretKey))
+}
+
+func ParseToken(c *gin.Context) (jwt.MapClaims, error) {
 	accessToken := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
-	secretKey := "supersecret"
 
 	token, err := jwt.ParseWithClaims(accessToken, jwt.MapClaims{}, func(token *jwt.Token) (interface{}, error) {
 		return []byte(secretKey), nil
 	})
 
-	if err != nil || !token.Valid {
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, fmt.Errorf("token is invalid")
+	}
+	return token.Claims.(jwt.MapClaims), nil
+}
+
+func AuthRequired(c *gin.Context) {
+	claims, err := ParseToken(c)
+	if err != nil {
+		metrics.TokenFailures.WithLabelValues(tokenFailure(c, err)).Inc()
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		c.AbortWithError(http.StatusUnauthorized, err)
 		return
 	}
 
-	claims := token.Claims.(jwt.MapClaims)
 	c.Set("payload", claims)
+	if username, ok := claims["username"].(string); ok {
+		c.Request = c.Request.WithContext(database.WithActor(c.Request.Context(), username))
+	}
 	c.Next()
 }
+
+func tokenFailure(c *gin.Context, err error) string {
+	switch {
+	case c.GetHeader("Authorization") == "":
+		return "missing"
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return "expired"
+	}
+	return "invalid"
+}
+
+// ClaimRole returns the caller's role. Tokens issued before roles existed
+// carry none and get the least privileged role.
+func ClaimRole(claims jwt.MapClaims) string {
+	if role, ok := claims["role"].(string); ok && role != "" {
+		return role
+	}
+	return "receptionist"
+}
+
+// RoleRequired only lets callers with one of roles through. It must run
+// after AuthRequired.
+func RoleRequired(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := ClaimRole(c.MustGet("payload").(jwt.MapClaims))
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		c.Abort()
+	}
+}
+
+// ClaimUint reads a numeric claim, which JSON decoding leaves as float64.
+func ClaimUint(claims jwt.MapClaims, key string) (uint, bool) {
+	value, ok := claims[key].(float64)
+	if !ok || value < 0 {
+		return 0, false
+	}
+	return uint(value), true
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"agnos/pkg/logging"
+	"log/slog"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// requestIds are the caller supplied IDs we accept; anything else is
+// replaced so that headers cannot inject into the logs.
+var requestIds = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestID reads X-Request-ID or generates one, echoes it in the response
+// and puts it in the request context for the loggers and repositories.
+func RequestID(c *gin.Context) {
+	id := c.GetHeader(RequestIDHeader)
+	if !requestIds.MatchString(id) {
+		id = logging.NewRequestID()
+	}
+
+	c.Set("request_id", id)
+	c.Header(RequestIDHeader, id)
+	c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+	c.Next()
+}
+
+// RequestLogger logs one line per request. It logs the route template
+// rather than the URL because search queries carry national IDs.
+func RequestLogger(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		level := slog.LevelInfo
+		if c.Writer.Status() >= 500 {
+			level = slog.LevelError
+		}
+		attrs := []any{
+			slog.String("method", c.Request.Method),
+			slog.String("route", route),
+			slog.Int("status", c.Writer.Status()),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("error", c.Errors.String()))
+		}
+		logger.Log(c.Request.Context(), level, "request", attrs...)
+	}
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Recovery turns a panic into a 500 and logs it. Unlike gin.Recovery it
+// passes http.ErrAbortHandler on to the server, which is how a handler
+// drops a connection whose response has already started.
+func Recovery(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+			logger.ErrorContext(c.Request.Context(), "panic recovered", "error", fmt.Sprint(err), "stack", string(debug.Stack()))
+			c.AbortWithStatus(http.StatusInternalServerError)
+		}()
+		c.Next()
+	}
+}
@@ -0,0 +1,388 @@
+// Package migrate applies ordered, versioned SQL migrations and records them
+// in a schema version table.
+//
+// Migrations are files named NNNN_name.up.sql and NNNN_name.down.sql. The
+// version is the numeric prefix; a missing down file makes that migration
+// irreversible. Statements in a file are separated by a semicolon at the end
+// of a line, ignoring any trailing comment, and each migration runs in its
+// own transaction.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const Table = "schema_migrations"
+
+// lockId keys the Postgres advisory lock and lockName the MySQL named lock
+// that serialise migrators started at the same time, for example by several
+// replicas.
+const (
+	lockId   = 4304301
+	lockName = "agnos_migrate"
+)
+
+var (
+	ErrSchemaBehind = errors.New("schema is behind")
+	ErrIrreversible = errors.New("migration has no down file")
+)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	// Unknown marks a version recorded in the database that this binary
+	// does not ship, as after a rollback to an older build.
+	Unknown bool `json:"unknown,omitempty"`
+}
+
+type appliedMigration struct {
+	Version   int64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (appliedMigration) TableName() string {
+	return Table
+}
+
+// Load reads the migrations in the root of fsys, ordered by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		version, name, direction, err := parseName(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		} else if migration.Name != name {
+			return nil, fmt.Errorf("migration %d has two names: %q and %q", version, migration.Name, name)
+		}
+		if direction == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if strings.TrimSpace(migration.Up) == "" {
+			return nil, fmt.Errorf("migration %s has no up file", migration)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func parseName(fileName string) (version int64, name string, direction string, err error) {
+	base := strings.TrimSuffix(fileName, ".sql")
+	switch {
+	case strings.HasSuffix(base, ".up"):
+		direction = "up"
+	case strings.HasSuffix(base, ".down"):
+		direction = "down"
+	default:
+		return 0, "", "", fmt.Errorf("migration file %s must end in .up.sql or .down.sql", fileName)
+	}
+	base = strings.TrimSuffix(base, "."+direction)
+	prefix, name, ok := strings.Cut(base, "_")
+	if !ok || name == "" {
+		return 0, "", "", fmt.Errorf("migration file %s must be named NNNN_name.%s.sql", fileName, direction)
+	}
+	version, err = strconv.ParseInt(prefix, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, "", "", fmt.Errorf("migration file %s has an invalid version", fileName)
+	}
+	return version, name, direction, nil
+}
+
+// Statements splits a migration into the statements it runs.
+func Statements(sql string) []string {
+	var statements []string
+	var current strings.Builder
+	flush := func() {
+		statement := strings.TrimSpace(current.String())
+		current.Reset()
+		if hasCode(statement) {
+			statements = append(statements, statement)
+		}
+	}
+	for _, line := range strings.SplitAfter(sql, "\n") {
+		current.WriteString(line)
+		code, _, _ := strings.Cut(line, "--")
+		if strings.HasSuffix(strings.TrimSpace(code), ";") {
+			flush()
+		}
+	}
+	flush()
+	return statements
+}
+
+// hasCode reports whether a statement is more than comments.
+func hasCode(statement string) bool {
+	for _, line := range strings.Split(statement, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "--") {
+			return true
+		}
+	}
+	return false
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+func New(db *gorm.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Up applies every pending migration in order and returns those applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for _, migration := range pending {
+		applied, err := m.apply(ctx, migration)
+		if err != nil {
+			return done, fmt.Errorf("migration %s: %w", migration, err)
+		}
+		if applied {
+			done = append(done, migration)
+		}
+	}
+	return done, nil
+}
+
+func (m *Migrator) apply(ctx context.Context, migration Migration) (applied bool, err error) {
+	err = m.transaction(ctx, func(tx *gorm.DB) error {
+		// Another migrator may have applied it while this one waited.
+		var count int64
+		if err := tx.Model(&appliedMigration{}).Where("version = ?", migration.Version).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+		if err := exec(tx, migration.Up); err != nil {
+			return err
+		}
+		applied = true
+		return tx.Create(&appliedMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now().UTC()}).Error
+	})
+	return applied, err
+}
+
+// Down rolls back the latest steps applied migrations, newest first, and
+// returns those rolled back.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	known := map[int64]Migration{}
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+
+	var done []Migration
+	for i := len(applied) - 1; i >= 0 && len(done) < steps; i-- {
+		migration, ok := known[applied[i].Version]
+		if !ok {
+			return done, fmt.Errorf("migration %d is applied but not shipped with this build", applied[i].Version)
+		}
+		if strings.TrimSpace(migration.Down) == "" {
+			return done, fmt.Errorf("migration %s: %w", migration, ErrIrreversible)
+		}
+		err := m.transaction(ctx, func(tx *gorm.DB) error {
+			if err := exec(tx, migration.Down); err != nil {
+				return err
+			}
+			return tx.Where("version = ?", migration.Version).Delete(&appliedMigration{}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %s: %w", migration, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Status lists every known migration and whether it is applied, followed by
+// any applied versions this build does not know about.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]appliedMigration{}
+	for _, row := range applied {
+		byVersion[row.Version] = row
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if row, ok := byVersion[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			delete(byVersion, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, row := range applied {
+		if _, ok := byVersion[row.Version]; ok {
+			appliedAt := row.AppliedAt
+			statuses = append(statuses, Status{Version: row.Version, Name: row.Name, Applied: true, AppliedAt: &appliedAt, Unknown: true})
+		}
+	}
+	return statuses, nil
+}
+
+// Pending returns the known migrations that are not applied yet.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	done := map[int64]bool{}
+	for _, row := range applied {
+		done[row.Version] = true
+	}
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if !done[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Check returns ErrSchemaBehind when any migration is pending, so that a
+// build never serves against a schema older than the one it expects.
+func (m *Migrator) Check(ctx context.Context) error {
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d pending migration(s), first %s", ErrSchemaBehind, len(pending), pending[0])
+	}
+	return nil
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	return m.db.WithContext(ctx).Exec("CREATE TABLE IF NOT EXISTS " + Table + " (version BIGINT NOT NULL PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at TIMESTAMP NOT NULL)").Error
+}
+
+// applied reads the version table without creating it, so that status and
+// checks stay read-only.
+func (m *Migrator) applied(ctx context.Context) ([]appliedMigration, error) {
+	db := m.db.WithContext(ctx)
+	if !db.Migrator().HasTable(Table) {
+		return nil, nil
+	}
+	var rows []appliedMigration
+	if err := db.Order("version").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func exec(tx *gorm.DB, sql string) error {
+	for _, statement := range Statements(sql) {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// transaction runs fn in a transaction that holds the migration lock. On
+// Postgres the advisory lock ends with the transaction. MySQL's named lock
+// belongs to the session, so it is taken on a pinned connection and released
+// after the commit. SQLite allows one writer at a time and takes no lock of
+// its own.
+func (m *Migrator) transaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	db := m.db.WithContext(ctx)
+	switch db.Dialector.Name() {
+	case "postgres":
+		return db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", lockId).Error; err != nil {
+				return err
+			}
+			return fn(tx)
+		})
+	case "mysql":
+		return db.Connection(func(conn *gorm.DB) error {
+			var locked sql.NullInt64
+			if err := conn.Raw("SELECT GET_LOCK(?, -1)", lockName).Row().Scan(&locked); err != nil {
+				return err
+			}
+			if locked.Int64 != 1 {
+				return fmt.Errorf("could not take lock %q", lockName)
+			}
+			err := conn.Transaction(fn)
+			if releaseErr := conn.Exec("SELECT RELEASE_LOCK(?)", lockName).Error; err == nil {
+				err = releaseErr
+			}
+			return err
+		})
+	default:
+		return db.Transaction(fn)
+	}
+}
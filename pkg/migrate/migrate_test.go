@@ -0,0 +1,60 @@
+package migrate_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"agnos/pkg/migrate"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad_OrdersAndPairsFiles(t *testing.T) {
+	migrations, err := migrate.Load(fstest.MapFS{
+		"0002_add_index.up.sql":        {Data: []byte("CREATE INDEX a ON t (b);")},
+		"0001_initial.up.sql":          {Data: []byte("CREATE TABLE t (b TEXT);")},
+		"0001_initial.down.sql":        {Data: []byte("DROP TABLE t;")},
+		"README.md":                    {Data: []byte("not a migration")},
+		"0010_backfill_names.up.sql":   {Data: []byte("UPDATE t SET b = '';")},
+		"0010_backfill_names.down.sql": {Data: []byte("SELECT 1;")},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, migrations, 3)
+	assert.Equal(t, "0001_initial", migrations[0].String())
+	assert.Equal(t, "DROP TABLE t;", migrations[0].Down)
+	assert.Equal(t, int64(2), migrations[1].Version)
+	assert.Empty(t, migrations[1].Down)
+	assert.Equal(t, "backfill_names", migrations[2].Name)
+}
+
+func TestLoad_RejectsBadFiles(t *testing.T) {
+	for name, fsys := range map[string]fstest.MapFS{
+		"no direction":  {"0001_initial.sql": {Data: []byte("SELECT 1;")}},
+		"no version":    {"initial.up.sql": {Data: []byte("SELECT 1;")}},
+		"zero version":  {"0000_initial.up.sql": {Data: []byte("SELECT 1;")}},
+		"down only":     {"0001_initial.down.sql": {Data: []byte("SELECT 1;")}},
+		"name mismatch": {"0001_a.up.sql": {Data: []byte("SELECT 1;")}, "0001_b.down.sql": {Data: []byte("SELECT 1;")}},
+		"empty up":      {"0001_initial.up.sql": {Data: []byte("  \n")}},
+	} {
+		_, err := migrate.Load(fsys)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestStatements(t *testing.T) {
+	statements := migrate.Statements(`-- leading comment
+CREATE TABLE t (
+    a TEXT,
+    b TEXT
+);
+CREATE INDEX i ON t (a); -- trailing comment
+CREATE INDEX j ON t (b) WHERE a = 'x;y';
+
+-- only a comment;
+`)
+	assert.Equal(t, []string{
+		"-- leading comment\nCREATE TABLE t (\n    a TEXT,\n    b TEXT\n);",
+		"CREATE INDEX i ON t (a); -- trailing comment",
+		"CREATE INDEX j ON t (b) WHERE a = 'x;y';",
+	}, statements)
+}
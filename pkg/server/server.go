@@ -0,0 +1,182 @@
+// Package server builds the hardened HTTP server: timeouts, header limits
+// and optional TLS or mutual TLS, all read from the environment.
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	Addr              string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration
+	MaxHeaderBytes    int
+
+	// ShutdownDrainDelay is how long readiness fails before shutdown
+	// starts, so that load balancers stop sending requests first.
+	ShutdownDrainDelay time.Duration
+
+	// RequestTimeout bounds the context of a request, and so the queries
+	// it makes. RouteTimeouts overrides it by "METHOD /route", with the
+	// route as registered.
+	RequestTimeout time.Duration
+	RouteTimeouts  map[string]time.Duration
+
+	// CertFile and KeyFile turn on TLS. ClientCAFile additionally requires
+	// every client to present a certificate signed by one of its CAs.
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string
+}
+
+// ConfigFromEnv reads the HTTP_* and TLS_* variables through getEnv,
+// which returns the fallback when a variable is unset.
+func ConfigFromEnv(getEnv func(key, fallback string) string) (Config, error) {
+	config := Config{
+		Addr:         getEnv("HTTP_ADDR", ":8080"),
+		CertFile:     getEnv("TLS_CERT_FILE", ""),
+		KeyFile:      getEnv("TLS_KEY_FILE", ""),
+		ClientCAFile: getEnv("TLS_CLIENT_CA_FILE", ""),
+	}
+
+	durations := []struct {
+		key      string
+		fallback string
+		target   *time.Duration
+	}{
+		{"HTTP_READ_TIMEOUT", "15s", &config.ReadTimeout},
+		{"HTTP_READ_HEADER_TIMEOUT", "5s", &config.ReadHeaderTimeout},
+		// Exports stream large files, so writes get longer than reads.
+		{"HTTP_WRITE_TIMEOUT", "120s", &config.WriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", "120s", &config.IdleTimeout},
+		{"SHUTDOWN_TIMEOUT", "30s", &config.ShutdownTimeout},
+		{"SHUTDOWN_DRAIN_DELAY", "5s", &config.ShutdownDrainDelay},
+		{"HTTP_REQUEST_TIMEOUT", "30s", &config.RequestTimeout},
+	}
+	for _, d := range durations {
+		value, err := time.ParseDuration(getEnv(d.key, d.fallback))
+		if err != nil {
+			return config, fmt.Errorf("%s is invalid: %w", d.key, err)
+		}
+		*d.target = value
+	}
+
+	maxHeaderBytes, err := strconv.Atoi(getEnv("HTTP_MAX_HEADER_BYTES", "1048576"))
+	if err != nil {
+		return config, fmt.Errorf("HTTP_MAX_HEADER_BYTES is invalid: %w", err)
+	}
+	config.MaxHeaderBytes = maxHeaderBytes
+
+	// Exports stream for as long as writes are allowed to.
+	config.RouteTimeouts = map[string]time.Duration{"GET /patient/export": config.WriteTimeout}
+	if err := parseRouteTimeouts(getEnv("HTTP_ROUTE_TIMEOUTS", ""), config.RouteTimeouts); err != nil {
+		return config, fmt.Errorf("HTTP_ROUTE_TIMEOUTS is invalid: %w", err)
+	}
+
+	if (config.CertFile == "") != (config.KeyFile == "") {
+		return config, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if config.ClientCAFile != "" && config.CertFile == "" {
+		return config, errors.New("TLS_CLIENT_CA_FILE needs TLS_CERT_FILE and TLS_KEY_FILE")
+	}
+	return config, nil
+}
+
+// parseRouteTimeouts reads "METHOD /route=duration" pairs separated by
+// commas into timeouts.
+func parseRouteTimeouts(value string, timeouts map[string]time.Duration) error {
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		route, duration, ok := strings.Cut(pair, "=")
+		if !ok || len(strings.Fields(route)) != 2 {
+			return fmt.Errorf("%q is not METHOD /route=duration", pair)
+		}
+		timeout, err := time.ParseDuration(strings.TrimSpace(duration))
+		if err != nil {
+			return err
+		}
+		timeouts[strings.Join(strings.Fields(route), " ")] = timeout
+	}
+	return nil
+}
+
+func (c Config) TLS() bool {
+	return c.CertFile != ""
+}
+
+// New returns a server for handler configured by config.
+func New(config Config, handler http.Handler) (*http.Server, error) {
+	server := &http.Server{
+		Addr:              config.Addr,
+		Handler:           handler,
+		ReadTimeout:       config.ReadTimeout,
+		ReadHeaderTimeout: config.ReadHeaderTimeout,
+		WriteTimeout:      config.WriteTimeout,
+		IdleTimeout:       config.IdleTimeout,
+		MaxHeaderBytes:    config.MaxHeaderBytes,
+	}
+	if !config.TLS() {
+		return server, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load TLS certificate: %w", err)
+	}
+	server.TLSConfig = &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{certificate},
+	}
+
+	if config.ClientCAFile != "" {
+		pem, err := os.ReadFile(config.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("client CA file holds no certificate")
+		}
+		server.TLSConfig.ClientCAs = pool
+		server.TLSConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return server, nil
+}
+
+// Serve serves on listener, over TLS when the server has a TLS config. It
+// returns nil once the server is shut down.
+func Serve(server *http.Server, listener net.Listener) error {
+	var err error
+	if server.TLSConfig != nil {
+		err = server.ServeTLS(listener, "", "")
+	} else {
+		err = server.Serve(listener)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// ListenAndServe listens on the server's address and serves.
+func ListenAndServe(server *http.Server) error {
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return err
+	}
+	return Serve(server, listener)
+}
@@ -0,0 +1,188 @@
+package server_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"agnos/pkg/server"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func envOf(values map[string]string) func(string, string) string {
+	return func(key, fallback string) string {
+		if value, ok := values[key]; ok {
+			return value
+		}
+		return fallback
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	config, err := server.ConfigFromEnv(envOf(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, ":8080", config.Addr)
+	assert.Equal(t, 5*time.Second, config.ReadHeaderTimeout)
+	assert.Equal(t, 30*time.Second, config.ShutdownTimeout)
+	assert.Equal(t, 5*time.Second, config.ShutdownDrainDelay)
+	assert.Equal(t, 1<<20, config.MaxHeaderBytes)
+	assert.False(t, config.TLS())
+
+	assert.Equal(t, 30*time.Second, config.RequestTimeout)
+	assert.Equal(t, map[string]time.Duration{"GET /patient/export": 120 * time.Second}, config.RouteTimeouts)
+
+	_, err = server.ConfigFromEnv(envOf(map[string]string{"HTTP_READ_TIMEOUT": "soon"}))
+	assert.Error(t, err)
+	config, err = server.ConfigFromEnv(envOf(map[string]string{"SHUTDOWN_DRAIN_DELAY": "0s"}))
+	assert.NoError(t, err)
+	assert.Zero(t, config.ShutdownDrainDelay)
+	_, err = server.ConfigFromEnv(envOf(map[string]string{"HTTP_ROUTE_TIMEOUTS": "/patient/search=5s"}))
+	assert.Error(t, err)
+	_, err = server.ConfigFromEnv(envOf(map[string]string{"TLS_CERT_FILE": "cert.pem"}))
+	assert.Error(t, err)
+	_, err = server.ConfigFromEnv(envOf(map[string]string{"TLS_CLIENT_CA_FILE": "ca.pem"}))
+	assert.Error(t, err)
+}
+
+func TestConfigFromEnv_RouteTimeouts(t *testing.T) {
+	config, err := server.ConfigFromEnv(envOf(map[string]string{
+		"HTTP_ROUTE_TIMEOUTS": "GET /patient/search=5s, POST  /retention/run=10m,GET /patient/export=5m",
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]time.Duration{
+		"GET /patient/search": 5 * time.Second,
+		"POST /retention/run": 10 * time.Minute,
+		"GET /patient/export": 5 * time.Minute,
+	}, config.RouteTimeouts)
+}
+
+func TestShutdown_DrainsInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	srv, err := server.New(server.Config{ReadHeaderTimeout: time.Second}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("created"))
+	}))
+	assert.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(srv, listener) }()
+
+	type result struct {
+		body string
+		err  error
+	}
+	response := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err != nil {
+			response <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		response <- result{body: string(body)}
+	}()
+
+	<-started
+	assert.NoError(t, srv.Shutdown(context.Background()))
+	got := <-response
+	assert.NoError(t, got.err)
+	assert.Equal(t, "created", got.body)
+	assert.NoError(t, <-served)
+}
+
+type pair struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+	der  []byte
+}
+
+func issue(t *testing.T, name string, parent *pair, usage x509.ExtKeyUsage) *pair {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA, template.BasicConstraintsValid = true, true
+		template.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	assert.NoError(t, err)
+	cert, _ := x509.ParseCertificate(der)
+	return &pair{cert: cert, key: key, der: der, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+func (p *pair) tls() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{p.der}, PrivateKey: p.key}
+}
+
+func TestMutualTLS_RequiresClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca := issue(t, "hospital ca", nil, x509.ExtKeyUsageAny)
+	serverPair := issue(t, "agnos", ca, x509.ExtKeyUsageServerAuth)
+	clientPair := issue(t, "partner", ca, x509.ExtKeyUsageClientAuth)
+
+	keyDer, err := x509.MarshalECPrivateKey(serverPair.key)
+	assert.NoError(t, err)
+	os.WriteFile(filepath.Join(dir, "ca.pem"), ca.pem, 0o600)
+	os.WriteFile(filepath.Join(dir, "cert.pem"), serverPair.pem, 0o600)
+	os.WriteFile(filepath.Join(dir, "key.pem"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600)
+
+	config, err := server.ConfigFromEnv(envOf(map[string]string{
+		"TLS_CERT_FILE":      filepath.Join(dir, "cert.pem"),
+		"TLS_KEY_FILE":       filepath.Join(dir, "key.pem"),
+		"TLS_CLIENT_CA_FILE": filepath.Join(dir, "ca.pem"),
+	}))
+	assert.NoError(t, err)
+	srv, err := server.New(config, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	assert.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go server.Serve(srv, listener)
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	url := "https://" + listener.Addr().String()
+
+	anonymous := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	_, err = anonymous.Get(url)
+	assert.Error(t, err)
+
+	partner := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{clientPair.tls()}}}}
+	resp, err := partner.Get(url)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "partner", string(body))
+}
@@ -0,0 +1,71 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const spanKey = "tracing:span"
+
+// GormPlugin opens a client span around every GORM statement. The span
+// carries the SQL with its placeholders only; parameters are never
+// recorded because they hold national IDs and password hashes.
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string {
+	return "tracing"
+}
+
+func (p GormPlugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	for _, err := range []error{
+		callback.Create().Before("gorm:create").Register("tracing:before_create", before("create")),
+		callback.Create().After("gorm:create").Register("tracing:after_create", after),
+		callback.Query().Before("gorm:query").Register("tracing:before_query", before("query")),
+		callback.Query().After("gorm:query").Register("tracing:after_query", after),
+		callback.Update().Before("gorm:update").Register("tracing:before_update", before("update")),
+		callback.Update().After("gorm:update").Register("tracing:after_update", after),
+		callback.Delete().Before("gorm:delete").Register("tracing:before_delete", before("delete")),
+		callback.Delete().After("gorm:delete").Register("tracing:after_delete", after),
+		callback.Row().Before("gorm:row").Register("tracing:before_row", before("row")),
+		callback.Row().After("gorm:row").Register("tracing:after_row", after),
+		callback.Raw().Before("gorm:raw").Register("tracing:before_raw", before("raw")),
+		callback.Raw().After("gorm:raw").Register("tracing:after_raw", after),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func before(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		_, span := Tracer("gorm").Start(db.Statement.Context, "gorm."+operation, trace.WithSpanKind(trace.SpanKindClient))
+		span.SetAttributes(attribute.String("db.system", db.Dialector.Name()), attribute.String("db.operation", operation))
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+func after(db *gorm.DB) {
+	value, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("db.sql.table", db.Statement.Table),
+		attribute.String("db.statement", db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.RowsAffected),
+	)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
@@ -0,0 +1,51 @@
+package tracing_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"agnos/pkg/middleware"
+	"agnos/pkg/tracing"
+	"agnos/pkg/tracing/tracingtest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func attributeOf(span tracetest.SpanStub, key string) attribute.Value {
+	for _, attr := range span.Attributes {
+		if string(attr.Key) == key {
+			return attr.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracing_ContinuesW3CTraceByRouteTemplate(t *testing.T) {
+	exporter := tracingtest.SetupInMemory()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.Tracing)
+	r.GET("/patient/search/:id", func(c *gin.Context) {
+		_, span := tracing.Tracer("test").Start(c.Request.Context(), "child")
+		span.End()
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", "/patient/search/1234567890123", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	assert.Equal(t, 2, len(spans))
+	child, server := spans[0], spans[1]
+	assert.Equal(t, "GET /patient/search/:id", server.Name)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent.SpanID().String())
+	assert.Equal(t, server.SpanContext.SpanID(), child.Parent.SpanID())
+	assert.Equal(t, int64(200), attributeOf(server, "http.response.status_code").AsInt64())
+}
@@ -0,0 +1,19 @@
+// Package tracingtest records spans in memory for tests.
+package tracingtest
+
+import (
+	"agnos/pkg/tracing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// SetupInMemory installs a provider that exports synchronously to memory
+// and returns the exporter holding the spans.
+func SetupInMemory() *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	otel.SetTextMapPropagator(tracing.Propagator())
+	return exporter
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	adaptersHl7 "agnos/internal/adapters/hl7"
+	"agnos/internal/routes"
+	"agnos/pkg/logging"
+	"agnos/pkg/metrics"
+	"agnos/pkg/middleware"
+	"agnos/pkg/server"
+	"agnos/pkg/tracing"
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serve runs the API, the MLLP listener and the background workers until
+// SIGINT or SIGTERM, then drains them in order.
+func serve() {
+	shutdownTracing, err := tracing.Setup(context.Background(), getEnv("OTEL_SERVICE_NAME", "agnos"), getEnv("OTEL_TRACES_EXPORTER", tracing.ExporterNone))
+	if err != nil {
+		panic("Can't set up tracing: " + err.Error())
+	}
+
+	db := openMigratedDatabase()
+	keyring := loadKeyring(db)
+	if backfilled, err := routes.KeyService(db, keyring).BackfillIndexes(context.Background()); err != nil {
+		panic("Can't backfill blind indexes: " + err.Error())
+	} else if backfilled > 0 {
+		logging.For("keys").Info("backfilled blind indexes", "patients", backfilled)
+	}
+
+	serverConfig, err := server.ConfigFromEnv(getEnv)
+	if err != nil {
+		panic("Can't load server config: " + err.Error())
+	}
+
+	router := gin.New()
+	router.Use(middleware.Tracing, middleware.RequestID, middleware.RequestLogger(logging.For("http")), middleware.Metrics, middleware.Recovery(logging.For("http")),
+		middleware.Timeout(serverConfig.RequestTimeout, serverConfig.RouteTimeouts))
+
+	repos := routes.GormRepositories(db)
+	routes.StaffRoutes(&router.RouterGroup, repos)
+
+	importService := routes.ImportService(repos)
+	routes.PatientRoutes(&router.RouterGroup, repos, importService)
+
+	routes.ConsentRoutes(&router.RouterGroup, repos)
+
+	routes.SharingRoutes(&router.RouterGroup, repos)
+
+	routes.DsarRoutes(&router.RouterGroup, repos)
+
+	retentionService := routes.RetentionService(repos)
+	routes.RetentionRoutes(&router.RouterGroup, retentionService)
+	if interval := getEnv("RETENTION_INTERVAL", ""); interval != "" {
+		every, err := time.ParseDuration(interval)
+		if err != nil {
+			panic("RETENTION_INTERVAL is invalid: " + err.Error())
+		}
+		retentionService.Start(every, getEnv("RETENTION_DRY_RUN", "false") == "true")
+	}
+
+	// Readiness fails as soon as shutdown starts so that load balancers stop
+	// routing here while in-flight requests drain.
+	var draining atomic.Bool
+	checker := routes.HealthChecker(db)
+	checker.Register("shutdown", func(ctx context.Context) error {
+		if draining.Load() {
+			return errors.New("server is shutting down")
+		}
+		return nil
+	})
+	routes.HealthRoutes(&router.RouterGroup, db, checker)
+
+	routes.MpiRoutes(&router.RouterGroup, repos)
+
+	routes.FhirRoutes(&router.RouterGroup, repos)
+
+	routes.Hl7Routes(&router.RouterGroup, repos)
+
+	var mllpListener *adaptersHl7.MllpHl7Listener
+	if mllpAddr := getEnv("HL7_MLLP_ADDR", ""); mllpAddr != "" {
+		messageTimeout, err := time.ParseDuration(getEnv("HL7_MESSAGE_TIMEOUT", "10s"))
+		if err != nil {
+			panic("HL7_MESSAGE_TIMEOUT is invalid: " + err.Error())
+		}
+		senders, err := adaptersHl7.ParseMllpSenders(getEnv("HL7_MLLP_SENDERS", ""))
+		if err != nil {
+			panic("HL7_MLLP_SENDERS is invalid: " + err.Error())
+		}
+		if len(senders) == 0 {
+			panic("HL7_MLLP_SENDERS is required with HL7_MLLP_ADDR")
+		}
+		mllpListener = routes.Hl7Listener(repos, senders, messageTimeout)
+		checker.Register("mllp", mllpListener.Check)
+		go func() {
+			if err := mllpListener.ListenAndServe(mllpAddr); err != nil && !errors.Is(err, net.ErrClosed) {
+				logging.For("hl7").Error("mllp listener stopped", "error", err.Error())
+			}
+		}()
+	}
+
+	// Metrics are served on their own port so that they are never exposed
+	// with the public API.
+	if err := metrics.RegisterExpvar("retention", "Retention worker counters by key."); err != nil {
+		panic("Can't register retention metrics: " + err.Error())
+	}
+	adminMux := http.NewServeMux()
+	adminMux.Handle("/metrics", metrics.Handler())
+	adminServer := &http.Server{Addr: getEnv("METRICS_ADDR", ":9090"), Handler: adminMux, ReadHeaderTimeout: serverConfig.ReadHeaderTimeout}
+	go func() {
+		if err := server.ListenAndServe(adminServer); err != nil {
+			logging.For("http").Error("metrics server stopped", "error", err.Error())
+		}
+	}()
+
+	router.GET("/hello", func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"message": "test",
+		})
+	})
+
+	apiServer, err := server.New(serverConfig, router)
+	if err != nil {
+		panic("Can't create server: " + err.Error())
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("server listening", "addr", serverConfig.Addr, "tls", serverConfig.TLS(), "mtls", serverConfig.ClientCAFile != "")
+		serveErr <- server.ListenAndServe(apiServer)
+	}()
+
+	select {
+	case err := <-serveErr:
+		if err != nil {
+			slog.Error("server stopped", "error", err.Error())
+		}
+	case <-ctx.Done():
+		slog.Info("shutdown signal received, draining")
+	}
+	// Readiness fails from here on. New requests are still served until
+	// load balancers have noticed and stopped sending them.
+	draining.Store(true)
+	time.Sleep(serverConfig.ShutdownDrainDelay)
+
+	// Shut down from the edge inwards: stop taking requests and messages,
+	// let background work finish, then flush traces and close the pool.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), serverConfig.ShutdownTimeout)
+	defer cancel()
+
+	if err := apiServer.Shutdown(shutdownCtx); err != nil {
+		slog.Error("http drain incomplete", "error", err.Error())
+	}
+	if mllpListener != nil {
+		if err := mllpListener.Shutdown(shutdownCtx); err != nil {
+			slog.Error("mllp drain incomplete", "error", err.Error())
+		}
+	}
+	if err := waitContext(shutdownCtx, retentionService.Stop); err != nil {
+		slog.Error("retention worker did not stop", "error", err.Error())
+	}
+	if err := waitContext(shutdownCtx, importService.Wait); err != nil {
+		slog.Error("imports did not finish", "error", err.Error())
+	}
+	adminServer.Shutdown(shutdownCtx)
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("trace flush failed", "error", err.Error())
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+	slog.Info("shutdown complete")
+}